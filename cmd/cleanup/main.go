@@ -0,0 +1,167 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// cleanup deletes the objects net-contour created, so that removing it
+// doesn't leave orphans confusing the networking layer replacing it: the
+// HTTPProxies it programmed, the KIngresses it probes Endpoints with and the
+// TLSCertificateDelegation of the default TLS secret.  net-contour copies no
+// Secrets.  Run it once the controller is gone, or it recreates them:
+//
+//	kubectl delete deployment/net-contour-controller -n knative-serving
+//	go run ./cmd/cleanup -dry-run
+//	go run ./cmd/cleanup
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"strings"
+
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	contourclientset "knative.dev/net-contour/pkg/client/clientset/versioned"
+	"knative.dev/net-contour/pkg/ownership"
+	"knative.dev/net-contour/pkg/reconciler/contour"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+	ingressclientset "knative.dev/networking/pkg/client/clientset/versioned"
+	"knative.dev/pkg/injection"
+	"knative.dev/pkg/signals"
+)
+
+var (
+	namespaces = flag.String("namespaces", "", "A comma separated list of namespaces to clean up, defaults to all of them.")
+	dryRun     = flag.Bool("dry-run", false, "Only print the objects that would be deleted.")
+)
+
+func main() {
+	cfg := injection.ParseAndGetRESTConfigOrDie()
+	ctx := signals.NewContext()
+	c := &cleaner{
+		contourClient: contourclientset.NewForConfigOrDie(cfg),
+		ingressClient: ingressclientset.NewForConfigOrDie(cfg),
+	}
+
+	deleted := 0
+	for _, ns := range namespacesToList() {
+		for _, clean := range []func(context.Context, string) (int, error){
+			c.cleanHTTPProxies,
+			c.cleanProbeIngresses,
+			c.cleanDelegations,
+		} {
+			n, err := clean(ctx, ns)
+			if err != nil {
+				log.Fatal("Error cleaning up: ", err)
+			}
+			deleted += n
+		}
+	}
+	if *dryRun {
+		log.Printf("Would delete %d objects.", deleted)
+	} else {
+		log.Printf("Deleted %d objects.", deleted)
+	}
+}
+
+type cleaner struct {
+	contourClient contourclientset.Interface
+	ingressClient ingressclientset.Interface
+}
+
+// cleanHTTPProxies deletes the HTTPProxies we programmed, which carry the
+// label of their parent KIngress.
+func (c *cleaner) cleanHTTPProxies(ctx context.Context, ns string) (int, error) {
+	proxies, err := c.contourClient.ProjectcontourV1().HTTPProxies(ns).List(ctx, metav1.ListOptions{
+		LabelSelector: ownership.Children().String(),
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, proxy := range proxies.Items {
+		if err := c.delete("HTTPProxy", proxy.Namespace, proxy.Name, func() error {
+			return c.contourClient.ProjectcontourV1().HTTPProxies(proxy.Namespace).Delete(ctx, proxy.Name, metav1.DeleteOptions{})
+		}); err != nil {
+			return 0, err
+		}
+	}
+	return len(proxies.Items), nil
+}
+
+// cleanProbeIngresses deletes the KIngresses we probe Endpoints with, which
+// carry the endpoints probe annotation.
+func (c *cleaner) cleanProbeIngresses(ctx context.Context, ns string) (int, error) {
+	ings, err := c.ingressClient.NetworkingV1alpha1().Ingresses(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, ing := range ings.Items {
+		if _, ok := ing.Annotations[resources.EndpointsProbeKey]; !ok {
+			continue
+		}
+		if err := c.delete("KIngress", ing.Namespace, ing.Name, func() error {
+			return c.ingressClient.NetworkingV1alpha1().Ingresses(ing.Namespace).Delete(ctx, ing.Name, metav1.DeleteOptions{})
+		}); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// cleanDelegations deletes the TLSCertificateDelegations we maintain next to
+// the default TLS secret.
+func (c *cleaner) cleanDelegations(ctx context.Context, ns string) (int, error) {
+	delegations, err := c.contourClient.ProjectcontourV1().TLSCertificateDelegations(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, d := range delegations.Items {
+		if d.Name != contour.DefaultTLSDelegationName {
+			continue
+		}
+		if err := c.delete("TLSCertificateDelegation", d.Namespace, d.Name, func() error {
+			return c.contourClient.ProjectcontourV1().TLSCertificateDelegations(d.Namespace).Delete(ctx, d.Name, metav1.DeleteOptions{})
+		}); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// delete runs the deletion of the object unless this is a dry run, and
+// ignores objects that are already gone.
+func (c *cleaner) delete(kind, namespace, name string, del func() error) error {
+	if *dryRun {
+		log.Printf("Would delete %s %s/%s.", kind, namespace, name)
+		return nil
+	}
+	if err := del(); err != nil && !apierrs.IsNotFound(err) {
+		return err
+	}
+	log.Printf("Deleted %s %s/%s.", kind, namespace, name)
+	return nil
+}
+
+func namespacesToList() []string {
+	if *namespaces == "" {
+		return []string{metav1.NamespaceAll}
+	}
+	return strings.Split(*namespaces, ",")
+}
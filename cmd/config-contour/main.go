@@ -0,0 +1,130 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// config-contour checks config-contour ConfigMaps against the version of
+// net-contour it is built from, e.g. in the CI of the repositories holding
+// the platform's configuration.
+//
+// The schema subcommand prints the JSON schema of config-contour, which
+// editors and generic linters can check the ConfigMap with.  The schema we
+// ship is generated with it:
+//
+//	go run ./cmd/config-contour schema > schema/config-contour.json
+//
+// The validate-config subcommand parses the config-contour and
+// config-contour-features ConfigMaps of the given manifests exactly like the
+// controller does, and also fails on the config-contour keys it doesn't
+// read, which are most likely misspelled:
+//
+//	go run ./cmd/config-contour validate-config config/config-contour.yaml
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+)
+
+const usage = `Usage:
+  config-contour schema
+  config-contour validate-config FILE...
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "schema":
+		b, err := config.ContourSchema()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error generating the schema:", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(b))
+	case "validate-config":
+		if len(os.Args) < 3 {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		failed := false
+		for _, file := range os.Args[2:] {
+			for _, err := range validateFile(file) {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+				failed = true
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// validateFile returns the problems of the config-contour and
+// config-contour-features ConfigMaps among the documents of the file.  Other
+// objects are ignored.
+func validateFile(file string) []error {
+	f, err := os.Open(file)
+	if err != nil {
+		return []error{err}
+	}
+	defer f.Close()
+
+	var errs []error
+	decoder := k8syaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		cm := &corev1.ConfigMap{}
+		if err := decoder.Decode(cm); errors.Is(err, io.EOF) {
+			return errs
+		} else if err != nil {
+			return append(errs, err)
+		}
+		if cm.Kind != "ConfigMap" {
+			continue
+		}
+		errs = append(errs, validate(cm)...)
+	}
+}
+
+// validate returns the problems of the ConfigMap when it is one of ours.
+func validate(cm *corev1.ConfigMap) []error {
+	switch cm.Name {
+	case config.ContourConfigName:
+		var errs []error
+		if _, err := config.NewContourFromConfigMap(cm); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", cm.Name, err))
+		}
+		for _, key := range config.UnknownContourKeys(cm) {
+			errs = append(errs, fmt.Errorf("%s: unknown key %q", cm.Name, key))
+		}
+		return errs
+	case config.FeaturesConfigName:
+		if _, err := config.NewFeaturesFromConfigMap(cm); err != nil {
+			return []error{fmt.Errorf("%s: %w", cm.Name, err)}
+		}
+	}
+	return nil
+}
@@ -17,13 +17,152 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"flag"
+	"os"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"k8s.io/client-go/rest"
+	"knative.dev/net-contour/pkg/client/clientset/versioned"
+	contourclient "knative.dev/net-contour/pkg/client/injection/client"
+	"knative.dev/pkg/injection"
+
 	// The set of controllers this controller process runs.
 	"knative.dev/net-contour/pkg/reconciler/contour"
+	"knative.dev/net-contour/pkg/reconciler/kubeingress"
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/leaderelection"
+	"knative.dev/pkg/logging"
 
 	// This defines the shared main for injected controllers.
 	"knative.dev/pkg/injection/sharedmain"
 )
 
+var (
+	debugAddress = flag.String("debug-address", "",
+		"The address (e.g. localhost:8009) to serve pprof profiles and internal gauges on, disabled when empty.")
+	contourAPIQPS = flag.Float64("contour-api-qps", 0,
+		"Maximum QPS of the writes of HTTPProxies to the server, defaults to the kube-api-qps.")
+	contourAPIBurst = flag.Int("contour-api-burst", 0,
+		"Maximum burst of the writes of HTTPProxies to the server, defaults to the kube-api-burst.")
+	skipStatusUpdates = flag.Bool("skip-status-updates", false,
+		"Only program HTTPProxies, leaving the status of KIngresses and probing the Envoys to an external component.")
+	translateKubernetesIngresses = flag.Bool("translate-kubernetes-ingresses", false,
+		"Watch the Kubernetes Ingresses of the cluster, and translate those of the kubernetes-ingress-class of config-contour.")
+)
+
+// envFlags are the environment variables setting the client limits, whether
+// we update statuses and whether we translate Kubernetes Ingresses, when
+// their flag isn't passed, so that they can be tuned without replacing the
+// arguments of the container.
+var envFlags = map[string]string{
+	"KUBE_API_QPS":                   "kube-api-qps",
+	"KUBE_API_BURST":                 "kube-api-burst",
+	"CONTOUR_API_QPS":                "contour-api-qps",
+	"CONTOUR_API_BURST":              "contour-api-burst",
+	"SKIP_STATUS_UPDATES":            "skip-status-updates",
+	"TRANSLATE_KUBERNETES_INGRESSES": "translate-kubernetes-ingresses",
+}
+
+const component = "net-contour-controller"
+
 func main() {
-	sharedmain.Main("net-contour-controller", contour.NewController)
+	os.Args = append(os.Args, flagsFromEnv(os.Args[1:], os.Getenv)...)
+	sharedmain.Main(component, newContourController, newKubeIngressController)
+}
+
+// newContourController runs contour.NewController once sharedmain parsed our
+// flags.
+func newContourController(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+	checkStatefulSetOrdinal(ctx)
+	ctx = withContourClientLimits(ctx)
+	return contour.NewControllerWithOptions(contour.WithDebugAddress(ctx, *debugAddress), cmw, contour.Options{
+		SkipStatusUpdates: *skipStatusUpdates,
+	})
+}
+
+// newKubeIngressController runs kubeingress.NewController, which only
+// watches the Kubernetes Ingresses of the cluster when we translate them.
+func newKubeIngressController(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+	return kubeingress.NewControllerWithOptions(ctx, cmw, kubeingress.Options{
+		Enabled: *translateKubernetesIngresses,
+	})
+}
+
+// flagsFromEnv returns the flags to add to args for the envFlags which are
+// set in the environment but not passed.
+func flagsFromEnv(args []string, getenv func(string) string) []string {
+	names := make([]string, 0, len(envFlags))
+	for env := range envFlags {
+		names = append(names, env)
+	}
+	sort.Strings(names)
+
+	var flags []string
+	for _, env := range names {
+		value := getenv(env)
+		if value == "" || passed(args, envFlags[env]) {
+			continue
+		}
+		flags = append(flags, "--"+envFlags[env]+"="+value)
+	}
+	return flags
+}
+
+// passed returns whether the flag with the given name is among args.
+func passed(args []string, name string) bool {
+	for _, arg := range args {
+		arg = strings.TrimLeft(arg, "-")
+		if arg == name || strings.HasPrefix(arg, name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// withContourClientLimits gives the reconciler a Contour client with its own
+// limits, so that programming HTTPProxies during large resync waves isn't
+// throttled along with the rest of our requests.  The informers keep the
+// client sharedmain built for them.
+func withContourClientLimits(ctx context.Context) context.Context {
+	if *contourAPIQPS == 0 && *contourAPIBurst == 0 {
+		return ctx
+	}
+	logger := logging.FromContext(ctx)
+	if *contourAPIQPS < 0 || *contourAPIBurst < 0 {
+		logger.Fatalf("contour-api-qps and contour-api-burst must not be negative, got %v and %d",
+			*contourAPIQPS, *contourAPIBurst)
+	}
+	cfg := rest.CopyConfig(injection.GetConfig(ctx))
+	if *contourAPIQPS > 0 {
+		cfg.QPS = float32(*contourAPIQPS)
+	}
+	if *contourAPIBurst > 0 {
+		cfg.Burst = *contourAPIBurst
+	}
+	logger.Infof("Writing HTTPProxies with a QPS of %v and a burst of %d.", cfg.QPS, cfg.Burst)
+	return context.WithValue(ctx, contourclient.Key{}, versioned.NewForConfigOrDie(cfg))
+}
+
+// checkStatefulSetOrdinal refuses to start a replica of the StatefulSet
+// deployment mode (see config/statefulset) whose ordinal has no bucket.
+// sharedmain would otherwise silently fall back to leader election, and
+// the replicas would no longer deterministically shard the Ingresses.
+func checkStatefulSetOrdinal(ctx context.Context) {
+	if os.Getenv("STATEFUL_CONTROLLER_ORDINAL") == "" {
+		return
+	}
+	logger := logging.FromContext(ctx)
+	cfg, err := sharedmain.GetLeaderElectionConfig(ctx)
+	if err != nil {
+		logger.Fatalw("Error loading leader election configuration", zap.Error(err))
+	}
+	buckets := cfg.GetComponentConfig(component).Buckets
+	if _, _, err := leaderelection.NewStatefulSetBucketAndSet(int(buckets)); err != nil {
+		logger.Fatalw("Unable to run in StatefulSet ordinal mode, the buckets of config-leader-election must match the replicas",
+			zap.Uint32("buckets", buckets), zap.Error(err))
+	}
 }
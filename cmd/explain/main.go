@@ -0,0 +1,85 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// explain prints why an Ingress isn't ready, as explained by the controller
+// serving debug information (see its -debug-address flag): the conditions of
+// the Ingress which aren't true, the HTTPProxies Contour didn't accept, the
+// Envoy pods which don't serve its current version and its missing secrets.
+//
+//	kubectl port-forward -n knative-serving deployment/net-contour-controller 8009 &
+//	go run ./cmd/explain ns/name
+//
+// It exits with a non-zero status when the Ingress isn't ready.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"knative.dev/net-contour/pkg/reconciler/contour"
+)
+
+var address = flag.String("address", "localhost:8009", "The debug address of the controller.")
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 1 {
+		log.Fatal("Usage: explain [-address host:port] namespace/name")
+	}
+	parts := strings.SplitN(flag.Arg(0), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		log.Fatalf("The Ingress must be given as namespace/name, got %q", flag.Arg(0))
+	}
+
+	u := url.URL{
+		Scheme:   "http",
+		Host:     *address,
+		Path:     "/debug/explain",
+		RawQuery: url.Values{"namespace": {parts[0]}, "name": {parts[1]}}.Encode(),
+	}
+	resp, err := http.Get(u.String())
+	if err != nil {
+		log.Fatal("Error reaching the controller: ", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Fatal("Error reading the explanation: ", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		log.Fatalf("The controller answered %d: %s", resp.StatusCode, body)
+	}
+
+	var exp contour.Explanation
+	if err := json.Unmarshal(body, &exp); err != nil {
+		log.Fatal("Error parsing the explanation: ", err)
+	}
+	out, err := json.MarshalIndent(exp, "", "  ")
+	if err != nil {
+		log.Fatal("Error printing the explanation: ", err)
+	}
+	fmt.Println(string(out))
+	if !exp.Ready {
+		os.Exit(1)
+	}
+}
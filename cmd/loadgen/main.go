@@ -0,0 +1,306 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// loadgen creates synthetic KIngresses for net-contour to reconcile, waits
+// for them to become ready and reports how long that took and how much API
+// churn it caused, to help capacity-plan a cluster.
+//
+// The KIngresses route to an existing Service that answers Knative's network
+// probes, e.g. the private Service of a Knative Revision:
+//
+//	go run ./cmd/loadgen -namespace=default -service=hello-00001-private -port=80 -count=500
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/cache"
+
+	contourclientset "knative.dev/net-contour/pkg/client/clientset/versioned"
+	contourinformers "knative.dev/net-contour/pkg/client/informers/externalversions"
+	"knative.dev/net-contour/pkg/reconciler/contour"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+	"knative.dev/networking/pkg/apis/networking"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	ingressclientset "knative.dev/networking/pkg/client/clientset/versioned"
+	ingressinformers "knative.dev/networking/pkg/client/informers/externalversions"
+	"knative.dev/pkg/injection"
+	"knative.dev/pkg/signals"
+)
+
+// runLabelKey labels the KIngresses of a run, so we can clean them up.
+const runLabelKey = "contour.networking.knative.dev/loadgen-run"
+
+var (
+	namespace   = flag.String("namespace", "default", "The namespace to create the KIngresses in.")
+	count       = flag.Int("count", 10, "The number of KIngresses to create.")
+	hosts       = flag.Int("hosts", 1, "The number of hosts of each KIngress.")
+	splits      = flag.Int("splits", 1, "The number of traffic splits of each KIngress.")
+	service     = flag.String("service", "", "The Service the KIngresses route to, which must answer Knative network probes.")
+	port        = flag.Int("port", 80, "The port of the Service.")
+	domain      = flag.String("domain", "example.com", "The domain of the hosts of the KIngresses.")
+	concurrency = flag.Int("concurrency", 10, "The number of KIngresses to create at once.")
+	timeout     = flag.Duration("timeout", 10*time.Minute, "How long to wait for the KIngresses to become ready.")
+	keep        = flag.Bool("keep", false, "Whether to keep the KIngresses instead of deleting them afterwards.")
+)
+
+func main() {
+	cfg := injection.ParseAndGetRESTConfigOrDie()
+	if *service == "" {
+		log.Fatal("-service is required")
+	}
+	if *count < 1 || *hosts < 1 || *splits < 1 || *splits > 100 || *concurrency < 1 {
+		log.Fatal("-count, -hosts and -concurrency must be positive, -splits must be between 1 and 100")
+	}
+
+	ctx := signals.NewContext()
+	ingressClient := ingressclientset.NewForConfigOrDie(cfg)
+	contourClient := contourclientset.NewForConfigOrDie(cfg)
+
+	run := fmt.Sprintf("loadgen-%d", time.Now().Unix())
+	tracker := newTracker(run)
+
+	ingressInformers := ingressinformers.NewSharedInformerFactoryWithOptions(ingressClient, 0,
+		ingressinformers.WithNamespace(*namespace))
+	ingressInformers.Networking().V1alpha1().Ingresses().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    tracker.onIngress,
+		UpdateFunc: func(_, obj interface{}) { tracker.onIngress(obj) },
+		DeleteFunc: tracker.onIngress,
+	})
+	contourInformers := contourinformers.NewSharedInformerFactoryWithOptions(contourClient, 0,
+		contourinformers.WithNamespace(*namespace))
+	contourInformers.Projectcontour().V1().HTTPProxies().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    tracker.onProxy,
+		UpdateFunc: func(_, obj interface{}) { tracker.onProxy(obj) },
+		DeleteFunc: tracker.onProxy,
+	})
+	ingressInformers.Start(ctx.Done())
+	contourInformers.Start(ctx.Done())
+	ingressInformers.WaitForCacheSync(ctx.Done())
+	contourInformers.WaitForCacheSync(ctx.Done())
+
+	if !*keep {
+		defer func() {
+			log.Printf("Deleting the KIngresses of %s.", run)
+			if err := ingressClient.NetworkingV1alpha1().Ingresses(*namespace).DeleteCollection(context.Background(),
+				metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: runLabelKey + "=" + run}); err != nil {
+				log.Print("Error deleting the KIngresses: ", err)
+			}
+		}()
+	}
+
+	log.Printf("Creating %d KIngresses with %d hosts and %d splits each.", *count, *hosts, *splits)
+	start := time.Now()
+	var (
+		wg    sync.WaitGroup
+		names = make(chan string)
+	)
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range names {
+				ing := makeIngress(run, name)
+				if _, err := ingressClient.NetworkingV1alpha1().Ingresses(*namespace).Create(ctx, ing, metav1.CreateOptions{}); err != nil {
+					log.Printf("Error creating KIngress %s: %v", name, err)
+					continue
+				}
+				tracker.created(name)
+			}
+		}()
+	}
+	for i := 0; i < *count && ctx.Err() == nil; i++ {
+		names <- fmt.Sprintf("%s-%d", run, i)
+	}
+	close(names)
+	wg.Wait()
+	createDuration := time.Since(start)
+
+	waitCtx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for !tracker.allReady() {
+		select {
+		case <-waitCtx.Done():
+			log.Print("Gave up waiting for the KIngresses to become ready.")
+			tracker.report(createDuration, time.Since(start))
+			return
+		case <-ticker.C:
+		}
+	}
+	tracker.report(createDuration, time.Since(start))
+}
+
+func makeIngress(run, name string) *v1alpha1.Ingress {
+	ingHosts := make([]string, 0, *hosts)
+	for i := 0; i < *hosts; i++ {
+		ingHosts = append(ingHosts, fmt.Sprintf("%s-%d.%s.%s", name, i, *namespace, *domain))
+	}
+	ingSplits := make([]v1alpha1.IngressBackendSplit, 0, *splits)
+	for i := 0; i < *splits; i++ {
+		percent := 100 / *splits
+		if i == 0 {
+			percent += 100 % *splits
+		}
+		ingSplits = append(ingSplits, v1alpha1.IngressBackendSplit{
+			IngressBackend: v1alpha1.IngressBackend{
+				ServiceName:      *service,
+				ServiceNamespace: *namespace,
+				ServicePort:      intstr.FromInt(*port),
+			},
+			Percent: percent,
+			AppendHeaders: map[string]string{
+				"Loadgen-Split": fmt.Sprint(i),
+			},
+		})
+	}
+	return &v1alpha1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: *namespace,
+			Labels: map[string]string{
+				runLabelKey: run,
+			},
+			Annotations: map[string]string{
+				networking.IngressClassAnnotationKey: contour.ContourIngressClassName,
+			},
+		},
+		Spec: v1alpha1.IngressSpec{
+			Rules: []v1alpha1.IngressRule{{
+				Hosts:      ingHosts,
+				Visibility: v1alpha1.IngressVisibilityExternalIP,
+				HTTP: &v1alpha1.HTTPIngressRuleValue{
+					Paths: []v1alpha1.HTTPIngressPath{{
+						Splits: ingSplits,
+					}},
+				},
+			}},
+		},
+	}
+}
+
+// tracker follows the KIngresses of a run and the resources net-contour
+// creates for them.
+type tracker struct {
+	run string
+
+	mu      sync.Mutex
+	start   map[string]time.Time
+	latency map[string]time.Duration
+
+	ingressEvents int64
+	proxyEvents   int64
+}
+
+func newTracker(run string) *tracker {
+	return &tracker{
+		run:     run,
+		start:   make(map[string]time.Time),
+		latency: make(map[string]time.Duration),
+	}
+}
+
+func (t *tracker) created(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.start[name] = time.Now()
+}
+
+func (t *tracker) onIngress(obj interface{}) {
+	if acc, err := accessor(obj); err != nil || !strings.HasPrefix(acc.GetName(), t.run+"-") {
+		return
+	}
+	// This also counts the endpoint probes net-contour creates.
+	atomic.AddInt64(&t.ingressEvents, 1)
+
+	ing, ok := obj.(*v1alpha1.Ingress)
+	if !ok || !ing.IsReady() || ing.Status.ObservedGeneration != ing.Generation {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if start, ok := t.start[ing.Name]; ok {
+		if _, ok := t.latency[ing.Name]; !ok {
+			t.latency[ing.Name] = time.Since(start)
+		}
+	}
+}
+
+func (t *tracker) onProxy(obj interface{}) {
+	if acc, err := accessor(obj); err == nil && strings.HasPrefix(acc.GetLabels()[resources.ParentKey], t.run+"-") {
+		atomic.AddInt64(&t.proxyEvents, 1)
+	}
+}
+
+func (t *tracker) allReady() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.latency) == len(t.start)
+}
+
+func (t *tracker) report(createDuration, totalDuration time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	latencies := make([]time.Duration, 0, len(t.latency))
+	for _, l := range t.latency {
+		latencies = append(latencies, l)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	fmt.Printf("Run:          %s\n", t.run)
+	fmt.Printf("Created:      %d KIngresses in %v\n", len(t.start), createDuration.Round(time.Millisecond))
+	fmt.Printf("Ready:        %d of %d after %v\n", len(latencies), len(t.start), totalDuration.Round(time.Millisecond))
+	if len(latencies) != 0 {
+		fmt.Printf("Convergence:  p50=%v p90=%v p99=%v max=%v\n",
+			percentile(latencies, 50), percentile(latencies, 90), percentile(latencies, 99),
+			latencies[len(latencies)-1].Round(time.Millisecond))
+	}
+	ingressEvents, proxyEvents := atomic.LoadInt64(&t.ingressEvents), atomic.LoadInt64(&t.proxyEvents)
+	fmt.Printf("API churn:    %d KIngress and %d HTTPProxy changes", ingressEvents, proxyEvents)
+	if len(t.start) != 0 {
+		fmt.Printf(" (%.1f per KIngress)", float64(ingressEvents+proxyEvents)/float64(len(t.start)))
+	}
+	fmt.Println()
+}
+
+// percentile returns the p-th percentile of the sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted)*p+99)/100 - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i].Round(time.Millisecond)
+}
+
+func accessor(obj interface{}) (metav1.Object, error) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	return meta.Accessor(obj)
+}
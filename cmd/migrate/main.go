@@ -0,0 +1,325 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// migrate moves the Knative Routes served by another networking layer to
+// net-contour, a batch of namespaces at a time.  It annotates the Routes of a
+// batch with the Contour ingress class, waits for net-contour to report their
+// KIngresses ready, and moves the batch back when they don't become ready in
+// time.  The class each Route had before is kept in an annotation, so that a
+// later run with -rollback can move them back:
+//
+//	go run ./cmd/migrate -from=istio -batch-size=5
+//	go run ./cmd/migrate -rollback
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+
+	"knative.dev/net-contour/pkg/reconciler/contour"
+	"knative.dev/networking/pkg/apis/networking"
+	ingressclientset "knative.dev/networking/pkg/client/clientset/versioned"
+	"knative.dev/pkg/injection"
+	"knative.dev/pkg/signals"
+)
+
+// migratedFromKey records on each Route we migrate the ingress class
+// annotation it had before, which is empty when it used the default class.
+const migratedFromKey = "contour.networking.knative.dev/migrated-from"
+
+var routeResource = schema.GroupVersionResource{Group: "serving.knative.dev", Version: "v1", Resource: "routes"}
+
+// classAliases are the short names of the ingress classes we migrate from.
+var classAliases = map[string]string{
+	"istio":   "istio.ingress.networking.knative.dev",
+	"kourier": "kourier.ingress.networking.knative.dev",
+}
+
+var (
+	from       = flag.String("from", "", "The ingress class to migrate from, e.g. istio, kourier or a full class name.")
+	namespaces = flag.String("namespaces", "", "A comma separated list of namespaces to migrate, defaults to all of them.")
+	batchSize  = flag.Int("batch-size", 1, "The number of namespaces to migrate at once.")
+	timeout    = flag.Duration("timeout", 5*time.Minute, "How long to wait for the KIngresses of a batch to become ready.")
+	rollback   = flag.Bool("rollback", false, "Move the Routes we migrated back to the class they had before.")
+	dryRun     = flag.Bool("dry-run", false, "Only print the Routes that would be moved.")
+)
+
+// route identifies a Route and the class annotation we give it.
+type route struct {
+	namespace, name string
+	// class is the ingress class annotation to set, or empty to remove it.
+	class string
+	// previous is what we record in migratedFromKey, or nil to remove it.
+	previous *string
+}
+
+func main() {
+	cfg := injection.ParseAndGetRESTConfigOrDie()
+	if *rollback == (*from != "") {
+		log.Fatal("Exactly one of -from and -rollback is required.")
+	}
+	if *batchSize < 1 {
+		log.Fatal("-batch-size must be positive")
+	}
+
+	ctx := signals.NewContext()
+	m := &migrator{
+		routes:    dynamic.NewForConfigOrDie(cfg).Resource(routeResource),
+		ingresses: ingressclientset.NewForConfigOrDie(cfg),
+	}
+
+	var (
+		batches [][]route
+		err     error
+	)
+	if *rollback {
+		batches, err = m.planRollback(ctx)
+	} else {
+		class := *from
+		if alias, ok := classAliases[class]; ok {
+			class = alias
+		}
+		batches, err = m.planMigration(ctx, class)
+	}
+	if err != nil {
+		log.Fatal("Error planning the migration: ", err)
+	}
+
+	for i, batch := range batches {
+		log.Printf("Batch %d of %d: %d Routes in %s.", i+1, len(batches), len(batch), batchNamespaces(batch))
+		if *dryRun {
+			for _, r := range batch {
+				log.Printf("  Would move %s/%s to %s.", r.namespace, r.name, classOrDefault(r.class))
+			}
+			continue
+		}
+		if err := m.move(ctx, batch); err != nil {
+			log.Fatalf("Error moving batch %d: %v", i+1, err)
+		}
+		if *rollback {
+			// The other networking layer reports its own readiness.
+			continue
+		}
+		if err := m.waitReady(ctx, batch); err != nil {
+			log.Printf("The KIngresses of batch %d did not become ready: %v", i+1, err)
+			log.Printf("Moving batch %d back.", i+1)
+			if err := m.move(ctx, reverse(batch)); err != nil {
+				log.Fatalf("Error moving batch %d back: %v", i+1, err)
+			}
+			log.Fatal("Stopped the migration, the previous batches stay on Contour.")
+		}
+		log.Printf("Batch %d is ready on Contour.", i+1)
+	}
+}
+
+type migrator struct {
+	routes    dynamic.NamespaceableResourceInterface
+	ingresses ingressclientset.Interface
+}
+
+// planMigration returns batches of the Routes whose KIngresses have the
+// given class.
+func (m *migrator) planMigration(ctx context.Context, class string) ([][]route, error) {
+	byNamespace := make(map[string][]route)
+	for _, ns := range namespacesToList() {
+		ings, err := m.ingresses.NetworkingV1alpha1().Ingresses(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, ing := range ings.Items {
+			if ing.Annotations[networking.IngressClassAnnotationKey] != class {
+				continue
+			}
+			owner := metav1.GetControllerOf(&ing)
+			if owner == nil || owner.Kind != "Route" {
+				log.Printf("Skipping KIngress %s/%s, which isn't owned by a Route.", ing.Namespace, ing.Name)
+				continue
+			}
+			r, err := m.routes.Namespace(ing.Namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			// An absent annotation means the Route used the default class.
+			previous := r.GetAnnotations()[networking.IngressClassAnnotationKey]
+			byNamespace[ing.Namespace] = append(byNamespace[ing.Namespace], route{
+				namespace: ing.Namespace,
+				name:      owner.Name,
+				class:     contour.ContourIngressClassName,
+				previous:  &previous,
+			})
+		}
+	}
+	return batch(byNamespace), nil
+}
+
+// planRollback returns batches of the Routes we migrated before.
+func (m *migrator) planRollback(ctx context.Context) ([][]route, error) {
+	byNamespace := make(map[string][]route)
+	for _, ns := range namespacesToList() {
+		routes, err := m.routes.Namespace(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range routes.Items {
+			previous, ok := r.GetAnnotations()[migratedFromKey]
+			if !ok {
+				continue
+			}
+			byNamespace[r.GetNamespace()] = append(byNamespace[r.GetNamespace()], route{
+				namespace: r.GetNamespace(),
+				name:      r.GetName(),
+				class:     previous,
+			})
+		}
+	}
+	return batch(byNamespace), nil
+}
+
+// move patches the class annotations of the Routes.
+func (m *migrator) move(ctx context.Context, routes []route) error {
+	for _, r := range routes {
+		annotations := map[string]interface{}{
+			networking.IngressClassAnnotationKey: nil,
+			migratedFromKey:                      nil,
+		}
+		if r.class != "" {
+			annotations[networking.IngressClassAnnotationKey] = r.class
+		}
+		if r.previous != nil {
+			annotations[migratedFromKey] = *r.previous
+		}
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": annotations,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := m.routes.Namespace(r.namespace).Patch(ctx, r.name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return fmt.Errorf("failed to patch Route %s/%s: %w", r.namespace, r.name, err)
+		}
+		log.Printf("Moved %s/%s to %s.", r.namespace, r.name, classOrDefault(r.class))
+	}
+	return nil
+}
+
+// waitReady waits until net-contour reports the KIngresses of the Routes
+// ready, which means it programmed and probed their HTTPProxies.
+func (m *migrator) waitReady(ctx context.Context, routes []route) error {
+	pending := append([]route(nil), routes...)
+	return wait.PollImmediate(2*time.Second, *timeout, func() (bool, error) {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		var stillPending []route
+		for _, r := range pending {
+			// The KIngress of a Route has the same name.
+			ing, err := m.ingresses.NetworkingV1alpha1().Ingresses(r.namespace).Get(ctx, r.name, metav1.GetOptions{})
+			if err != nil ||
+				ing.Annotations[networking.IngressClassAnnotationKey] != contour.ContourIngressClassName ||
+				ing.Status.ObservedGeneration != ing.Generation || !ing.IsReady() {
+				stillPending = append(stillPending, r)
+			}
+		}
+		pending = stillPending
+		return len(pending) == 0, nil
+	})
+}
+
+// reverse returns the moves undoing the given ones.
+func reverse(routes []route) []route {
+	reversed := make([]route, 0, len(routes))
+	for _, r := range routes {
+		reversed = append(reversed, route{namespace: r.namespace, name: r.name, class: *r.previous})
+	}
+	return reversed
+}
+
+func namespacesToList() []string {
+	if *namespaces == "" {
+		return []string{metav1.NamespaceAll}
+	}
+	return strings.Split(*namespaces, ",")
+}
+
+// batch groups the Routes into batches of -batch-size namespaces.
+func batch(byNamespace map[string][]route) [][]route {
+	nss := make([]string, 0, len(byNamespace))
+	for ns := range byNamespace {
+		nss = append(nss, ns)
+	}
+	sort.Strings(nss)
+
+	var batches [][]route
+	for i := 0; i < len(nss); i += *batchSize {
+		var b []route
+		for _, ns := range nss[i:min(i+*batchSize, len(nss))] {
+			b = append(b, dedupe(byNamespace[ns])...)
+		}
+		batches = append(batches, b)
+	}
+	return batches
+}
+
+// dedupe drops the repeated Routes of a namespace, as a Route may own
+// several KIngresses.
+func dedupe(routes []route) []route {
+	seen := sets.NewString()
+	var unique []route
+	for _, r := range routes {
+		if !seen.Has(r.name) {
+			seen.Insert(r.name)
+			unique = append(unique, r)
+		}
+	}
+	return unique
+}
+
+func batchNamespaces(batch []route) string {
+	nss := sets.NewString()
+	for _, r := range batch {
+		nss.Insert(r.namespace)
+	}
+	return strings.Join(nss.List(), ", ")
+}
+
+func classOrDefault(class string) string {
+	if class == "" {
+		return "the default class"
+	}
+	return class
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// render prints the HTTPProxies net-contour programs for the KIngresses of a
+// cluster, as YAML that kubectl diff compares with the live ones.  Given an
+// edited config-contour, it shows what the edit would change before it hits
+// the cluster, e.g. in CI:
+//
+//	go run ./cmd/render -config-contour=config/config-contour.yaml | kubectl diff -f -
+//
+// KIngresses that can't be programmed are reported on stderr and skipped.
+//
+// With -output-dir, it instead writes each HTTPProxy to
+// <output-dir>/<namespace>/<name>.yaml and removes the manifests of the
+// HTTPProxies we no longer program, for pipelines that commit all cluster
+// objects to Git rather than letting net-contour apply them:
+//
+//	go run ./cmd/render -output-dir=clusters/prod/httpproxies
+//	git add -A clusters/prod/httpproxies && git commit -m "Render HTTPProxies"
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/yaml"
+
+	"knative.dev/net-contour/pkg/reconciler/contour"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+	"knative.dev/networking/pkg/apis/networking"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	ingressclientset "knative.dev/networking/pkg/client/clientset/versioned"
+	"knative.dev/pkg/injection"
+	"knative.dev/pkg/signals"
+)
+
+var (
+	configContour   = flag.String("config-contour", "", "A file with the config-contour ConfigMap to render with, defaults to the one of the cluster.")
+	systemNamespace = flag.String("system-namespace", "knative-serving", "The namespace of the config-contour ConfigMap of the cluster.")
+	namespaces      = flag.String("namespaces", "", "A comma separated list of namespaces to render the KIngresses of, defaults to all of them.")
+	outputDir       = flag.String("output-dir", "", "A directory to write a manifest per HTTPProxy to, instead of printing them.")
+)
+
+func main() {
+	cfg := injection.ParseAndGetRESTConfigOrDie()
+	ctx := signals.NewContext()
+	kube := kubernetes.NewForConfigOrDie(cfg)
+	ingresses := ingressclientset.NewForConfigOrDie(cfg)
+
+	cm, err := loadConfigContour(ctx, kube)
+	if err != nil {
+		log.Fatal("Error loading config-contour: ", err)
+	}
+	contourConfig, err := config.NewContourFromConfigMap(cm)
+	if err != nil {
+		log.Fatal("Error parsing config-contour: ", err)
+	}
+	ctx = config.ToContext(ctx, &config.Config{Contour: contourConfig})
+
+	services := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	serviceLister := corev1listers.NewServiceLister(services)
+
+	failed, written := 0, sets.NewString()
+	for _, ns := range namespacesToList() {
+		svcs, err := kube.CoreV1().Services(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			log.Fatal("Error listing Services: ", err)
+		}
+		for i := range svcs.Items {
+			services.Add(&svcs.Items[i])
+		}
+
+		ings, err := ingresses.NetworkingV1alpha1().Ingresses(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			log.Fatal("Error listing KIngresses: ", err)
+		}
+		for i := range ings.Items {
+			ing := &ings.Items[i]
+			if !ours(contourConfig, ing) {
+				continue
+			}
+			proxies, err := contour.DesiredHTTPProxies(ctx, ing, serviceLister)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Skipping KIngress %s/%s: %v\n", ing.Namespace, ing.Name, err)
+				failed++
+				continue
+			}
+			for _, proxy := range proxies {
+				path, err := writeProxy(proxy)
+				if err != nil {
+					log.Fatal("Error writing HTTPProxy: ", err)
+				}
+				written.Insert(path)
+			}
+		}
+	}
+	if *outputDir != "" {
+		if err := prune(written); err != nil {
+			log.Fatal("Error removing stale manifests: ", err)
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d KIngresses can't be programmed with this config-contour.\n", failed)
+		os.Exit(1)
+	}
+}
+
+// loadConfigContour reads the config-contour ConfigMap from -config-contour,
+// or from the cluster.
+func loadConfigContour(ctx context.Context, kube kubernetes.Interface) (*corev1.ConfigMap, error) {
+	if *configContour == "" {
+		return kube.CoreV1().ConfigMaps(*systemNamespace).Get(ctx, config.ContourConfigName, metav1.GetOptions{})
+	}
+	b, err := ioutil.ReadFile(*configContour)
+	if err != nil {
+		return nil, err
+	}
+	cm := &corev1.ConfigMap{}
+	if err := yaml.Unmarshal(b, cm); err != nil {
+		return nil, err
+	}
+	return cm, nil
+}
+
+// ours returns whether net-contour programs the KIngress, like the filter of
+// its controller.
+func ours(cfg *config.Contour, ing *v1alpha1.Ingress) bool {
+	if _, ok := ing.Annotations[resources.EndpointsProbeKey]; ok {
+		// Endpoint probes are derived from the KIngresses we render.
+		return false
+	}
+	class, ok := ing.Annotations[networking.IngressClassAnnotationKey]
+	return class == contour.ContourIngressClassName || (!ok && cfg.ClaimUnsetIngressClass)
+}
+
+// writeProxy prints the HTTPProxy as a YAML document with the fields kubectl
+// diff needs to find the live one, or writes it to its file under
+// -output-dir, whose path it returns.
+func writeProxy(proxy *contourv1.HTTPProxy) (string, error) {
+	proxy.APIVersion = contourv1.GroupVersion.String()
+	proxy.Kind = "HTTPProxy"
+	b, err := yaml.Marshal(proxy)
+	if err != nil {
+		return "", err
+	}
+	if *outputDir == "" {
+		fmt.Printf("---\n%s", b)
+		return "", nil
+	}
+
+	path := filepath.Join(*outputDir, proxy.Namespace, proxy.Name+".yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	return path, ioutil.WriteFile(path, b, 0o644) //nolint:gosec // Manifests aren't secret.
+}
+
+// prune removes the manifests under -output-dir that we didn't write, which
+// belong to HTTPProxies we no longer program.  It only looks at the
+// namespaces we rendered.
+func prune(written sets.String) error {
+	dirs := []string{*outputDir}
+	if *namespaces != "" {
+		dirs = nil
+		for _, ns := range namespacesToList() {
+			dirs = append(dirs, filepath.Join(*outputDir, ns))
+		}
+	}
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			switch {
+			case os.IsNotExist(err):
+				return nil
+			case err != nil:
+				return err
+			case info.IsDir() || filepath.Ext(path) != ".yaml" || written.Has(path):
+				return nil
+			}
+			log.Print("Removing stale manifest ", path)
+			return os.Remove(path)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func namespacesToList() []string {
+	if *namespaces == "" {
+		return []string{metav1.NamespaceAll}
+	}
+	return strings.Split(*namespaces, ",")
+}
@@ -0,0 +1,318 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// soak continuously shifts the traffic splits of a KIngress while sending
+// requests to it through Envoy, and fails when any of them is answered with a
+// 404 or 503, which would mean that Envoy routed traffic according to a
+// programming it wasn't ready for.
+//
+// The splits are copied from a path of an existing KIngress with at least two
+// splits, e.g. one created for a Knative Service serving two Revisions, into
+// a standalone KIngress that soak creates and deletes when done.  Shifting
+// the splits of the existing KIngress would race its owner, e.g. the Route
+// reconciler, which reverts them.
+//
+//	go run ./cmd/soak -namespace=default -name=hello -url=http://$ENVOY_IP -duration=30m
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+
+	"knative.dev/networking/pkg/apis/networking"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	ingressclientset "knative.dev/networking/pkg/client/clientset/versioned"
+	networkingv1alpha1 "knative.dev/networking/pkg/client/clientset/versioned/typed/networking/v1alpha1"
+	"knative.dev/pkg/injection"
+	"knative.dev/pkg/signals"
+)
+
+var (
+	namespace = flag.String("namespace", "default", "The namespace of the KIngress.")
+	name      = flag.String("name", "", "The name of the KIngress whose splits to copy.")
+	target    = flag.String("url", "", "The URL of Envoy to send requests to, e.g. http://10.0.0.1.")
+	host      = flag.String("host", "", "The host of the soaked KIngress, defaults to soak-<name>.<namespace>.example.com.")
+	ready     = flag.Duration("ready-timeout", 2*time.Minute, "How long to wait for the soaked KIngress to become ready.")
+	duration  = flag.Duration("duration", 10*time.Minute, "How long to soak.")
+	interval  = flag.Duration("interval", 10*time.Second, "How often to shift the splits.")
+	step      = flag.Int("step", 10, "How many percent of traffic to shift at a time.")
+	qps       = flag.Int("qps", 50, "How many requests to send per second.")
+)
+
+func main() {
+	if !soak() {
+		os.Exit(1)
+	}
+}
+
+// soak runs the soak test and returns whether it passed.
+func soak() bool {
+	cfg := injection.ParseAndGetRESTConfigOrDie()
+	if *name == "" || *target == "" {
+		log.Fatal("-name and -url are required")
+	}
+	if *step < 1 || *step > 100 || *qps < 1 || *interval <= 0 {
+		log.Fatal("-step must be between 1 and 100, -qps and -interval must be positive")
+	}
+
+	ctx, cancel := context.WithTimeout(signals.NewContext(), *duration)
+	defer cancel()
+	ingresses := ingressclientset.NewForConfigOrDie(cfg).NetworkingV1alpha1().Ingresses(*namespace)
+
+	template, err := ingresses.Get(ctx, *name, metav1.GetOptions{})
+	if err != nil {
+		log.Fatal("Error getting the KIngress: ", err)
+	}
+	rule, path, ok := splitPath(template)
+	if !ok {
+		log.Fatal("The KIngress has no path with at least two splits.")
+	}
+	if *host == "" {
+		*host = fmt.Sprintf("soak-%s.%s.example.com", *name, *namespace)
+	}
+	ing, err := ingresses.Create(ctx, standalone(template, rule, path), metav1.CreateOptions{})
+	if err != nil {
+		log.Fatal("Error creating the soaked KIngress: ", err)
+	}
+	defer func() {
+		log.Printf("Deleting the soaked KIngress %s.", ing.Name)
+		if err := ingresses.Delete(context.Background(), ing.Name, metav1.DeleteOptions{}); err != nil {
+			log.Print("Error deleting the soaked KIngress: ", err)
+		}
+	}()
+	if err := waitReady(ctx, ingresses, ing.Name); err != nil {
+		log.Print("Error waiting for the soaked KIngress to become ready: ", err)
+		return false
+	}
+	original := percents(ing.Spec.Rules[0].HTTP.Paths[0].Splits)
+
+	results := &results{codes: make(map[int]int)}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		drive(ctx, results)
+	}()
+
+	current := original
+	wait.Until(func() {
+		current = shift(current, *step)
+		if err := setPercents(ctx, ingresses, ing.Name, current); err != nil {
+			if ctx.Err() == nil {
+				log.Print("Error shifting the splits: ", err)
+			}
+			return
+		}
+		log.Printf("Shifted the splits to %v.", current)
+	}, *interval, ctx.Done())
+	wg.Wait()
+
+	return results.report()
+}
+
+// splitPath returns the indices of the first rule and path of the KIngress
+// that split traffic between at least two backends.
+func splitPath(ing *v1alpha1.Ingress) (int, int, bool) {
+	for i, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil || len(rule.Hosts) == 0 {
+			continue
+		}
+		for j, path := range rule.HTTP.Paths {
+			if len(path.Splits) >= 2 {
+				return i, j, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// standalone returns a KIngress for the soak that routes our host with the
+// given path of the template.  It carries none of the template's labels or
+// owners, so that nothing but us reconciles its spec.
+func standalone(template *v1alpha1.Ingress, rule, path int) *v1alpha1.Ingress {
+	ing := &v1alpha1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: template.Namespace,
+			Name:      "soak-" + template.Name,
+		},
+		Spec: v1alpha1.IngressSpec{
+			Rules: []v1alpha1.IngressRule{{
+				Hosts:      []string{*host},
+				Visibility: v1alpha1.IngressVisibilityExternalIP,
+				HTTP: &v1alpha1.HTTPIngressRuleValue{
+					Paths: []v1alpha1.HTTPIngressPath{
+						*template.Spec.Rules[rule].HTTP.Paths[path].DeepCopy(),
+					},
+				},
+			}},
+		},
+	}
+	if class, ok := template.Annotations[networking.IngressClassAnnotationKey]; ok {
+		ing.Annotations = map[string]string{networking.IngressClassAnnotationKey: class}
+	}
+	return ing
+}
+
+// waitReady waits for the KIngress with the given name to become ready.
+func waitReady(ctx context.Context, ingresses networkingv1alpha1.IngressInterface, name string) error {
+	return wait.PollImmediate(time.Second, *ready, func() (bool, error) {
+		ing, err := ingresses.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return ing.IsReady(), nil
+	})
+}
+
+func percents(splits []v1alpha1.IngressBackendSplit) []int {
+	ps := make([]int, 0, len(splits))
+	for _, split := range splits {
+		ps = append(ps, split.Percent)
+	}
+	return ps
+}
+
+// shift moves step percent of traffic from the first split that has any to
+// the split after it, so that over time traffic rotates through the splits.
+func shift(ps []int, step int) []int {
+	next := append([]int(nil), ps...)
+	for i, p := range next {
+		if p == 0 {
+			continue
+		}
+		moved := step
+		if p < moved {
+			moved = p
+		}
+		next[i] -= moved
+		next[(i+1)%len(next)] += moved
+		break
+	}
+	return next
+}
+
+// setPercents updates the percents of the splits of the soaked KIngress.
+func setPercents(ctx context.Context, ingresses networkingv1alpha1.IngressInterface, name string, ps []int) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		ing, err := ingresses.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		splits := ing.Spec.Rules[0].HTTP.Paths[0].Splits
+		if len(splits) != len(ps) {
+			return fmt.Errorf("the KIngress now has %d splits instead of %d", len(splits), len(ps))
+		}
+		for i := range splits {
+			splits[i].Percent = ps[i]
+		}
+		_, err = ingresses.Update(ctx, ing, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// drive sends qps requests per second to Envoy until the context is done.
+func drive(ctx context.Context, results *results) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	ticker := time.NewTicker(time.Second / time.Duration(*qps))
+	defer ticker.Stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, *target, nil)
+			if err != nil {
+				log.Fatal("Error creating the request: ", err)
+			}
+			req.Host = *host
+			resp, err := client.Do(req)
+			if err != nil {
+				if ctx.Err() == nil {
+					results.failed(err)
+				}
+				return
+			}
+			defer resp.Body.Close()
+			io.Copy(ioutil.Discard, resp.Body)
+			results.observe(resp.StatusCode)
+		}()
+	}
+}
+
+type results struct {
+	mu     sync.Mutex
+	codes  map[int]int
+	errors int
+}
+
+func (r *results) observe(code int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.codes[code]++
+	if code == http.StatusNotFound || code == http.StatusServiceUnavailable {
+		log.Printf("Got a %d response.", code)
+	}
+}
+
+func (r *results) failed(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors++
+	log.Print("Error sending a request: ", err)
+}
+
+// report prints the results and returns whether the soak passed.
+func (r *results) report() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	codes := make([]int, 0, len(r.codes))
+	for code := range r.codes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		fmt.Printf("%d: %d\n", code, r.codes[code])
+	}
+	fmt.Printf("errors: %d\n", r.errors)
+
+	if bad := r.codes[http.StatusNotFound] + r.codes[http.StatusServiceUnavailable]; bad != 0 {
+		fmt.Printf("FAIL: %d requests were answered with a 404 or 503.\n", bad)
+		return false
+	}
+	fmt.Println("PASS")
+	return true
+}
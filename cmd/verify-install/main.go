@@ -0,0 +1,387 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// verify-install checks that net-contour and the Contour installations it
+// programs are set up consistently: the Contour CRDs are served, the
+// controller is allowed to do its job, config-contour parses, the Envoy
+// Services of every visibility exist and the default certificate is
+// delegated.  Given a Service that answers Knative's network probes, e.g.
+// the private Service of a Knative Revision, it then creates a KIngress,
+// waits for it to become ready, sends it a request through Envoy and deletes
+// it again.  It prints a report and exits non-zero when any check failed:
+//
+//	go run ./cmd/verify-install -namespace=default -service=hello-00001-private -url=http://$ENVOY_IP
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	contourclientset "knative.dev/net-contour/pkg/client/clientset/versioned"
+	"knative.dev/net-contour/pkg/reconciler/contour"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/networking/pkg/apis/networking"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	ingressclientset "knative.dev/networking/pkg/client/clientset/versioned"
+	"knative.dev/pkg/injection"
+	"knative.dev/pkg/signals"
+)
+
+var (
+	systemNamespace = flag.String("system-namespace", "knative-serving", "The namespace net-contour and config-contour are installed in.")
+	serviceAccount  = flag.String("service-account", "controller", "The ServiceAccount net-contour runs as.")
+	namespace       = flag.String("namespace", "default", "The namespace to create the smoke test KIngress in.")
+	service         = flag.String("service", "", "The Service the smoke test KIngress routes to, which must answer Knative network probes.  Empty skips the smoke test.")
+	port            = flag.Int("port", 80, "The port of the Service.")
+	domain          = flag.String("domain", "example.com", "The domain of the host of the smoke test KIngress.")
+	target          = flag.String("url", "", "The URL of Envoy to send the smoke test request to, e.g. http://10.0.0.1.  Empty skips the request.")
+	timeout         = flag.Duration("timeout", 5*time.Minute, "How long to wait for the smoke test KIngress to become ready.")
+)
+
+// permissions are the permissions net-contour needs, by API group and
+// resource.
+var permissions = []struct {
+	group, resource string
+	verbs           []string
+}{
+	{"projectcontour.io", "httpproxies", []string{"get", "list", "watch", "create", "update", "delete", "deletecollection"}},
+	{"networking.internal.knative.dev", "ingresses", []string{"get", "list", "watch", "create", "update", "delete"}},
+	{"networking.internal.knative.dev", "ingresses/status", []string{"update"}},
+	{"", "services", []string{"get", "list", "watch"}},
+	{"", "endpoints", []string{"get", "list", "watch"}},
+	{"", "pods", []string{"get", "list", "watch"}},
+	{"", "configmaps", []string{"get", "list", "watch"}},
+}
+
+func main() {
+	cfg := injection.ParseAndGetRESTConfigOrDie()
+	if *service != "" && (*port < 1 || *timeout <= 0) {
+		log.Fatal("-port and -timeout must be positive")
+	}
+
+	ctx := signals.NewContext()
+	v := &verifier{
+		kubeClient:    kubernetes.NewForConfigOrDie(cfg),
+		contourClient: contourclientset.NewForConfigOrDie(cfg),
+		ingressClient: ingressclientset.NewForConfigOrDie(cfg),
+	}
+
+	v.check("Contour CRDs", v.checkCRDs(ctx))
+	v.check("Permissions", v.checkPermissions(ctx))
+	contourConfig, err := v.loadConfig(ctx)
+	v.check("config-contour", err)
+	if contourConfig != nil {
+		v.check("Visibility Services", v.checkVisibilityServices(ctx, contourConfig))
+		v.check("Certificate delegation", v.checkDelegation(ctx, contourConfig))
+	}
+	if *service != "" {
+		v.check("Smoke test", v.smokeTest(ctx))
+	}
+
+	if v.failed {
+		fmt.Println("FAIL")
+		os.Exit(1)
+	}
+	fmt.Println("PASS")
+}
+
+type verifier struct {
+	kubeClient    kubernetes.Interface
+	contourClient contourclientset.Interface
+	ingressClient ingressclientset.Interface
+
+	failed bool
+}
+
+// check prints the outcome of a check.
+func (v *verifier) check(name string, err error) {
+	if err != nil {
+		v.failed = true
+		fmt.Printf("FAIL  %s: %v\n", name, err)
+		return
+	}
+	fmt.Printf("ok    %s\n", name)
+}
+
+// checkCRDs checks that the APIs we program and reconcile are served.
+func (v *verifier) checkCRDs(ctx context.Context) error {
+	for gv, resources := range map[string][]string{
+		"projectcontour.io/v1":                     {"httpproxies", "tlscertificatedelegations"},
+		"networking.internal.knative.dev/v1alpha1": {"ingresses"},
+	} {
+		list, err := v.kubeClient.Discovery().ServerResourcesForGroupVersion(gv)
+		if err != nil {
+			return fmt.Errorf("the %s API is not served: %w", gv, err)
+		}
+		served := sets.NewString()
+		for _, r := range list.APIResources {
+			served.Insert(r.Name)
+		}
+		for _, r := range resources {
+			if !served.Has(r) {
+				return fmt.Errorf("the %s API doesn't serve %s", gv, r)
+			}
+		}
+	}
+	return nil
+}
+
+// checkPermissions checks that net-contour's ServiceAccount may do
+// everything it needs to.
+func (v *verifier) checkPermissions(ctx context.Context) error {
+	user := fmt.Sprintf("system:serviceaccount:%s:%s", *systemNamespace, *serviceAccount)
+	var missing []string
+	for _, p := range permissions {
+		resource, subresource := p.resource, ""
+		if i := strings.Index(resource, "/"); i >= 0 {
+			resource, subresource = resource[:i], resource[i+1:]
+		}
+		for _, verb := range p.verbs {
+			review, err := v.kubeClient.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+				Spec: authorizationv1.SubjectAccessReviewSpec{
+					User:   user,
+					Groups: []string{"system:serviceaccounts", "system:serviceaccounts:" + *systemNamespace},
+					ResourceAttributes: &authorizationv1.ResourceAttributes{
+						Group:       p.group,
+						Resource:    resource,
+						Subresource: subresource,
+						Verb:        verb,
+					},
+				},
+			}, metav1.CreateOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to review the permissions of %s: %w", user, err)
+			}
+			if !review.Status.Allowed {
+				missing = append(missing, verb+" "+p.resource)
+			}
+		}
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("%s may not %s", user, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// loadConfig parses config-contour as net-contour would.
+func (v *verifier) loadConfig(ctx context.Context) (*config.Contour, error) {
+	cm, err := v.kubeClient.CoreV1().ConfigMaps(*systemNamespace).Get(ctx, config.ContourConfigName, metav1.GetOptions{})
+	if apierrs.IsNotFound(err) {
+		// net-contour runs with the defaults.
+		cm = &corev1.ConfigMap{}
+	} else if err != nil {
+		return nil, err
+	}
+	contourConfig, err := config.NewContourFromConfigMap(cm)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	for vis, class := range contourConfig.VisibilityClasses {
+		if class == "" {
+			return nil, fmt.Errorf("visibility %s has no Contour class", vis)
+		}
+	}
+	return contourConfig, nil
+}
+
+// checkVisibilityServices checks that the Envoy Services of every visibility
+// exist and have ready endpoints.
+func (v *verifier) checkVisibilityServices(ctx context.Context, cfg *config.Contour) error {
+	for vis, keys := range cfg.VisibilityKeys {
+		var services []corev1.Service
+		switch {
+		case cfg.VisibilityGateways[vis].Name != "":
+			gw := cfg.VisibilityGateways[vis]
+			list, err := v.kubeClient.CoreV1().Services(gw.Namespace).List(ctx, metav1.ListOptions{
+				LabelSelector: labels.SelectorFromSet(labels.Set{contour.OwningGatewayLabel: gw.Name}).String(),
+			})
+			if err != nil {
+				return err
+			}
+			services = list.Items
+		case cfg.VisibilitySelectors[vis] != "":
+			list, err := v.kubeClient.CoreV1().Services(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+				LabelSelector: cfg.VisibilitySelectors[vis],
+			})
+			if err != nil {
+				return err
+			}
+			services = list.Items
+		default:
+			for _, key := range keys.List() {
+				ns, name, err := cache.SplitMetaNamespaceKey(key)
+				if err != nil {
+					return err
+				}
+				svc, err := v.kubeClient.CoreV1().Services(ns).Get(ctx, name, metav1.GetOptions{})
+				if err != nil {
+					return fmt.Errorf("the Envoy Service of visibility %s: %w", vis, err)
+				}
+				services = append(services, *svc)
+			}
+		}
+		if len(services) == 0 {
+			return fmt.Errorf("no Envoy Services found for visibility %s", vis)
+		}
+
+		for _, svc := range services {
+			eps, err := v.kubeClient.CoreV1().Endpoints(svc.Namespace).Get(ctx, svc.Name, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("the Endpoints of Envoy Service %s/%s: %w", svc.Namespace, svc.Name, err)
+			}
+			ready := 0
+			for _, subset := range eps.Subsets {
+				ready += len(subset.Addresses)
+			}
+			if ready == 0 {
+				return fmt.Errorf("the Envoy Service %s/%s of visibility %s has no ready endpoints", svc.Namespace, svc.Name, vis)
+			}
+		}
+	}
+	return nil
+}
+
+// checkDelegation checks that the default certificate may be referenced by
+// the HTTPProxies of the smoke test namespace, or of every namespace.
+func (v *verifier) checkDelegation(ctx context.Context, cfg *config.Contour) error {
+	secret := cfg.DefaultTLSSecret
+	if secret == nil {
+		return nil
+	}
+	if _, err := v.kubeClient.CoreV1().Secrets(secret.Namespace).Get(ctx, secret.Name, metav1.GetOptions{}); err != nil {
+		return fmt.Errorf("the default certificate %s: %w", secret, err)
+	}
+	delegations, err := v.contourClient.ProjectcontourV1().TLSCertificateDelegations(secret.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, d := range delegations.Items {
+		for _, cd := range d.Spec.Delegations {
+			if cd.SecretName != secret.Name {
+				continue
+			}
+			if targets := sets.NewString(cd.TargetNamespaces...); targets.Has("*") || targets.Has(*namespace) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("no TLSCertificateDelegation in %s delegates the default certificate %s to namespace %s",
+		secret.Namespace, secret, *namespace)
+}
+
+// smokeTest creates a KIngress, waits for it to become ready, sends it a
+// request through Envoy and deletes it.
+func (v *verifier) smokeTest(ctx context.Context) error {
+	name := fmt.Sprintf("verify-install-%d", time.Now().Unix())
+	host := fmt.Sprintf("%s.%s.%s", name, *namespace, *domain)
+	ingresses := v.ingressClient.NetworkingV1alpha1().Ingresses(*namespace)
+
+	if _, err := ingresses.Create(ctx, makeIngress(name, host), metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create KIngress %s: %w", name, err)
+	}
+	defer func() {
+		if err := ingresses.Delete(context.Background(), name, metav1.DeleteOptions{}); err != nil {
+			log.Printf("Error deleting KIngress %s: %v", name, err)
+		}
+	}()
+
+	start := time.Now()
+	var last *v1alpha1.Ingress
+	if err := wait.PollImmediate(time.Second, *timeout, func() (bool, error) {
+		ing, err := ingresses.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		last = ing
+		return ing.IsReady() && ing.Status.ObservedGeneration == ing.Generation, nil
+	}); err != nil {
+		if last != nil {
+			if cond := last.Status.GetCondition(v1alpha1.IngressConditionReady); cond != nil {
+				return fmt.Errorf("KIngress %s didn't become ready: %s: %s", name, cond.Reason, cond.Message)
+			}
+		}
+		return fmt.Errorf("KIngress %s didn't become ready: %w", name, err)
+	}
+	log.Printf("KIngress %s became ready after %v.", name, time.Since(start).Round(time.Millisecond))
+
+	if *target == "" {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, *target, nil)
+	if err != nil {
+		return err
+	}
+	req.Host = host
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send a request to %s: %w", host, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusServiceUnavailable {
+		return fmt.Errorf("the request to %s was answered with a %d", host, resp.StatusCode)
+	}
+	return nil
+}
+
+func makeIngress(name, host string) *v1alpha1.Ingress {
+	return &v1alpha1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: *namespace,
+			Annotations: map[string]string{
+				networking.IngressClassAnnotationKey: contour.ContourIngressClassName,
+			},
+		},
+		Spec: v1alpha1.IngressSpec{
+			Rules: []v1alpha1.IngressRule{{
+				Hosts:      []string{host},
+				Visibility: v1alpha1.IngressVisibilityExternalIP,
+				HTTP: &v1alpha1.HTTPIngressRuleValue{
+					Paths: []v1alpha1.HTTPIngressPath{{
+						Splits: []v1alpha1.IngressBackendSplit{{
+							IngressBackend: v1alpha1.IngressBackend{
+								ServiceName:      *service,
+								ServiceNamespace: *namespace,
+								ServicePort:      intstr.FromInt(*port),
+							},
+							Percent: 100,
+						}},
+					}},
+				},
+			}},
+		},
+	}
+}
@@ -6,6 +6,7 @@ require (
 	github.com/google/go-cmp v0.5.6
 	github.com/mikefarah/yq/v3 v3.0.0-20200601230220-721dd57ed41b
 	github.com/projectcontour/contour v1.18.1
+	go.opencensus.io v0.23.0
 	go.uber.org/zap v1.19.0
 	k8s.io/api v0.21.4
 	k8s.io/apimachinery v0.21.4
@@ -0,0 +1,34 @@
+#!/usr/bin/env bash
+
+# Copyright 2021 The Knative Authors
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# This script runs the benchmarks of translating Ingresses into HTTPProxies.
+# Compare two runs (e.g. before and after a change) with benchstat:
+#
+#   ./hack/benchmark.sh > old.txt
+#   git checkout my-change
+#   ./hack/benchmark.sh > new.txt
+#   benchstat old.txt new.txt
+#
+# Extra arguments are passed to go test, e.g. -bench=MakeHTTPProxies.
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+cd "$(dirname "$0")/.."
+
+go test -run='^$' -bench=. -benchmem -count="${COUNT:-10}" "$@" \
+  ./pkg/reconciler/contour/resources/
@@ -24,6 +24,7 @@ declare -A COMPONENTS
 COMPONENTS=(
   ["net-contour.yaml"]="config"
   ["contour.yaml"]="config/contour"
+  ["net-contour-statefulset.yaml"]="config/statefulset"
 )
 readonly COMPONENTS
 
@@ -49,6 +49,11 @@ ${GOPATH}/bin/deepcopy-gen \
   --go-header-file ${REPO_ROOT_DIR}/hack/boilerplate/boilerplate.go.txt \
   -i knative.dev/net-contour/pkg/reconciler/contour/config
 
+group "Config Schema"
+
+# The JSON schema of config-contour, from the keys the controller reads.
+GOFLAGS=-mod=vendor go run ${REPO_ROOT_DIR}/cmd/config-contour schema > ${REPO_ROOT_DIR}/schema/config-contour.json
+
 group "Update deps post-codegen"
 
 # Make sure our dependencies are up-to-date
@@ -0,0 +1,94 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ownership holds the labels net-contour puts on the resources it
+// generates, and selectors for them, so that other tools can find and clean
+// up what net-contour created without knowing how it names them.
+package ownership
+
+import (
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+const (
+	// ManagedByKey is the well-known label naming the tool managing a
+	// resource, which we set to ManagedByValue on every resource we generate.
+	ManagedByKey = "app.kubernetes.io/managed-by"
+	// ManagedByValue is the value of ManagedByKey on our resources.
+	ManagedByValue = "net-contour"
+
+	// ParentKey holds the name of the KIngress a resource was generated for,
+	// since OwnerReferences cannot be used in filter expressions.
+	ParentKey = "contour.networking.knative.dev/parent"
+	// GenerationKey holds the generation of the parent KIngress that the
+	// HTTPProxies were generated from.  We clean up the HTTPProxies of other
+	// generations once the current one is rolled out.
+	GenerationKey = "contour.networking.knative.dev/generation"
+)
+
+// Labels returns the labels of the resources we generate for the named
+// KIngress.
+func Labels(parent string) map[string]string {
+	return map[string]string{
+		ManagedByKey: ManagedByValue,
+		ParentKey:    parent,
+	}
+}
+
+// GenerationLabels returns the labels of the HTTPProxies we generate for the
+// given generation of the named KIngress.
+func GenerationLabels(parent string, generation int64) map[string]string {
+	l := Labels(parent)
+	l[GenerationKey] = strconv.FormatInt(generation, 10)
+	return l
+}
+
+// Managed selects the resources labeled as managed by net-contour.
+func Managed() labels.Selector {
+	return labels.SelectorFromSet(labels.Set{ManagedByKey: ManagedByValue})
+}
+
+// Children selects the resources generated for any KIngress, including
+// those generated before we labeled them as managed.
+func Children() labels.Selector {
+	// The requirement is valid for any constant key.
+	req, _ := labels.NewRequirement(ParentKey, selection.Exists, nil)
+	return labels.NewSelector().Add(*req)
+}
+
+// Parent selects the resources generated for the named KIngress.
+func Parent(parent string) labels.Selector {
+	return labels.SelectorFromSet(labels.Set{ParentKey: parent})
+}
+
+// Generation selects the HTTPProxies generated for the given generation of
+// the named KIngress.
+func Generation(parent string, generation int64) labels.Selector {
+	return labels.SelectorFromSet(labels.Set{
+		ParentKey:     parent,
+		GenerationKey: strconv.FormatInt(generation, 10),
+	})
+}
+
+// OtherGenerations selects the HTTPProxies generated for the named KIngress
+// but another generation than the given one.  It fails for names that
+// aren't valid label values.
+func OtherGenerations(parent string, generation int64) (labels.Selector, error) {
+	return labels.Parse(ParentKey + "=" + parent + "," + GenerationKey + "!=" + strconv.FormatInt(generation, 10))
+}
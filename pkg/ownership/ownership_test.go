@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ownership
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestSelectors(t *testing.T) {
+	current := labels.Set(GenerationLabels("foo", 2))
+	older := labels.Set(GenerationLabels("foo", 1))
+	other := labels.Set(GenerationLabels("bar", 2))
+	legacy := labels.Set{ParentKey: "foo", GenerationKey: "1"}
+
+	others, err := OtherGenerations("foo", 2)
+	if err != nil {
+		t.Fatal("OtherGenerations() =", err)
+	}
+
+	tests := []struct {
+		name     string
+		selector labels.Selector
+		matches  []labels.Set
+		misses   []labels.Set
+	}{{
+		name:     "managed",
+		selector: Managed(),
+		matches:  []labels.Set{current, older, other},
+		misses:   []labels.Set{legacy, {}},
+	}, {
+		name:     "children",
+		selector: Children(),
+		matches:  []labels.Set{current, older, other, legacy},
+		misses:   []labels.Set{{ManagedByKey: ManagedByValue}},
+	}, {
+		name:     "parent",
+		selector: Parent("foo"),
+		matches:  []labels.Set{current, older, legacy},
+		misses:   []labels.Set{other},
+	}, {
+		name:     "generation",
+		selector: Generation("foo", 2),
+		matches:  []labels.Set{current},
+		misses:   []labels.Set{older, other, legacy},
+	}, {
+		name:     "other generations",
+		selector: others,
+		matches:  []labels.Set{older, legacy},
+		misses:   []labels.Set{current, other},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, l := range test.matches {
+				if !test.selector.Matches(l) {
+					t.Errorf("%v doesn't match %v", test.selector, l)
+				}
+			}
+			for _, l := range test.misses {
+				if test.selector.Matches(l) {
+					t.Errorf("%v matches %v", test.selector, l)
+				}
+			}
+		})
+	}
+}
+
+func TestOtherGenerationsInvalidName(t *testing.T) {
+	if _, err := OtherGenerations("not a label value", 1); err == nil {
+		t.Error("OtherGenerations() succeeded for an invalid name, wanted error")
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"knative.dev/pkg/logging"
+)
+
+const (
+	// caBundleKey is the key of the probe-ca-bundle ConfigMap holding the
+	// PEM certificates our HTTPS probes trust.
+	caBundleKey = "ca.crt"
+
+	// caBundleTTL is how long we trust a CA bundle we read before reading
+	// it again, so that rotations are picked up without a read per probe.
+	caBundleTTL = time.Minute
+)
+
+// caBundles reads the CA bundle config-contour's probe-ca-bundle points our
+// HTTPS probes at.
+type caBundles struct {
+	kubeClient kubernetes.Interface
+
+	mu      sync.Mutex
+	ref     types.NamespacedName
+	data    string
+	pool    *x509.CertPool
+	expires time.Time
+}
+
+// get returns the certificates of the CA bundle of ref, or nil to use the
+// system roots when ref is nil.  A bundle we can't read trusts nothing, so
+// that our probes fail instead of silently trusting other certificates.
+func (c *caBundles) get(ctx context.Context, ref *types.NamespacedName) *x509.CertPool {
+	if c == nil || ref == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if c.pool != nil && c.ref == *ref && now.Before(c.expires) {
+		return c.pool
+	}
+
+	data, err := c.read(ctx, *ref)
+	if err != nil {
+		logging.FromContext(ctx).Errorw("Unable to read the CA bundle of our probes", zap.Error(err))
+		data = ""
+	}
+	// Keep the pool of an unchanged bundle, so the transports built with it
+	// are kept as well.
+	if c.pool == nil || c.ref != *ref || c.data != data {
+		pool := x509.NewCertPool()
+		if data != "" && !pool.AppendCertsFromPEM([]byte(data)) {
+			logging.FromContext(ctx).Errorf("The %s of ConfigMap %s holds no PEM certificate", caBundleKey, ref)
+		}
+		c.pool = pool
+	}
+	c.ref, c.data, c.expires = *ref, data, now.Add(caBundleTTL)
+	return c.pool
+}
+
+func (c *caBundles) read(ctx context.Context, ref types.NamespacedName) (string, error) {
+	cm, err := c.kubeClient.CoreV1().ConfigMaps(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get ConfigMap %s: %w", ref, err)
+	}
+	data, ok := cm.Data[caBundleKey]
+	if !ok {
+		return "", fmt.Errorf("ConfigMap %s has no %s", ref, caBundleKey)
+	}
+	return data, nil
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCABundles(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	s.Close()
+	ca := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw}))
+
+	ctx := context.Background()
+	ref := types.NamespacedName{Namespace: "knative-serving", Name: "envoy-ca"}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ref.Namespace, Name: ref.Name},
+		Data:       map[string]string{caBundleKey: ca},
+	}
+	kubeClient := fakekubeclientset.NewSimpleClientset(cm)
+	c := &caBundles{kubeClient: kubeClient}
+
+	if got := c.get(ctx, nil); got != nil {
+		t.Error("get(nil) != nil, wanted the system roots")
+	}
+	first := c.get(ctx, &ref)
+	if first == nil || len(first.Subjects()) != 1 { //nolint:staticcheck // Only counts the certificates.
+		t.Fatal("get() didn't return the certificate of the bundle")
+	}
+
+	// An unchanged bundle keeps its pool, even once read again.
+	c.expires = time.Time{}
+	if got := c.get(ctx, &ref); got != first {
+		t.Error("get() returned another pool for an unchanged bundle")
+	}
+
+	// A deleted bundle trusts nothing, once read again.
+	if err := kubeClient.CoreV1().ConfigMaps(ref.Namespace).Delete(ctx, ref.Name, metav1.DeleteOptions{}); err != nil {
+		t.Fatal("Delete() =", err)
+	}
+	if got := c.get(ctx, &ref); got != first {
+		t.Error("get() read the bundle again before it expired")
+	}
+	c.expires = time.Time{}
+	if got := c.get(ctx, &ref); got == first || len(got.Subjects()) != 0 { //nolint:staticcheck // Only counts the certificates.
+		t.Error("get() of a deleted bundle trusts certificates")
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/networking/pkg/apis/networking"
+	ingressclientset "knative.dev/networking/pkg/client/clientset/versioned"
+	networkingv1alpha1 "knative.dev/networking/pkg/client/listers/networking/v1alpha1"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/reconciler"
+)
+
+// leaderAwareReconciler is the subset of the generated Ingress reconciler
+// that classClaimer wraps.
+type leaderAwareReconciler interface {
+	controller.Reconciler
+	reconciler.LeaderAware
+	IsLeaderFor(types.NamespacedName) bool
+}
+
+// classClaimer wraps the generated Ingress reconciler, which skips Ingresses
+// without our class annotation, to claim those Ingresses when net-contour is
+// configured as the cluster's default networking layer.  Claimed Ingresses
+// are annotated with our class, and then reconciled as any other.
+type classClaimer struct {
+	leaderAwareReconciler
+
+	ingressClient ingressclientset.Interface
+	ingressLister networkingv1alpha1.IngressLister
+	className     string
+	contourConfig func() *config.Contour
+}
+
+var _ controller.Reconciler = (*classClaimer)(nil)
+
+// Reconcile implements controller.Reconciler
+func (c *classClaimer) Reconcile(ctx context.Context, key string) error {
+	if !c.contourConfig().ClaimUnsetIngressClass {
+		return c.leaderAwareReconciler.Reconcile(ctx, key)
+	}
+
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return c.leaderAwareReconciler.Reconcile(ctx, key)
+	}
+	ing, err := c.ingressLister.Ingresses(namespace).Get(name)
+	if err != nil {
+		return c.leaderAwareReconciler.Reconcile(ctx, key)
+	}
+	if _, ok := ing.Annotations[networking.IngressClassAnnotationKey]; ok {
+		return c.leaderAwareReconciler.Reconcile(ctx, key)
+	}
+
+	if !c.IsLeaderFor(types.NamespacedName{Namespace: namespace, Name: name}) {
+		return nil
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				networking.IngressClassAnnotationKey: c.className,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := c.ingressClient.NetworkingV1alpha1().Ingresses(namespace).Patch(
+		ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil && !apierrs.IsNotFound(err) {
+		return fmt.Errorf("failed to claim Ingress %s: %w", key, err)
+	}
+	// The update of the annotation enqueues the Ingress again.
+	logging.FromContext(ctx).Infof("Claimed Ingress %s without an ingress class.", key)
+	return nil
+}
+
+// unsetIngressClass returns whether obj is an Ingress without a class annotation.
+func unsetIngressClass(obj interface{}) bool {
+	if mo, ok := obj.(metav1.Object); ok {
+		_, ok := mo.GetAnnotations()[networking.IngressClassAnnotationKey]
+		return !ok
+	}
+	return false
+}
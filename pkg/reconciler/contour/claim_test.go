@@ -0,0 +1,107 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgotesting "k8s.io/client-go/testing"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	fakeingressclientset "knative.dev/networking/pkg/client/clientset/versioned/fake"
+	"knative.dev/pkg/reconciler"
+
+	. "knative.dev/net-contour/pkg/reconciler/testing"
+)
+
+type fakeIngressReconciler struct {
+	reconciler.LeaderAwareFuncs
+	reconciled []string
+}
+
+func (r *fakeIngressReconciler) Reconcile(_ context.Context, key string) error {
+	r.reconciled = append(r.reconciled, key)
+	return nil
+}
+
+func TestClassClaimer(t *testing.T) {
+	tests := []struct {
+		name          string
+		claim         bool
+		ing           *v1alpha1.Ingress
+		wantPatch     string
+		wantReconcile bool
+	}{{
+		name:          "claiming disabled",
+		ing:           ing("name", "ns", withBasicSpec),
+		wantReconcile: true,
+	}, {
+		name:          "class already set",
+		claim:         true,
+		ing:           ing("name", "ns", withBasicSpec, withContour),
+		wantReconcile: true,
+	}, {
+		name:          "class set to another controller",
+		claim:         true,
+		ing:           ing("name", "ns", withBasicSpec, withAnnotation(map[string]string{"networking.knative.dev/ingress.class": "istio"})),
+		wantReconcile: true,
+	}, {
+		name:      "class unset",
+		claim:     true,
+		ing:       ing("name", "ns", withBasicSpec),
+		wantPatch: `{"metadata":{"annotations":{"networking.knative.dev/ingress.class":"contour.ingress.networking.knative.dev"}}}`,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inner := &fakeIngressReconciler{}
+			if err := inner.Promote(reconciler.UniversalBucket(), nil); err != nil {
+				t.Fatal("Promote() =", err)
+			}
+			tl := NewListers([]runtime.Object{test.ing})
+			client := fakeingressclientset.NewSimpleClientset(test.ing)
+			c := &classClaimer{
+				leaderAwareReconciler: inner,
+				ingressClient:         client,
+				ingressLister:         tl.GetIngressLister(),
+				className:             ContourIngressClassName,
+				contourConfig: func() *config.Contour {
+					return &config.Contour{ClaimUnsetIngressClass: test.claim}
+				},
+			}
+
+			if err := c.Reconcile(context.Background(), "ns/name"); err != nil {
+				t.Fatal("Reconcile() =", err)
+			}
+
+			var gotPatch string
+			for _, action := range client.Actions() {
+				if patch, ok := action.(clientgotesting.PatchAction); ok {
+					gotPatch = string(patch.GetPatch())
+				}
+			}
+			if gotPatch != test.wantPatch {
+				t.Errorf("Patch = %s, wanted %s", gotPatch, test.wantPatch)
+			}
+			if got := len(inner.reconciled) != 0; got != test.wantReconcile {
+				t.Errorf("Reconciled = %v, wanted %v", got, test.wantReconcile)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+)
+
+// withClassOverrides returns a context whose configuration routes the given
+// visibilities through the overriding Contour classes and their Envoys, so
+// that everything below programs and probes those instead.  It errors when
+// the class-overrides of config-contour don't approve a class for the
+// namespace.
+func withClassOverrides(ctx context.Context, ns *corev1.Namespace, overrides map[v1alpha1.IngressVisibility]string) (context.Context, error) {
+	if len(overrides) == 0 {
+		return ctx, nil
+	}
+	cfg := config.FromContext(ctx).DeepCopy()
+	if cfg.Contour.VisibilityClasses == nil {
+		cfg.Contour.VisibilityClasses = make(map[v1alpha1.IngressVisibility]string, len(overrides))
+	}
+	if cfg.Contour.VisibilityKeys == nil {
+		cfg.Contour.VisibilityKeys = make(map[v1alpha1.IngressVisibility]sets.String, len(overrides))
+	}
+	for vis, class := range overrides {
+		approved, ok := cfg.Contour.ClassOverrides[class]
+		if !ok {
+			return ctx, fmt.Errorf("the Contour class %q is not approved for overrides", class)
+		}
+		selector, err := labels.Parse(approved.Namespaces)
+		if err != nil {
+			return ctx, fmt.Errorf("failed to parse selector %q: %w", approved.Namespaces, err)
+		}
+		if ns == nil || !selector.Matches(labels.Set(ns.Labels)) {
+			return ctx, fmt.Errorf("the namespace isn't approved to use the Contour class %q", class)
+		}
+
+		cfg.Contour.VisibilityClasses[vis] = class
+		cfg.Contour.VisibilityKeys[vis] = sets.NewString(approved.Service)
+		// The Envoys of the class replace those discovered for the visibility.
+		delete(cfg.Contour.VisibilityGateways, vis)
+		delete(cfg.Contour.VisibilitySelectors, vis)
+	}
+	return config.ToContext(ctx, cfg), nil
+}
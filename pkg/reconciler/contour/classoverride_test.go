@@ -0,0 +1,124 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+)
+
+func TestWithClassOverrides(t *testing.T) {
+	pci := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "payments",
+		Labels: map[string]string{"compliance": "pci"},
+	}}
+	other := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}}
+
+	tests := []struct {
+		name        string
+		ns          *corev1.Namespace
+		overrides   map[v1alpha1.IngressVisibility]string
+		wantClasses map[v1alpha1.IngressVisibility]string
+		wantKeys    map[v1alpha1.IngressVisibility]sets.String
+		wantErr     bool
+	}{{
+		name: "no overrides",
+		ns:   pci,
+		wantClasses: map[v1alpha1.IngressVisibility]string{
+			v1alpha1.IngressVisibilityClusterLocal: "contour-internal",
+			v1alpha1.IngressVisibilityExternalIP:   "contour-external",
+		},
+		wantKeys: map[v1alpha1.IngressVisibility]sets.String{
+			v1alpha1.IngressVisibilityClusterLocal: sets.NewString(privateKey),
+			v1alpha1.IngressVisibilityExternalIP:   sets.NewString(publicKey),
+		},
+	}, {
+		name: "approved namespace",
+		ns:   pci,
+		overrides: map[v1alpha1.IngressVisibility]string{
+			v1alpha1.IngressVisibilityExternalIP: "contour-pci",
+		},
+		wantClasses: map[v1alpha1.IngressVisibility]string{
+			v1alpha1.IngressVisibilityClusterLocal: "contour-internal",
+			v1alpha1.IngressVisibilityExternalIP:   "contour-pci",
+		},
+		wantKeys: map[v1alpha1.IngressVisibility]sets.String{
+			v1alpha1.IngressVisibilityClusterLocal: sets.NewString(privateKey),
+			v1alpha1.IngressVisibilityExternalIP:   sets.NewString("contour-pci/envoy"),
+		},
+	}, {
+		name: "namespace not approved",
+		ns:   other,
+		overrides: map[v1alpha1.IngressVisibility]string{
+			v1alpha1.IngressVisibilityExternalIP: "contour-pci",
+		},
+		wantErr: true,
+	}, {
+		name: "no namespace",
+		overrides: map[v1alpha1.IngressVisibility]string{
+			v1alpha1.IngressVisibilityExternalIP: "contour-pci",
+		},
+		wantErr: true,
+	}, {
+		name: "class not approved",
+		ns:   pci,
+		overrides: map[v1alpha1.IngressVisibility]string{
+			v1alpha1.IngressVisibilityExternalIP: "contour-internal",
+		},
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := defaultConfig.DeepCopy()
+			cfg.Contour.VisibilityClasses = map[v1alpha1.IngressVisibility]string{
+				v1alpha1.IngressVisibilityClusterLocal: "contour-internal",
+				v1alpha1.IngressVisibilityExternalIP:   "contour-external",
+			}
+			cfg.Contour.ClassOverrides = map[string]config.ClassOverride{
+				"contour-pci": {Service: "contour-pci/envoy", Namespaces: "compliance=pci"},
+			}
+			ctx := (&testConfigStore{config: cfg}).ToContext(context.Background())
+
+			got, err := withClassOverrides(ctx, test.ns, test.overrides)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("withClassOverrides() = %v, wanted error %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			contour := config.FromContext(got).Contour
+			if !cmp.Equal(test.wantClasses, contour.VisibilityClasses) {
+				t.Error("VisibilityClasses (-want, +got):", cmp.Diff(test.wantClasses, contour.VisibilityClasses))
+			}
+			if !cmp.Equal(test.wantKeys, contour.VisibilityKeys) {
+				t.Error("VisibilityKeys (-want, +got):", cmp.Diff(test.wantKeys, contour.VisibilityKeys))
+			}
+			// The configuration of other Ingresses is left alone.
+			if c := config.FromContext(ctx).Contour.VisibilityClasses[v1alpha1.IngressVisibilityExternalIP]; c != "contour-external" {
+				t.Errorf("Original ExternalIP class = %q, wanted contour-external", c)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"strings"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/pkg/apis"
+)
+
+// The sub-conditions we report on top of LoadBalancerReady, so that users can
+// tell which stage of programming an Ingress is waiting for.  They are
+// informational and don't affect the Ingress' readiness themselves.
+const (
+	// EndpointsProbedCondition is whether the Envoys received the Endpoints
+	// of the Services of the Ingress' current generation.
+	EndpointsProbedCondition apis.ConditionType = "EndpointsProbed"
+
+	// ProxiesProgrammedCondition is whether we wrote the HTTPProxies of the
+	// Ingress' current generation.
+	ProxiesProgrammedCondition apis.ConditionType = "ProxiesProgrammed"
+
+	// CertificatesReadyCondition is whether Contour accepted the TLS
+	// certificates of the Ingress' HTTPProxies.
+	CertificatesReadyCondition apis.ConditionType = "CertificatesReady"
+)
+
+// FeaturesIgnoredCondition warns that parts of the Ingress' spec can't be
+// programmed into HTTPProxies and are dropped.  Unlike the sub-conditions
+// above it is only present while something is ignored.
+const FeaturesIgnoredCondition apis.ConditionType = "FeaturesIgnored"
+
+// DomainNotAllowedCondition warns that external hosts of the Ingress aren't
+// under the allowed-domains of config-contour and are not programmed.  Like
+// FeaturesIgnored it is only present while there are such hosts.
+const DomainNotAllowedCondition apis.ConditionType = "DomainNotAllowed"
+
+// DataPlaneNotReadyCondition warns that the Ingress was probed successfully
+// but is held back from becoming ready, as Contour hasn't shown that it
+// processes HTTPProxies yet.  It is only present while it holds it back.
+const DataPlaneNotReadyCondition apis.ConditionType = "DataPlaneNotReady"
+
+// RolloutPausedCondition warns that we hold the changes of the Ingress while
+// the Envoy pods roll out, or that we stopped doing so because their rollout
+// is stuck.  It is only present in either case.
+const RolloutPausedCondition apis.ConditionType = "RolloutPaused"
+
+// subConditions only manages the sub-conditions, which we set directly so that
+// they never touch the Ingress' Ready condition.
+var subConditions = apis.NewLivingConditionSet(
+	EndpointsProbedCondition,
+	ProxiesProgrammedCondition,
+	CertificatesReadyCondition,
+)
+
+func markSubCondition(ing *v1alpha1.Ingress, t apis.ConditionType, status corev1.ConditionStatus, reason, message string) {
+	subConditions.Manage(&ing.Status).SetCondition(apis.Condition{
+		Type:     t,
+		Status:   status,
+		Severity: apis.ConditionSeverityInfo,
+		Reason:   reason,
+		Message:  message,
+	})
+}
+
+// markFeaturesIgnored sets FeaturesIgnored when translation ignores any of
+// the given parts of the Ingress, and clears it otherwise.
+func markFeaturesIgnored(ing *v1alpha1.Ingress, ignored []string) {
+	if len(ignored) == 0 {
+		subConditions.Manage(&ing.Status).ClearCondition(FeaturesIgnoredCondition)
+		return
+	}
+	subConditions.Manage(&ing.Status).SetCondition(apis.Condition{
+		Type:     FeaturesIgnoredCondition,
+		Status:   corev1.ConditionTrue,
+		Severity: apis.ConditionSeverityWarning,
+		Reason:   "FeaturesIgnored",
+		Message:  "The installed Contour can't express, and ignores: " + strings.Join(ignored, "; "),
+	})
+}
+
+// markDomainsNotAllowed sets DomainNotAllowed when any of the external hosts
+// isn't allowed, and clears it otherwise.
+func markDomainsNotAllowed(ing *v1alpha1.Ingress, hosts []string) {
+	if len(hosts) == 0 {
+		subConditions.Manage(&ing.Status).ClearCondition(DomainNotAllowedCondition)
+		return
+	}
+	subConditions.Manage(&ing.Status).SetCondition(apis.Condition{
+		Type:     DomainNotAllowedCondition,
+		Status:   corev1.ConditionTrue,
+		Severity: apis.ConditionSeverityWarning,
+		Reason:   "DomainNotAllowed",
+		Message:  "These hosts aren't under the allowed domains and are not exposed externally: " + strings.Join(hosts, ", "),
+	})
+}
+
+// markDataPlaneNotReady sets DataPlaneNotReady while the Ingress is held back,
+// and clears it otherwise.
+func markDataPlaneNotReady(ing *v1alpha1.Ingress, heldBack bool) {
+	if !heldBack {
+		subConditions.Manage(&ing.Status).ClearCondition(DataPlaneNotReadyCondition)
+		return
+	}
+	subConditions.Manage(&ing.Status).SetCondition(apis.Condition{
+		Type:     DataPlaneNotReadyCondition,
+		Status:   corev1.ConditionTrue,
+		Severity: apis.ConditionSeverityWarning,
+		Reason:   "ContourNotProcessing",
+		Message:  "Waiting for Contour to process HTTPProxies before reporting the Ingress ready.",
+	})
+}
+
+// markRolloutPaused sets RolloutPaused with the given reason, and clears it
+// when the reason is empty.
+func markRolloutPaused(ing *v1alpha1.Ingress, reason, message string) {
+	if reason == "" {
+		subConditions.Manage(&ing.Status).ClearCondition(RolloutPausedCondition)
+		return
+	}
+	subConditions.Manage(&ing.Status).SetCondition(apis.Condition{
+		Type:     RolloutPausedCondition,
+		Status:   corev1.ConditionTrue,
+		Severity: apis.ConditionSeverityWarning,
+		Reason:   reason,
+		Message:  message,
+	})
+}
+
+// markCertificates sets CertificatesReady from the status Contour reported on
+// the HTTPProxies that terminate TLS.
+func markCertificates(ing *v1alpha1.Ingress, proxies []*contourv1.HTTPProxy) {
+	var (
+		errs    []string
+		pending bool
+	)
+	for _, proxy := range proxies {
+		if proxy.Spec.VirtualHost == nil || proxy.Spec.VirtualHost.TLS == nil {
+			continue
+		}
+		valid := findValidCondition(proxy)
+		if valid == nil || valid.ObservedGeneration != proxy.Generation {
+			pending = true
+			continue
+		}
+		for _, e := range valid.Errors {
+			if e.Type == contourv1.ConditionTypeTLSError {
+				errs = append(errs, proxy.Spec.VirtualHost.Fqdn+": "+e.Message)
+			}
+		}
+	}
+
+	switch {
+	case len(errs) != 0:
+		markSubCondition(ing, CertificatesReadyCondition, corev1.ConditionFalse,
+			"CertificatesInvalid", strings.Join(errs, "; "))
+	case pending:
+		markSubCondition(ing, CertificatesReadyCondition, corev1.ConditionUnknown,
+			"CertificatesPending", "Waiting for Contour to validate the TLS certificates.")
+	default:
+		markSubCondition(ing, CertificatesReadyCondition, corev1.ConditionTrue, "", "")
+	}
+}
+
+func findValidCondition(proxy *contourv1.HTTPProxy) *contourv1.DetailedCondition {
+	for i, cond := range proxy.Status.Conditions {
+		if cond.Type == contourv1.ValidConditionType {
+			return &proxy.Status.Conditions[i]
+		}
+	}
+	return nil
+}
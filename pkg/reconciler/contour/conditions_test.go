@@ -0,0 +1,144 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"strings"
+	"testing"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+)
+
+func TestMarkCertificates(t *testing.T) {
+	tlsProxy := func(status *contourv1.DetailedCondition) *contourv1.HTTPProxy {
+		p := &contourv1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{Generation: 2},
+			Spec: contourv1.HTTPProxySpec{
+				VirtualHost: &contourv1.VirtualHost{
+					Fqdn: "example.com",
+					TLS:  &contourv1.TLS{SecretName: "ns/secret"},
+				},
+			},
+		}
+		if status != nil {
+			p.Status.Conditions = []contourv1.DetailedCondition{*status}
+		}
+		return p
+	}
+	valid := func(generation int64, errs ...contourv1.SubCondition) *contourv1.DetailedCondition {
+		return &contourv1.DetailedCondition{
+			Condition: metav1.Condition{
+				Type:               contourv1.ValidConditionType,
+				Status:             metav1.ConditionTrue,
+				ObservedGeneration: generation,
+			},
+			Errors: errs,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		proxies []*contourv1.HTTPProxy
+		want    corev1.ConditionStatus
+		message string
+	}{{
+		name: "no proxies",
+		want: corev1.ConditionTrue,
+	}, {
+		name:    "no tls",
+		proxies: []*contourv1.HTTPProxy{{Spec: contourv1.HTTPProxySpec{VirtualHost: &contourv1.VirtualHost{Fqdn: "example.com"}}}},
+		want:    corev1.ConditionTrue,
+	}, {
+		name:    "not validated yet",
+		proxies: []*contourv1.HTTPProxy{tlsProxy(nil)},
+		want:    corev1.ConditionUnknown,
+		message: "Waiting for Contour to validate the TLS certificates.",
+	}, {
+		name:    "validated an older generation",
+		proxies: []*contourv1.HTTPProxy{tlsProxy(valid(1))},
+		want:    corev1.ConditionUnknown,
+		message: "Waiting for Contour to validate the TLS certificates.",
+	}, {
+		name:    "valid",
+		proxies: []*contourv1.HTTPProxy{tlsProxy(valid(2))},
+		want:    corev1.ConditionTrue,
+	}, {
+		name: "invalid certificate",
+		proxies: []*contourv1.HTTPProxy{tlsProxy(valid(2, contourv1.SubCondition{
+			Type:    contourv1.ConditionTypeTLSError,
+			Message: "Secret not found",
+		}))},
+		want:    corev1.ConditionFalse,
+		message: "example.com: Secret not found",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			i := ing("name", "ns")
+			markCertificates(i, test.proxies)
+			cond := i.Status.GetCondition(CertificatesReadyCondition)
+			if cond == nil {
+				t.Fatal("CertificatesReady is not set")
+			}
+			if cond.Status != test.want || cond.Message != test.message {
+				t.Errorf("CertificatesReady = %s %q, wanted %s %q", cond.Status, cond.Message, test.want, test.message)
+			}
+			if ready := i.Status.GetCondition("Ready"); ready != nil {
+				t.Errorf("Ready = %v, wanted it untouched", ready)
+			}
+		})
+	}
+}
+
+func TestMarkFeaturesIgnored(t *testing.T) {
+	i := ing("name", "ns")
+	markFeaturesIgnored(i, []string{"foo", "bar"})
+	cond := i.Status.GetCondition(FeaturesIgnoredCondition)
+	if cond == nil {
+		t.Fatal("FeaturesIgnored is not set")
+	}
+	if want := "The installed Contour can't express, and ignores: foo; bar"; cond.Status != corev1.ConditionTrue ||
+		cond.Severity != apis.ConditionSeverityWarning || cond.Message != want {
+		t.Errorf("FeaturesIgnored = %v, wanted a True warning with message %q", cond, want)
+	}
+
+	markFeaturesIgnored(i, nil)
+	if cond := i.Status.GetCondition(FeaturesIgnoredCondition); cond != nil {
+		t.Errorf("FeaturesIgnored = %v, wanted it cleared", cond)
+	}
+}
+
+func TestMarkDomainsNotAllowed(t *testing.T) {
+	i := ing("name", "ns")
+	markDomainsNotAllowed(i, []string{"a.example.com", "b.example.com"})
+	cond := i.Status.GetCondition(DomainNotAllowedCondition)
+	if cond == nil {
+		t.Fatal("DomainNotAllowed is not set")
+	}
+	if want := "a.example.com, b.example.com"; cond.Status != corev1.ConditionTrue ||
+		cond.Severity != apis.ConditionSeverityWarning || !strings.HasSuffix(cond.Message, want) {
+		t.Errorf("DomainNotAllowed = %v, wanted a True warning listing %q", cond, want)
+	}
+
+	markDomainsNotAllowed(i, nil)
+	if cond := i.Status.GetCondition(DomainNotAllowedCondition); cond != nil {
+		t.Errorf("DomainNotAllowed = %v, wanted it cleared", cond)
+	}
+}
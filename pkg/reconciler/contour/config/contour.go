@@ -17,13 +17,21 @@ limitations under the License.
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/tools/cache"
+	network "knative.dev/networking/pkg"
 	"knative.dev/networking/pkg/apis/networking/v1alpha1"
 	"knative.dev/pkg/configmap"
 	"sigs.k8s.io/yaml"
@@ -37,23 +45,270 @@ const (
 	visibilityConfigKey = "visibility"
 	// nolint:gosec // Not an actual secret.
 	defaultTLSSecretConfigKey = "default-tls-secret"
+	delegateDefaultTLSKey     = "delegate-default-tls-secret"
+	delegationNamespacesKey   = "delegate-default-tls-secret-namespaces"
+	authorizationServerKey    = "authorization-server"
+	proxyIncludesKey          = "proxy-includes"
+	exposeRequestIDKey        = "expose-request-id"
+	forwardedPrefixKey        = "forwarded-prefix"
+	splitOverrideHeaderKey    = "split-override-header"
+	networkPoliciesKey        = "generate-network-policies"
+	namespaceVisibilityKey    = "namespace-visibility"
+	classOverridesKey         = "class-overrides"
+	maxInvalidProxiesKey      = "max-invalid-proxies"
+	maxProxiesPerIngressKey   = "max-proxies-per-ingress"
+	allowedDomainsKey         = "allowed-domains"
+	probeKeepAliveKey         = "probe-keep-alive"
+	probeMaxIdleConnsKey      = "probe-max-idle-conns"
+	probeTLSHandshakeKey      = "probe-tls-handshake-timeout"
+	probeHostEchoHeaderKey    = "probe-host-echo-header"
+	staleProbeAgeKey          = "stale-probe-age"
 	timeoutPolicyIdleKey      = "timeout-policy-idle"
 	timeoutPolicyResponseKey  = "timeout-policy-response"
+	loadBalancerPolicyKey     = "load-balancer-policy"
+	endpointProbeTimeoutKey   = "endpoint-probe-timeout"
+	readinessQuorumKey        = "readiness-quorum"
+	pauseDuringRolloutsKey    = "pause-during-rollouts"
+	claimUnsetIngressClassKey = "claim-unset-ingress-class"
+	kubernetesIngressClassKey = "kubernetes-ingress-class"
+	shadowModeKey             = "shadow-mode"
+	probeOverHTTPSKey         = "probe-over-https"
+	probeCABundleKey          = "probe-ca-bundle"
+	probeInsecureKey          = "probe-insecure-skip-verify"
+	probeUserAgentKey         = "probe-user-agent"
+	probeHeadersKey           = "probe-headers"
+	probeStatusCodesKey       = "probe-expected-status-codes"
+	driftRepairPeriodKey      = "drift-repair-period"
+	maxInformerStalenessKey   = "max-informer-staleness"
+	defaultCORSPolicyKey      = "default-cors-policy"
+	probeTimeoutKey           = "probe-timeout"
+	probeSampleSizeKey        = "probe-sample-size"
+	probePathKey              = "probe-path"
+	probeHTTPPortKey          = "probe-http-port"
+	probeHTTPSPortKey         = "probe-https-port"
+	websocketResponseKey      = "websocket-timeout-policy-response"
+	websocketIdleKey          = "websocket-timeout-policy-idle"
+)
+
+// normalizeDomains returns the allowed-domains without empty entries, in
+// lower case and without the wildcard labels operators may prefix them with.
+func normalizeDomains(domains sets.String) (sets.String, error) {
+	normalized := sets.NewString()
+	for domain := range domains {
+		domain = strings.TrimSuffix(strings.TrimPrefix(strings.ToLower(domain), "*."), ".")
+		if domain == "" {
+			continue
+		}
+		if strings.ContainsAny(domain, "*/: ") {
+			return nil, fmt.Errorf("%s must list domain names, got %q", allowedDomainsKey, domain)
+		}
+		normalized.Insert(domain)
+	}
+	return normalized, nil
+}
+
+// validateDelegationNamespaces drops the empty entries of the namespaces, and
+// checks that the others are namespace names or "*".
+func validateDelegationNamespaces(namespaces sets.String) (sets.String, error) {
+	valid := sets.NewString()
+	for ns := range namespaces {
+		if ns == "" {
+			continue
+		}
+		if ns != "*" {
+			if errs := validation.IsDNS1123Label(ns); len(errs) != 0 {
+				return nil, fmt.Errorf("%s must list namespaces, got %q: %s", delegationNamespacesKey, ns, strings.Join(errs, ", "))
+			}
+		}
+		valid.Insert(ns)
+	}
+	return valid, nil
+}
+
+// loadBalancerStrategies are the load balancing strategies understood by
+// Contour's HTTPProxy loadBalancerPolicy.
+var loadBalancerStrategies = sets.NewString(
+	"RoundRobin",
+	"WeightedLeastRequest",
+	"Random",
+	"RequestHash",
+	"Cookie",
 )
 
 // Contour contains contour related configuration defined in the
 // contour config map.
 type Contour struct {
-	VisibilityKeys        map[v1alpha1.IngressVisibility]sets.String
-	VisibilityClasses     map[v1alpha1.IngressVisibility]string
+	VisibilityKeys    map[v1alpha1.IngressVisibility]sets.String
+	VisibilityClasses map[v1alpha1.IngressVisibility]string
+	// VisibilityGateways holds the Contour Gateway provisioning the Envoy
+	// Services of each visibility.  The Services are discovered at runtime
+	// instead of being listed in VisibilityKeys.
+	VisibilityGateways map[v1alpha1.IngressVisibility]types.NamespacedName
+	// VisibilitySelectors holds a label selector matching the Envoy Services
+	// of each visibility across all namespaces.  Like VisibilityGateways, the
+	// Services are discovered at runtime.
+	VisibilitySelectors   map[v1alpha1.IngressVisibility]string
 	DefaultTLSSecret      *types.NamespacedName
 	TimeoutPolicyResponse string
 	TimeoutPolicyIdle     string
+	// WebsocketResponseTimeout and WebsocketIdleTimeout replace the
+	// timeouts of the routes for websocket upgrade requests, so that
+	// long-lived connections outlive the timeouts of other requests.  Empty
+	// keeps the route's timeout.
+	WebsocketResponseTimeout string
+	WebsocketIdleTimeout     string
+	// LoadBalancerPolicies holds the default load balancing strategy to
+	// apply to the routes of each visibility.  Visibilities without an
+	// entry use Contour's default.
+	LoadBalancerPolicies map[v1alpha1.IngressVisibility]string
+	// EndpointProbeTimeout bounds how long we wait for the Envoys to receive
+	// the Endpoints of a new generation before failing its rollout and
+	// cleaning up the endpoint probe.  Zero means we wait forever.
+	EndpointProbeTimeout time.Duration
+	// ReadinessQuorum is the fraction of Envoy pods that must serve an
+	// Ingress' current version before it is marked ready.  With the default
+	// of 1 every pod must pass its probes.
+	ReadinessQuorum float64
+	// ProbeTimeout bounds each of the probes we send ourselves to count a
+	// readiness quorum or to check proxied TCP hosts.
+	ProbeTimeout time.Duration
+	// ProbeSampleSize bounds how many of the Envoy pods of each visibility we
+	// probe for a version of an Ingress.  Zero probes every pod.
+	ProbeSampleSize int
+	// ProbePath is the path Knative's probe path is appended to in the probes
+	// we send, for when they must traverse something routing by path.
+	ProbePath string
+	// ProbeHTTPPort and ProbeHTTPSPort override the ports of the Envoy pods we
+	// probe over HTTP and HTTPS, which we otherwise take from the Endpoints
+	// of their Service.  Zero keeps the port of the Endpoints.
+	ProbeHTTPPort  int
+	ProbeHTTPSPort int
+	// PauseDuringRollouts holds HTTPProxy changes and readiness flips while
+	// the Envoy pods of any visibility are rolling out, unless their rollout
+	// is stuck.
+	PauseDuringRollouts bool
+	// ClaimUnsetIngressClass makes us reconcile Ingresses without an ingress
+	// class annotation, for when net-contour is the default networking layer.
+	ClaimUnsetIngressClass bool
+	// KubernetesIngressClass is the class of the networking.k8s.io/v1
+	// Ingresses we program through the same pipeline as Knative Ingresses.
+	// Empty disables translating Kubernetes Ingresses.
+	KubernetesIngressClass string
+	// ShadowMode makes us only log the HTTPProxies we would program for
+	// every Ingress, whatever its class, without writing anything.
+	ShadowMode bool
+	// ProbeOverHTTPS makes us probe the Envoys of every visibility over
+	// HTTPS, for clusters whose Envoys don't serve plain HTTP.  Our own
+	// probes verify the certificates Envoy presents as ProbeCABundle and
+	// ProbeInsecureSkipVerify say, but the status prober of
+	// knative.dev/networking never verifies them.
+	ProbeOverHTTPS bool
+	// ProbeCABundle, when set, is the namespace/name of the ConfigMap whose
+	// ca.crt holds the certificates our HTTPS probes trust, instead of the
+	// system roots.
+	ProbeCABundle *types.NamespacedName
+	// ProbeInsecureSkipVerify makes our HTTPS probes accept any certificate
+	// Envoy presents.
+	ProbeInsecureSkipVerify bool
+	// ProbeUserAgent, when set, replaces the User-Agent of Knative's probes
+	// on the probes we send ourselves, e.g. for WAFs in front of Envoy to let
+	// them through.
+	ProbeUserAgent string
+	// ProbeHeaders are extra headers of the probes we send ourselves.
+	ProbeHeaders map[string]string
+	// ProbeExpectedStatusCodes, when set, are the only status codes of the
+	// responses to our probes that count as ready, instead of anything but
+	// 404 and 503.
+	ProbeExpectedStatusCodes []int
+	// DriftRepairPeriod is how often we reconcile every Ingress even when
+	// nothing changed, to repair HTTPProxies that were deleted or edited.
+	// Zero disables these resyncs.
+	DriftRepairPeriod time.Duration
+	// MaxInformerStaleness is how long our informers may be unable to watch
+	// the API server before we stop reconciling and report ourselves not
+	// ready.  Zero disables the check.
+	MaxInformerStaleness time.Duration
+	// DefaultCORSPolicy is the CORS policy of the external virtual hosts of
+	// Ingresses that don't set their own.  Nil disables CORS.
+	DefaultCORSPolicy *contourv1.CORSPolicy
+	// DelegateDefaultTLSSecret makes us delegate the DefaultTLSSecret to the
+	// namespaces of the Ingresses using it with a TLSCertificateDelegation,
+	// instead of relying on operators to set one up.
+	DelegateDefaultTLSSecret bool
+	// DelegationNamespaces, when not empty, are the only namespaces we
+	// delegate the DefaultTLSSecret to, instead of those of its Ingresses.
+	DelegationNamespaces sets.String
+	// AuthorizationServer, when set, is the ExtensionService that authorizes
+	// the requests to the external virtual hosts terminating TLS, which the
+	// per-route authorization annotations of Ingresses need.
+	AuthorizationServer *types.NamespacedName
+	// ProxyIncludes makes the HTTPProxy of each host include the routes of
+	// its rule from a shared HTTPProxy, instead of carrying its own copy.
+	ProxyIncludes bool
+	// ExposeRequestID makes the routes we generate return the X-Request-Id
+	// Envoy gives each request to the callers.
+	ExposeRequestID bool
+	// GenerateNetworkPolicies makes us allow the traffic from the Envoys to
+	// the backends of each Ingress with a NetworkPolicy, in the namespaces
+	// denying ingress traffic by default.
+	GenerateNetworkPolicies bool
+	// NamespaceVisibility holds a label selector of the namespaces whose
+	// Ingresses are only ever exposed with each visibility, regardless of
+	// the visibility of their rules.
+	NamespaceVisibility map[v1alpha1.IngressVisibility]string
+	// MaxInvalidProxies is how many of our HTTPProxies Contour may reject
+	// before we report ourselves unready.  Negative never does.
+	MaxInvalidProxies int
+	// MaxProxiesPerIngress is how many HTTPProxies a single Ingress may
+	// generate before we fail it instead of programming them.  Zero allows
+	// any number.
+	MaxProxiesPerIngress int
+	// AllowedDomains holds the domains under which we may program external
+	// hosts, which is any when empty.
+	AllowedDomains sets.String
+	// ProbeKeepAlive is how long the connections of the probes we send
+	// ourselves stay open for the next probes of the same Envoy pod.  Zero
+	// opens new connections for every round of probes.
+	ProbeKeepAlive time.Duration
+	// ProbeMaxIdleConns bounds the connections kept open per Envoy pod and
+	// probed host.
+	ProbeMaxIdleConns int
+	// ProbeTLSHandshakeTimeout bounds the TLS handshakes of our probes.
+	ProbeTLSHandshakeTimeout time.Duration
+	// ProbeHostEchoHeader, when set, is the response header in which the
+	// upstreams echo the Host they received, which our probes compare with
+	// the RewriteHost of the probed paths.
+	ProbeHostEchoHeader string
+	// StaleProbeAge is the age from which the endpoint probe Ingresses and
+	// their HTTPProxies count as leaked in the stale_probe_resources metric.
+	StaleProbeAge time.Duration
+	// ForwardedPrefix makes the routes matching a path prefix tell their
+	// backends that prefix in the X-Forwarded-Prefix header.
+	ForwardedPrefix bool
+	// SplitOverrideHeader, when set, is the request header naming the
+	// backend Service of a split to route the request to regardless of the
+	// split percents.
+	SplitOverrideHeader string
+	// ClassOverrides holds the Contour classes Ingresses may override the
+	// class of their visibilities with, by class.
+	ClassOverrides map[string]ClassOverride
 }
 
-type visibilityValue struct {
-	Class   string `json:"class"`
+// ClassOverride approves a Contour class for the class-override annotation of
+// the Ingresses of some namespaces.
+type ClassOverride struct {
+	// Service is the namespace/name of the Envoy Service of the class, which
+	// we probe instead of the Envoys of the overridden visibility.
 	Service string `json:"service"`
+	// Namespaces is a label selector of the namespaces allowed to use it.
+	Namespaces string `json:"namespaces"`
+}
+
+type visibilityValue struct {
+	Class    string `json:"class"`
+	Service  string `json:"service,omitempty"`
+	Gateway  string `json:"gateway,omitempty"`
+	Selector string `json:"selector,omitempty"`
 }
 
 // NewContourFromConfigMap creates an Contour config from the supplied ConfigMap
@@ -61,31 +316,234 @@ func NewContourFromConfigMap(configMap *corev1.ConfigMap) (*Contour, error) {
 	var tlsSecret *types.NamespacedName
 	var timeoutPolicyResponse = "infinity"
 	var timeoutPolicyIdle = "infinity"
+	var websocketResponseTimeout = "infinity"
+	var websocketIdleTimeout = "infinity"
+	var endpointProbeTimeout time.Duration
+	var readinessQuorum = 1.0
+	var probeTimeout = time.Second
+	var probeSampleSize int
+	var delegateDefaultTLSSecret bool
+	var delegationNamespaces sets.String
+	var authorizationServer *types.NamespacedName
+	var proxyIncludes bool
+	var exposeRequestID bool
+	var forwardedPrefix bool
+	var splitOverrideHeader string
+	var generateNetworkPolicies bool
+	var maxInvalidProxies = -1
+	var maxProxiesPerIngress int
+	var allowedDomains sets.String
+	var probeKeepAlive = 90 * time.Second
+	var probeMaxIdleConns = 2
+	var probeTLSHandshakeTimeout = 10 * time.Second
+	var probeHostEchoHeader string
+	var staleProbeAge = 30 * time.Minute
+	var probePath string
+	var probeHTTPPort int
+	var probeHTTPSPort int
+	var pauseDuringRollouts bool
+	var claimUnsetIngressClass bool
+	var kubernetesIngressClass string
+	var shadowMode bool
+	var probeOverHTTPS bool
+	var probeCABundle *types.NamespacedName
+	var probeInsecureSkipVerify bool
+	var probeUserAgent string
+	var probeStatusCodes []int
+	var driftRepairPeriod time.Duration
+	var maxInformerStaleness = 2 * time.Minute
 
 	if err := configmap.Parse(configMap.Data,
 		configmap.AsOptionalNamespacedName(defaultTLSSecretConfigKey, &tlsSecret),
+		configmap.AsOptionalNamespacedName(authorizationServerKey, &authorizationServer),
 		asContourDuration(timeoutPolicyResponseKey, &timeoutPolicyResponse),
 		asContourDuration(timeoutPolicyIdleKey, &timeoutPolicyIdle),
+		asContourDuration(websocketResponseKey, &websocketResponseTimeout),
+		asContourDuration(websocketIdleKey, &websocketIdleTimeout),
+		configmap.AsDuration(endpointProbeTimeoutKey, &endpointProbeTimeout),
+		configmap.AsFloat64(readinessQuorumKey, &readinessQuorum),
+		configmap.AsDuration(probeTimeoutKey, &probeTimeout),
+		configmap.AsInt(probeSampleSizeKey, &probeSampleSize),
+		configmap.AsBool(delegateDefaultTLSKey, &delegateDefaultTLSSecret),
+		configmap.AsStringSet(delegationNamespacesKey, &delegationNamespaces),
+		configmap.AsBool(proxyIncludesKey, &proxyIncludes),
+		configmap.AsBool(exposeRequestIDKey, &exposeRequestID),
+		configmap.AsBool(forwardedPrefixKey, &forwardedPrefix),
+		configmap.AsString(splitOverrideHeaderKey, &splitOverrideHeader),
+		configmap.AsBool(networkPoliciesKey, &generateNetworkPolicies),
+		configmap.AsInt(maxInvalidProxiesKey, &maxInvalidProxies),
+		configmap.AsInt(maxProxiesPerIngressKey, &maxProxiesPerIngress),
+		configmap.AsStringSet(allowedDomainsKey, &allowedDomains),
+		configmap.AsDuration(probeKeepAliveKey, &probeKeepAlive),
+		configmap.AsInt(probeMaxIdleConnsKey, &probeMaxIdleConns),
+		configmap.AsDuration(probeTLSHandshakeKey, &probeTLSHandshakeTimeout),
+		configmap.AsString(probeHostEchoHeaderKey, &probeHostEchoHeader),
+		configmap.AsDuration(staleProbeAgeKey, &staleProbeAge),
+		configmap.AsString(probePathKey, &probePath),
+		configmap.AsInt(probeHTTPPortKey, &probeHTTPPort),
+		configmap.AsInt(probeHTTPSPortKey, &probeHTTPSPort),
+		configmap.AsBool(pauseDuringRolloutsKey, &pauseDuringRollouts),
+		configmap.AsBool(claimUnsetIngressClassKey, &claimUnsetIngressClass),
+		configmap.AsString(kubernetesIngressClassKey, &kubernetesIngressClass),
+		configmap.AsBool(shadowModeKey, &shadowMode),
+		configmap.AsBool(probeOverHTTPSKey, &probeOverHTTPS),
+		configmap.AsOptionalNamespacedName(probeCABundleKey, &probeCABundle),
+		configmap.AsBool(probeInsecureKey, &probeInsecureSkipVerify),
+		configmap.AsString(probeUserAgentKey, &probeUserAgent),
+		asStatusCodes(probeStatusCodesKey, &probeStatusCodes),
+		configmap.AsDuration(driftRepairPeriodKey, &driftRepairPeriod),
+		configmap.AsDuration(maxInformerStalenessKey, &maxInformerStaleness),
 	); err != nil {
 		return nil, err
 	}
+	if readinessQuorum <= 0 || readinessQuorum > 1 {
+		return nil, fmt.Errorf("%s must be in the range (0, 1], got %v", readinessQuorumKey, readinessQuorum)
+	}
+	if probeTimeout <= 0 {
+		return nil, fmt.Errorf("%s must be positive, got %v", probeTimeoutKey, probeTimeout)
+	}
+	if probeSampleSize < 0 {
+		return nil, fmt.Errorf("%s must not be negative, got %d", probeSampleSizeKey, probeSampleSize)
+	}
+	if probePath != "" && !strings.HasPrefix(probePath, "/") {
+		return nil, fmt.Errorf("%s must start with a slash, got %q", probePathKey, probePath)
+	}
+	if probeHTTPPort < 0 || probeHTTPPort > 65535 {
+		return nil, fmt.Errorf("%s must be a port number, got %d", probeHTTPPortKey, probeHTTPPort)
+	}
+	if probeHTTPSPort < 0 || probeHTTPSPort > 65535 {
+		return nil, fmt.Errorf("%s must be a port number, got %d", probeHTTPSPortKey, probeHTTPSPort)
+	}
+	if probeCABundle != nil && probeInsecureSkipVerify {
+		return nil, fmt.Errorf("%s and %s are mutually exclusive", probeCABundleKey, probeInsecureKey)
+	}
+	if driftRepairPeriod < 0 {
+		return nil, fmt.Errorf("%s must not be negative, got %v", driftRepairPeriodKey, driftRepairPeriod)
+	}
+	if maxInformerStaleness < 0 {
+		return nil, fmt.Errorf("%s must not be negative, got %v", maxInformerStalenessKey, maxInformerStaleness)
+	}
+	if probeKeepAlive < 0 {
+		return nil, fmt.Errorf("%s must not be negative, got %v", probeKeepAliveKey, probeKeepAlive)
+	}
+	if probeMaxIdleConns < 1 {
+		return nil, fmt.Errorf("%s must be positive, got %d", probeMaxIdleConnsKey, probeMaxIdleConns)
+	}
+	if probeTLSHandshakeTimeout <= 0 {
+		return nil, fmt.Errorf("%s must be positive, got %v", probeTLSHandshakeKey, probeTLSHandshakeTimeout)
+	}
+	if strings.ContainsAny(probeHostEchoHeader, " \t\r\n:") {
+		return nil, fmt.Errorf("%s must be a header name, got %q", probeHostEchoHeaderKey, probeHostEchoHeader)
+	}
+	if strings.ContainsAny(splitOverrideHeader, " \t\r\n:") {
+		return nil, fmt.Errorf("%s must be a header name, got %q", splitOverrideHeaderKey, splitOverrideHeader)
+	}
+	if maxProxiesPerIngress < 0 {
+		return nil, fmt.Errorf("%s must not be negative, got %d", maxProxiesPerIngressKey, maxProxiesPerIngress)
+	}
+	if staleProbeAge <= 0 {
+		return nil, fmt.Errorf("%s must be positive, got %v", staleProbeAgeKey, staleProbeAge)
+	}
+	if endpointProbeTimeout < 0 {
+		return nil, fmt.Errorf("%s must not be negative, got %v", endpointProbeTimeoutKey, endpointProbeTimeout)
+	}
+
+	allowedDomains, err := normalizeDomains(allowedDomains)
+	if err != nil {
+		return nil, err
+	}
+
+	delegationNamespaces, err = validateDelegationNamespaces(delegationNamespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	lbPolicies, err := parseLoadBalancerPolicies(configMap.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaceVisibility, err := parseNamespaceVisibility(configMap.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	classOverrides, err := parseClassOverrides(configMap.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	probeHeaders, err := parseProbeHeaders(configMap.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	var corsPolicy *contourv1.CORSPolicy
+	if raw, ok := configMap.Data[defaultCORSPolicyKey]; ok {
+		if corsPolicy, err = ParseCORSPolicy(raw); err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %w", defaultCORSPolicyKey, err)
+		}
+	}
+
+	contour := &Contour{
+		DefaultTLSSecret:         tlsSecret,
+		DelegateDefaultTLSSecret: delegateDefaultTLSSecret,
+		DelegationNamespaces:     delegationNamespaces,
+		AuthorizationServer:      authorizationServer,
+		ProxyIncludes:            proxyIncludes,
+		ExposeRequestID:          exposeRequestID,
+		GenerateNetworkPolicies:  generateNetworkPolicies,
+		NamespaceVisibility:      namespaceVisibility,
+		MaxInvalidProxies:        maxInvalidProxies,
+		MaxProxiesPerIngress:     maxProxiesPerIngress,
+		AllowedDomains:           allowedDomains,
+		ProbeKeepAlive:           probeKeepAlive,
+		ProbeMaxIdleConns:        probeMaxIdleConns,
+		ProbeTLSHandshakeTimeout: probeTLSHandshakeTimeout,
+		ProbeHostEchoHeader:      probeHostEchoHeader,
+		StaleProbeAge:            staleProbeAge,
+		ForwardedPrefix:          forwardedPrefix,
+		SplitOverrideHeader:      splitOverrideHeader,
+		ClassOverrides:           classOverrides,
+		TimeoutPolicyResponse:    timeoutPolicyResponse,
+		TimeoutPolicyIdle:        timeoutPolicyIdle,
+		WebsocketResponseTimeout: websocketResponseTimeout,
+		WebsocketIdleTimeout:     websocketIdleTimeout,
+		LoadBalancerPolicies:     lbPolicies,
+		EndpointProbeTimeout:     endpointProbeTimeout,
+		ReadinessQuorum:          readinessQuorum,
+		ProbeTimeout:             probeTimeout,
+		ProbeSampleSize:          probeSampleSize,
+		ProbePath:                probePath,
+		ProbeHTTPPort:            probeHTTPPort,
+		ProbeHTTPSPort:           probeHTTPSPort,
+		PauseDuringRollouts:      pauseDuringRollouts,
+		ClaimUnsetIngressClass:   claimUnsetIngressClass,
+		KubernetesIngressClass:   kubernetesIngressClass,
+		ShadowMode:               shadowMode,
+		ProbeOverHTTPS:           probeOverHTTPS,
+		ProbeCABundle:            probeCABundle,
+		ProbeInsecureSkipVerify:  probeInsecureSkipVerify,
+		ProbeUserAgent:           probeUserAgent,
+		ProbeHeaders:             probeHeaders,
+		ProbeExpectedStatusCodes: probeStatusCodes,
+		DriftRepairPeriod:        driftRepairPeriod,
+		MaxInformerStaleness:     maxInformerStaleness,
+		DefaultCORSPolicy:        corsPolicy,
+	}
 
 	v, ok := configMap.Data[visibilityConfigKey]
 	if !ok {
 		// These are the defaults.
-		return &Contour{
-			DefaultTLSSecret: tlsSecret,
-			VisibilityKeys: map[v1alpha1.IngressVisibility]sets.String{
-				v1alpha1.IngressVisibilityClusterLocal: sets.NewString("contour-internal/envoy"),
-				v1alpha1.IngressVisibilityExternalIP:   sets.NewString("contour-external/envoy"),
-			},
-			VisibilityClasses: map[v1alpha1.IngressVisibility]string{
-				v1alpha1.IngressVisibilityClusterLocal: "contour-internal",
-				v1alpha1.IngressVisibilityExternalIP:   "contour-external",
-			},
-			TimeoutPolicyResponse: timeoutPolicyResponse,
-			TimeoutPolicyIdle:     timeoutPolicyIdle,
-		}, nil
+		contour.VisibilityKeys = map[v1alpha1.IngressVisibility]sets.String{
+			v1alpha1.IngressVisibilityClusterLocal: sets.NewString("contour-internal/envoy"),
+			v1alpha1.IngressVisibilityExternalIP:   sets.NewString("contour-external/envoy"),
+		}
+		contour.VisibilityClasses = map[v1alpha1.IngressVisibility]string{
+			v1alpha1.IngressVisibilityClusterLocal: "contour-internal",
+			v1alpha1.IngressVisibilityExternalIP:   "contour-external",
+		}
+		return contour, nil
 	}
 	entry := make(map[v1alpha1.IngressVisibility]visibilityValue)
 	if err := yaml.Unmarshal([]byte(v), &entry); err != nil {
@@ -101,13 +559,8 @@ func NewContourFromConfigMap(configMap *corev1.ConfigMap) (*Contour, error) {
 		}
 	}
 
-	contour := &Contour{
-		DefaultTLSSecret:      tlsSecret,
-		VisibilityKeys:        make(map[v1alpha1.IngressVisibility]sets.String, 2),
-		VisibilityClasses:     make(map[v1alpha1.IngressVisibility]string, 2),
-		TimeoutPolicyResponse: timeoutPolicyResponse,
-		TimeoutPolicyIdle:     timeoutPolicyIdle,
-	}
+	contour.VisibilityKeys = make(map[v1alpha1.IngressVisibility]sets.String, 2)
+	contour.VisibilityClasses = make(map[v1alpha1.IngressVisibility]string, 2)
 	for key, value := range entry {
 		// Check that the visibility makes sense.
 		switch key {
@@ -116,11 +569,41 @@ func NewContourFromConfigMap(configMap *corev1.ConfigMap) (*Contour, error) {
 			return nil, fmt.Errorf("unrecognized visibility: %q", key)
 		}
 
-		// See if the Service is a valid namespace/name token.
-		if _, _, err := cache.SplitMetaNamespaceKey(value.Service); err != nil {
-			return nil, err
+		switch {
+		case countNonEmpty(value.Service, value.Gateway, value.Selector) > 1:
+			return nil, fmt.Errorf("visibility %q must specify only one of service, gateway or selector", key)
+
+		case value.Selector != "":
+			if _, err := labels.Parse(value.Selector); err != nil {
+				return nil, fmt.Errorf("failed to parse selector for visibility %q: %w", key, err)
+			}
+			if contour.VisibilitySelectors == nil {
+				contour.VisibilitySelectors = make(map[v1alpha1.IngressVisibility]string, 2)
+			}
+			contour.VisibilitySelectors[key] = value.Selector
+			contour.VisibilityKeys[key] = sets.NewString()
+
+		case value.Gateway != "":
+			namespace, name, err := cache.SplitMetaNamespaceKey(value.Gateway)
+			if err != nil {
+				return nil, err
+			}
+			if namespace == "" {
+				return nil, fmt.Errorf("gateway for visibility %q must be of the form namespace/name, got %q", key, value.Gateway)
+			}
+			if contour.VisibilityGateways == nil {
+				contour.VisibilityGateways = make(map[v1alpha1.IngressVisibility]types.NamespacedName, 2)
+			}
+			contour.VisibilityGateways[key] = types.NamespacedName{Namespace: namespace, Name: name}
+			contour.VisibilityKeys[key] = sets.NewString()
+
+		default:
+			// See if the Service is a valid namespace/name token.
+			if _, _, err := cache.SplitMetaNamespaceKey(value.Service); err != nil {
+				return nil, err
+			}
+			contour.VisibilityKeys[key] = sets.NewString(value.Service)
 		}
-		contour.VisibilityKeys[key] = sets.NewString(value.Service)
 		contour.VisibilityClasses[key] = value.Class
 	}
 	return contour, nil
@@ -142,3 +625,161 @@ func asContourDuration(key string, target *string) configmap.ParseFunc {
 		return nil
 	}
 }
+
+// asStatusCodes parses a comma separated list of HTTP status codes.
+func asStatusCodes(key string, target *[]int) configmap.ParseFunc {
+	return func(data map[string]string) error {
+		raw, ok := data[key]
+		if !ok {
+			return nil
+		}
+		var codes []int
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v == "" {
+				continue
+			}
+			code, err := strconv.Atoi(v)
+			if err != nil || code < 100 || code > 599 {
+				return fmt.Errorf("%s must list HTTP status codes, got %q", key, v)
+			}
+			codes = append(codes, code)
+		}
+		*target = codes
+		return nil
+	}
+}
+
+func countNonEmpty(values ...string) (n int) {
+	for _, v := range values {
+		if v != "" {
+			n++
+		}
+	}
+	return
+}
+
+func parseLoadBalancerPolicies(data map[string]string) (map[v1alpha1.IngressVisibility]string, error) {
+	raw, ok := data[loadBalancerPolicyKey]
+	if !ok {
+		return nil, nil
+	}
+	entry := make(map[v1alpha1.IngressVisibility]string)
+	if err := yaml.Unmarshal([]byte(raw), &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %w", loadBalancerPolicyKey, err)
+	}
+	for vis, strategy := range entry {
+		switch vis {
+		case v1alpha1.IngressVisibilityClusterLocal, v1alpha1.IngressVisibilityExternalIP:
+		default:
+			return nil, fmt.Errorf("unrecognized visibility in %q: %q", loadBalancerPolicyKey, vis)
+		}
+		if !loadBalancerStrategies.Has(strategy) {
+			return nil, fmt.Errorf("unsupported load balancer strategy for %q: %q, must be one of %v",
+				vis, strategy, loadBalancerStrategies.List())
+		}
+	}
+	return entry, nil
+}
+
+func parseNamespaceVisibility(data map[string]string) (map[v1alpha1.IngressVisibility]string, error) {
+	raw, ok := data[namespaceVisibilityKey]
+	if !ok {
+		return nil, nil
+	}
+	entry := make(map[v1alpha1.IngressVisibility]string)
+	if err := yaml.Unmarshal([]byte(raw), &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %w", namespaceVisibilityKey, err)
+	}
+	for vis, selector := range entry {
+		switch vis {
+		case v1alpha1.IngressVisibilityClusterLocal, v1alpha1.IngressVisibilityExternalIP:
+		default:
+			return nil, fmt.Errorf("unrecognized visibility in %q: %q", namespaceVisibilityKey, vis)
+		}
+		if _, err := labels.Parse(selector); err != nil {
+			return nil, fmt.Errorf("failed to parse namespace selector for visibility %q: %w", vis, err)
+		}
+	}
+	return entry, nil
+}
+
+func parseClassOverrides(data map[string]string) (map[string]ClassOverride, error) {
+	raw, ok := data[classOverridesKey]
+	if !ok {
+		return nil, nil
+	}
+	entry := make(map[string]ClassOverride)
+	if err := yaml.Unmarshal([]byte(raw), &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %w", classOverridesKey, err)
+	}
+	for class, o := range entry {
+		if class == "" {
+			return nil, fmt.Errorf("%s must not approve an empty class", classOverridesKey)
+		}
+		if namespace, _, err := cache.SplitMetaNamespaceKey(o.Service); err != nil || namespace == "" {
+			return nil, fmt.Errorf("service of class %q in %s must be of the form namespace/name, got %q",
+				class, classOverridesKey, o.Service)
+		}
+		// An empty selector would select every namespace, which is what
+		// approving the class is meant to prevent.
+		if o.Namespaces == "" {
+			return nil, fmt.Errorf("class %q in %s must select the namespaces allowed to use it", class, classOverridesKey)
+		}
+		if _, err := labels.Parse(o.Namespaces); err != nil {
+			return nil, fmt.Errorf("failed to parse namespace selector of class %q: %w", class, err)
+		}
+	}
+	return entry, nil
+}
+
+// reservedProbeHeaders are the headers of our probes that probe-headers
+// can't set, as the probes rely on them or other keys set them.
+var reservedProbeHeaders = sets.NewString(
+	"Host",
+	network.UserAgentKey,
+	network.ProbeHeaderName,
+	network.HashHeaderName,
+)
+
+func parseProbeHeaders(data map[string]string) (map[string]string, error) {
+	raw, ok := data[probeHeadersKey]
+	if !ok {
+		return nil, nil
+	}
+	entry := make(map[string]string)
+	if err := yaml.Unmarshal([]byte(raw), &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %w", probeHeadersKey, err)
+	}
+	for name := range entry {
+		if name == "" || strings.ContainsAny(name, " \t\r\n:") {
+			return nil, fmt.Errorf("%s must map header names to values, got %q", probeHeadersKey, name)
+		}
+		if reservedProbeHeaders.Has(http.CanonicalHeaderKey(name)) {
+			return nil, fmt.Errorf("%s must not set %s", probeHeadersKey, name)
+		}
+	}
+	return entry, nil
+}
+
+// ParseCORSPolicy parses a Contour CORS policy from its YAML or JSON form and
+// checks that Contour will accept it.
+func ParseCORSPolicy(raw string) (*contourv1.CORSPolicy, error) {
+	policy := &contourv1.CORSPolicy{}
+	if err := yaml.UnmarshalStrict([]byte(raw), policy); err != nil {
+		return nil, err
+	}
+	if len(policy.AllowOrigin) == 0 {
+		return nil, errors.New("allowOrigin must list at least one origin")
+	}
+	if len(policy.AllowMethods) == 0 {
+		return nil, errors.New("allowMethods must list at least one method")
+	}
+	if policy.MaxAge != "" {
+		if d, err := time.ParseDuration(policy.MaxAge); err != nil {
+			return nil, fmt.Errorf("invalid maxAge: %w", err)
+		} else if d < 0 {
+			return nil, fmt.Errorf("maxAge must not be negative, got %v", d)
+		}
+	}
+	return policy, nil
+}
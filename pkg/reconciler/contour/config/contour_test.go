@@ -18,10 +18,15 @@ package config
 
 import (
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
 	"knative.dev/pkg/system"
 
 	. "knative.dev/pkg/configmap/testing"
@@ -60,6 +65,16 @@ func TestDefaultTLSSecret(t *testing.T) {
 	if got := cfg.DefaultTLSSecret; got == nil || *got != want {
 		t.Errorf("TLSDefaultSecretName got %q want %q", got, want)
 	}
+	if cfg.DelegateDefaultTLSSecret {
+		t.Error("DelegateDefaultTLSSecret = true by default, wanted false")
+	}
+
+	cm.Data[delegateDefaultTLSKey] = "true"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Error("NewContourFromConfigMap(delegate-default-tls-secret:true) =", err)
+	} else if !cfg.DelegateDefaultTLSSecret {
+		t.Error("DelegateDefaultTLSSecret = false, wanted true")
+	}
 
 	delete(cm.Data, "default-tls-secret")
 
@@ -195,92 +210,1332 @@ func TestTimeoutPolicyIdle(t *testing.T) {
 	}
 }
 
-func TestConfigurationErrors(t *testing.T) {
+func TestLoadBalancerPolicy(t *testing.T) {
 	tests := []struct {
 		name    string
+		data    map[string]string
+		want    map[v1alpha1.IngressVisibility]string
 		wantErr bool
-		config  *corev1.ConfigMap
 	}{{
-		name:    "no errors",
-		wantErr: false,
-		config: &corev1.ConfigMap{
-			ObjectMeta: metav1.ObjectMeta{
-				Namespace: system.Namespace(),
-				Name:      ContourConfigName,
-			},
-			Data: map[string]string{
-				visibilityConfigKey: `
-ExternalIP:
-  service: foo/bar
-  class: baz
-ClusterLocal:
-  service: blah/bleh
-  class: bloop`,
-			},
+		name: "not set",
+		data: map[string]string{},
+	}, {
+		name: "per visibility",
+		data: map[string]string{
+			loadBalancerPolicyKey: `
+ExternalIP: Cookie
+ClusterLocal: WeightedLeastRequest`,
+		},
+		want: map[v1alpha1.IngressVisibility]string{
+			v1alpha1.IngressVisibilityExternalIP:   "Cookie",
+			v1alpha1.IngressVisibilityClusterLocal: "WeightedLeastRequest",
 		},
 	}, {
-		name:    "failure parsing yaml",
-		wantErr: true,
-		config: &corev1.ConfigMap{
-			ObjectMeta: metav1.ObjectMeta{
-				Namespace: system.Namespace(),
-				Name:      ContourConfigName,
-			},
-			Data: map[string]string{
-				visibilityConfigKey: `
-ExternalIP:
-  service: foo/bar
-  class: baz
-ClusterLocal: bad-yaml`,
-			},
+		name: "single visibility",
+		data: map[string]string{
+			loadBalancerPolicyKey: `ClusterLocal: Random`,
+		},
+		want: map[v1alpha1.IngressVisibility]string{
+			v1alpha1.IngressVisibilityClusterLocal: "Random",
 		},
 	}, {
-		name:    "missing cluster local",
+		name: "unknown strategy",
+		data: map[string]string{
+			loadBalancerPolicyKey: `ExternalIP: Sticky`,
+		},
 		wantErr: true,
-		config: &corev1.ConfigMap{
-			ObjectMeta: metav1.ObjectMeta{
-				Namespace: system.Namespace(),
-				Name:      ContourConfigName,
-			},
-			Data: map[string]string{
-				visibilityConfigKey: `
-ExternalIP:
-  service: foo/bar
-  class: baz`,
-			},
+	}, {
+		name: "unknown visibility",
+		data: map[string]string{
+			loadBalancerPolicyKey: `Public: Cookie`,
 		},
+		wantErr: true,
 	}, {
-		name:    "missing external",
+		name: "bad yaml",
+		data: map[string]string{
+			loadBalancerPolicyKey: `ExternalIP: [Cookie`,
+		},
 		wantErr: true,
-		config: &corev1.ConfigMap{
-			ObjectMeta: metav1.ObjectMeta{
-				Namespace: system.Namespace(),
-				Name:      ContourConfigName,
-			},
-			Data: map[string]string{
-				visibilityConfigKey: `
-ClusterLocal:
-  service: foo/bar
-  class: baz`,
-			},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := NewContourFromConfigMap(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: system.Namespace(),
+					Name:      ContourConfigName,
+				},
+				Data: tt.data,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewContourFromConfigMap() error = %v, WantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !cmp.Equal(tt.want, cfg.LoadBalancerPolicies) {
+				t.Error("LoadBalancerPolicies (-want, +got):", cmp.Diff(tt.want, cfg.LoadBalancerPolicies))
+			}
+		})
+	}
+}
+
+func TestEndpointProbeTimeout(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.EndpointProbeTimeout != 0 {
+		t.Errorf("EndpointProbeTimeout = %v by default, wanted 0", cfg.EndpointProbeTimeout)
+	}
+
+	cm.Data[endpointProbeTimeoutKey] = "5m"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap(endpoint-probe-timeout:5m) =", err)
+	}
+	if got, want := cfg.EndpointProbeTimeout, 5*time.Minute; got != want {
+		t.Errorf("EndpointProbeTimeout = %v, wanted %v", got, want)
+	}
+
+	for _, bad := range []string{"-1m", "soon"} {
+		cm.Data[endpointProbeTimeoutKey] = bad
+		if _, err := NewContourFromConfigMap(cm); err == nil {
+			t.Errorf("NewContourFromConfigMap(endpoint-probe-timeout:%s) succeeded, wanted error", bad)
+		}
+	}
+}
+
+func TestStaleProbeAge(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if got, want := cfg.StaleProbeAge, 30*time.Minute; got != want {
+		t.Errorf("StaleProbeAge = %v by default, wanted %v", got, want)
+	}
+
+	cm.Data[staleProbeAgeKey] = "1h"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap(stale-probe-age:1h) =", err)
+	}
+	if got, want := cfg.StaleProbeAge, time.Hour; got != want {
+		t.Errorf("StaleProbeAge = %v, wanted %v", got, want)
+	}
+
+	for _, bad := range []string{"0s", "-1m", "soon"} {
+		cm.Data[staleProbeAgeKey] = bad
+		if _, err := NewContourFromConfigMap(cm); err == nil {
+			t.Errorf("NewContourFromConfigMap(stale-probe-age:%s) succeeded, wanted error", bad)
+		}
+	}
+}
+
+func TestPauseDuringRollouts(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.PauseDuringRollouts {
+		t.Error("PauseDuringRollouts = true by default, wanted false")
+	}
+
+	cm.Data[pauseDuringRolloutsKey] = "true"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap(pause-during-rollouts:true) =", err)
+	}
+	if !cfg.PauseDuringRollouts {
+		t.Error("PauseDuringRollouts = false, wanted true")
+	}
+}
+
+func TestClaimUnsetIngressClass(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.ClaimUnsetIngressClass {
+		t.Error("ClaimUnsetIngressClass = true by default, wanted false")
+	}
+
+	cm.Data[claimUnsetIngressClassKey] = "true"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap(claim-unset-ingress-class:true) =", err)
+	}
+	if !cfg.ClaimUnsetIngressClass {
+		t.Error("ClaimUnsetIngressClass = false, wanted true")
+	}
+}
+
+func TestKubernetesIngressClass(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.KubernetesIngressClass != "" {
+		t.Errorf("KubernetesIngressClass = %q by default, wanted empty", cfg.KubernetesIngressClass)
+	}
+
+	cm.Data[kubernetesIngressClassKey] = "contour-knative"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap(kubernetes-ingress-class:contour-knative) =", err)
+	}
+	if got, want := cfg.KubernetesIngressClass, "contour-knative"; got != want {
+		t.Errorf("KubernetesIngressClass = %q, wanted %q", got, want)
+	}
+}
+
+func TestShadowMode(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.ShadowMode {
+		t.Error("ShadowMode = true by default, wanted false")
+	}
+
+	cm.Data[shadowModeKey] = "true"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap(shadow-mode:true) =", err)
+	}
+	if !cfg.ShadowMode {
+		t.Error("ShadowMode = false, wanted true")
+	}
+}
+
+func TestProbeOverHTTPS(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.ProbeOverHTTPS {
+		t.Error("ProbeOverHTTPS = true by default, wanted false")
+	}
+
+	cm.Data[probeOverHTTPSKey] = "true"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap(probe-over-https:true) =", err)
+	}
+	if !cfg.ProbeOverHTTPS {
+		t.Error("ProbeOverHTTPS = false, wanted true")
+	}
+}
+
+func TestProbeCertificateVerification(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.ProbeCABundle != nil {
+		t.Errorf("ProbeCABundle = %v by default, wanted nil", cfg.ProbeCABundle)
+	}
+	if cfg.ProbeInsecureSkipVerify {
+		t.Error("ProbeInsecureSkipVerify = true by default, wanted false")
+	}
+
+	cm.Data[probeCABundleKey] = "knative-serving/envoy-ca"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap(probe-ca-bundle:knative-serving/envoy-ca) =", err)
+	}
+	if want := (types.NamespacedName{Namespace: "knative-serving", Name: "envoy-ca"}); cfg.ProbeCABundle == nil || *cfg.ProbeCABundle != want {
+		t.Errorf("ProbeCABundle = %v, wanted %v", cfg.ProbeCABundle, want)
+	}
+
+	cm.Data[probeInsecureKey] = "true"
+	if _, err := NewContourFromConfigMap(cm); err == nil {
+		t.Error("NewContourFromConfigMap(probe-ca-bundle and probe-insecure-skip-verify) succeeded, wanted error")
+	}
+
+	delete(cm.Data, probeCABundleKey)
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap(probe-insecure-skip-verify:true) =", err)
+	}
+	if !cfg.ProbeInsecureSkipVerify {
+		t.Error("ProbeInsecureSkipVerify = false, wanted true")
+	}
+}
+
+func TestProbeRequests(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]string
+		want    *Contour
+		wantErr bool
+	}{{
+		name: "defaults",
+		data: map[string]string{},
+		want: &Contour{},
+	}, {
+		name: "user agent, headers and status codes",
+		data: map[string]string{
+			probeUserAgentKey:   "waf-friendly/1.0",
+			probeHeadersKey:     "X-Probe-Token: secret",
+			probeStatusCodesKey: "200, 204",
+		},
+		want: &Contour{
+			ProbeUserAgent:           "waf-friendly/1.0",
+			ProbeHeaders:             map[string]string{"X-Probe-Token": "secret"},
+			ProbeExpectedStatusCodes: []int{200, 204},
 		},
 	}, {
-		name:    "extra visibility",
+		name:    "header the probes rely on",
+		data:    map[string]string{probeHeadersKey: "k-network-probe: nope"},
 		wantErr: true,
-		config: &corev1.ConfigMap{
-			ObjectMeta: metav1.ObjectMeta{
-				Namespace: system.Namespace(),
-				Name:      ContourConfigName,
-			},
-			Data: map[string]string{
-				visibilityConfigKey: `
-ExternalIP:
-  service: foo/bar
-  class: baz
-ClusterLocal:
-  service: blah/bleh
-  class: bloop
-extra:
+	}, {
+		name:    "user agent header",
+		data:    map[string]string{probeHeadersKey: "User-Agent: nope"},
+		wantErr: true,
+	}, {
+		name:    "malformed header name",
+		data:    map[string]string{probeHeadersKey: "'X Token': secret"},
+		wantErr: true,
+	}, {
+		name:    "malformed headers",
+		data:    map[string]string{probeHeadersKey: "- X-Probe-Token"},
+		wantErr: true,
+	}, {
+		name:    "malformed status code",
+		data:    map[string]string{probeStatusCodesKey: "200,OK"},
+		wantErr: true,
+	}, {
+		name:    "out of range status code",
+		data:    map[string]string{probeStatusCodesKey: "2000"},
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := NewContourFromConfigMap(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: system.Namespace(),
+					Name:      ContourConfigName,
+				},
+				Data: test.data,
+			})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("NewContourFromConfigMap() = %v, wanted error: %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			if got.ProbeUserAgent != test.want.ProbeUserAgent {
+				t.Errorf("ProbeUserAgent = %q, wanted %q", got.ProbeUserAgent, test.want.ProbeUserAgent)
+			}
+			if !cmp.Equal(got.ProbeHeaders, test.want.ProbeHeaders) {
+				t.Error("ProbeHeaders (-want, +got) =", cmp.Diff(test.want.ProbeHeaders, got.ProbeHeaders))
+			}
+			if !cmp.Equal(got.ProbeExpectedStatusCodes, test.want.ProbeExpectedStatusCodes) {
+				t.Error("ProbeExpectedStatusCodes (-want, +got) =",
+					cmp.Diff(test.want.ProbeExpectedStatusCodes, got.ProbeExpectedStatusCodes))
+			}
+		})
+	}
+}
+
+func TestMaxInformerStaleness(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.MaxInformerStaleness != 2*time.Minute {
+		t.Errorf("MaxInformerStaleness = %v by default, wanted 2m", cfg.MaxInformerStaleness)
+	}
+
+	cm.Data[maxInformerStalenessKey] = "0"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap(max-informer-staleness:0) =", err)
+	}
+	if cfg.MaxInformerStaleness != 0 {
+		t.Errorf("MaxInformerStaleness = %v, wanted 0", cfg.MaxInformerStaleness)
+	}
+
+	cm.Data[maxInformerStalenessKey] = "-1m"
+	if _, err := NewContourFromConfigMap(cm); err == nil {
+		t.Error("NewContourFromConfigMap(max-informer-staleness:-1m) succeeded, wanted error")
+	}
+}
+
+func TestDriftRepairPeriod(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.DriftRepairPeriod != 0 {
+		t.Errorf("DriftRepairPeriod = %v by default, wanted 0", cfg.DriftRepairPeriod)
+	}
+
+	cm.Data[driftRepairPeriodKey] = "1h"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap(drift-repair-period:1h) =", err)
+	}
+	if cfg.DriftRepairPeriod != time.Hour {
+		t.Errorf("DriftRepairPeriod = %v, wanted 1h", cfg.DriftRepairPeriod)
+	}
+
+	cm.Data[driftRepairPeriodKey] = "-1h"
+	if _, err := NewContourFromConfigMap(cm); err == nil {
+		t.Error("NewContourFromConfigMap(drift-repair-period:-1h) succeeded, wanted error")
+	}
+}
+
+func TestDefaultCORSPolicy(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.DefaultCORSPolicy != nil {
+		t.Errorf("DefaultCORSPolicy = %v by default, wanted nil", cfg.DefaultCORSPolicy)
+	}
+
+	cm.Data[defaultCORSPolicyKey] = `
+allowOrigin: ["*"]
+allowMethods: [GET, POST]
+maxAge: 10m`
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap(default-cors-policy) =", err)
+	}
+	want := &contourv1.CORSPolicy{
+		AllowOrigin:  []string{"*"},
+		AllowMethods: []contourv1.CORSHeaderValue{"GET", "POST"},
+		MaxAge:       "10m",
+	}
+	if !cmp.Equal(cfg.DefaultCORSPolicy, want) {
+		t.Error("DefaultCORSPolicy (-want, +got) =", cmp.Diff(want, cfg.DefaultCORSPolicy))
+	}
+
+	for _, bad := range []string{
+		`allowMethods: [GET]`,
+		`allowOrigin: ["*"]`,
+		`{allowOrigin: ["*"], allowMethods: [GET], maxAge: forever}`,
+		`{allowOrigin: ["*"], allowMethods: [GET], maxAge: -1m}`,
+		`{allowOrigin: ["*"], allowMethods: [GET], allowOrigins: [foo]}`,
+	} {
+		cm.Data[defaultCORSPolicyKey] = bad
+		if _, err := NewContourFromConfigMap(cm); err == nil {
+			t.Errorf("NewContourFromConfigMap(default-cors-policy: %s) succeeded, wanted error", bad)
+		}
+	}
+}
+
+func TestWebsocketTimeouts(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.WebsocketResponseTimeout != "infinity" || cfg.WebsocketIdleTimeout != "infinity" {
+		t.Errorf("Websocket timeouts = %q, %q by default, wanted infinity",
+			cfg.WebsocketResponseTimeout, cfg.WebsocketIdleTimeout)
+	}
+
+	cm.Data[websocketIdleKey] = "1h"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap(websocket-timeout-policy-idle:1h) =", err)
+	}
+	if cfg.WebsocketIdleTimeout != "1h" {
+		t.Errorf("WebsocketIdleTimeout = %q, wanted 1h", cfg.WebsocketIdleTimeout)
+	}
+
+	cm.Data[websocketResponseKey] = "forever"
+	if _, err := NewContourFromConfigMap(cm); err == nil {
+		t.Error("NewContourFromConfigMap(websocket-timeout-policy-response:forever) succeeded, wanted error")
+	}
+}
+
+func TestProbeTimeout(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.ProbeTimeout != time.Second {
+		t.Errorf("ProbeTimeout = %v by default, wanted 1s", cfg.ProbeTimeout)
+	}
+
+	cm.Data[probeTimeoutKey] = "3s"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap(probe-timeout:3s) =", err)
+	}
+	if cfg.ProbeTimeout != 3*time.Second {
+		t.Errorf("ProbeTimeout = %v, wanted 3s", cfg.ProbeTimeout)
+	}
+
+	cm.Data[probeTimeoutKey] = "0s"
+	if _, err := NewContourFromConfigMap(cm); err == nil {
+		t.Error("NewContourFromConfigMap(probe-timeout:0s) succeeded, wanted error")
+	}
+}
+
+func TestProbeTransport(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.ProbeKeepAlive != 90*time.Second || cfg.ProbeMaxIdleConns != 2 || cfg.ProbeTLSHandshakeTimeout != 10*time.Second {
+		t.Errorf("Probe transport = %v, %d, %v by default, wanted 90s, 2, 10s",
+			cfg.ProbeKeepAlive, cfg.ProbeMaxIdleConns, cfg.ProbeTLSHandshakeTimeout)
+	}
+
+	cm.Data[probeKeepAliveKey] = "0s"
+	cm.Data[probeMaxIdleConnsKey] = "8"
+	cm.Data[probeTLSHandshakeKey] = "2s"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.ProbeKeepAlive != 0 || cfg.ProbeMaxIdleConns != 8 || cfg.ProbeTLSHandshakeTimeout != 2*time.Second {
+		t.Errorf("Probe transport = %v, %d, %v, wanted 0s, 8, 2s",
+			cfg.ProbeKeepAlive, cfg.ProbeMaxIdleConns, cfg.ProbeTLSHandshakeTimeout)
+	}
+
+	for key, value := range map[string]string{
+		probeKeepAliveKey:    "-1s",
+		probeMaxIdleConnsKey: "0",
+		probeTLSHandshakeKey: "0s",
+	} {
+		cm := cm.DeepCopy()
+		cm.Data[key] = value
+		if _, err := NewContourFromConfigMap(cm); err == nil {
+			t.Errorf("NewContourFromConfigMap(%s:%s) succeeded, wanted error", key, value)
+		}
+	}
+}
+
+func TestProbeHostEchoHeader(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.ProbeHostEchoHeader != "" {
+		t.Errorf("ProbeHostEchoHeader = %q by default, wanted empty", cfg.ProbeHostEchoHeader)
+	}
+
+	cm.Data[probeHostEchoHeaderKey] = "X-Echo-Host"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.ProbeHostEchoHeader != "X-Echo-Host" {
+		t.Errorf("ProbeHostEchoHeader = %q, wanted X-Echo-Host", cfg.ProbeHostEchoHeader)
+	}
+
+	cm.Data[probeHostEchoHeaderKey] = "X-Echo: Host"
+	if _, err := NewContourFromConfigMap(cm); err == nil {
+		t.Error("NewContourFromConfigMap() succeeded with an invalid header name, wanted error")
+	}
+}
+
+func TestSplitOverrideHeader(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.SplitOverrideHeader != "" {
+		t.Errorf("SplitOverrideHeader = %q by default, wanted empty", cfg.SplitOverrideHeader)
+	}
+
+	cm.Data[splitOverrideHeaderKey] = "X-Split-Override"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.SplitOverrideHeader != "X-Split-Override" {
+		t.Errorf("SplitOverrideHeader = %q, wanted X-Split-Override", cfg.SplitOverrideHeader)
+	}
+
+	cm.Data[splitOverrideHeaderKey] = "X Split"
+	if _, err := NewContourFromConfigMap(cm); err == nil {
+		t.Error("NewContourFromConfigMap() succeeded with an invalid header name, wanted error")
+	}
+}
+
+func TestProbeSampleSize(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.ProbeSampleSize != 0 {
+		t.Errorf("ProbeSampleSize = %d by default, wanted 0", cfg.ProbeSampleSize)
+	}
+
+	cm.Data[probeSampleSizeKey] = "5"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap(probe-sample-size:5) =", err)
+	}
+	if cfg.ProbeSampleSize != 5 {
+		t.Errorf("ProbeSampleSize = %d, wanted 5", cfg.ProbeSampleSize)
+	}
+
+	cm.Data[probeSampleSizeKey] = "-1"
+	if _, err := NewContourFromConfigMap(cm); err == nil {
+		t.Error("NewContourFromConfigMap(probe-sample-size:-1) succeeded, wanted error")
+	}
+}
+
+func TestProbePathAndPorts(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{
+			probePathKey:      "/envoy",
+			probeHTTPPortKey:  "8080",
+			probeHTTPSPortKey: "8443",
+		},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.ProbePath != "/envoy" || cfg.ProbeHTTPPort != 8080 || cfg.ProbeHTTPSPort != 8443 {
+		t.Errorf("ProbePath, ProbeHTTPPort, ProbeHTTPSPort = %q, %d, %d, wanted /envoy, 8080, 8443",
+			cfg.ProbePath, cfg.ProbeHTTPPort, cfg.ProbeHTTPSPort)
+	}
+
+	for key, value := range map[string]string{
+		probePathKey:      "envoy",
+		probeHTTPPortKey:  "-1",
+		probeHTTPSPortKey: "65536",
+	} {
+		cm := cm.DeepCopy()
+		cm.Data[key] = value
+		if _, err := NewContourFromConfigMap(cm); err == nil {
+			t.Errorf("NewContourFromConfigMap(%s:%s) succeeded, wanted error", key, value)
+		}
+	}
+}
+
+func TestProxyIncludes(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.ProxyIncludes {
+		t.Error("ProxyIncludes = true by default, wanted false")
+	}
+
+	cm.Data[proxyIncludesKey] = "true"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap(proxy-includes:true) =", err)
+	}
+	if !cfg.ProxyIncludes {
+		t.Error("ProxyIncludes = false, wanted true")
+	}
+}
+
+func TestExposeRequestID(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.ExposeRequestID {
+		t.Error("ExposeRequestID = true by default, wanted false")
+	}
+
+	cm.Data[exposeRequestIDKey] = "true"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap(expose-request-id:true) =", err)
+	}
+	if !cfg.ExposeRequestID {
+		t.Error("ExposeRequestID = false, wanted true")
+	}
+}
+
+func TestForwardedPrefix(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.ForwardedPrefix {
+		t.Error("ForwardedPrefix = true by default, wanted false")
+	}
+
+	cm.Data[forwardedPrefixKey] = "true"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap(forwarded-prefix:true) =", err)
+	}
+	if !cfg.ForwardedPrefix {
+		t.Error("ForwardedPrefix = false, wanted true")
+	}
+}
+
+func TestGenerateNetworkPolicies(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.GenerateNetworkPolicies {
+		t.Error("GenerateNetworkPolicies = true by default, wanted false")
+	}
+
+	cm.Data[networkPoliciesKey] = "true"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap(generate-network-policies:true) =", err)
+	}
+	if !cfg.GenerateNetworkPolicies {
+		t.Error("GenerateNetworkPolicies = false, wanted true")
+	}
+}
+
+func TestNamespaceVisibility(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]string
+		want    map[v1alpha1.IngressVisibility]string
+		wantErr bool
+	}{{
+		name: "not set",
+		data: map[string]string{},
+	}, {
+		name: "per visibility",
+		data: map[string]string{
+			namespaceVisibilityKey: `
+ClusterLocal: team=internal
+ExternalIP: "exposure in (public)"`,
+		},
+		want: map[v1alpha1.IngressVisibility]string{
+			v1alpha1.IngressVisibilityClusterLocal: "team=internal",
+			v1alpha1.IngressVisibilityExternalIP:   "exposure in (public)",
+		},
+	}, {
+		name: "bad selector",
+		data: map[string]string{
+			namespaceVisibilityKey: `ClusterLocal: "team in internal"`,
+		},
+		wantErr: true,
+	}, {
+		name: "unknown visibility",
+		data: map[string]string{
+			namespaceVisibilityKey: `Internal: team=internal`,
+		},
+		wantErr: true,
+	}, {
+		name: "bad yaml",
+		data: map[string]string{
+			namespaceVisibilityKey: `ClusterLocal: [team`,
+		},
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := NewContourFromConfigMap(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: system.Namespace(),
+					Name:      ContourConfigName,
+				},
+				Data: tt.data,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewContourFromConfigMap() error = %v, WantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !cmp.Equal(tt.want, cfg.NamespaceVisibility) {
+				t.Error("NamespaceVisibility (-want, +got):", cmp.Diff(tt.want, cfg.NamespaceVisibility))
+			}
+		})
+	}
+}
+
+func TestClassOverrides(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]string
+		want    map[string]ClassOverride
+		wantErr bool
+	}{{
+		name: "not set",
+		data: map[string]string{},
+	}, {
+		name: "approved classes",
+		data: map[string]string{
+			classOverridesKey: `
+contour-pci:
+  service: contour-pci/envoy
+  namespaces: compliance=pci`,
+		},
+		want: map[string]ClassOverride{
+			"contour-pci": {
+				Service:    "contour-pci/envoy",
+				Namespaces: "compliance=pci",
+			},
+		},
+	}, {
+		name: "service without namespace",
+		data: map[string]string{
+			classOverridesKey: `
+contour-pci:
+  service: envoy
+  namespaces: compliance=pci`,
+		},
+		wantErr: true,
+	}, {
+		name: "no namespaces",
+		data: map[string]string{
+			classOverridesKey: `
+contour-pci:
+  service: contour-pci/envoy`,
+		},
+		wantErr: true,
+	}, {
+		name: "bad selector",
+		data: map[string]string{
+			classOverridesKey: `
+contour-pci:
+  service: contour-pci/envoy
+  namespaces: "compliance in pci"`,
+		},
+		wantErr: true,
+	}, {
+		name: "bad yaml",
+		data: map[string]string{
+			classOverridesKey: `contour-pci: [pci`,
+		},
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := NewContourFromConfigMap(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: system.Namespace(),
+					Name:      ContourConfigName,
+				},
+				Data: tt.data,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewContourFromConfigMap() error = %v, WantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !cmp.Equal(tt.want, cfg.ClassOverrides) {
+				t.Error("ClassOverrides (-want, +got):", cmp.Diff(tt.want, cfg.ClassOverrides))
+			}
+		})
+	}
+}
+
+func TestMaxInvalidProxies(t *testing.T) {
+	cfg, err := NewContourFromConfigMap(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+	})
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.MaxInvalidProxies != -1 {
+		t.Errorf("MaxInvalidProxies = %d, wanted -1 by default", cfg.MaxInvalidProxies)
+	}
+
+	cfg, err = NewContourFromConfigMap(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{
+			maxInvalidProxiesKey: "0",
+		},
+	})
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.MaxInvalidProxies != 0 {
+		t.Errorf("MaxInvalidProxies = %d, wanted 0", cfg.MaxInvalidProxies)
+	}
+}
+
+func TestMaxProxiesPerIngress(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.MaxProxiesPerIngress != 0 {
+		t.Errorf("MaxProxiesPerIngress = %d, wanted 0 by default", cfg.MaxProxiesPerIngress)
+	}
+
+	cm.Data[maxProxiesPerIngressKey] = "50"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.MaxProxiesPerIngress != 50 {
+		t.Errorf("MaxProxiesPerIngress = %d, wanted 50", cfg.MaxProxiesPerIngress)
+	}
+
+	cm.Data[maxProxiesPerIngressKey] = "-1"
+	if _, err := NewContourFromConfigMap(cm); err == nil {
+		t.Error("NewContourFromConfigMap(max-proxies-per-ingress:-1) succeeded, wanted error")
+	}
+}
+
+func TestAllowedDomains(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    sets.String
+		wantErr bool
+	}{{
+		name: "empty",
+		want: sets.NewString(),
+	}, {
+		name:  "normalized",
+		value: "Example.com, *.apps.example.org,, internal.",
+		want:  sets.NewString("example.com", "apps.example.org", "internal"),
+	}, {
+		name:    "not a domain",
+		value:   "example.com, https://example.org",
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := NewContourFromConfigMap(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: system.Namespace(),
+					Name:      ContourConfigName,
+				},
+				Data: map[string]string{
+					allowedDomainsKey: tt.value,
+				},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewContourFromConfigMap() = %v, wanted error %v", err, tt.wantErr)
+			}
+			if err == nil && !cmp.Equal(tt.want, cfg.AllowedDomains) {
+				t.Error("AllowedDomains (-want, +got):", cmp.Diff(tt.want, cfg.AllowedDomains))
+			}
+		})
+	}
+}
+
+func TestDelegationNamespaces(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    sets.String
+		wantErr bool
+	}{{
+		name: "empty",
+		want: sets.NewString(),
+	}, {
+		name:  "namespaces",
+		value: "contour-external, ,knative-serving",
+		want:  sets.NewString("contour-external", "knative-serving"),
+	}, {
+		name:  "every namespace",
+		value: "*",
+		want:  sets.NewString("*"),
+	}, {
+		name:    "not a namespace",
+		value:   "contour-external, Not_A_Namespace",
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := NewContourFromConfigMap(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: system.Namespace(),
+					Name:      ContourConfigName,
+				},
+				Data: map[string]string{
+					delegationNamespacesKey: tt.value,
+				},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewContourFromConfigMap() = %v, wanted error %v", err, tt.wantErr)
+			}
+			if err == nil && !cmp.Equal(tt.want, cfg.DelegationNamespaces) {
+				t.Error("DelegationNamespaces (-want, +got):", cmp.Diff(tt.want, cfg.DelegationNamespaces))
+			}
+		})
+	}
+}
+
+func TestReadinessQuorum(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.ReadinessQuorum != 1 {
+		t.Errorf("ReadinessQuorum = %v by default, wanted 1", cfg.ReadinessQuorum)
+	}
+
+	cm.Data[readinessQuorumKey] = "0.9"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap(readiness-quorum:0.9) =", err)
+	}
+	if got, want := cfg.ReadinessQuorum, 0.9; got != want {
+		t.Errorf("ReadinessQuorum = %v, wanted %v", got, want)
+	}
+
+	for _, bad := range []string{"0", "1.5", "-0.5", "most"} {
+		cm.Data[readinessQuorumKey] = bad
+		if _, err := NewContourFromConfigMap(cm); err == nil {
+			t.Errorf("NewContourFromConfigMap(readiness-quorum:%s) succeeded, wanted error", bad)
+		}
+	}
+}
+
+func TestConfigurationErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+		config  *corev1.ConfigMap
+	}{{
+		name:    "no errors",
+		wantErr: false,
+		config: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace(),
+				Name:      ContourConfigName,
+			},
+			Data: map[string]string{
+				visibilityConfigKey: `
+ExternalIP:
+  service: foo/bar
+  class: baz
+ClusterLocal:
+  service: blah/bleh
+  class: bloop`,
+			},
+		},
+	}, {
+		name:    "failure parsing yaml",
+		wantErr: true,
+		config: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace(),
+				Name:      ContourConfigName,
+			},
+			Data: map[string]string{
+				visibilityConfigKey: `
+ExternalIP:
+  service: foo/bar
+  class: baz
+ClusterLocal: bad-yaml`,
+			},
+		},
+	}, {
+		name:    "missing cluster local",
+		wantErr: true,
+		config: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace(),
+				Name:      ContourConfigName,
+			},
+			Data: map[string]string{
+				visibilityConfigKey: `
+ExternalIP:
+  service: foo/bar
+  class: baz`,
+			},
+		},
+	}, {
+		name:    "missing external",
+		wantErr: true,
+		config: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace(),
+				Name:      ContourConfigName,
+			},
+			Data: map[string]string{
+				visibilityConfigKey: `
+ClusterLocal:
+  service: foo/bar
+  class: baz`,
+			},
+		},
+	}, {
+		name:    "extra visibility",
+		wantErr: true,
+		config: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace(),
+				Name:      ContourConfigName,
+			},
+			Data: map[string]string{
+				visibilityConfigKey: `
+ExternalIP:
+  service: foo/bar
+  class: baz
+ClusterLocal:
+  service: blah/bleh
+  class: bloop
+extra:
+  service: blah/bleh
+  class: bloop`,
+			},
+		},
+	}, {
+		name:    "gateway instead of service",
+		wantErr: false,
+		config: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace(),
+				Name:      ContourConfigName,
+			},
+			Data: map[string]string{
+				visibilityConfigKey: `
+ExternalIP:
+  gateway: foo/bar
+  class: baz
+ClusterLocal:
+  service: blah/bleh
+  class: bloop`,
+			},
+		},
+	}, {
+		name:    "both gateway and service",
+		wantErr: true,
+		config: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace(),
+				Name:      ContourConfigName,
+			},
+			Data: map[string]string{
+				visibilityConfigKey: `
+ExternalIP:
+  gateway: foo/bar
+  service: foo/envoy
+  class: baz
+ClusterLocal:
+  service: blah/bleh
+  class: bloop`,
+			},
+		},
+	}, {
+		name:    "selector instead of service",
+		wantErr: false,
+		config: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace(),
+				Name:      ContourConfigName,
+			},
+			Data: map[string]string{
+				visibilityConfigKey: `
+ExternalIP:
+  selector: projectcontour.io/owning-gateway in (blue,green)
+  class: baz
+ClusterLocal:
+  service: blah/bleh
+  class: bloop`,
+			},
+		},
+	}, {
+		name:    "bad selector",
+		wantErr: true,
+		config: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace(),
+				Name:      ContourConfigName,
+			},
+			Data: map[string]string{
+				visibilityConfigKey: `
+ExternalIP:
+  selector: "!!=bad"
+  class: baz
+ClusterLocal:
+  service: blah/bleh
+  class: bloop`,
+			},
+		},
+	}, {
+		name:    "gateway without namespace",
+		wantErr: true,
+		config: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace(),
+				Name:      ContourConfigName,
+			},
+			Data: map[string]string{
+				visibilityConfigKey: `
+ExternalIP:
+  gateway: bar
+  class: baz
+ClusterLocal:
   service: blah/bleh
   class: bloop`,
 			},
@@ -0,0 +1,81 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// FeaturesConfigName is the name of the optional configmap gating our
+	// experimental behaviors.  It isn't named config-features, which Knative
+	// Serving owns in the same namespace.
+	FeaturesConfigName = "config-contour-features"
+
+	contourReadinessGateFeatureKey = "contour-readiness-gate"
+)
+
+// Flag is the state of a feature.
+type Flag string
+
+const (
+	// Enabled turns a feature on.
+	Enabled Flag = "Enabled"
+	// Disabled turns a feature off, which is the default of every feature.
+	Disabled Flag = "Disabled"
+)
+
+// Features gates the behaviors that ship dark until clusters enable them,
+// so that new subsystems can be rolled out incrementally.
+type Features struct {
+	// ContourReadinessGate holds Ingresses back from becoming ready until
+	// Contour showed it processes HTTPProxies since we started leading.
+	ContourReadinessGate Flag
+}
+
+// NewFeaturesFromConfigMap creates the Features from the supplied ConfigMap.
+func NewFeaturesFromConfigMap(configMap *corev1.ConfigMap) (*Features, error) {
+	features := &Features{
+		ContourReadinessGate: Disabled,
+	}
+	for key, flag := range map[string]*Flag{
+		contourReadinessGateFeatureKey: &features.ContourReadinessGate,
+	} {
+		raw, ok := configMap.Data[key]
+		if !ok {
+			continue
+		}
+		switch {
+		case strings.EqualFold(raw, string(Enabled)):
+			*flag = Enabled
+		case strings.EqualFold(raw, string(Disabled)):
+			*flag = Disabled
+		default:
+			return nil, fmt.Errorf("%s must be %s or %s, got %q", key, Enabled, Disabled, raw)
+		}
+	}
+	return features, nil
+}
+
+// ContourReadinessGate returns whether the features hold Ingresses back from
+// becoming ready until Contour processes HTTPProxies.
+func (c *Config) ContourReadinessGate() bool {
+	return c.Features != nil && c.Features.ContourReadinessGate == Enabled
+}
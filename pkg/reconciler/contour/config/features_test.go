@@ -0,0 +1,87 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/system"
+
+	. "knative.dev/pkg/configmap/testing"
+)
+
+func TestFeatures(t *testing.T) {
+	cm, example := ConfigMapsFromTestFile(t, FeaturesConfigName)
+
+	if _, err := NewFeaturesFromConfigMap(cm); err != nil {
+		t.Error("NewFeaturesFromConfigMap(actual) =", err)
+	}
+	if _, err := NewFeaturesFromConfigMap(example); err != nil {
+		t.Error("NewFeaturesFromConfigMap(example) =", err)
+	}
+}
+
+func TestFeatureFlags(t *testing.T) {
+	features := func(value string) (*Features, error) {
+		return NewFeaturesFromConfigMap(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace(),
+				Name:      FeaturesConfigName,
+			},
+			Data: map[string]string{contourReadinessGateFeatureKey: value},
+		})
+	}
+
+	for value, want := range map[string]Flag{
+		"Enabled":  Enabled,
+		"enabled":  Enabled,
+		"Disabled": Disabled,
+	} {
+		got, err := features(value)
+		if err != nil {
+			t.Fatalf("NewFeaturesFromConfigMap(%s) = %v", value, err)
+		}
+		if got.ContourReadinessGate != want {
+			t.Errorf("ContourReadinessGate = %s for %q, wanted %s", got.ContourReadinessGate, value, want)
+		}
+	}
+	if _, err := features("true"); err == nil {
+		t.Error("NewFeaturesFromConfigMap(true) succeeded, wanted error")
+	}
+}
+
+func TestConfigContourReadinessGate(t *testing.T) {
+	if (&Config{Contour: &Contour{}}).ContourReadinessGate() {
+		t.Error("ContourReadinessGate() = true without features, wanted false")
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      FeaturesConfigName,
+		},
+		Data: map[string]string{contourReadinessGateFeatureKey: "Enabled"},
+	}
+	features, err := NewFeaturesFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewFeaturesFromConfigMap() =", err)
+	}
+	if !(&Config{Contour: &Contour{}, Features: features}).ContourReadinessGate() {
+		t.Error("ContourReadinessGate() = false with the feature enabled, wanted true")
+	}
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// valueKind is how NewContourFromConfigMap parses the value of a key, which
+// determines the pattern the schema requires of it.
+type valueKind string
+
+const (
+	kindString         valueKind = "string"
+	kindBool           valueKind = "bool"
+	kindInt            valueKind = "int"
+	kindFloat          valueKind = "float"
+	kindDuration       valueKind = "duration"
+	kindContourDur     valueKind = "contour-duration"
+	kindList           valueKind = "list"
+	kindYAML           valueKind = "yaml"
+	kindNamespacedName valueKind = "namespaced-name"
+)
+
+// kindPatterns are the patterns of the values of each kind, as the parsers of
+// knative.dev/pkg/configmap and ours accept them.  Lists, YAML and strings
+// are checked by NewContourFromConfigMap only.
+var kindPatterns = map[valueKind]string{
+	kindBool:           `^(1|t|T|TRUE|true|True|0|f|F|FALSE|false|False)$`,
+	kindInt:            `^[-+]?[0-9]+$`,
+	kindFloat:          `^[-+]?([0-9]+(\.[0-9]*)?|\.[0-9]+)([eE][-+]?[0-9]+)?$`,
+	kindDuration:       `^[-+]?(0|(([0-9]+(\.[0-9]*)?|\.[0-9]+)(ns|us|µs|ms|s|m|h))+)$`,
+	kindContourDur:     `^(infinity|[-+]?(0|(([0-9]+(\.[0-9]*)?|\.[0-9]+)(ns|us|µs|ms|s|m|h))+))$`,
+	kindNamespacedName: `^[a-z0-9]([-a-z0-9]*[a-z0-9])?/[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`,
+}
+
+// contourKeys describes every key of config-contour that
+// NewContourFromConfigMap reads.  Keep it in sync when adding keys, which
+// TestContourSchema checks against the example of config-contour.yaml.
+var contourKeys = map[string]struct {
+	kind        valueKind
+	description string
+}{
+	visibilityConfigKey:       {kindYAML, "The Contour class, and the Service or Gateway of the Envoys, of each visibility. As YAML."},
+	defaultTLSSecretConfigKey: {kindNamespacedName, "The namespace/name of the certificate of hosts without one of their own."},
+	delegateDefaultTLSKey:     {kindBool, "Whether to maintain a TLSCertificateDelegation of the default-tls-secret."},
+	delegationNamespacesKey:   {kindList, "The only namespaces, or *, the default-tls-secret is delegated to. Comma separated."},
+	authorizationServerKey:    {kindNamespacedName, "The namespace/name of the ExtensionService authorizing requests to external hosts."},
+	proxyIncludesKey:          {kindBool, "Whether the HTTPProxies of hosts include their routes from a shared HTTPProxy."},
+	exposeRequestIDKey:        {kindBool, "Whether responses carry the X-Request-Id of their request."},
+	forwardedPrefixKey:        {kindBool, "Whether routes matching a path prefix set X-Forwarded-Prefix."},
+	splitOverrideHeaderKey:    {kindString, "The request header naming the split backend to route to regardless of percents."},
+	networkPoliciesKey:        {kindBool, "Whether to generate NetworkPolicies letting the Envoys reach the backends."},
+	namespaceVisibilityKey:    {kindYAML, "The namespace selector pinning the visibility of the Ingresses of the namespaces it selects. As YAML."},
+	classOverridesKey:         {kindYAML, "The Contour classes, with the Service of their Envoys and a namespace selector, Ingresses may override the class of their visibilities with. As YAML."},
+	maxInvalidProxiesKey:      {kindInt, "How many HTTPProxies Contour may reject before the controller is unready, negative for any."},
+	maxProxiesPerIngressKey:   {kindInt, "How many HTTPProxies a single Ingress may generate, zero for any."},
+	allowedDomainsKey:         {kindList, "The domains under which external hosts may be programmed, any when empty. Comma separated."},
+	probeKeepAliveKey:         {kindDuration, "How long the connections of probes stay open for the next probes."},
+	probeMaxIdleConnsKey:      {kindInt, "The connections of probes kept open per Envoy pod and host."},
+	probeTLSHandshakeKey:      {kindDuration, "The timeout of the TLS handshakes of probes."},
+	probeHostEchoHeaderKey:    {kindString, "The header in which upstreams echo the Host they received, to verify host rewrites."},
+	staleProbeAgeKey:          {kindDuration, "The age from which endpoint probes count as leaked."},
+	timeoutPolicyIdleKey:      {kindContourDur, "The idle timeout of routes."},
+	timeoutPolicyResponseKey:  {kindContourDur, "The response timeout of routes."},
+	loadBalancerPolicyKey:     {kindYAML, "The load balancer strategy of the routes of each visibility. As YAML."},
+	endpointProbeTimeoutKey:   {kindDuration, "How long a new generation may wait for the Envoys to receive its Endpoints."},
+	readinessQuorumKey:        {kindFloat, "The fraction of Envoy pods that must serve an Ingress before it is ready."},
+	pauseDuringRolloutsKey:    {kindBool, "Whether to hold off changes while the Envoys roll out."},
+	claimUnsetIngressClassKey: {kindBool, "Whether to reconcile the Ingresses without an ingress class."},
+	kubernetesIngressClassKey: {kindString, "The class of the Kubernetes Ingresses to reconcile, none when empty."},
+	shadowModeKey:             {kindBool, "Whether to compute HTTPProxies without writing them."},
+	probeOverHTTPSKey:         {kindBool, "Whether to probe the hosts that terminate TLS over HTTPS."},
+	probeCABundleKey:          {kindNamespacedName, "The namespace/name of the ConfigMap whose ca.crt HTTPS probes trust."},
+	probeInsecureKey:          {kindBool, "Whether HTTPS probes accept any certificate."},
+	probeUserAgentKey:         {kindString, "The User-Agent of the probes we send ourselves, that of Knative's probes when empty."},
+	probeHeadersKey:           {kindYAML, "The extra headers of the probes we send ourselves. As YAML."},
+	probeStatusCodesKey:       {kindList, "The only status codes of responses to probes that count as ready, any but 404 and 503 when empty. Comma separated."},
+	driftRepairPeriodKey:      {kindDuration, "How often every Ingress is reconciled to repair drifted HTTPProxies, never when zero."},
+	maxInformerStalenessKey:   {kindDuration, "How long informers may fail to watch before the controller stops reconciling."},
+	defaultCORSPolicyKey:      {kindYAML, "The CORS policy of external hosts without one of their own. As YAML."},
+	probeTimeoutKey:           {kindDuration, "The timeout of each of the probes we send ourselves."},
+	probeSampleSizeKey:        {kindInt, "How many Envoy pods of each visibility are probed, all when zero."},
+	probePathKey:              {kindString, "The path probes are sent to."},
+	probeHTTPPortKey:          {kindInt, "The port HTTP probes are sent to."},
+	probeHTTPSPortKey:         {kindInt, "The port HTTPS probes are sent to."},
+	websocketResponseKey:      {kindContourDur, "The response timeout of the routes of websockets."},
+	websocketIdleKey:          {kindContourDur, "The idle timeout of the routes of websockets."},
+}
+
+// UnknownContourKeys returns the keys of the ConfigMap that we don't read,
+// which are most likely misspelled, in order.
+func UnknownContourKeys(cm *corev1.ConfigMap) []string {
+	var unknown []string
+	for key := range cm.Data {
+		if _, ok := contourKeys[key]; !ok && key != "_example" {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// ContourSchema returns the JSON schema of the config-contour ConfigMap.  It
+// catches misspelled keys and malformed values, but can't express all of
+// the checks of NewContourFromConfigMap.
+func ContourSchema() ([]byte, error) {
+	properties := map[string]interface{}{
+		"_example": map[string]interface{}{
+			"type":        "string",
+			"description": "The documentation of the keys, which is ignored.",
+		},
+	}
+	for key, s := range contourKeys {
+		property := map[string]interface{}{
+			"type":        "string",
+			"description": s.description,
+		}
+		if pattern, ok := kindPatterns[s.kind]; ok {
+			property["pattern"] = pattern
+		}
+		properties[key] = property
+	}
+
+	return json.MarshalIndent(map[string]interface{}{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"title":       ContourConfigName,
+		"description": "The " + ContourConfigName + " ConfigMap of net-contour.",
+		"type":        "object",
+		"required":    []string{"apiVersion", "kind", "metadata"},
+		"properties": map[string]interface{}{
+			"apiVersion": map[string]interface{}{"const": "v1"},
+			"kind":       map[string]interface{}{"const": "ConfigMap"},
+			"metadata": map[string]interface{}{
+				"type":     "object",
+				"required": []string{"name"},
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{"const": ContourConfigName},
+				},
+			},
+			"data": map[string]interface{}{
+				"type":                 "object",
+				"properties":           properties,
+				"additionalProperties": false,
+			},
+		},
+	}, "", "  ")
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"testing"
+
+	. "knative.dev/pkg/configmap/testing"
+)
+
+func TestContourSchema(t *testing.T) {
+	_, example := ConfigMapsFromTestFile(t, ContourConfigName)
+
+	if unknown := UnknownContourKeys(example); len(unknown) != 0 {
+		t.Errorf("The example sets keys missing from contourKeys: %v", unknown)
+	}
+	for key := range contourKeys {
+		if _, ok := example.Data[key]; !ok {
+			t.Errorf("The example doesn't document %q", key)
+		}
+	}
+
+	b, err := ContourSchema()
+	if err != nil {
+		t.Fatal("ContourSchema() =", err)
+	}
+	var schema struct {
+		Properties struct {
+			Data struct {
+				Properties map[string]struct {
+					Pattern string `json:"pattern"`
+				} `json:"properties"`
+			} `json:"data"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(b, &schema); err != nil {
+		t.Fatal("Unmarshal() =", err)
+	}
+	for key, value := range example.Data {
+		property, ok := schema.Properties.Data.Properties[key]
+		if !ok {
+			t.Errorf("The schema lacks %q", key)
+			continue
+		}
+		if property.Pattern == "" {
+			continue
+		}
+		if !regexp.MustCompile(property.Pattern).MatchString(value) {
+			t.Errorf("%s: %q doesn't match the pattern %q", key, value, property.Pattern)
+		}
+	}
+}
+
+func TestShippedContourSchema(t *testing.T) {
+	shipped, err := ioutil.ReadFile("../../../../schema/config-contour.json")
+	if err != nil {
+		t.Fatal("ReadFile() =", err)
+	}
+	b, err := ContourSchema()
+	if err != nil {
+		t.Fatal("ContourSchema() =", err)
+	}
+	if strings.TrimSpace(string(shipped)) != string(b) {
+		t.Error("schema/config-contour.json is out of date, run ./hack/update-codegen.sh")
+	}
+}
+
+func TestKindPatterns(t *testing.T) {
+	tests := []struct {
+		kind    valueKind
+		matches []string
+		rejects []string
+	}{{
+		kind:    kindBool,
+		matches: []string{"true", "False", "1"},
+		rejects: []string{"yes", ""},
+	}, {
+		kind:    kindInt,
+		matches: []string{"0", "-1", "+42"},
+		rejects: []string{"1.5", "ten"},
+	}, {
+		kind:    kindFloat,
+		matches: []string{"1", "0.5", ".5", "1e-3"},
+		rejects: []string{"half", "1,5"},
+	}, {
+		kind:    kindDuration,
+		matches: []string{"0", "10s", "1h30m", "1.5s", "-1m"},
+		rejects: []string{"10", "infinity", "soon"},
+	}, {
+		kind:    kindContourDur,
+		matches: []string{"infinity", "10s", "0"},
+		rejects: []string{"forever"},
+	}, {
+		kind:    kindNamespacedName,
+		matches: []string{"ns/name", "some-namespace/some.secret"},
+		rejects: []string{"name", "ns/name/other", "NS/name"},
+	}}
+	for _, test := range tests {
+		re := regexp.MustCompile(kindPatterns[test.kind])
+		for _, v := range test.matches {
+			if !re.MatchString(v) {
+				t.Errorf("%s pattern rejects %q", test.kind, v)
+			}
+		}
+		for _, v := range test.rejects {
+			if re.MatchString(v) {
+				t.Errorf("%s pattern matches %q", test.kind, v)
+			}
+		}
+	}
+}
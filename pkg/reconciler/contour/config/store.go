@@ -19,7 +19,11 @@ package config
 import (
 	"context"
 
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	network "knative.dev/networking/pkg"
 	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/system"
 )
 
 type cfgKey struct{}
@@ -27,6 +31,10 @@ type cfgKey struct{}
 // Config of Contour.
 type Config struct {
 	Contour *Contour
+	Network *network.Config
+	// Features is nil until config-contour-features is observed, which
+	// disables every feature.
+	Features *Features
 }
 
 // FromContext fetch config from context.
@@ -60,7 +68,9 @@ func NewStore(logger configmap.Logger, onAfterStore ...func(name string, value i
 			"ingress",
 			logger,
 			configmap.Constructors{
-				ContourConfigName: NewContourFromConfigMap,
+				ContourConfigName:  NewContourFromConfigMap,
+				network.ConfigName: network.NewConfigFromConfigMap,
+				FeaturesConfigName: NewFeaturesFromConfigMap,
 			},
 			onAfterStore...,
 		),
@@ -69,14 +79,45 @@ func NewStore(logger configmap.Logger, onAfterStore ...func(name string, value i
 	return store
 }
 
+// WatchConfigs watches our configmaps with the watcher.  Watchers that can
+// default to an empty config-contour-features keep it optional.
+func (s *Store) WatchConfigs(w configmap.Watcher) {
+	for _, name := range []string{ContourConfigName, network.ConfigName} {
+		w.Watch(name, s.OnConfigChanged)
+	}
+	if dw, ok := w.(configmap.DefaultingWatcher); ok {
+		dw.WatchWithDefault(corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace(),
+				Name:      FeaturesConfigName,
+			},
+		}, s.OnConfigChanged)
+	} else {
+		w.Watch(FeaturesConfigName, s.OnConfigChanged)
+	}
+}
+
 // ToContext adds Store contents to given context.
 func (s *Store) ToContext(ctx context.Context) context.Context {
 	return ToContext(ctx, s.Load())
 }
 
+// LoadContour fetches the config-contour from Store, or its defaults until
+// Store loaded it, for loops that run before the configmap watcher starts.
+func (s *Store) LoadContour() *Contour {
+	if contour, ok := s.UntypedLoad(ContourConfigName).(*Contour); ok {
+		return contour.DeepCopy()
+	}
+	contour, _ := NewContourFromConfigMap(&corev1.ConfigMap{})
+	return contour
+}
+
 // Load fetches config from Store.
 func (s *Store) Load() *Config {
+	features, _ := s.UntypedLoad(FeaturesConfigName).(*Features)
 	return &Config{
-		Contour: s.UntypedLoad(ContourConfigName).(*Contour).DeepCopy(),
+		Contour:  s.UntypedLoad(ContourConfigName).(*Contour).DeepCopy(),
+		Network:  s.UntypedLoad(network.ConfigName).(*network.Config).DeepCopy(),
+		Features: features.DeepCopy(),
 	}
 }
@@ -21,8 +21,12 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	logtesting "knative.dev/pkg/logging/testing"
+	"knative.dev/pkg/system"
 
+	network "knative.dev/networking/pkg"
 	"knative.dev/networking/pkg/apis/networking/v1alpha1"
 	. "knative.dev/pkg/configmap/testing"
 )
@@ -31,19 +35,47 @@ func TestStoreLoadWithContext(t *testing.T) {
 	store := NewStore(logtesting.TestLogger(t))
 
 	contourConfig := ConfigMapFromTestFile(t, ContourConfigName)
+	networkConfig := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      network.ConfigName,
+		},
+		Data: map[string]string{
+			network.MeshCompatibilityModeKey: string(network.MeshCompatibilityModeEnabled),
+		},
+	}
 	store.OnConfigChanged(contourConfig)
+	featuresConfig := ConfigMapFromTestFile(t, FeaturesConfigName)
+	store.OnConfigChanged(networkConfig)
+	store.OnConfigChanged(featuresConfig)
 	config := FromContext(store.ToContext(context.Background()))
 
 	expectedContour, _ := NewContourFromConfigMap(contourConfig)
 	if diff := cmp.Diff(expectedContour, config.Contour); diff != "" {
 		t.Error("Unexpected contour config (-want, +got):", diff)
 	}
+
+	expectedNetwork, _ := network.NewConfigFromConfigMap(networkConfig)
+	if diff := cmp.Diff(expectedNetwork, config.Network); diff != "" {
+		t.Error("Unexpected network config (-want, +got):", diff)
+	}
+
+	expectedFeatures, _ := NewFeaturesFromConfigMap(featuresConfig)
+	if diff := cmp.Diff(expectedFeatures, config.Features); diff != "" {
+		t.Error("Unexpected features config (-want, +got):", diff)
+	}
 }
 
 func TestStoreImmutableConfig(t *testing.T) {
 	store := NewStore(logtesting.TestLogger(t))
 
 	store.OnConfigChanged(ConfigMapFromTestFile(t, ContourConfigName))
+	store.OnConfigChanged(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      network.ConfigName,
+		},
+	})
 
 	config := store.Load()
 
@@ -58,3 +90,19 @@ func TestStoreImmutableConfig(t *testing.T) {
 		t.Error("Contour config is not immutable")
 	}
 }
+
+func TestStoreLoadContourBeforeLoaded(t *testing.T) {
+	store := NewStore(logtesting.TestLogger(t))
+
+	defaults, _ := NewContourFromConfigMap(&corev1.ConfigMap{})
+	if diff := cmp.Diff(defaults, store.LoadContour()); diff != "" {
+		t.Error("LoadContour() before loading (-want, +got):", diff)
+	}
+
+	contourConfig := ConfigMapFromTestFile(t, ContourConfigName)
+	store.OnConfigChanged(contourConfig)
+	loaded, _ := NewContourFromConfigMap(contourConfig)
+	if diff := cmp.Diff(loaded, store.LoadContour()); diff != "" {
+		t.Error("LoadContour() (-want, +got):", diff)
+	}
+}
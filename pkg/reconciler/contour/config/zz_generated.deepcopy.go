@@ -21,11 +21,29 @@ limitations under the License.
 package config
 
 import (
+	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	types "k8s.io/apimachinery/pkg/types"
 	sets "k8s.io/apimachinery/pkg/util/sets"
+	pkg "knative.dev/networking/pkg"
 	v1alpha1 "knative.dev/networking/pkg/apis/networking/v1alpha1"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClassOverride) DeepCopyInto(out *ClassOverride) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClassOverride.
+func (in *ClassOverride) DeepCopy() *ClassOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(ClassOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Config) DeepCopyInto(out *Config) {
 	*out = *in
@@ -34,6 +52,16 @@ func (in *Config) DeepCopyInto(out *Config) {
 		*out = new(Contour)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Network != nil {
+		in, out := &in.Network, &out.Network
+		*out = new(pkg.Config)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Features != nil {
+		in, out := &in.Features, &out.Features
+		*out = new(Features)
+		**out = **in
+	}
 	return
 }
 
@@ -74,11 +102,82 @@ func (in *Contour) DeepCopyInto(out *Contour) {
 			(*out)[key] = val
 		}
 	}
+	if in.VisibilityGateways != nil {
+		in, out := &in.VisibilityGateways, &out.VisibilityGateways
+		*out = make(map[v1alpha1.IngressVisibility]types.NamespacedName, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.VisibilitySelectors != nil {
+		in, out := &in.VisibilitySelectors, &out.VisibilitySelectors
+		*out = make(map[v1alpha1.IngressVisibility]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.DefaultTLSSecret != nil {
 		in, out := &in.DefaultTLSSecret, &out.DefaultTLSSecret
 		*out = new(types.NamespacedName)
 		**out = **in
 	}
+	if in.LoadBalancerPolicies != nil {
+		in, out := &in.LoadBalancerPolicies, &out.LoadBalancerPolicies
+		*out = make(map[v1alpha1.IngressVisibility]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ProbeCABundle != nil {
+		in, out := &in.ProbeCABundle, &out.ProbeCABundle
+		*out = new(types.NamespacedName)
+		**out = **in
+	}
+	if in.ProbeHeaders != nil {
+		in, out := &in.ProbeHeaders, &out.ProbeHeaders
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ProbeExpectedStatusCodes != nil {
+		in, out := &in.ProbeExpectedStatusCodes, &out.ProbeExpectedStatusCodes
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+	if in.DefaultCORSPolicy != nil {
+		in, out := &in.DefaultCORSPolicy, &out.DefaultCORSPolicy
+		*out = new(v1.CORSPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NamespaceVisibility != nil {
+		in, out := &in.NamespaceVisibility, &out.NamespaceVisibility
+		*out = make(map[v1alpha1.IngressVisibility]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.AllowedDomains != nil {
+		in, out := &in.AllowedDomains, &out.AllowedDomains
+		*out = make(sets.String, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DelegationNamespaces != nil {
+		in, out := &in.DelegationNamespaces, &out.DelegationNamespaces
+		*out = make(sets.String, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ClassOverrides != nil {
+		in, out := &in.ClassOverrides, &out.ClassOverrides
+		*out = make(map[string]ClassOverride, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -91,3 +190,19 @@ func (in *Contour) DeepCopy() *Contour {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Features) DeepCopyInto(out *Features) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Features.
+func (in *Features) DeepCopy() *Features {
+	if in == nil {
+		return nil
+	}
+	out := new(Features)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -19,14 +19,21 @@ package contour
 import (
 	"context"
 	"fmt"
+	"time"
 
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
 	corev1listers "k8s.io/client-go/listers/core/v1"
+	networkingv1listers "k8s.io/client-go/listers/networking/v1"
 	"k8s.io/client-go/tools/cache"
 
 	contourclientset "knative.dev/net-contour/pkg/client/clientset/versioned"
@@ -35,14 +42,17 @@ import (
 	ingressreconciler "knative.dev/networking/pkg/client/injection/reconciler/networking/v1alpha1/ingress"
 	networkingv1alpha1 "knative.dev/networking/pkg/client/listers/networking/v1alpha1"
 
+	"knative.dev/net-contour/pkg/ownership"
 	"knative.dev/net-contour/pkg/reconciler/contour/config"
 	"knative.dev/net-contour/pkg/reconciler/contour/resources"
 	"knative.dev/net-contour/pkg/reconciler/contour/resources/names"
 	"knative.dev/networking/pkg/apis/networking"
 	"knative.dev/networking/pkg/apis/networking/v1alpha1"
 	"knative.dev/networking/pkg/status"
+	"knative.dev/pkg/controller"
 	"knative.dev/pkg/kmp"
 	"knative.dev/pkg/logging"
+	"knative.dev/pkg/metrics"
 	"knative.dev/pkg/network"
 	"knative.dev/pkg/reconciler"
 	"knative.dev/pkg/tracker"
@@ -52,6 +62,14 @@ const (
 	// ContourIngressClassName value for specifying knative's Contour
 	// Ingress reconciler.
 	ContourIngressClassName = "contour.ingress.networking.knative.dev"
+
+	// endpointsProbeTimeoutReason is the reason we fail a generation's
+	// rollout with when its endpoint probe didn't become ready in time.
+	endpointsProbeTimeoutReason = "EndpointsProbeTimeout"
+
+	// pausedReason is the reason of ProxiesProgrammed while the Ingress asks
+	// us to leave its HTTPProxies alone.
+	pausedReason = "Paused"
 )
 
 // Reconciler implements controller.Reconciler for Ingress resources.
@@ -63,9 +81,56 @@ type Reconciler struct {
 	contourLister contourlisters.HTTPProxyLister
 	ingressLister networkingv1alpha1.IngressLister
 	serviceLister corev1listers.ServiceLister
+	podLister     corev1listers.PodLister
+
+	// delegationLister lets us delegate the default TLS secret.
+	delegationLister contourlisters.TLSCertificateDelegationLister
+
+	// kubeClient and networkPolicyLister let us generate the NetworkPolicies
+	// of Ingresses.
+	kubeClient          kubernetes.Interface
+	networkPolicyLister networkingv1listers.NetworkPolicyLister
+
+	// namespaceLister lets the labels of namespaces pin the visibility of
+	// their Ingresses, and approve the Contour classes their Ingresses
+	// override.
+	namespaceLister corev1listers.NamespaceLister
 
 	statusManager status.Manager
 	tracker       tracker.Interface
+
+	// apiChecker, when set, is consulted before programming Contour so that
+	// a missing HTTPProxy CRD is surfaced on the Ingress.
+	apiChecker *apiChecker
+
+	// programming, when set, tracks how long Envoy takes to serve the
+	// generations whose HTTPProxies we program.
+	programming *programmingTracker
+
+	// written, when set, remembers the HTTPProxies we wrote so that we can
+	// tell the ones we repair from the ones the Ingress or our config changed.
+	written *writtenProxies
+
+	// cacheHealth, when set, holds off reconciling while our informers are
+	// unable to watch the API server.
+	cacheHealth *cacheHealth
+
+	// reprober, when set, makes sure we probe the new Envoys of a ready
+	// Ingress whose Envoy Services were replaced.
+	reprober *envoyReprober
+
+	// endpointProbes, when set, lets Ingresses skip probing the Endpoints
+	// which the endpoint probes of other Ingresses just verified.
+	endpointProbes *endpointProbeCache
+
+	// skipStatus makes us only program HTTPProxies, and leave the status of
+	// Ingresses, and the probing it relies on, to an external component.
+	skipStatus bool
+
+	// contourGate, when set, holds Ingresses back from becoming ready while
+	// the contour-readiness-gate feature is enabled, until Contour showed
+	// that it processes HTTPProxies.
+	contourGate *contourGate
 }
 
 var _ ingressreconciler.Interface = (*Reconciler)(nil)
@@ -78,6 +143,95 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, ing *v1alpha1.Ingress) r
 		zap.String("resource-version", ing.ResourceVersion),
 	)
 
+	if name, staleness := r.cacheHealth.stale(); name != "" {
+		logger.Warnf("The %s informer has been unable to watch the API server for %v, waiting for it to catch up.",
+			name, staleness.Round(time.Second))
+		return controller.NewRequeueAfter(staleRecheckPeriod)
+	}
+
+	if resources.IsPaused(ing) {
+		logger.Info("The Ingress is paused, leaving its HTTPProxies alone.")
+		markSubCondition(ing, ProxiesProgrammedCondition, corev1.ConditionUnknown, pausedReason,
+			"Programming is paused by the "+resources.PausedKey+" annotation.")
+		return nil
+	}
+
+	// HTTPProxies we have to rewrite the way we already wrote them drifted
+	// from what we programmed.
+	repairs := 0
+	defer func() {
+		if repairs > 0 {
+			logger.Warnf("Repaired %d HTTPProxies that drifted from the Ingress.", repairs)
+			metrics.Record(ctx, driftRepairsM.M(int64(repairs)))
+		}
+	}()
+
+	if r.apiChecker != nil {
+		if served, err := r.apiChecker.HTTPProxyServed(); err != nil {
+			return err
+		} else if !served {
+			logger.Warnf("The %s API is not served, waiting for it to be installed.", httpProxyResource)
+			ing.Status.MarkLoadBalancerFailed("DependencyMissing",
+				fmt.Sprintf("The Contour HTTPProxy API (%s) is not installed.", contourv1.GroupVersion))
+			return controller.NewRequeueAfter(dependencyRecheckPeriod)
+		}
+	}
+
+	if reason, err := checkAnnotations(ctx, ing); err != nil {
+		ing.Status.MarkLoadBalancerFailed(reason, err.Error())
+		return nil
+	}
+	ctx, err := withProbeOverrides(ctx, ing)
+	if err != nil {
+		ing.Status.MarkLoadBalancerFailed("InvalidProbeSettings", err.Error())
+		return nil
+	}
+	if vis, err := namespaceVisibility(ctx, r.namespaceLister, ing.Namespace); err != nil {
+		return err
+	} else if vis != "" {
+		// We get a copy of the Ingress and only write back its status, so
+		// everything below programs and probes the pinned visibility.
+		logger.Debugf("The namespace pins the visibility of the Ingress to %s.", vis)
+		pinVisibility(ing, vis)
+	}
+	if overrides, _ := resources.ClassOverrides(ing); len(overrides) != 0 {
+		var ns *corev1.Namespace
+		if ns, err = r.namespaceLister.Get(ing.Namespace); err != nil {
+			return fmt.Errorf("failed to get namespace %s: %w", ing.Namespace, err)
+		}
+		if ctx, err = withClassOverrides(ctx, ns, overrides); err != nil {
+			ing.Status.MarkLoadBalancerFailed("ClassOverrideNotAllowed", err.Error())
+			return nil
+		}
+		logger.Debugf("The Ingress overrides the Contour classes of %v.", overrides)
+	}
+	markFeaturesIgnored(ing, resources.IgnoredFeatures(ing))
+	markDomainsNotAllowed(ing, resources.DisallowedHosts(ctx, ing))
+	if err := checkProxyCount(ctx, ing); err != nil {
+		ing.Status.MarkLoadBalancerFailed("TooManyHTTPProxies", err.Error())
+		return nil
+	}
+
+	if config.FromContext(ctx).Contour.PauseDuringRollouts {
+		key, progressed, err := envoyRollingOut(ctx, r.serviceLister, r.podLister)
+		if err != nil {
+			return err
+		}
+		switch {
+		case key == "":
+			markRolloutPaused(ing, "", "")
+		case time.Since(progressed) > rolloutStuckAfter:
+			logger.Warnf("The rollout of the Envoy pods of %s made no progress for %v, no longer holding changes.", key, rolloutStuckAfter)
+			markRolloutPaused(ing, "EnvoyRolloutStuck", fmt.Sprintf(
+				"The Envoy pods of %s made no progress rolling out for %v, changes are no longer held.", key, rolloutStuckAfter))
+		default:
+			logger.Infof("The Envoy pods of %s are rolling out, holding changes until they are stable.", key)
+			markRolloutPaused(ing, "EnvoyRollingOut", fmt.Sprintf(
+				"Holding changes while the Envoy pods of %s roll out.", key))
+			return controller.NewRequeueAfter(rolloutRecheckPeriod)
+		}
+	}
+
 	// Track whether there is an endpoint probe kingress to clean up.
 	haveEndpointProbe := false
 
@@ -86,16 +240,30 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, ing *v1alpha1.Ingress) r
 		// kingress. Stop recursing when we see our annotation and proceed to
 		// HTTP Proxy and probing.
 		logger.Debug("Avoiding endpoint probe recursion.")
+	} else if r.skipStatus {
+		// Endpoint probes become ready through the status we don't write, so
+		// we program every generation right away.
+		logger.Debug("Status updates are disabled, skipping the endpoint probe.")
 	} else if currentGeneration, err := r.contourLister.HTTPProxies(ing.Namespace).List(
 		// See if we have any HTTPProxy resources for this generation.
 		// We only create HTTPProxy resources once we have successfully probed
 		// a generation's endpoints.
-		labels.Set(map[string]string{
-			resources.ParentKey:     ing.Name,
-			resources.GenerationKey: fmt.Sprintf("%d", ing.Generation),
-		}).AsSelector()); err != nil {
+		ownership.Generation(ing.Name, ing.Generation)); err != nil {
 		return err
+	} else if len(currentGeneration) == 0 && r.endpointProbes.covers(ctx, ing) {
+		// The endpoint probes of other Ingresses just verified that every
+		// Envoy has the Endpoints of our Services.
+		logger.Debug("Skipping the endpoint probe of Endpoints verified by other Ingresses.")
+		markSubCondition(ing, EndpointsProbedCondition, corev1.ConditionTrue, "", "")
+		_, err := r.ingressLister.Ingresses(ing.Namespace).Get(names.EndpointProbeIngress(ing))
+		haveEndpointProbe = (err == nil || !apierrs.IsNotFound(err))
 	} else if len(currentGeneration) == 0 {
+		if endpointProbeTimedOut(ing) {
+			// We gave up on this generation, wait for the Ingress to change.
+			logger.Debug("Endpoint probe timed out for this generation.")
+			return nil
+		}
+
 		// There are no HTTPProxy resources with the current generation.
 		// Reconcile an endpoint probe child kingress to ensure the Contour
 		// gateways have the endpoints for our generation's services.
@@ -104,9 +272,7 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, ing *v1alpha1.Ingress) r
 		// in the Endpoint Probe.  The Endpoint probe is used to warm new Envoy
 		// "clusters" (Endpoints), but also to keep the prior HTTP Proxy's "clusters"
 		// in existence until the new generation has been rolled out as fully ready.
-		selector, err := labels.Parse(fmt.Sprintf("%s=%s,%s!=%d",
-			resources.ParentKey, ing.Name,
-			resources.GenerationKey, ing.Generation))
+		selector, err := ownership.OtherGenerations(ing.Name, ing.Generation)
 		if err != nil {
 			return err
 		}
@@ -142,16 +308,47 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, ing *v1alpha1.Ingress) r
 		}
 
 		if !actualChIng.IsReady() {
+			var remaining time.Duration
+			if timeout := config.FromContext(ctx).Contour.EndpointProbeTimeout; timeout > 0 {
+				if started, ok := endpointProbeStarted(actualChIng); ok {
+					if remaining = timeout - time.Since(started); remaining <= 0 {
+						// Clean up the probe along with its HTTPProxy resources, so
+						// we stop programming its hosts into Envoy.
+						logger.Warnf("Envoys did not receive Endpoints data within %v, deleting endpoint probe.", timeout)
+						if err := r.ingressClient.NetworkingV1alpha1().Ingresses(actualChIng.Namespace).Delete(
+							ctx, actualChIng.Name, metav1.DeleteOptions{}); err != nil && !apierrs.IsNotFound(err) {
+							return err
+						}
+						message := fmt.Sprintf("Envoys did not receive Endpoints data within %v.", timeout)
+						ing.Status.MarkLoadBalancerFailed(endpointsProbeTimeoutReason, message)
+						markSubCondition(ing, EndpointsProbedCondition, corev1.ConditionFalse, endpointsProbeTimeoutReason, message)
+						return nil
+					}
+				}
+			}
+
 			// This won't be toggled back until probing has completed.
 			ing.Status.MarkLoadBalancerNotReady()
 			ing.Status.MarkIngressNotReady("EndpointsNotReady", "Waiting for Envoys to receive Endpoints data.")
+			markSubCondition(ing, EndpointsProbedCondition, corev1.ConditionUnknown,
+				"EndpointsNotReady", "Waiting for Envoys to receive Endpoints data.")
+			markSubCondition(ing, ProxiesProgrammedCondition, corev1.ConditionUnknown,
+				"EndpointsNotReady", "Waiting for Envoys to receive Endpoints data before programming.")
+			if remaining > 0 {
+				// Make sure we notice the deadline even when nothing changes.
+				return controller.NewRequeueAfter(remaining)
+			}
 			return nil
 		}
 
 		// The endpoints ingress is ready, we are good to go!
 		haveEndpointProbe = true
+		r.endpointProbes.record(ctx, ing, actualChIng)
+		markSubCondition(ing, EndpointsProbedCondition, corev1.ConditionTrue, "", "")
 		logger.Debugf("We have an endpoint probe: %#v.", actualChIng.Spec)
 	} else {
+		// We only program a generation once its endpoints were probed.
+		markSubCondition(ing, EndpointsProbedCondition, corev1.ConditionTrue, "", "")
 		ing, err := r.ingressLister.Ingresses(ing.Namespace).Get(names.EndpointProbeIngress(ing))
 		haveEndpointProbe = (err == nil || !apierrs.IsNotFound(err))
 		if haveEndpointProbe {
@@ -165,6 +362,7 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, ing *v1alpha1.Ingress) r
 	info := resources.ServiceNames(ctx, ing)
 	serviceNames := make(sets.String, len(info))
 	serviceToProtocol := make(map[string]string, len(info))
+	services := make([]*corev1.Service, 0, len(info))
 	for name := range info {
 		serviceNames.Insert(name)
 	}
@@ -185,15 +383,28 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, ing *v1alpha1.Ingress) r
 		if err != nil {
 			return err
 		}
-		for _, port := range svc.Spec.Ports {
-			if port.Name == networking.ServicePortNameH2C {
-				serviceToProtocol[name] = "h2c"
-				break
-			}
+		if protocol := serviceProtocol(svc); protocol != "" {
+			serviceToProtocol[name] = protocol
 		}
+		services = append(services, svc)
+	}
+
+	// Let the Envoys reach the backends before routing traffic to them.
+	if err := r.reconcileNetworkPolicy(ctx, ing, services); err != nil {
+		return fmt.Errorf("failed to reconcile NetworkPolicy: %w", err)
 	}
 
-	for _, proxy := range resources.MakeHTTPProxies(ctx, ing, serviceToProtocol) {
+	proxies := resources.MakeHTTPProxies(ctx, ing, serviceToProtocol)
+	if config.FromContext(ctx).Contour.DelegateDefaultTLSSecret && usesDefaultTLSSecret(ctx, proxies) {
+		if err := r.delegateDefaultTLSSecret(ctx, ing.Namespace); err != nil {
+			return fmt.Errorf("failed to delegate the default TLS secret: %w", err)
+		}
+	}
+
+	desired := sets.NewString()
+	var programmed []*contourv1.HTTPProxy
+	for _, proxy := range proxies {
+		desired.Insert(proxy.Name)
 		selector := labels.Set(map[string]string{
 			resources.ParentKey:     proxy.Labels[resources.ParentKey],
 			resources.DomainHashKey: proxy.Labels[resources.DomainHashKey],
@@ -204,50 +415,116 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, ing *v1alpha1.Ingress) r
 			return err
 		}
 		if len(matches) == 0 {
-			proxy, err := r.contourClient.ProjectcontourV1().HTTPProxies(proxy.Namespace).Create(ctx, proxy, metav1.CreateOptions{})
+			created, err := r.createHTTPProxy(ctx, proxy)
 			if err != nil {
 				return err
 			}
-			logger.Debugf("Created http proxy: %#v", proxy)
+			logger.Debugf("Created http proxy: %#v", created)
+			programmed = append(programmed, created)
+			r.programming.programmed(ing)
+			if r.written.drifted(ing, proxy) {
+				repairs++
+			}
+			r.written.wrote(ing, proxy)
+			continue
+		}
+		if resources.StringMapsEqual(matches[0].Annotations, proxy.Annotations) &&
+			resources.StringMapsEqual(matches[0].Labels, proxy.Labels) &&
+			resources.HTTPProxySpecEqual(&matches[0].Spec, &proxy.Spec) {
+			// Avoid updates that don't change anything.
+			programmed = append(programmed, matches[0])
+			r.written.wrote(ing, proxy)
 			continue
 		}
 		update := matches[0].DeepCopy()
 		update.Annotations = proxy.Annotations
 		update.Labels = proxy.Labels
 		update.Spec = proxy.Spec
-		if equality.Semantic.DeepEqual(matches[0], update) {
-			// Avoid updates that don't change anything.
-			continue
-		}
-		if _, err = r.contourClient.ProjectcontourV1().HTTPProxies(proxy.Namespace).Update(ctx, update, metav1.UpdateOptions{}); err != nil {
+		updated, err := r.updateHTTPProxy(ctx, update)
+		if err != nil {
 			return err
 		}
-		if diff, err := kmp.SafeDiff(update, matches[0]); err == nil {
-			logger.Debug("Updated http proxy diff: ", diff)
-		} else {
-			logger.Warnw("Error diffing http proxy", zap.Error(err))
+		programmed = append(programmed, updated)
+		r.programming.programmed(ing)
+		if r.written.drifted(ing, proxy) {
+			repairs++
+		}
+		r.written.wrote(ing, proxy)
+		if recorder := controller.GetEventRecorder(ctx); recorder != nil {
+			recorder.Eventf(ing, corev1.EventTypeNormal, "Updated", "Updated HTTPProxy %q: %s",
+				update.Name, describeProxyChanges(matches[0], update))
+		}
+		// Only diff at debug verbosity, which support asks for.
+		if logger.Desugar().Core().Enabled(zapcore.DebugLevel) {
+			if changes, err := fieldChanges(matches[0], update); err == nil {
+				logger.Debugw("Updated http proxy fields", zap.String("httpproxy", update.Name), zap.Any("diff", changes))
+			} else {
+				logger.Warnw("Error diffing http proxy", zap.Error(err))
+			}
 		}
 		logger.Debugf("Updated http proxy: %#v", update)
 	}
 
+	// Hosts may stop being programmed without the generation changing, e.g.
+	// when they are listed as unmanaged, so clean up their proxies too.
+	current, err := r.contourLister.HTTPProxies(ing.Namespace).List(ownership.Generation(ing.Name, ing.Generation))
+	if err != nil {
+		return err
+	}
+	for _, proxy := range current {
+		if desired.Has(proxy.Name) {
+			continue
+		}
+		if err := r.deleteHTTPProxy(ctx, ing.Namespace, proxy.Name); err != nil {
+			return err
+		}
+		logger.Debugf("Deleted http proxy of an unprogrammed host: %s", proxy.Name)
+	}
+
 	// Before deleting old programming, check our cache to see whether there is anything to clean up.
-	if selector, err := labels.Parse(fmt.Sprintf("%s=%s,%s!=%d",
-		resources.ParentKey, ing.Name,
-		resources.GenerationKey, ing.Generation)); err != nil {
+	if selector, err := ownership.OtherGenerations(ing.Name, ing.Generation); err != nil {
 		return err
 	} else if leftovers, err := r.contourLister.HTTPProxies(ing.Namespace).List(selector); err != nil {
 		return err
 	} else if len(leftovers) != 0 {
+		var retired []*contourv1.HTTPProxy
+		if !ing.IsReady() && !r.skipStatus {
+			// Keep the hosts this generation drops until it was probed.
+			leftovers, retired = retiredHosts(leftovers, proxies)
+			logger.Debugf("Keeping %d older http proxies of retired hosts until probed.", len(retired))
+		}
 		logger.Debugf("Deleting %d older http proxies.", len(leftovers))
 		for _, leftover := range leftovers {
 			logger.Debugf("Leftover: %#v.", leftover)
 		}
-		if err := r.contourClient.ProjectcontourV1().HTTPProxies(ing.Namespace).DeleteCollection(
-			ctx, metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: selector.String()}); err != nil {
-			return err
+		if len(retired) == 0 {
+			if err := r.contourClient.ProjectcontourV1().HTTPProxies(ing.Namespace).DeleteCollection(
+				ctx, metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: selector.String()}); err != nil {
+				return err
+			}
+		} else {
+			for _, leftover := range leftovers {
+				if err := r.deleteHTTPProxy(ctx, ing.Namespace, leftover.Name); err != nil {
+					return err
+				}
+			}
 		}
 	}
 	ing.Status.MarkNetworkConfigured()
+	markSubCondition(ing, ProxiesProgrammedCondition, corev1.ConditionTrue, "", "")
+	markCertificates(ing, programmed)
+
+	if r.skipStatus {
+		// The external component owning the status probes the Envoys too.
+		return nil
+	}
+
+	visibilityKeys, err := resolveVisibilityKeys(ctx, r.serviceLister)
+	if err != nil {
+		return err
+	}
+	publicLbs := lbStatus(visibilityKeys, v1alpha1.IngressVisibilityExternalIP)
+	privateLbs := lbStatus(visibilityKeys, v1alpha1.IngressVisibilityClusterLocal)
 
 	if ing.IsReady() {
 		// When the kingress has already been marked Ready for this generation,
@@ -260,21 +537,56 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, ing *v1alpha1.Ingress) r
 		// skew we might see when the resource is actually in flux, we simply care
 		// about the steady state.
 		logger.Debug("kingress is ready, skipping probe.")
+
+		// The Envoy Services of a visibility may have been replaced (e.g. by
+		// the Gateway provisioner or a blue/green Contour upgrade), so keep the
+		// reported addresses current, but only once the new Envoys serve the
+		// Ingress.  Until then we keep reporting the previous addresses.
+		if !equality.Semantic.DeepEqual(ing.Status.PublicLoadBalancer, &v1alpha1.LoadBalancerStatus{Ingress: publicLbs}) ||
+			!equality.Semantic.DeepEqual(ing.Status.PrivateLoadBalancer, &v1alpha1.LoadBalancerStatus{Ingress: privateLbs}) {
+			r.reprober.reprobe(ing, fmt.Sprint(publicLbs, privateLbs))
+			ready, err := r.statusManager.IsReady(ctx, ing)
+			if err != nil {
+				return fmt.Errorf("failed to probe Ingress %s/%s: %w", ing.GetNamespace(), ing.GetName(), err)
+			}
+			if ready {
+				ing.Status.MarkLoadBalancerReady(publicLbs, privateLbs)
+				r.reprober.forget(types.NamespacedName{Namespace: ing.Namespace, Name: ing.Name})
+			} else {
+				logger.Info("Waiting for the new Envoys to serve the Ingress before reporting their addresses.")
+			}
+		}
 	} else {
 		ready, err := r.statusManager.IsReady(ctx, ing)
 		if err != nil {
 			return fmt.Errorf("failed to probe Ingress %s/%s: %w", ing.GetNamespace(), ing.GetName(), err)
 		}
 		logger.Debugf("Status prober returned %v.", ready)
+		if ready && config.FromContext(ctx).ContourReadinessGate() {
+			processing, err := r.contourGate.processing(ing)
+			if err != nil {
+				return err
+			}
+			markDataPlaneNotReady(ing, !processing)
+			if !processing {
+				logger.Info("Waiting for Contour to process HTTPProxies before reporting the Ingress ready.")
+				ready = false
+			}
+		} else {
+			markDataPlaneNotReady(ing, false)
+		}
 		if ready {
-			ing.Status.MarkLoadBalancerReady(
-				lbStatus(ctx, v1alpha1.IngressVisibilityExternalIP),
-				lbStatus(ctx, v1alpha1.IngressVisibilityClusterLocal))
+			ing.Status.MarkLoadBalancerReady(publicLbs, privateLbs)
+			if latency, ok := r.programming.ready(ing); ok {
+				metrics.Record(ctx, programmingLatencyM.M(float64(latency.Milliseconds())))
+			}
 		} else {
 			ing.Status.MarkLoadBalancerNotReady()
 		}
 	}
 
+	markHostStatus(ing, programmed)
+
 	// Having fully reflected our status, set this before checking
 	// readiness below for deletion.
 	ing.Status.ObservedGeneration = ing.Generation
@@ -295,8 +607,94 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, ing *v1alpha1.Ingress) r
 	return nil
 }
 
-func lbStatus(ctx context.Context, vis v1alpha1.IngressVisibility) (lbs []v1alpha1.LoadBalancerIngressStatus) {
-	if keys, ok := config.FromContext(ctx).Contour.VisibilityKeys[vis]; ok {
+// checkAnnotations returns an error, and the reason to fail the Ingress for,
+// when its annotations ask for something we can't program.
+func checkAnnotations(ctx context.Context, ing *v1alpha1.Ingress) (string, error) {
+	if _, err := resources.HeaderMatchOperators(ing); err != nil {
+		return "UnsupportedHeaderMatch", err
+	}
+	if _, err := resources.CORSPolicy(ctx, ing); err != nil {
+		return "InvalidCORSPolicy", err
+	}
+	if _, err := resources.MinimumTLSVersion(ing); err != nil {
+		return "InvalidTLSVersion", err
+	}
+	if _, err := resources.AuthContext(ing); err != nil {
+		return "InvalidAuthContext", err
+	}
+	if config.FromContext(ctx).Contour.AuthorizationServer == nil && len(resources.AuthDisabledPaths(ing)) != 0 {
+		return "AuthorizationNotConfigured", fmt.Errorf(
+			"annotation %s needs the authorization-server of config-contour, which isn't set", resources.AuthDisabledPathsKey)
+	}
+	if _, err := resources.PathRetryPolicies(ing); err != nil {
+		return "InvalidRetryPolicy", err
+	}
+	if _, err := resources.ClassOverrides(ing); err != nil {
+		return "InvalidClassOverride", err
+	}
+	return "", nil
+}
+
+// checkProxyCount returns an error when the Ingress generates more HTTPProxies
+// than the max-proxies-per-ingress of the configuration, before we probe or
+// program anything for it.  The protocols of the backends don't change the
+// number of HTTPProxies, so we don't look them up.
+func checkProxyCount(ctx context.Context, ing *v1alpha1.Ingress) error {
+	max := config.FromContext(ctx).Contour.MaxProxiesPerIngress
+	if max <= 0 {
+		return nil
+	}
+	if n := len(resources.MakeHTTPProxies(ctx, ing, nil)); n > max {
+		return fmt.Errorf("the Ingress generates %d HTTPProxies, more than the %d allowed", n, max)
+	}
+	return nil
+}
+
+// DesiredHTTPProxies returns the HTTPProxies we program for the Ingress with
+// the configuration of the context, looking up the protocols of its backends
+// with the lister.  It errors when the Ingress can't be programmed.
+func DesiredHTTPProxies(ctx context.Context, ing *v1alpha1.Ingress, serviceLister corev1listers.ServiceLister) ([]*contourv1.HTTPProxy, error) {
+	if _, err := checkAnnotations(ctx, ing); err != nil {
+		return nil, err
+	}
+	if err := checkProxyCount(ctx, ing); err != nil {
+		return nil, err
+	}
+	serviceToProtocol := make(map[string]string)
+	for name := range resources.ServiceNames(ctx, ing) {
+		svc, err := serviceLister.Services(ing.Namespace).Get(name)
+		if err != nil {
+			return nil, err
+		}
+		if protocol := serviceProtocol(svc); protocol != "" {
+			serviceToProtocol[name] = protocol
+		}
+	}
+	return resources.MakeHTTPProxies(ctx, ing, serviceToProtocol), nil
+}
+
+// h2cAppProtocols are the appProtocols of Service ports that speak HTTP/2
+// with prior knowledge.
+var h2cAppProtocols = sets.NewString("h2c", "kubernetes.io/h2c")
+
+// serviceProtocol returns the protocol Envoy should speak to the Service.
+// Our probes reach the backends through Envoy too, so an h2c backend that
+// rejects HTTP/1.1 only becomes ready once we detect it here, whether its
+// port is named h2c or its appProtocol says so.
+func serviceProtocol(svc *corev1.Service) string {
+	for _, port := range svc.Spec.Ports {
+		if port.Name == networking.ServicePortNameH2C {
+			return "h2c"
+		}
+		if port.AppProtocol != nil && h2cAppProtocols.Has(*port.AppProtocol) {
+			return "h2c"
+		}
+	}
+	return ""
+}
+
+func lbStatus(visibilityKeys map[v1alpha1.IngressVisibility]sets.String, vis v1alpha1.IngressVisibility) (lbs []v1alpha1.LoadBalancerIngressStatus) {
+	if keys, ok := visibilityKeys[vis]; ok {
 		for _, key := range keys.List() {
 			namespace, name, _ := cache.SplitMetaNamespaceKey(key)
 			lbs = append(lbs, v1alpha1.LoadBalancerIngressStatus{
@@ -306,3 +704,24 @@ func lbStatus(ctx context.Context, vis v1alpha1.IngressVisibility) (lbs []v1alph
 	}
 	return
 }
+
+// endpointProbeTimedOut returns whether we already gave up on probing the
+// endpoints of the Ingress' current generation.
+func endpointProbeTimedOut(ing *v1alpha1.Ingress) bool {
+	cond := ing.Status.GetCondition(v1alpha1.IngressConditionLoadBalancerReady)
+	return ing.Status.ObservedGeneration == ing.Generation &&
+		cond.IsFalse() && cond.Reason == endpointsProbeTimeoutReason
+}
+
+// endpointProbeStarted returns when the endpoint probe last stopped being
+// ready, which is when probing of its current contents started.  It returns
+// false while the probe's latest spec hasn't been observed yet.
+func endpointProbeStarted(chIng *v1alpha1.Ingress) (time.Time, bool) {
+	if chIng.Status.ObservedGeneration != chIng.Generation {
+		return time.Time{}, false
+	}
+	if cond := chIng.Status.GetCondition(v1alpha1.IngressConditionReady); cond != nil {
+		return cond.LastTransitionTime.Inner.Time, true
+	}
+	return chIng.CreationTimestamp.Time, true
+}
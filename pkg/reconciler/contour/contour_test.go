@@ -28,6 +28,7 @@ import (
 
 	fakecontourclient "knative.dev/net-contour/pkg/client/injection/client/fake"
 	fakeingressclient "knative.dev/networking/pkg/client/injection/client/fake"
+	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -38,11 +39,13 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
 	clientgotesting "k8s.io/client-go/testing"
+	"knative.dev/net-contour/pkg/ownership"
 	"knative.dev/net-contour/pkg/reconciler/contour/config"
 	"knative.dev/net-contour/pkg/reconciler/contour/resources"
 	"knative.dev/networking/pkg/apis/networking"
 	"knative.dev/networking/pkg/apis/networking/v1alpha1"
 	ingressreconciler "knative.dev/networking/pkg/client/injection/reconciler/networking/v1alpha1/ingress"
+	"knative.dev/pkg/apis"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/kmeta"
@@ -89,6 +92,7 @@ func TestReconcile(t *testing.T) {
 				i.Status.InitializeConditions()
 				i.Status.MarkLoadBalancerNotReady()
 				i.Status.MarkIngressNotReady("EndpointsNotReady", "Waiting for Envoys to receive Endpoints data.")
+				markWaitingForEndpoints(i)
 			}),
 		}},
 	}, {
@@ -124,6 +128,7 @@ func TestReconcile(t *testing.T) {
 				// These are the things we expect to change in status.
 				i.Status.InitializeConditions()
 				i.Status.MarkNetworkConfigured()
+				markProgrammed(i)
 				i.Status.MarkLoadBalancerReady(
 					[]v1alpha1.LoadBalancerIngressStatus{{
 						DomainInternal: publicSvc,
@@ -157,6 +162,7 @@ func TestReconcile(t *testing.T) {
 				// These are the things we expect to change in status.
 				i.Status.InitializeConditions()
 				i.Status.MarkNetworkConfigured()
+				markProgrammed(i)
 				i.Status.MarkLoadBalancerReady(
 					[]v1alpha1.LoadBalancerIngressStatus{{
 						DomainInternal: publicSvc,
@@ -176,6 +182,170 @@ func TestReconcile(t *testing.T) {
 		WantEvents: []string{
 			Eventf(corev1.EventTypeWarning, "InternalError", "inducing failure for delete ingresses"),
 		},
+	}, {
+		Name: "header match that can't be programmed",
+		Key:  "ns/name",
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, withAnnotation(map[string]string{
+				resources.HeaderMatchKey: `{"X-Canary": "prefix"}`,
+			})),
+		}, servicesAndEndpoints...),
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: ing("name", "ns", withBasicSpec, withContour, withAnnotation(map[string]string{
+				resources.HeaderMatchKey: `{"X-Canary": "prefix"}`,
+			}), func(i *v1alpha1.Ingress) {
+				// These are the things we expect to change in status.
+				i.Status.InitializeConditions()
+				i.Status.MarkLoadBalancerFailed("UnsupportedHeaderMatch",
+					`header "X-Canary": the installed Contour can't match headers by prefix`)
+			}),
+		}},
+	}, {
+		Name: "invalid probe settings",
+		Key:  "ns/name",
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, withAnnotation(map[string]string{
+				resources.ReadinessQuorumKey: "0",
+			})),
+		}, servicesAndEndpoints...),
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: ing("name", "ns", withBasicSpec, withContour, withAnnotation(map[string]string{
+				resources.ReadinessQuorumKey: "0",
+			}), func(i *v1alpha1.Ingress) {
+				// These are the things we expect to change in status.
+				i.Status.InitializeConditions()
+				i.Status.MarkLoadBalancerFailed("InvalidProbeSettings", "annotation "+
+					resources.ReadinessQuorumKey+` must be in the range (0, 1], got "0"`)
+			}),
+		}},
+	}, {
+		Name: "cors policy that can't be programmed",
+		Key:  "ns/name",
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, withAnnotation(map[string]string{
+				resources.CORSPolicyKey: `{"allowOrigin": ["*"]}`,
+			})),
+		}, servicesAndEndpoints...),
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: ing("name", "ns", withBasicSpec, withContour, withAnnotation(map[string]string{
+				resources.CORSPolicyKey: `{"allowOrigin": ["*"]}`,
+			}), func(i *v1alpha1.Ingress) {
+				// These are the things we expect to change in status.
+				i.Status.InitializeConditions()
+				i.Status.MarkLoadBalancerFailed("InvalidCORSPolicy", "failed to parse annotation "+
+					resources.CORSPolicyKey+": allowMethods must list at least one method")
+			}),
+		}},
+	}, {
+		Name: "paused ingress",
+		Key:  "ns/name",
+		// Neither the hand-patched proxy nor the new spec are programmed.
+		Objects: append(append([]runtime.Object{
+			ing("name", "ns", withBasicSpec2, withContour, makeItReady, withAnnotation(map[string]string{
+				resources.PausedKey: "true",
+			})),
+		}, mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour), func(p *v1.HTTPProxy) {
+			p.Spec.Routes[0].TimeoutPolicy = nil
+		})...), servicesAndEndpoints...),
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: ing("name", "ns", withBasicSpec2, withContour, makeItReady, withAnnotation(map[string]string{
+				resources.PausedKey: "true",
+			}), func(i *v1alpha1.Ingress) {
+				markSubCondition(i, ProxiesProgrammedCondition, corev1.ConditionUnknown, pausedReason,
+					"Programming is paused by the "+resources.PausedKey+" annotation.")
+			}),
+		}},
+	}, {
+		Name: "tls version that can't be programmed",
+		Key:  "ns/name",
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, withAnnotation(map[string]string{
+				resources.MinimumTLSVersionKey: "1.1",
+			})),
+		}, servicesAndEndpoints...),
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: ing("name", "ns", withBasicSpec, withContour, withAnnotation(map[string]string{
+				resources.MinimumTLSVersionKey: "1.1",
+			}), func(i *v1alpha1.Ingress) {
+				// These are the things we expect to change in status.
+				i.Status.InitializeConditions()
+				i.Status.MarkLoadBalancerFailed("InvalidTLSVersion", "annotation "+
+					resources.MinimumTLSVersionKey+`: unsupported TLS version "1.1", must be one of [1.2 1.3]`)
+			}),
+		}},
+	}, {
+		Name: "auth context that can't be programmed",
+		Key:  "ns/name",
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, withAnnotation(map[string]string{
+				resources.AuthContextKey: `["tenant"]`,
+			})),
+		}, servicesAndEndpoints...),
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: ing("name", "ns", withBasicSpec, withContour, withAnnotation(map[string]string{
+				resources.AuthContextKey: `["tenant"]`,
+			}), func(i *v1alpha1.Ingress) {
+				// These are the things we expect to change in status.
+				i.Status.InitializeConditions()
+				i.Status.MarkLoadBalancerFailed("InvalidAuthContext", "failed to parse annotation "+
+					resources.AuthContextKey+": json: cannot unmarshal array into Go value of type map[string]string")
+			}),
+		}},
+	}, {
+		Name: "retry policies that can't be programmed",
+		Key:  "ns/name",
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, withAnnotation(map[string]string{
+				resources.PathRetryPoliciesKey: `{"api": {"numRetries": 1}}`,
+			})),
+		}, servicesAndEndpoints...),
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: ing("name", "ns", withBasicSpec, withContour, withAnnotation(map[string]string{
+				resources.PathRetryPoliciesKey: `{"api": {"numRetries": 1}}`,
+			}), func(i *v1alpha1.Ingress) {
+				// These are the things we expect to change in status.
+				i.Status.InitializeConditions()
+				i.Status.MarkLoadBalancerFailed("InvalidRetryPolicy", "annotation "+
+					resources.PathRetryPoliciesKey+`: path "api" must be absolute`)
+			}),
+		}},
+	}, {
+		Name: "class override that can't be parsed",
+		Key:  "ns/name",
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, withAnnotation(map[string]string{
+				resources.ClassOverrideKey: `{"Public": "contour-pci"}`,
+			})),
+		}, servicesAndEndpoints...),
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: ing("name", "ns", withBasicSpec, withContour, withAnnotation(map[string]string{
+				resources.ClassOverrideKey: `{"Public": "contour-pci"}`,
+			}), func(i *v1alpha1.Ingress) {
+				// These are the things we expect to change in status.
+				i.Status.InitializeConditions()
+				i.Status.MarkLoadBalancerFailed("InvalidClassOverride", "annotation "+
+					resources.ClassOverrideKey+`: unrecognized visibility "Public"`)
+			}),
+		}},
+	}, {
+		Name: "class override that isn't approved",
+		Key:  "ns/name",
+		Objects: append([]runtime.Object{
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns"}},
+			ing("name", "ns", withBasicSpec, withContour, withAnnotation(map[string]string{
+				resources.ClassOverrideKey: `{"ExternalIP": "contour-pci"}`,
+			})),
+		}, servicesAndEndpoints...),
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: ing("name", "ns", withBasicSpec, withContour, withAnnotation(map[string]string{
+				resources.ClassOverrideKey: `{"ExternalIP": "contour-pci"}`,
+			}), func(i *v1alpha1.Ingress) {
+				// These are the things we expect to change in status.
+				i.Status.InitializeConditions()
+				i.Status.MarkLoadBalancerFailed("ClassOverrideNotAllowed",
+					`the Contour class "contour-pci" is not approved for overrides`)
+			}),
+		}},
 	}, {
 		Name: "first reconcile basic ingress (endpoints probe not ready)",
 		Key:  "ns/name",
@@ -185,6 +355,7 @@ func TestReconcile(t *testing.T) {
 				i.Status.InitializeConditions()
 				i.Status.MarkLoadBalancerNotReady()
 				i.Status.MarkIngressNotReady("EndpointsNotReady", "Waiting for Envoys to receive Endpoints data.")
+				markWaitingForEndpoints(i)
 			}),
 			mustMakeProbe(t, ing("name", "ns", withBasicSpec, withContour)),
 		}, servicesAndEndpoints...),
@@ -204,6 +375,7 @@ func TestReconcile(t *testing.T) {
 				i.Status.InitializeConditions()
 				i.Status.MarkLoadBalancerNotReady()
 				i.Status.MarkIngressNotReady("EndpointsNotReady", "Waiting for Envoys to receive Endpoints data.")
+				markWaitingForEndpoints(i)
 			}),
 		}},
 	}, {
@@ -249,6 +421,45 @@ func TestReconcile(t *testing.T) {
 		Objects: append(append([]runtime.Object{
 			ing("name", "ns", withBasicSpec, withContour, makeItReady),
 		}, mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour))...), servicesAndEndpoints...),
+	}, {
+		Name: "host became unmanaged",
+		Key:  "ns/name",
+		Objects: append(append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, makeItReady,
+				withAnnotation(map[string]string{resources.UnmanagedHostsKey: "example.com"})),
+		}, mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour))...), servicesAndEndpoints...),
+		WantDeletes: []clientgotesting.DeleteActionImpl{{
+			ActionImpl: clientgotesting.ActionImpl{
+				Namespace: "ns",
+				Resource:  v1.SchemeGroupVersion.WithResource("httpproxies"),
+			},
+			Name: mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour))[0].(*v1.HTTPProxy).Name,
+		}},
+	}, {
+		Name: "edited proxy of a ready ingress is repaired",
+		Key:  "ns/name",
+		Objects: append(append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, makeItReady),
+		}, mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour), func(p *v1.HTTPProxy) {
+			p.Spec.Routes[0].TimeoutPolicy = nil
+		})...), servicesAndEndpoints...),
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour))[0],
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Updated", `Updated HTTPProxy "name--example.com": no changes to routes or TLS`),
+		},
+	}, {
+		Name: "steady state basic ingress (stale addresses)",
+		Key:  "ns/name",
+		Objects: append(append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, makeItReady, func(i *v1alpha1.Ingress) {
+				i.Status.PublicLoadBalancer.Ingress[0].DomainInternal = "envoy.old-contour.svc.cluster.local"
+			}),
+		}, mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour))...), servicesAndEndpoints...),
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: ing("name", "ns", withBasicSpec, withContour, makeItReady),
+		}},
 	}, {
 		Name: "basic ingress changed",
 		Key:  "ns/name",
@@ -280,6 +491,7 @@ func TestReconcile(t *testing.T) {
 				// These are the things we expect to change in status.
 				i.Status.InitializeConditions()
 				i.Status.MarkNetworkConfigured()
+				markProgrammed(i)
 				i.Status.MarkLoadBalancerReady(
 					[]v1alpha1.LoadBalancerIngressStatus{{
 						DomainInternal: publicSvc,
@@ -293,6 +505,10 @@ func TestReconcile(t *testing.T) {
 				i.Status.ObservedGeneration = 1
 			}),
 		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Updated", `Updated HTTPProxy "name--example.com": changed backends `+
+				`/ (goo:123=100 -> doo:123=100), / [K-Network-Hash="override"] (goo:123=100 -> doo:123=100)`),
+		},
 	}, {
 		Name: "first reconcile multi-httpproxy ingress",
 		Key:  "ns/name",
@@ -306,6 +522,7 @@ func TestReconcile(t *testing.T) {
 				// These are the things we expect to change in status.
 				i.Status.InitializeConditions()
 				i.Status.MarkNetworkConfigured()
+				markProgrammed(i)
 				i.Status.MarkLoadBalancerReady(
 					[]v1alpha1.LoadBalancerIngressStatus{{
 						DomainInternal: publicSvc,
@@ -338,6 +555,7 @@ func TestReconcile(t *testing.T) {
 			Object: ing("name", "ns", withBasicSpec, withContour, func(i *v1alpha1.Ingress) {
 				// These are the things we expect to change in status.
 				i.Status.InitializeConditions()
+				markSubCondition(i, EndpointsProbedCondition, corev1.ConditionTrue, "", "")
 			}),
 		}},
 		WantEvents: []string{
@@ -365,6 +583,7 @@ func TestReconcile(t *testing.T) {
 				i.Status.InitializeConditions()
 				i.Status.ObservedGeneration = 1
 				i.Status.MarkIngressNotReady("NewObservedGenFailure", "unsuccessfully observed a new generation")
+				markSubCondition(i, EndpointsProbedCondition, corev1.ConditionTrue, "", "")
 			}),
 		}},
 		WantEvents: []string{
@@ -399,9 +618,12 @@ func TestReconcile(t *testing.T) {
 				i.Status.InitializeConditions()
 				i.Status.ObservedGeneration = 1
 				i.Status.MarkIngressNotReady("NewObservedGenFailure", "unsuccessfully observed a new generation")
+				markSubCondition(i, EndpointsProbedCondition, corev1.ConditionTrue, "", "")
 			}),
 		}},
 		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Updated", `Updated HTTPProxy "name--example.com": changed backends `+
+				`/ (goo:123=100 -> doo:123=100), / [K-Network-Hash="override"] (goo:123=100 -> doo:123=100)`),
 			Eventf(corev1.EventTypeWarning, "InternalError", "inducing failure for delete-collection httpproxies"),
 		},
 	}, {
@@ -421,6 +643,7 @@ func TestReconcile(t *testing.T) {
 				// These are the things we expect to change in status.
 				i.Status.InitializeConditions()
 				i.Status.MarkNetworkConfigured()
+				markProgrammed(i)
 				i.Status.MarkLoadBalancerReady(
 					[]v1alpha1.LoadBalancerIngressStatus{{
 						DomainInternal: publicSvc,
@@ -460,12 +683,16 @@ func TestReconcile(t *testing.T) {
 
 	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
 		r := &Reconciler{
-			ingressClient: fakeingressclient.Get(ctx),
-			contourClient: fakecontourclient.Get(ctx),
-			ingressLister: listers.GetIngressLister(),
-			contourLister: listers.GetHTTPProxyLister(),
-			serviceLister: listers.GetK8sServiceLister(),
-			tracker:       &NullTracker{},
+			ingressClient:       fakeingressclient.Get(ctx),
+			contourClient:       fakecontourclient.Get(ctx),
+			ingressLister:       listers.GetIngressLister(),
+			contourLister:       listers.GetHTTPProxyLister(),
+			serviceLister:       listers.GetK8sServiceLister(),
+			delegationLister:    listers.GetTLSCertificateDelegationLister(),
+			kubeClient:          fakekubeclient.Get(ctx),
+			networkPolicyLister: listers.GetNetworkPolicyLister(),
+			namespaceLister:     listers.GetNamespaceLister(),
+			tracker:             &NullTracker{},
 			statusManager: &fakeStatusManager{
 				FakeIsReady: func(context.Context, *v1alpha1.Ingress) (bool, error) {
 					return true, nil
@@ -484,6 +711,258 @@ func TestReconcile(t *testing.T) {
 	}))
 }
 
+func TestReconcileDependencyMissing(t *testing.T) {
+	table := TableTest{{
+		Name: "httpproxy crd missing",
+		Key:  "ns/name",
+		// We requeue to check for the CRD again later.
+		WantErr: true,
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour),
+		}, servicesAndEndpoints...),
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: ing("name", "ns", withBasicSpec, withContour, func(i *v1alpha1.Ingress) {
+				// These are the things we expect to change in status.
+				i.Status.InitializeConditions()
+				i.Status.MarkLoadBalancerFailed("DependencyMissing",
+					"The Contour HTTPProxy API (projectcontour.io/v1) is not installed.")
+			}),
+		}},
+	}}
+
+	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
+		r := &Reconciler{
+			ingressClient:       fakeingressclient.Get(ctx),
+			contourClient:       fakecontourclient.Get(ctx),
+			ingressLister:       listers.GetIngressLister(),
+			contourLister:       listers.GetHTTPProxyLister(),
+			serviceLister:       listers.GetK8sServiceLister(),
+			delegationLister:    listers.GetTLSCertificateDelegationLister(),
+			kubeClient:          fakekubeclient.Get(ctx),
+			networkPolicyLister: listers.GetNetworkPolicyLister(),
+			namespaceLister:     listers.GetNamespaceLister(),
+			tracker:             &NullTracker{},
+			apiChecker:          &apiChecker{discovery: fakeDiscovery("tlscertificatedelegations")},
+		}
+		return ingressreconciler.NewReconciler(ctx, logging.FromContext(ctx), fakeingressclient.Get(ctx),
+			listers.GetIngressLister(), controller.GetEventRecorder(ctx), r, ContourIngressClassName,
+			controller.Options{
+				ConfigStore: &testConfigStore{
+					config: defaultConfig,
+				}})
+	}))
+}
+
+func TestReconcileEndpointProbeTimeout(t *testing.T) {
+	cfg := defaultConfig.DeepCopy()
+	cfg.Contour.EndpointProbeTimeout = 5 * time.Minute
+
+	probingSince := func(d time.Duration) IngressOption {
+		return func(i *v1alpha1.Ingress) {
+			i.Status.InitializeConditions()
+			i.Status.MarkLoadBalancerNotReady()
+			for j := range i.Status.Conditions {
+				i.Status.Conditions[j].LastTransitionTime = apis.VolatileTime{
+					Inner: metav1.NewTime(time.Now().Add(-d)),
+				}
+			}
+		}
+	}
+
+	table := TableTest{{
+		Name: "endpoints probe not ready (within deadline)",
+		Key:  "ns/name",
+		// We requeue to check the deadline again later.
+		WantErr: true,
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour),
+			mustMakeProbe(t, ing("name", "ns", withBasicSpec, withContour), probingSince(time.Minute)),
+		}, servicesAndEndpoints...),
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: ing("name", "ns", withBasicSpec, withContour, func(i *v1alpha1.Ingress) {
+				// These are the things we expect to change in status.
+				i.Status.InitializeConditions()
+				i.Status.MarkLoadBalancerNotReady()
+				i.Status.MarkIngressNotReady("EndpointsNotReady", "Waiting for Envoys to receive Endpoints data.")
+				markWaitingForEndpoints(i)
+			}),
+		}},
+	}, {
+		Name: "endpoints probe not ready (deadline exceeded)",
+		Key:  "ns/name",
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour),
+			mustMakeProbe(t, ing("name", "ns", withBasicSpec, withContour), probingSince(time.Hour)),
+		}, servicesAndEndpoints...),
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: ing("name", "ns", withBasicSpec, withContour, func(i *v1alpha1.Ingress) {
+				// These are the things we expect to change in status.
+				i.Status.InitializeConditions()
+				i.Status.MarkLoadBalancerFailed("EndpointsProbeTimeout",
+					"Envoys did not receive Endpoints data within 5m0s.")
+				markSubCondition(i, EndpointsProbedCondition, corev1.ConditionFalse, "EndpointsProbeTimeout",
+					"Envoys did not receive Endpoints data within 5m0s.")
+			}),
+		}},
+		WantDeletes: []clientgotesting.DeleteActionImpl{{
+			ActionImpl: clientgotesting.ActionImpl{
+				Namespace: "ns",
+				Resource:  v1alpha1.SchemeGroupVersion.WithResource("ingresses"),
+			},
+			Name: "name--ep",
+		}},
+	}, {
+		Name: "endpoints probe already timed out",
+		Key:  "ns/name",
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, func(i *v1alpha1.Ingress) {
+				i.Status.InitializeConditions()
+				i.Status.MarkLoadBalancerFailed("EndpointsProbeTimeout",
+					"Envoys did not receive Endpoints data within 5m0s.")
+			}),
+		}, servicesAndEndpoints...),
+	}}
+
+	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
+		r := &Reconciler{
+			ingressClient:       fakeingressclient.Get(ctx),
+			contourClient:       fakecontourclient.Get(ctx),
+			ingressLister:       listers.GetIngressLister(),
+			contourLister:       listers.GetHTTPProxyLister(),
+			serviceLister:       listers.GetK8sServiceLister(),
+			delegationLister:    listers.GetTLSCertificateDelegationLister(),
+			kubeClient:          fakekubeclient.Get(ctx),
+			networkPolicyLister: listers.GetNetworkPolicyLister(),
+			namespaceLister:     listers.GetNamespaceLister(),
+			tracker:             &NullTracker{},
+		}
+		return ingressreconciler.NewReconciler(ctx, logging.FromContext(ctx), fakeingressclient.Get(ctx),
+			listers.GetIngressLister(), controller.GetEventRecorder(ctx), r, ContourIngressClassName,
+			controller.Options{
+				ConfigStore: &testConfigStore{
+					config: cfg,
+				}})
+	}))
+}
+
+func TestReconcileTooManyProxies(t *testing.T) {
+	cfg := defaultConfig.DeepCopy()
+	cfg.Contour.MaxProxiesPerIngress = 1
+
+	withHosts := func(i *v1alpha1.Ingress) {
+		i.Spec.Rules[0].Hosts = []string{"a.example.com", "b.example.com"}
+	}
+
+	table := TableTest{{
+		Name: "too many proxies",
+		Key:  "ns/name",
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, withHosts),
+		}, servicesAndEndpoints...),
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: ing("name", "ns", withBasicSpec, withContour, withHosts, func(i *v1alpha1.Ingress) {
+				i.Status.InitializeConditions()
+				i.Status.MarkLoadBalancerFailed("TooManyHTTPProxies",
+					"the Ingress generates 2 HTTPProxies, more than the 1 allowed")
+			}),
+		}},
+	}}
+
+	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
+		r := &Reconciler{
+			ingressClient:       fakeingressclient.Get(ctx),
+			contourClient:       fakecontourclient.Get(ctx),
+			ingressLister:       listers.GetIngressLister(),
+			contourLister:       listers.GetHTTPProxyLister(),
+			serviceLister:       listers.GetK8sServiceLister(),
+			delegationLister:    listers.GetTLSCertificateDelegationLister(),
+			kubeClient:          fakekubeclient.Get(ctx),
+			networkPolicyLister: listers.GetNetworkPolicyLister(),
+			namespaceLister:     listers.GetNamespaceLister(),
+			tracker:             &NullTracker{},
+		}
+		return ingressreconciler.NewReconciler(ctx, logging.FromContext(ctx), fakeingressclient.Get(ctx),
+			listers.GetIngressLister(), controller.GetEventRecorder(ctx), r, ContourIngressClassName,
+			controller.Options{
+				ConfigStore: &testConfigStore{
+					config: cfg,
+				}})
+	}))
+}
+
+func TestReconcilePausedDuringRollout(t *testing.T) {
+	cfg := defaultConfig.DeepCopy()
+	cfg.Contour.PauseDuringRollouts = true
+
+	public := publicService.DeepCopy()
+	public.Spec.Selector = map[string]string{"app": "envoy"}
+
+	table := TableTest{{
+		Name: "envoy pods rolling out",
+		Key:  "ns/name",
+		// We requeue to check on the rollout again later.
+		WantErr: true,
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour),
+			public,
+			privateService,
+			envoyPod(publicNS, "a", "v1"),
+			envoyPod(publicNS, "b", "v2"),
+		}, servicesAndEndpoints...),
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			// Nothing but the initialized conditions and the pause changes.
+			Object: ing("name", "ns", withBasicSpec, withContour, func(i *v1alpha1.Ingress) {
+				i.Status.InitializeConditions()
+				markRolloutPaused(i, "EnvoyRollingOut", "Holding changes while the Envoy pods of "+publicKey+" roll out.")
+			}),
+		}},
+	}, {
+		Name: "envoy pods stuck rolling out",
+		Key:  "ns/name",
+		Objects: append(append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, makeItReady),
+			public,
+			privateService,
+			envoyPod(publicNS, "a", "v1", stuck),
+			envoyPod(publicNS, "b", "v1", stuck, notReady),
+		}, mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour))...), servicesAndEndpoints...),
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			// We stop holding changes, and say why.
+			Object: ing("name", "ns", withBasicSpec, withContour, makeItReady, func(i *v1alpha1.Ingress) {
+				markRolloutPaused(i, "EnvoyRolloutStuck", "The Envoy pods of "+publicKey+
+					" made no progress rolling out for 10m0s, changes are no longer held.")
+			}),
+		}},
+	}}
+
+	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
+		r := &Reconciler{
+			ingressClient:       fakeingressclient.Get(ctx),
+			contourClient:       fakecontourclient.Get(ctx),
+			ingressLister:       listers.GetIngressLister(),
+			contourLister:       listers.GetHTTPProxyLister(),
+			serviceLister:       listers.GetK8sServiceLister(),
+			delegationLister:    listers.GetTLSCertificateDelegationLister(),
+			kubeClient:          fakekubeclient.Get(ctx),
+			networkPolicyLister: listers.GetNetworkPolicyLister(),
+			namespaceLister:     listers.GetNamespaceLister(),
+			podLister:           listers.GetPodsLister(),
+			tracker:             &NullTracker{},
+			statusManager: &fakeStatusManager{
+				FakeIsReady: func(context.Context, *v1alpha1.Ingress) (bool, error) {
+					return true, nil
+				},
+			},
+		}
+		return ingressreconciler.NewReconciler(ctx, logging.FromContext(ctx), fakeingressclient.Get(ctx),
+			listers.GetIngressLister(), controller.GetEventRecorder(ctx), r, ContourIngressClassName,
+			controller.Options{
+				ConfigStore: &testConfigStore{
+					config: cfg,
+				}})
+	}))
+}
+
 func TestReconcileProberNotReady(t *testing.T) {
 	table := TableTest{{
 		Name: "first reconcile basic ingress",
@@ -498,19 +977,36 @@ func TestReconcileProberNotReady(t *testing.T) {
 				// These are the things we expect to change in status.
 				i.Status.InitializeConditions()
 				i.Status.MarkNetworkConfigured()
+				markProgrammed(i)
 				i.Status.MarkLoadBalancerNotReady()
+				i.Status.Annotations = map[string]string{
+					HostStatusAnnotationKey: `{"example.com":"programmed"}`,
+				}
 			}),
 		}},
+	}, {
+		// We keep reporting the previous Envoys until the new ones serve it.
+		Name: "steady state basic ingress (stale addresses)",
+		Key:  "ns/name",
+		Objects: append(append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, makeItReady, func(i *v1alpha1.Ingress) {
+				i.Status.PublicLoadBalancer.Ingress[0].DomainInternal = "envoy.old-contour.svc.cluster.local"
+			}),
+		}, mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour))...), servicesAndEndpoints...),
 	}}
 
 	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
 		r := &Reconciler{
-			ingressClient: fakeingressclient.Get(ctx),
-			contourClient: fakecontourclient.Get(ctx),
-			ingressLister: listers.GetIngressLister(),
-			contourLister: listers.GetHTTPProxyLister(),
-			serviceLister: listers.GetK8sServiceLister(),
-			tracker:       &NullTracker{},
+			ingressClient:       fakeingressclient.Get(ctx),
+			contourClient:       fakecontourclient.Get(ctx),
+			ingressLister:       listers.GetIngressLister(),
+			contourLister:       listers.GetHTTPProxyLister(),
+			serviceLister:       listers.GetK8sServiceLister(),
+			delegationLister:    listers.GetTLSCertificateDelegationLister(),
+			kubeClient:          fakekubeclient.Get(ctx),
+			networkPolicyLister: listers.GetNetworkPolicyLister(),
+			namespaceLister:     listers.GetNamespaceLister(),
+			tracker:             &NullTracker{},
 			statusManager: &fakeStatusManager{
 				FakeIsReady: func(context.Context, *v1alpha1.Ingress) (bool, error) {
 					return false, nil
@@ -526,6 +1022,117 @@ func TestReconcileProberNotReady(t *testing.T) {
 	}))
 }
 
+func TestReconcileContourReadinessGate(t *testing.T) {
+	// A proxy of another Ingress which Contour never validated.
+	pending := &v1.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:  "ns",
+			Name:       "other",
+			Generation: 1,
+			Labels:     ownership.GenerationLabels("other", 1),
+		},
+	}
+	table := TableTest{{
+		Name: "first reconcile basic ingress",
+		Key:  "ns/name",
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour),
+			mustMakeProbe(t, ing("name", "ns", withBasicSpec, withContour), makeItReady),
+			pending,
+		}, servicesAndEndpoints...),
+		WantCreates: mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour)),
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: ing("name", "ns", withBasicSpec, withContour, func(i *v1alpha1.Ingress) {
+				i.Status.InitializeConditions()
+				i.Status.MarkNetworkConfigured()
+				markProgrammed(i)
+				markDataPlaneNotReady(i, true)
+				i.Status.MarkLoadBalancerNotReady()
+				i.Status.Annotations = map[string]string{
+					HostStatusAnnotationKey: `{"example.com":"programmed"}`,
+				}
+			}),
+		}},
+	}}
+
+	cfg := defaultConfig.DeepCopy()
+	cfg.Features = &config.Features{ContourReadinessGate: config.Enabled}
+	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
+		r := &Reconciler{
+			ingressClient:       fakeingressclient.Get(ctx),
+			contourClient:       fakecontourclient.Get(ctx),
+			ingressLister:       listers.GetIngressLister(),
+			contourLister:       listers.GetHTTPProxyLister(),
+			serviceLister:       listers.GetK8sServiceLister(),
+			delegationLister:    listers.GetTLSCertificateDelegationLister(),
+			kubeClient:          fakekubeclient.Get(ctx),
+			networkPolicyLister: listers.GetNetworkPolicyLister(),
+			namespaceLister:     listers.GetNamespaceLister(),
+			tracker:             &NullTracker{},
+			statusManager: &fakeStatusManager{
+				FakeIsReady: func(context.Context, *v1alpha1.Ingress) (bool, error) {
+					return true, nil
+				},
+			},
+			contourGate: newContourGate(listers.GetHTTPProxyLister(), func(interface{}, time.Duration) {}),
+		}
+		return ingressreconciler.NewReconciler(ctx, logging.FromContext(ctx), fakeingressclient.Get(ctx),
+			listers.GetIngressLister(), controller.GetEventRecorder(ctx), r, ContourIngressClassName,
+			controller.Options{
+				ConfigStore: &testConfigStore{
+					config: cfg,
+				}})
+	}))
+}
+
+func TestReconcileSkipStatusUpdates(t *testing.T) {
+	table := TableTest{{
+		// Without status updates there is no endpoint probe to wait for.
+		Name: "first reconcile basic ingress",
+		Key:  "ns/name",
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour),
+		}, servicesAndEndpoints...),
+		WantCreates: mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour)),
+	}, {
+		Name: "steady state basic ingress",
+		Key:  "ns/name",
+		Objects: append(append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour),
+		}, mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour))...), servicesAndEndpoints...),
+	}}
+
+	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
+		r := &Reconciler{
+			ingressClient:       fakeingressclient.Get(ctx),
+			contourClient:       fakecontourclient.Get(ctx),
+			ingressLister:       listers.GetIngressLister(),
+			contourLister:       listers.GetHTTPProxyLister(),
+			serviceLister:       listers.GetK8sServiceLister(),
+			delegationLister:    listers.GetTLSCertificateDelegationLister(),
+			kubeClient:          fakekubeclient.Get(ctx),
+			networkPolicyLister: listers.GetNetworkPolicyLister(),
+			namespaceLister:     listers.GetNamespaceLister(),
+			tracker:             &NullTracker{},
+			statusManager: &fakeStatusManager{
+				FakeIsReady: func(context.Context, *v1alpha1.Ingress) (bool, error) {
+					t.Error("IsReady called with status updates disabled")
+					return false, nil
+				},
+			},
+			skipStatus: true,
+		}
+		return ingressreconciler.NewReconciler(ctx, logging.FromContext(ctx), fakeingressclient.Get(ctx),
+			listers.GetIngressLister(), controller.GetEventRecorder(ctx), r, ContourIngressClassName,
+			controller.Options{
+				ConfigStore: &testConfigStore{
+					config: defaultConfig,
+				},
+				SkipStatusUpdates: true,
+			})
+	}))
+}
+
 func TestReconcileProbeError(t *testing.T) {
 	theError := errors.New("this is the error")
 
@@ -543,6 +1150,7 @@ func TestReconcileProbeError(t *testing.T) {
 				// These are the things we expect to change in status.
 				i.Status.InitializeConditions()
 				i.Status.MarkNetworkConfigured()
+				markProgrammed(i)
 			}),
 		}},
 		WantEvents: []string{
@@ -552,12 +1160,16 @@ func TestReconcileProbeError(t *testing.T) {
 
 	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
 		r := &Reconciler{
-			ingressClient: fakeingressclient.Get(ctx),
-			contourClient: fakecontourclient.Get(ctx),
-			ingressLister: listers.GetIngressLister(),
-			contourLister: listers.GetHTTPProxyLister(),
-			serviceLister: listers.GetK8sServiceLister(),
-			tracker:       &NullTracker{},
+			ingressClient:       fakeingressclient.Get(ctx),
+			contourClient:       fakecontourclient.Get(ctx),
+			ingressLister:       listers.GetIngressLister(),
+			contourLister:       listers.GetHTTPProxyLister(),
+			serviceLister:       listers.GetK8sServiceLister(),
+			delegationLister:    listers.GetTLSCertificateDelegationLister(),
+			kubeClient:          fakekubeclient.Get(ctx),
+			networkPolicyLister: listers.GetNetworkPolicyLister(),
+			namespaceLister:     listers.GetNamespaceLister(),
+			tracker:             &NullTracker{},
 			statusManager: &fakeStatusManager{
 				FakeIsReady: func(context.Context, *v1alpha1.Ingress) (bool, error) {
 					return false, theError
@@ -580,6 +1192,38 @@ func TestReconcileProbeError(t *testing.T) {
 	}))
 }
 
+func TestServiceProtocol(t *testing.T) {
+	appProtocol := func(p string) *string { return &p }
+	tests := []struct {
+		name string
+		port corev1.ServicePort
+		want string
+	}{{
+		name: "http",
+		port: corev1.ServicePort{Name: "http"},
+	}, {
+		name: "h2c port name",
+		port: corev1.ServicePort{Name: networking.ServicePortNameH2C},
+		want: "h2c",
+	}, {
+		name: "h2c appProtocol",
+		port: corev1.ServicePort{Name: "grpc", AppProtocol: appProtocol("kubernetes.io/h2c")},
+		want: "h2c",
+	}, {
+		name: "other appProtocol",
+		port: corev1.ServicePort{Name: "web", AppProtocol: appProtocol("http")},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			svc := &corev1.Service{Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{test.port}}}
+			if got := serviceProtocol(svc); got != test.want {
+				t.Errorf("serviceProtocol() = %q, wanted %q", got, test.want)
+			}
+		})
+	}
+}
+
 var (
 	publicNS      = "public-contour"
 	publicName    = "envoy-stuff"
@@ -702,9 +1346,27 @@ func mustMakeProbe(t *testing.T, i *v1alpha1.Ingress, opts ...IngressOption) run
 	return chIng
 }
 
+// markWaitingForEndpoints sets the sub-conditions of an Ingress whose
+// endpoint probe isn't ready.
+func markWaitingForEndpoints(i *v1alpha1.Ingress) {
+	markSubCondition(i, EndpointsProbedCondition, corev1.ConditionUnknown,
+		"EndpointsNotReady", "Waiting for Envoys to receive Endpoints data.")
+	markSubCondition(i, ProxiesProgrammedCondition, corev1.ConditionUnknown,
+		"EndpointsNotReady", "Waiting for Envoys to receive Endpoints data before programming.")
+}
+
+// markProgrammed sets the sub-conditions of an Ingress whose HTTPProxies we
+// programmed.
+func markProgrammed(i *v1alpha1.Ingress) {
+	markSubCondition(i, EndpointsProbedCondition, corev1.ConditionTrue, "", "")
+	markSubCondition(i, ProxiesProgrammedCondition, corev1.ConditionTrue, "", "")
+	markSubCondition(i, CertificatesReadyCondition, corev1.ConditionTrue, "", "")
+}
+
 func makeItReady(i *v1alpha1.Ingress) {
 	i.Status.InitializeConditions()
 	i.Status.MarkNetworkConfigured()
+	markProgrammed(i)
 	i.Status.MarkLoadBalancerReady(
 		[]v1alpha1.LoadBalancerIngressStatus{{
 			DomainInternal: publicSvc,
@@ -0,0 +1,121 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	contourlisters "knative.dev/net-contour/pkg/client/listers/projectcontour/v1"
+	"knative.dev/net-contour/pkg/ownership"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/pkg/reconciler"
+)
+
+// contourGate tells whether Contour processes HTTPProxies since we started
+// or were last promoted, before which Ingresses must not become ready: the
+// Envoys may answer our probes with the configuration of a Contour that has
+// since stopped updating them.  Contour processes HTTPProxies once it
+// validated one of ours since then, or when it caught up with all of them,
+// as it then has nothing left to show us.
+type contourGate struct {
+	contourLister contourlisters.HTTPProxyLister
+	// enqueueAfter rechecks the Ingresses the gate holds back.
+	enqueueAfter func(interface{}, time.Duration)
+
+	mu sync.Mutex
+	// since is when we started or were last promoted.
+	since time.Time
+	// open is whether Contour processed HTTPProxies since.
+	open bool
+}
+
+func newContourGate(contourLister contourlisters.HTTPProxyLister, enqueueAfter func(interface{}, time.Duration)) *contourGate {
+	return &contourGate{
+		contourLister: contourLister,
+		enqueueAfter:  enqueueAfter,
+		since:         time.Now(),
+	}
+}
+
+// reset closes the gate until Contour processes HTTPProxies again.
+func (g *contourGate) reset() {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.since = time.Now()
+	g.open = false
+}
+
+// processing returns whether Contour processed HTTPProxies since we started
+// or were last promoted, and otherwise rechecks the Ingress later.  It stays
+// open from then on.
+func (g *contourGate) processing(ing *v1alpha1.Ingress) (bool, error) {
+	if g == nil {
+		return true, nil
+	}
+	open, err := g.check()
+	if err == nil && !open {
+		g.enqueueAfter(ing, quorumRecheckPeriod)
+	}
+	return open, err
+}
+
+func (g *contourGate) check() (bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.open {
+		return true, nil
+	}
+
+	proxies, err := g.contourLister.List(ownership.Children())
+	if err != nil {
+		return false, err
+	}
+	// Condition times only have a precision of seconds.
+	since := g.since.Truncate(time.Second)
+	pending := false
+	for _, proxy := range proxies {
+		valid := findValidCondition(proxy)
+		if valid != nil && !valid.LastTransitionTime.Time.Before(since) {
+			g.open = true
+			return true, nil
+		}
+		if valid == nil || valid.ObservedGeneration != proxy.Generation {
+			pending = true
+		}
+	}
+	g.open = !pending
+	return g.open, nil
+}
+
+// gatedPromotion closes the gate whenever we are promoted, as another
+// replica led while we didn't watch Contour.
+type gatedPromotion struct {
+	leaderAwareReconciler
+
+	gate *contourGate
+}
+
+// Promote implements reconciler.LeaderAware
+func (p *gatedPromotion) Promote(b reconciler.Bucket, enq func(reconciler.Bucket, types.NamespacedName)) error {
+	p.gate.reset()
+	return p.leaderAwareReconciler.Promote(b, enq)
+}
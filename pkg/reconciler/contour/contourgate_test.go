@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"testing"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/net-contour/pkg/ownership"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/pkg/reconciler"
+
+	. "knative.dev/net-contour/pkg/reconciler/testing"
+)
+
+func TestContourGate(t *testing.T) {
+	start := time.Now()
+	proxy := func(name string, observed int64, validated time.Time) *contourv1.HTTPProxy {
+		p := &contourv1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:  "ns",
+				Name:       name,
+				Generation: 2,
+				Labels:     ownership.Labels("name"),
+			},
+		}
+		if !validated.IsZero() {
+			p.Status.Conditions = []contourv1.DetailedCondition{{
+				Condition: contourv1.Condition{
+					Type:               contourv1.ValidConditionType,
+					Status:             metav1.ConditionTrue,
+					ObservedGeneration: observed,
+					LastTransitionTime: metav1.NewTime(validated),
+				},
+			}}
+		}
+		return p
+	}
+	earlier := start.Add(-time.Hour)
+	later := start.Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		proxies []runtime.Object
+		want    bool
+	}{{
+		name: "no proxies",
+		want: true,
+	}, {
+		name:    "caught up",
+		proxies: []runtime.Object{proxy("a", 2, earlier), proxy("b", 2, earlier)},
+		want:    true,
+	}, {
+		name:    "never validated",
+		proxies: []runtime.Object{proxy("a", 2, earlier), proxy("b", 0, time.Time{})},
+	}, {
+		name:    "outdated",
+		proxies: []runtime.Object{proxy("a", 2, earlier), proxy("b", 1, earlier)},
+	}, {
+		name:    "validated since",
+		proxies: []runtime.Object{proxy("a", 2, later), proxy("b", 0, time.Time{})},
+		want:    true,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var enqueued []string
+			listers := NewListers(test.proxies)
+			g := newContourGate(listers.GetHTTPProxyLister(), func(obj interface{}, _ time.Duration) {
+				enqueued = append(enqueued, obj.(*v1alpha1.Ingress).Name)
+			})
+			g.since = start
+
+			got, err := g.processing(ing("name", "ns"))
+			if err != nil {
+				t.Fatal("processing() =", err)
+			}
+			if got != test.want {
+				t.Errorf("processing() = %v, wanted %v", got, test.want)
+			}
+			if wantEnqueued := !test.want; wantEnqueued != (len(enqueued) != 0) {
+				t.Errorf("enqueued = %v, wanted a recheck: %v", enqueued, wantEnqueued)
+			}
+		})
+	}
+}
+
+func TestContourGateLatches(t *testing.T) {
+	pending := &contourv1.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "a", Generation: 1, Labels: ownership.Labels("name")},
+	}
+	empty, withPending := NewListers(nil), NewListers([]runtime.Object{pending})
+	g := newContourGate(empty.GetHTTPProxyLister(), func(interface{}, time.Duration) {})
+	if open, err := g.processing(ing("name", "ns")); err != nil || !open {
+		t.Fatalf("processing() = %v, %v, wanted true", open, err)
+	}
+
+	// Once open, pending proxies don't close the gate.
+	g.contourLister = withPending.GetHTTPProxyLister()
+	if open, err := g.processing(ing("name", "ns")); err != nil || !open {
+		t.Fatalf("processing() = %v, %v, wanted true", open, err)
+	}
+
+	// Promotions do, as another replica led meanwhile.
+	p := &gatedPromotion{leaderAwareReconciler: &fakeIngressReconciler{}, gate: g}
+	if err := p.Promote(reconciler.UniversalBucket(), func(reconciler.Bucket, types.NamespacedName) {}); err != nil {
+		t.Fatal("Promote() =", err)
+	}
+	if open, err := g.processing(ing("name", "ns")); err != nil || open {
+		t.Fatalf("processing() = %v, %v after a promotion, wanted false", open, err)
+	}
+}
+
+func TestNilContourGate(t *testing.T) {
+	var g *contourGate
+	g.reset()
+	if open, err := g.processing(ing("name", "ns")); err != nil || !open {
+		t.Errorf("processing() = %v, %v, wanted true", open, err)
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package contourinformer provides injected informers of the Contour
+// resources we program that tolerate their CRDs being absent.  The generated
+// informers fail to list them until the CRDs are installed, so their caches
+// never sync and the controller never starts; ours list nothing instead, and
+// keep retrying their watches until the CRDs appear.
+package contourinformer
+
+import (
+	"context"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	informers "knative.dev/net-contour/pkg/client/informers/externalversions/projectcontour/v1"
+	contourclient "knative.dev/net-contour/pkg/client/injection/client"
+	listers "knative.dev/net-contour/pkg/client/listers/projectcontour/v1"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/injection"
+	"knative.dev/pkg/logging"
+)
+
+func init() {
+	// The informers only need a Contour client, which the fake injection
+	// provides as well.
+	injection.Default.RegisterInformer(withProxyInformer)
+	injection.Fake.RegisterInformer(withProxyInformer)
+	injection.Default.RegisterInformer(withDelegationInformer)
+	injection.Fake.RegisterInformer(withDelegationInformer)
+}
+
+// ProxyKey and DelegationKey are used for associating the Informers inside
+// the context.Context.
+type (
+	ProxyKey      struct{}
+	DelegationKey struct{}
+)
+
+type proxyInformer struct {
+	informer cache.SharedIndexInformer
+}
+
+var _ informers.HTTPProxyInformer = (*proxyInformer)(nil)
+
+func (i *proxyInformer) Informer() cache.SharedIndexInformer {
+	return i.informer
+}
+
+func (i *proxyInformer) Lister() listers.HTTPProxyLister {
+	return listers.NewHTTPProxyLister(i.informer.GetIndexer())
+}
+
+type delegationInformer struct {
+	informer cache.SharedIndexInformer
+}
+
+var _ informers.TLSCertificateDelegationInformer = (*delegationInformer)(nil)
+
+func (i *delegationInformer) Informer() cache.SharedIndexInformer {
+	return i.informer
+}
+
+func (i *delegationInformer) Lister() listers.TLSCertificateDelegationLister {
+	return listers.NewTLSCertificateDelegationLister(i.informer.GetIndexer())
+}
+
+func withProxyInformer(ctx context.Context) (context.Context, controller.Informer) {
+	client := contourclient.Get(ctx)
+	inf := &proxyInformer{informer: cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+				list, err := client.ProjectcontourV1().HTTPProxies(metav1.NamespaceAll).List(ctx, opts)
+				return tolerateMissing(list, &contourv1.HTTPProxyList{}, err)
+			},
+			WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+				return client.ProjectcontourV1().HTTPProxies(metav1.NamespaceAll).Watch(ctx, opts)
+			},
+		},
+		&contourv1.HTTPProxy{},
+		controller.GetResyncPeriod(ctx),
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)}
+	return context.WithValue(ctx, ProxyKey{}, inf), inf.Informer()
+}
+
+func withDelegationInformer(ctx context.Context) (context.Context, controller.Informer) {
+	client := contourclient.Get(ctx)
+	inf := &delegationInformer{informer: cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+				list, err := client.ProjectcontourV1().TLSCertificateDelegations(metav1.NamespaceAll).List(ctx, opts)
+				return tolerateMissing(list, &contourv1.TLSCertificateDelegationList{}, err)
+			},
+			WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+				return client.ProjectcontourV1().TLSCertificateDelegations(metav1.NamespaceAll).Watch(ctx, opts)
+			},
+		},
+		&contourv1.TLSCertificateDelegation{},
+		controller.GetResyncPeriod(ctx),
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)}
+	return context.WithValue(ctx, DelegationKey{}, inf), inf.Informer()
+}
+
+// tolerateMissing returns the empty list instead of the error when the
+// resource isn't served.  The watch that follows fails the same way, so the
+// informer lists again, with backoff, until the CRD is installed.
+func tolerateMissing(list, empty runtime.Object, err error) (runtime.Object, error) {
+	if apierrs.IsNotFound(err) {
+		return empty, nil
+	}
+	return list, err
+}
+
+// GetProxyInformer extracts the HTTPProxy informer from the context.
+func GetProxyInformer(ctx context.Context) informers.HTTPProxyInformer {
+	untyped := ctx.Value(ProxyKey{})
+	if untyped == nil {
+		logging.FromContext(ctx).Panic(
+			"Unable to fetch the HTTPProxy informer from context.")
+	}
+	return untyped.(informers.HTTPProxyInformer)
+}
+
+// GetDelegationInformer extracts the TLSCertificateDelegation informer from
+// the context.
+func GetDelegationInformer(ctx context.Context) informers.TLSCertificateDelegationInformer {
+	untyped := ctx.Value(DelegationKey{})
+	if untyped == nil {
+		logging.FromContext(ctx).Panic(
+			"Unable to fetch the TLSCertificateDelegation informer from context.")
+	}
+	return untyped.(informers.TLSCertificateDelegationInformer)
+}
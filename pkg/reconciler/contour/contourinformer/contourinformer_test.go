@@ -0,0 +1,59 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contourinformer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgotesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	fakecontourclient "knative.dev/net-contour/pkg/client/injection/client/fake"
+
+	. "knative.dev/pkg/reconciler/testing"
+)
+
+func TestInformersSyncWithoutCRDs(t *testing.T) {
+	ctx, cancel, informers := SetupFakeContextWithCancel(t)
+	defer cancel()
+	client := fakecontourclient.Get(ctx)
+	for _, resource := range []string{"httpproxies", "tlscertificatedelegations"} {
+		resource := resource
+		client.PrependReactor("list", resource, func(clientgotesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrs.NewNotFound(contourv1.GroupVersion.WithResource(resource).GroupResource(), "")
+		})
+	}
+
+	for _, inf := range informers {
+		go inf.Run(ctx.Done())
+	}
+	waitCtx, waitCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer waitCancel()
+	if !cache.WaitForCacheSync(waitCtx.Done(),
+		GetProxyInformer(ctx).Informer().HasSynced,
+		GetDelegationInformer(ctx).Informer().HasSynced) {
+		t.Fatal("The informers didn't sync while their CRDs are missing")
+	}
+	if proxies, err := GetProxyInformer(ctx).Lister().List(labels.Everything()); err != nil || len(proxies) != 0 {
+		t.Errorf("List() = %v, %v, wanted no HTTPProxies", proxies, err)
+	}
+}
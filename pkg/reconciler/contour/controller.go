@@ -18,28 +18,36 @@ package contour
 
 import (
 	"context"
+	"time"
+
+	"go.uber.org/zap"
 
 	contourclient "knative.dev/net-contour/pkg/client/injection/client"
-	proxyinformer "knative.dev/net-contour/pkg/client/injection/informers/projectcontour/v1/httpproxy"
 	ingressclient "knative.dev/networking/pkg/client/injection/client"
 	ingressinformer "knative.dev/networking/pkg/client/injection/informers/networking/v1alpha1/ingress"
 	ingressreconciler "knative.dev/networking/pkg/client/injection/reconciler/networking/v1alpha1/ingress"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
 	endpointsinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/endpoints"
-	podinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/pod"
+	namespaceinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/namespace"
 	serviceinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/service"
+	networkpolicyinformer "knative.dev/pkg/client/injection/kube/informers/networking/v1/networkpolicy"
 
 	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/net-contour/pkg/reconciler/contour/contourinformer"
+	"knative.dev/net-contour/pkg/reconciler/contour/podinformer"
 	network "knative.dev/networking/pkg"
 	"knative.dev/networking/pkg/apis/networking"
 	"knative.dev/networking/pkg/apis/networking/v1alpha1"
-	"knative.dev/networking/pkg/status"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
+	"knative.dev/pkg/kmeta"
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/reconciler"
 	"knative.dev/pkg/tracker"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/cache"
 )
 
@@ -47,14 +55,29 @@ import (
 func NewController(
 	ctx context.Context,
 	cmw configmap.Watcher,
+) *controller.Impl {
+	return NewControllerWithOptions(ctx, cmw, Options{})
+}
+
+// NewControllerWithOptions returns a new Ingress controller for Project
+// Contour, customized by the options.
+func NewControllerWithOptions(
+	ctx context.Context,
+	cmw configmap.Watcher,
+	opts Options,
 ) *controller.Impl {
 	logger := logging.FromContext(ctx)
 
 	endpointsInformer := endpointsinformer.Get(ctx)
 	serviceInformer := serviceinformer.Get(ctx)
 	ingressInformer := ingressinformer.Get(ctx)
-	proxyInformer := proxyinformer.Get(ctx)
+	proxyInformer := contourinformer.GetProxyInformer(ctx)
 	podInformer := podinformer.Get(ctx)
+	// These back features config-contour toggles at runtime, so they watch
+	// the whole cluster even while their features are disabled.
+	delegationInformer := contourinformer.GetDelegationInformer(ctx)
+	networkPolicyInformer := networkpolicyinformer.Get(ctx)
+	namespaceInformer := namespaceinformer.Get(ctx)
 
 	c := &Reconciler{
 		ingressClient: ingressclient.Get(ctx),
@@ -62,26 +85,80 @@ func NewController(
 		contourLister: proxyInformer.Lister(),
 		ingressLister: ingressInformer.Lister(),
 		serviceLister: serviceInformer.Lister(),
+		podLister:     podInformer.Lister(),
+		apiChecker:    &apiChecker{discovery: kubeclient.Get(ctx).Discovery()},
+		programming:   newProgrammingTracker(),
+		written:       newWrittenProxies(),
+
+		delegationLister: delegationInformer.Lister(),
+
+		kubeClient:          kubeclient.Get(ctx),
+		networkPolicyLister: networkPolicyInformer.Lister(),
+
+		namespaceLister: namespaceInformer.Lister(),
+	}
+	if opts.IngressClient != nil {
+		c.ingressClient = opts.IngressClient
+	}
+	if opts.ContourClient != nil {
+		c.contourClient = opts.ContourClient
 	}
-	myFilterFunc := reconciler.AnnotationFilterFunc(networking.IngressClassAnnotationKey, ContourIngressClassName, false)
-	impl := ingressreconciler.NewImpl(ctx, c, ContourIngressClassName,
+	var configStore *config.Store
+	classFilterFunc := reconciler.AnnotationFilterFunc(networking.IngressClassAnnotationKey, opts.className(), false)
+	myFilterFunc := func(obj interface{}) bool {
+		return (classFilterFunc(obj) ||
+			(configStore.LoadContour().ClaimUnsetIngressClass && unsetIngressClass(obj)) ||
+			configStore.LoadContour().ShadowMode) && opts.filter(obj)
+	}
+	// The reconciler and prober follow their own log levels, so that they
+	// can be debugged at runtime without the noise of the rest.
+	reconcilerCtx := logging.WithLogger(ctx, componentLogger(ctx, cmw, reconcilerComponent))
+	proberLogger := componentLogger(ctx, cmw, proberComponent)
+
+	impl := ingressreconciler.NewImpl(reconcilerCtx, c, opts.className(),
 		func(impl *controller.Impl) controller.Options {
 			configsToResync := []interface{}{
 				&config.Contour{},
 				&network.Config{},
 			}
 
-			resyncIngressesOnConfigChange := configmap.TypeFilter(configsToResync...)(func(string, interface{}) {
+			networkChanges := &networkConfigChanges{}
+			resyncIngressesOnConfigChange := configmap.TypeFilter(configsToResync...)(func(_ string, value interface{}) {
+				if cfg, ok := value.(*network.Config); ok && !networkChanges.relevant(cfg) {
+					return
+				}
 				impl.FilteredGlobalResync(myFilterFunc, ingressInformer.Informer())
 			})
-			configStore := config.NewStore(logger.Named("config-store"), resyncIngressesOnConfigChange)
+			configStore = config.NewStore(logger.Named("config-store"), resyncIngressesOnConfigChange)
 			configStore.WatchConfigs(cmw)
 			return controller.Options{
 				ConfigStore:       configStore,
 				PromoteFilterFunc: myFilterFunc,
+				SkipStatusUpdates: opts.SkipStatusUpdates,
 			}
 		})
 
+	c.contourGate = newContourGate(c.contourLister, impl.EnqueueAfter)
+	impl.Reconciler = &shadowReconciler{
+		leaderAwareReconciler: &classClaimer{
+			leaderAwareReconciler: &gatedPromotion{
+				leaderAwareReconciler: impl.Reconciler.(leaderAwareReconciler),
+				gate:                  c.contourGate,
+			},
+			ingressClient: c.ingressClient,
+			ingressLister: c.ingressLister,
+			className:     opts.className(),
+			contourConfig: func() *config.Contour { return configStore.LoadContour() },
+		},
+		ingressLister: c.ingressLister,
+		serviceLister: c.serviceLister,
+		toContext:     func(ctx context.Context) context.Context { return configStore.ToContext(ctx) },
+	}
+	impl.Reconciler = &stoppingReconciler{
+		leaderAwareReconciler: impl.Reconciler.(leaderAwareReconciler),
+		stopCh:                ctx.Done(),
+	}
+
 	ingressInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
 		FilterFunc: myFilterFunc,
 		Handler:    controller.HandleAll(impl.Enqueue),
@@ -98,27 +175,150 @@ func NewController(
 		Handler:    controller.HandleAll(impl.EnqueueControllerOf),
 	})
 
-	statusProber := status.NewProber(
-		logger.Named("status-manager"),
-		&lister{
-			ServiceLister:   serviceInformer.Lister(),
-			EndpointsLister: endpointsInformer.Lister(),
+	networkPolicyInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: controller.FilterController(&v1alpha1.Ingress{}),
+		Handler:    controller.HandleAll(impl.EnqueueControllerOf),
+	})
+
+	// Reconcile the Ingresses of a namespace whose labels change, as they
+	// may pin a different visibility.
+	namespaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldNs, newNs := oldObj.(*corev1.Namespace), newObj.(*corev1.Namespace)
+			if len(configStore.LoadContour().NamespaceVisibility) == 0 ||
+				labels.Equals(oldNs.Labels, newNs.Labels) {
+				return
+			}
+			ings, err := c.ingressLister.Ingresses(newNs.Name).List(labels.Everything())
+			if err != nil {
+				logger.Errorw("Error listing the Ingresses of namespace "+newNs.Name, zap.Error(err))
+				return
+			}
+			for _, ing := range ings {
+				if myFilterFunc(ing) {
+					impl.Enqueue(ing)
+				}
+			}
 		},
+	})
+
+	probeTargetLister := &lister{
+		ServiceLister:   serviceInformer.Lister(),
+		EndpointsLister: endpointsInformer.Lister(),
+	}
+	results := newProbeResults()
+	// status.Prober builds its requests and verifies their responses itself,
+	// so the probe-user-agent, probe-headers and probe-expected-status-codes
+	// of config-contour only apply to the probes we send ourselves.
+	statusProber := opts.newProber(
+		logging.WithLogger(ctx, proberLogger.Named("status-manager")),
+		probeTargetLister,
 		func(ia *v1alpha1.Ingress) { impl.Enqueue(ia) })
-	c.statusManager = statusProber
-	statusProber.Start(ctx.Done())
+	quorum := &quorumManager{
+		Manager:      statusProber,
+		targetLister: probeTargetLister,
+		enqueueAfter: impl.EnqueueAfter,
+		tcpTargets:   probeTargetLister.ListTCPProbeTargets,
+		transports:   newProbeTransports(&caBundles{kubeClient: c.kubeClient}),
+		podNames:     probeTargetLister.envoyPodNames,
+		results:      results,
+		stopCh:       ctx.Done(),
+	}
+	c.statusManager = quorum
+	c.skipStatus = opts.SkipStatusUpdates
+	c.reprober = newEnvoyReprober(statusProber.CancelIngressProbing)
+	c.endpointProbes = newEndpointProbeCache(probeTargetLister, endpointsInformer.Lister())
 
 	ingressInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		// Cancel probing when an Ingress is deleted
-		DeleteFunc: statusProber.CancelIngressProbing,
+		// Cancel probing and tracking when an Ingress is deleted
+		DeleteFunc: func(obj interface{}) {
+			statusProber.CancelIngressProbing(obj)
+			c.tracker.OnDeletedObserver(obj)
+			if acc, err := kmeta.DeletionHandlingAccessor(obj); err == nil {
+				key := types.NamespacedName{Namespace: acc.GetNamespace(), Name: acc.GetName()}
+				c.programming.forget(key)
+				c.written.forget(key)
+				c.reprober.forget(key)
+				quorum.forget(key)
+			}
+		},
 	})
 	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		// Cancel probing when a Pod is deleted
 		DeleteFunc: statusProber.CancelPodProbing,
 	})
+	endpointsInformer.Informer().AddEventHandler(&endpointsReadyHandler{
+		ingressLister: c.ingressLister,
+		filter:        myFilterFunc,
+		cancel:        statusProber.CancelIngressProbing,
+		enqueue:       impl.Enqueue,
+	})
+
+	// Resync when the discovered Envoy Services of a visibility change,
+	// so that probe targets and status addresses track them.
+	serviceInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: func(obj interface{}) bool {
+			return isDiscoveredService(configStore.LoadContour(), obj)
+		},
+		Handler: controller.HandleAll(func(interface{}) {
+			impl.FilteredGlobalResync(myFilterFunc, ingressInformer.Informer())
+		}),
+	})
+
+	// Hold off reconciling, and fail our readiness probe, while the informers
+	// we depend on are unable to watch the API server.
+	c.cacheHealth = newCacheHealth(ctx,
+		func() time.Duration { return configStore.LoadContour().MaxInformerStaleness },
+		map[string]cache.SharedIndexInformer{
+			"ingress":   ingressInformer.Informer(),
+			"httpproxy": proxyInformer.Informer(),
+			"service":   serviceInformer.Informer(),
+			"endpoints": endpointsInformer.Informer(),
+			"pod":       podInformer.Informer(),
+		})
+	go c.cacheHealth.report(ctx)
+	invalid := &invalidProxies{
+		contourLister: c.contourLister,
+		maxInvalid:    func() int { return configStore.LoadContour().MaxInvalidProxies },
+		next:          c.cacheHealth,
+	}
+	go invalid.report(ctx)
+	stale := &staleProbes{
+		ingressLister: c.ingressLister,
+		contourLister: c.contourLister,
+		maxAge:        func() time.Duration { return configStore.LoadContour().StaleProbeAge },
+	}
+	go stale.report(ctx)
+	progress := &rolloutProgress{
+		ingressLister: c.ingressLister,
+		filter:        myFilterFunc,
+	}
+	go progress.report(ctx)
+	go serveHealth(ctx, invalid)
+
+	// Periodically reconcile every Ingress to repair HTTPProxies that drifted
+	// without us seeing an event.
+	go runDriftRepair(ctx,
+		func() time.Duration { return configStore.LoadContour().DriftRepairPeriod },
+		func() { impl.FilteredGlobalResync(myFilterFunc, ingressInformer.Informer()) })
 
 	// Set up our tracker to facilitate tracking cross-references to objects we don't own.
-	c.tracker = tracker.New(impl.EnqueueKey, controller.GetTrackerLease(ctx))
+	lease := trackerLease(ctx)
+	counting := newCountingTracker(tracker.New(impl.EnqueueKey, lease), lease)
+	go counting.report(ctx)
+	c.tracker = counting
+	if addr := debugAddress(ctx); addr != "" {
+		explainer := &explainer{
+			ingressLister: c.ingressLister,
+			contourLister: c.contourLister,
+			targetLister:  probeTargetLister,
+			kubeClient:    c.kubeClient,
+			toContext:     func(ctx context.Context) context.Context { return configStore.ToContext(ctx) },
+			podNames:      probeTargetLister.envoyPodNames,
+			results:       results,
+		}
+		go serveDebug(ctx, addr, debugHandler(debugVars(impl, counting, results), explainer))
+	}
 	serviceInformer.Informer().AddEventHandler(controller.HandleAll(
 		// Call the tracker's OnChanged method, but we've seen the objects
 		// coming through this path missing TypeMeta, so ensure it is properly
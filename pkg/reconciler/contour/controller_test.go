@@ -17,19 +17,27 @@ limitations under the License.
 package contour
 
 import (
+	"context"
 	"testing"
+	"time"
 
-	_ "knative.dev/net-contour/pkg/client/injection/informers/projectcontour/v1/httpproxy/fake"
 	_ "knative.dev/networking/pkg/client/injection/informers/networking/v1alpha1/ingress/fake"
 	_ "knative.dev/pkg/client/injection/kube/informers/core/v1/endpoints/fake"
-	_ "knative.dev/pkg/client/injection/kube/informers/core/v1/pod/fake"
+	_ "knative.dev/pkg/client/injection/kube/informers/core/v1/namespace/fake"
 	_ "knative.dev/pkg/client/injection/kube/informers/core/v1/service/fake"
+	_ "knative.dev/pkg/client/injection/kube/informers/networking/v1/networkpolicy/fake"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakecontourclient "knative.dev/net-contour/pkg/client/injection/client/fake"
 	"knative.dev/net-contour/pkg/reconciler/contour/config"
 	network "knative.dev/networking/pkg"
+	"knative.dev/networking/pkg/apis/networking"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/networking/pkg/status"
+	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
 	"knative.dev/pkg/configmap"
+	cminformer "knative.dev/pkg/configmap/informer"
 	"knative.dev/pkg/system"
 
 	. "knative.dev/pkg/reconciler/testing"
@@ -48,9 +56,88 @@ func TestNew(t *testing.T) {
 			Namespace: system.Namespace(),
 			Name:      network.ConfigName,
 		},
+	}, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      config.FeaturesConfigName,
+		},
 	}))
 
 	if c == nil {
 		t.Fatal("Expected NewController to return a non-nil value")
 	}
 }
+
+func TestNewWithUnstartedWatcher(t *testing.T) {
+	ctx, cancel, _ := SetupFakeContextWithCancel(t)
+	defer cancel()
+
+	// Our background loops start before the watcher, and must not read the
+	// config it didn't load yet.
+	c := NewController(ctx, cminformer.NewInformedWatcher(fakekubeclient.Get(ctx), system.Namespace()))
+	if c == nil {
+		t.Fatal("Expected NewController to return a non-nil value")
+	}
+	time.Sleep(100 * time.Millisecond)
+}
+
+type fakeProber struct {
+	status.Manager
+}
+
+func (fakeProber) CancelIngressProbing(interface{}) {}
+func (fakeProber) CancelPodProbing(interface{})     {}
+
+func TestNewWithOptions(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+
+	var probers int
+	opts := Options{
+		ClassName:     "custom.ingress.networking.knative.dev",
+		ContourClient: fakecontourclient.Get(ctx),
+		Filter: func(obj interface{}) bool {
+			return obj.(*v1alpha1.Ingress).Namespace == "tenant"
+		},
+		NewProber: func(context.Context, status.ProbeTargetLister, func(*v1alpha1.Ingress)) Prober {
+			probers++
+			return fakeProber{}
+		},
+	}
+	c := NewControllerWithOptions(ctx, configmap.NewStaticWatcher(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      config.ContourConfigName,
+		},
+	}, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      network.ConfigName,
+		},
+	}, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      config.FeaturesConfigName,
+		},
+	}), opts)
+
+	if c == nil {
+		t.Fatal("Expected NewControllerWithOptions to return a non-nil value")
+	}
+	if probers != 1 {
+		t.Errorf("NewProber was called %d times, wanted once", probers)
+	}
+	if got := opts.className(); got != opts.ClassName {
+		t.Errorf("className() = %q, wanted %q", got, opts.ClassName)
+	}
+	if got := (Options{}).className(); got != ContourIngressClassName {
+		t.Errorf("className() = %q by default, wanted %q", got, ContourIngressClassName)
+	}
+
+	tenant := ing("name", "tenant", withAnnotation(map[string]string{networking.IngressClassAnnotationKey: opts.ClassName}))
+	if !opts.filter(tenant) || opts.filter(ing("name", "other")) {
+		t.Error("filter() doesn't follow the Filter")
+	}
+	if !(Options{}).filter(ing("name", "other")) {
+		t.Error("filter() = false without a Filter")
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"go.uber.org/zap"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+)
+
+type debugAddressKey struct{}
+
+// WithDebugAddress makes NewController serve profiles and internal gauges on
+// the given address, for diagnosing e.g. memory growth on large clusters.
+func WithDebugAddress(ctx context.Context, addr string) context.Context {
+	return context.WithValue(ctx, debugAddressKey{}, addr)
+}
+
+// debugAddress returns the address to serve debug information on, or empty
+// when it is disabled.
+func debugAddress(ctx context.Context) string {
+	addr, _ := ctx.Value(debugAddressKey{}).(string)
+	return addr
+}
+
+// debugVars returns the internal gauges of the reconciler.  They are not
+// published globally as NewController may run more than once in a process.
+func debugVars(impl *controller.Impl, tracker *countingTracker, results *probeResults) *expvar.Map {
+	vars := new(expvar.Map).Init()
+	vars.Set("workqueue_depth", expvar.Func(func() interface{} {
+		return impl.WorkQueue().Len()
+	}))
+	vars.Set("tracked_objects", expvar.Func(func() interface{} {
+		return tracker.counts(time.Now())
+	}))
+	vars.Set("envoy_probes", expvar.Func(func() interface{} {
+		return results.list()
+	}))
+	return vars
+}
+
+// debugHandler serves the pprof profiles, the runtime's expvars and our own,
+// and the explanations of the explainer when set.
+func debugHandler(vars *expvar.Map, explainer http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/contour", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(vars.String()))
+	})
+	if explainer != nil {
+		mux.Handle("/debug/explain", explainer)
+	}
+	return mux
+}
+
+// serveDebug serves the debug handler on the address until the context is
+// done.
+func serveDebug(ctx context.Context, addr string, handler http.Handler) {
+	server := &http.Server{Addr: addr, Handler: handler}
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+	logging.FromContext(ctx).Info("Serving debug information on ", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logging.FromContext(ctx).Errorw("Error serving debug information", zap.Error(err))
+	}
+}
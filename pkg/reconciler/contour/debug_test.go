@@ -0,0 +1,69 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/tracker"
+
+	. "knative.dev/pkg/reconciler/testing"
+)
+
+func TestDebugHandler(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+	impl := controller.NewContext(ctx, nil, controller.ControllerOptions{
+		WorkQueueName: "test",
+		Logger:        logging.FromContext(ctx),
+	})
+	defer impl.WorkQueue().ShutDown()
+	impl.EnqueueKey(types.NamespacedName{Namespace: "ns", Name: "name"})
+	counting := newCountingTracker(tracker.New(func(types.NamespacedName) {}, time.Hour), time.Hour)
+	if err := counting.TrackReference(tracker.Reference{APIVersion: "v1", Kind: "Service", Namespace: "ns", Name: "svc"}, ing("name", "ns")); err != nil {
+		t.Fatal("TrackReference() =", err)
+	}
+
+	handler := debugHandler(debugVars(impl, counting, newProbeResults()), nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/contour", nil))
+	var got struct {
+		WorkqueueDepth int            `json:"workqueue_depth"`
+		TrackedObjects map[string]int `json:"tracked_objects"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("Unmarshal(%s) = %v", rec.Body, err)
+	}
+	if got.WorkqueueDepth != 1 || got.TrackedObjects["Service"] != 1 {
+		t.Errorf("/debug/contour = %s, wanted a depth of 1 and 1 tracked Service", rec.Body)
+	}
+
+	for _, path := range []string{"/debug/pprof/", "/debug/vars"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s = %d, wanted %d", path, rec.Code, http.StatusOK)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"sort"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/net-contour/pkg/ownership"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/pkg/logging"
+)
+
+// DefaultTLSDelegationName is the name of the TLSCertificateDelegation we
+// maintain next to the default TLS secret.
+const DefaultTLSDelegationName = "knative-default-tls-secret"
+
+// usesDefaultTLSSecret returns whether any of the proxies terminates TLS
+// with the default TLS secret.
+func usesDefaultTLSSecret(ctx context.Context, proxies []*contourv1.HTTPProxy) bool {
+	s := config.FromContext(ctx).Contour.DefaultTLSSecret
+	if s == nil {
+		return false
+	}
+	for _, proxy := range proxies {
+		if vh := proxy.Spec.VirtualHost; vh != nil && vh.TLS != nil && vh.TLS.SecretName == s.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// delegateDefaultTLSSecret makes sure our TLSCertificateDelegation lets the
+// namespace reference the default TLS secret.  Namespaces are never removed,
+// as that would need to know about every Ingress using the secret.  When the
+// delegation namespaces are configured, it targets exactly those instead.
+func (r *Reconciler) delegateDefaultTLSSecret(ctx context.Context, namespace string) error {
+	cfg := config.FromContext(ctx).Contour
+	s := cfg.DefaultTLSSecret
+	fixed := cfg.DelegationNamespaces.Len() != 0
+	if s.Namespace == namespace && !fixed {
+		return nil
+	}
+
+	targets := []string{namespace}
+	if fixed {
+		targets = cfg.DelegationNamespaces.List()
+	}
+
+	existing, err := r.delegationLister.TLSCertificateDelegations(s.Namespace).Get(DefaultTLSDelegationName)
+	if apierrs.IsNotFound(err) {
+		_, err = r.contourClient.ProjectcontourV1().TLSCertificateDelegations(s.Namespace).Create(ctx,
+			&contourv1.TLSCertificateDelegation{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: s.Namespace,
+					Name:      DefaultTLSDelegationName,
+					// It is shared by every Ingress, so it has no parent.
+					Labels: map[string]string{
+						ownership.ManagedByKey: ownership.ManagedByValue,
+					},
+				},
+				Spec: contourv1.TLSCertificateDelegationSpec{
+					Delegations: []contourv1.CertificateDelegation{{
+						SecretName:       s.Name,
+						TargetNamespaces: targets,
+					}},
+				},
+			}, metav1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	desired := existing.DeepCopy()
+	delegation := -1
+	for i, d := range desired.Spec.Delegations {
+		if d.SecretName == s.Name {
+			delegation = i
+			break
+		}
+	}
+	if delegation < 0 {
+		desired.Spec.Delegations = append(desired.Spec.Delegations, contourv1.CertificateDelegation{SecretName: s.Name})
+		delegation = len(desired.Spec.Delegations) - 1
+	}
+	d := &desired.Spec.Delegations[delegation]
+	if fixed {
+		if sets.NewString(d.TargetNamespaces...).Equal(cfg.DelegationNamespaces) {
+			return nil
+		}
+		d.TargetNamespaces = targets
+		logging.FromContext(ctx).Infof("Delegating the default TLS secret %s to namespaces %v.", s, targets)
+	} else {
+		for _, ns := range d.TargetNamespaces {
+			if ns == namespace || ns == "*" {
+				return nil
+			}
+		}
+		d.TargetNamespaces = append(d.TargetNamespaces, namespace)
+		sort.Strings(d.TargetNamespaces)
+		logging.FromContext(ctx).Infof("Delegating the default TLS secret %s to namespace %s.", s, namespace)
+	}
+
+	_, err = r.contourClient.ProjectcontourV1().TLSCertificateDelegations(s.Namespace).Update(ctx, desired, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	fakecontourclient "knative.dev/net-contour/pkg/client/injection/client/fake"
+
+	. "knative.dev/net-contour/pkg/reconciler/testing"
+	. "knative.dev/pkg/reconciler/testing"
+)
+
+func TestDelegateDefaultTLSSecret(t *testing.T) {
+	delegation := func(namespaces ...string) *contourv1.TLSCertificateDelegation {
+		return &contourv1.TLSCertificateDelegation{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "admin",
+				Name:      DefaultTLSDelegationName,
+			},
+			Spec: contourv1.TLSCertificateDelegationSpec{
+				Delegations: []contourv1.CertificateDelegation{{
+					SecretName:       "wildcard",
+					TargetNamespaces: namespaces,
+				}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		namespace string
+		fixed     []string
+		existing  *contourv1.TLSCertificateDelegation
+		want      []string
+	}{{
+		name:      "creates the delegation",
+		namespace: "ns",
+		want:      []string{"ns"},
+	}, {
+		name:      "adds the namespace",
+		namespace: "ns",
+		existing:  delegation("other"),
+		want:      []string{"ns", "other"},
+	}, {
+		name:      "already delegated",
+		namespace: "ns",
+		existing:  delegation("ns"),
+		want:      []string{"ns"},
+	}, {
+		name:      "delegated to every namespace",
+		namespace: "ns",
+		existing:  delegation("*"),
+		want:      []string{"*"},
+	}, {
+		name:      "the secret's own namespace",
+		namespace: "admin",
+	}, {
+		name:      "creates the delegation to the configured namespaces",
+		namespace: "ns",
+		fixed:     []string{"contour-external", "contour-internal"},
+		want:      []string{"contour-external", "contour-internal"},
+	}, {
+		name:      "restricts the delegation to the configured namespaces",
+		namespace: "ns",
+		fixed:     []string{"contour-external"},
+		existing:  delegation("*"),
+		want:      []string{"contour-external"},
+	}, {
+		name:      "already delegated to the configured namespaces",
+		namespace: "admin",
+		fixed:     []string{"contour-external"},
+		existing:  delegation("contour-external"),
+		want:      []string{"contour-external"},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, _ := SetupFakeContext(t)
+			cfg := defaultConfig.DeepCopy()
+			cfg.Contour.DefaultTLSSecret = &types.NamespacedName{Namespace: "admin", Name: "wildcard"}
+			cfg.Contour.DelegationNamespaces = sets.NewString(test.fixed...)
+			ctx = (&testConfigStore{config: cfg}).ToContext(ctx)
+
+			var objs []runtime.Object
+			if test.existing != nil {
+				objs = append(objs, test.existing)
+				if _, err := fakecontourclient.Get(ctx).ProjectcontourV1().TLSCertificateDelegations("admin").Create(
+					ctx, test.existing, metav1.CreateOptions{}); err != nil {
+					t.Fatal("Create() =", err)
+				}
+			}
+			listers := NewListers(objs)
+			r := &Reconciler{
+				contourClient:    fakecontourclient.Get(ctx),
+				delegationLister: listers.GetTLSCertificateDelegationLister(),
+			}
+
+			if err := r.delegateDefaultTLSSecret(ctx, test.namespace); err != nil {
+				t.Fatal("delegateDefaultTLSSecret() =", err)
+			}
+
+			got, err := fakecontourclient.Get(ctx).ProjectcontourV1().TLSCertificateDelegations("admin").Get(
+				ctx, DefaultTLSDelegationName, metav1.GetOptions{})
+			if test.want == nil {
+				if err == nil {
+					t.Errorf("Got delegation %v, wanted none", got.Spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("Get() =", err)
+			}
+			if len(got.Spec.Delegations) != 1 || got.Spec.Delegations[0].SecretName != "wildcard" {
+				t.Fatalf("Delegations = %v, wanted one for the wildcard secret", got.Spec.Delegations)
+			}
+			if ns := got.Spec.Delegations[0].TargetNamespaces; !cmp.Equal(ns, test.want) {
+				t.Errorf("TargetNamespaces = %v, wanted %v", ns, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+const (
+	// httpProxyResource is the plural resource name of HTTPProxy.
+	httpProxyResource = "httpproxies"
+
+	// dependencyRecheckPeriod is how long we wait before checking again
+	// for a missing Contour API.
+	dependencyRecheckPeriod = 30 * time.Second
+)
+
+// apiChecker checks whether the Contour APIs we program are served by the
+// API server, so we can report a missing (or upgrading) CRD on the Ingresses
+// we own instead of failing every write.  Contour only serves HTTPProxy as
+// v1, which our resources are generated against, so there is no version to
+// choose between.
+type apiChecker struct {
+	discovery discovery.ServerResourcesInterface
+
+	// served caches a positive answer, as we don't want a discovery call per
+	// reconcile.  It is forgotten when writes fail as if it wasn't served.
+	mu     sync.RWMutex
+	served bool
+}
+
+// HTTPProxyServed returns whether the HTTPProxy resource is served.
+func (c *apiChecker) HTTPProxyServed() (bool, error) {
+	c.mu.RLock()
+	served := c.served
+	c.mu.RUnlock()
+	if served {
+		return true, nil
+	}
+
+	served, err := c.serves(v1.GroupVersion)
+	if err != nil {
+		return false, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.served = served
+	return served, nil
+}
+
+// forget drops the cached answer, so that the next call discovers it again.
+func (c *apiChecker) forget() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.served = false
+}
+
+func (c *apiChecker) serves(gv schema.GroupVersion) (bool, error) {
+	resources, err := c.discovery.ServerResourcesForGroupVersion(gv.String())
+	if apierrs.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to discover %s resources: %w", gv, err)
+	}
+	for _, r := range resources.APIResources {
+		if r.Name == httpProxyResource {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"testing"
+
+	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clientgotesting "k8s.io/client-go/testing"
+)
+
+func fakeDiscovery(resources ...string) *fakediscovery.FakeDiscovery {
+	list := &metav1.APIResourceList{GroupVersion: v1.GroupVersion.String()}
+	for _, r := range resources {
+		list.APIResources = append(list.APIResources, metav1.APIResource{Name: r})
+	}
+	return &fakediscovery.FakeDiscovery{
+		Fake: &clientgotesting.Fake{Resources: []*metav1.APIResourceList{list}},
+	}
+}
+
+func TestAPICheckerHTTPProxyServed(t *testing.T) {
+	missing := &apiChecker{discovery: fakeDiscovery("tlscertificatedelegations")}
+	if served, err := missing.HTTPProxyServed(); err != nil {
+		t.Fatal("HTTPProxyServed() =", err)
+	} else if served {
+		t.Error("HTTPProxyServed() = true, wanted false")
+	}
+
+	fake := fakeDiscovery("tlscertificatedelegations", httpProxyResource)
+	present := &apiChecker{discovery: fake}
+	for i := 0; i < 3; i++ {
+		if served, err := present.HTTPProxyServed(); err != nil {
+			t.Fatal("HTTPProxyServed() =", err)
+		} else if !served {
+			t.Error("HTTPProxyServed() = false, wanted true")
+		}
+	}
+	// Positive answers are cached.
+	if got := len(fake.Actions()); got != 1 {
+		t.Errorf("Discovery calls = %d, wanted 1", got)
+	}
+
+	// Until they are forgotten.
+	present.forget()
+	if _, err := present.HTTPProxyServed(); err != nil {
+		t.Fatal("HTTPProxyServed() =", err)
+	}
+	if got := len(fake.Actions()); got != 2 {
+		t.Errorf("Discovery calls = %d, wanted 2", got)
+	}
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+// describeProxyChanges returns a short human-readable summary of how the
+// HTTPProxy changes from old to new, which we attach to the Event we emit
+// for the update.
+func describeProxyChanges(old, new *v1.HTTPProxy) string {
+	var changes []string
+
+	oldTLS := old.Spec.VirtualHost != nil && old.Spec.VirtualHost.TLS != nil
+	newTLS := new.Spec.VirtualHost != nil && new.Spec.VirtualHost.TLS != nil
+	switch {
+	case !oldTLS && newTLS:
+		changes = append(changes, "TLS enabled")
+	case oldTLS && !newTLS:
+		changes = append(changes, "TLS disabled")
+	}
+
+	oldRoutes, newRoutes := routesByMatch(old), routesByMatch(new)
+	var added, removed, rebalanced []string
+	for match, route := range newRoutes {
+		oldRoute, ok := oldRoutes[match]
+		if !ok {
+			added = append(added, match)
+			continue
+		}
+		if before, after := describeWeights(oldRoute), describeWeights(route); before != after {
+			rebalanced = append(rebalanced, fmt.Sprintf("%s (%s -> %s)", match, before, after))
+		}
+	}
+	for match := range oldRoutes {
+		if _, ok := newRoutes[match]; !ok {
+			removed = append(removed, match)
+		}
+	}
+	for _, c := range []struct {
+		what   string
+		routes []string
+	}{
+		{"added routes", added},
+		{"removed routes", removed},
+		{"changed backends", rebalanced},
+	} {
+		if len(c.routes) != 0 {
+			sort.Strings(c.routes)
+			changes = append(changes, c.what+" "+strings.Join(c.routes, ", "))
+		}
+	}
+
+	if len(changes) == 0 {
+		return "no changes to routes or TLS"
+	}
+	return strings.Join(changes, "; ")
+}
+
+// routesByMatch indexes the routes of the HTTPProxy by the description of
+// their conditions.
+func routesByMatch(proxy *v1.HTTPProxy) map[string]v1.Route {
+	routes := make(map[string]v1.Route, len(proxy.Spec.Routes))
+	for _, route := range proxy.Spec.Routes {
+		routes[describeMatch(route.Conditions)] = route
+	}
+	return routes
+}
+
+func describeMatch(conditions []v1.MatchCondition) string {
+	prefix := "/"
+	var headers []string
+	for _, c := range conditions {
+		if c.Prefix != "" {
+			prefix = c.Prefix
+		}
+		if h := c.Header; h != nil {
+			switch {
+			case h.Present:
+				headers = append(headers, h.Name+" present")
+			case h.NotPresent:
+				headers = append(headers, h.Name+" not present")
+			case h.Contains != "":
+				headers = append(headers, fmt.Sprintf("%s contains %q", h.Name, h.Contains))
+			case h.NotContains != "":
+				headers = append(headers, fmt.Sprintf("%s not contains %q", h.Name, h.NotContains))
+			case h.NotExact != "":
+				headers = append(headers, fmt.Sprintf("%s!=%q", h.Name, h.NotExact))
+			default:
+				headers = append(headers, fmt.Sprintf("%s=%q", h.Name, h.Exact))
+			}
+		}
+	}
+	if len(headers) == 0 {
+		return prefix
+	}
+	sort.Strings(headers)
+	return prefix + " [" + strings.Join(headers, ", ") + "]"
+}
+
+func describeWeights(route v1.Route) string {
+	weights := make([]string, 0, len(route.Services))
+	for _, svc := range route.Services {
+		weights = append(weights, fmt.Sprintf("%s:%d=%d", svc.Name, svc.Port, svc.Weight))
+	}
+	return strings.Join(weights, ",")
+}
+
+// fieldChange is a field of an HTTPProxy that an update changes, as logged
+// by fieldChanges.
+type fieldChange struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// fieldChanges returns the fields of the JSON representation of the
+// HTTPProxy that change from old to new, sorted by their path.  Lists whose
+// length changes are reported as a whole.
+func fieldChanges(old, new *v1.HTTPProxy) ([]fieldChange, error) {
+	var before, after interface{}
+	for _, c := range []struct {
+		proxy *v1.HTTPProxy
+		into  *interface{}
+	}{{old, &before}, {new, &after}} {
+		raw, err := json.Marshal(c.proxy)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, c.into); err != nil {
+			return nil, err
+		}
+	}
+
+	var changes []fieldChange
+	diffFields("", before, after, &changes)
+	return changes, nil
+}
+
+func diffFields(path string, before, after interface{}, changes *[]fieldChange) {
+	switch b := before.(type) {
+	case map[string]interface{}:
+		if a, ok := after.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(a)+len(b))
+			for k := range b {
+				keys = append(keys, k)
+			}
+			for k := range a {
+				if _, ok := b[k]; !ok {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				diffFields(path+"."+k, b[k], a[k], changes)
+			}
+			return
+		}
+	case []interface{}:
+		if a, ok := after.([]interface{}); ok && len(a) == len(b) {
+			for i := range b {
+				diffFields(path+"["+strconv.Itoa(i)+"]", b[i], a[i], changes)
+			}
+			return
+		}
+	}
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, fieldChange{Path: path, Old: before, New: after})
+	}
+}
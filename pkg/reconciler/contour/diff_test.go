@@ -0,0 +1,117 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+func TestDescribeProxyChanges(t *testing.T) {
+	route := func(prefix string, weights map[string]int64, headers ...v1.HeaderMatchCondition) v1.Route {
+		r := v1.Route{Conditions: []v1.MatchCondition{{Prefix: prefix}}}
+		for i := range headers {
+			r.Conditions = append(r.Conditions, v1.MatchCondition{Header: &headers[i]})
+		}
+		for _, name := range []string{"blue", "green"} {
+			if w, ok := weights[name]; ok {
+				r.Services = append(r.Services, v1.Service{Name: name, Port: 80, Weight: w})
+			}
+		}
+		return r
+	}
+	proxy := func(tls bool, routes ...v1.Route) *v1.HTTPProxy {
+		p := &v1.HTTPProxy{Spec: v1.HTTPProxySpec{
+			VirtualHost: &v1.VirtualHost{Fqdn: "example.com"},
+			Routes:      routes,
+		}}
+		if tls {
+			p.Spec.VirtualHost.TLS = &v1.TLS{SecretName: "secret"}
+		}
+		return p
+	}
+
+	tests := []struct {
+		name     string
+		old, new *v1.HTTPProxy
+		want     string
+	}{{
+		name: "no changes",
+		old:  proxy(false, route("/", map[string]int64{"blue": 100})),
+		new:  proxy(false, route("/", map[string]int64{"blue": 100})),
+		want: "no changes to routes or TLS",
+	}, {
+		name: "weights changed",
+		old:  proxy(false, route("/", map[string]int64{"blue": 100})),
+		new:  proxy(false, route("/", map[string]int64{"blue": 90, "green": 10})),
+		want: "changed backends / (blue:80=100 -> blue:80=90,green:80=10)",
+	}, {
+		name: "TLS toggled and routes changed",
+		old: proxy(false,
+			route("/", map[string]int64{"blue": 100}),
+			route("/", map[string]int64{"green": 100}, v1.HeaderMatchCondition{Name: "Knative-Serving-Tag", Exact: "green"})),
+		new: proxy(true,
+			route("/", map[string]int64{"blue": 100}),
+			route("/api", map[string]int64{"blue": 100}, v1.HeaderMatchCondition{Name: "X-Canary", Present: true})),
+		want: `TLS enabled; added routes /api [X-Canary present]; removed routes / [Knative-Serving-Tag="green"]`,
+	}, {
+		name: "TLS disabled",
+		old:  proxy(true, route("/", map[string]int64{"blue": 100})),
+		new:  proxy(false, route("/", map[string]int64{"blue": 100})),
+		want: "TLS disabled",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := describeProxyChanges(test.old, test.new); got != test.want {
+				t.Errorf("describeProxyChanges() = %q, want: %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFieldChanges(t *testing.T) {
+	old := &v1.HTTPProxy{Spec: v1.HTTPProxySpec{
+		VirtualHost: &v1.VirtualHost{Fqdn: "example.com"},
+		Routes: []v1.Route{{
+			Services: []v1.Service{{Name: "blue", Port: 80, Weight: 100}},
+		}},
+	}}
+	new := old.DeepCopy()
+	new.Spec.VirtualHost.TLS = &v1.TLS{SecretName: "ns/secret"}
+	new.Spec.Routes[0].Services[0].Weight = 50
+	new.Spec.Routes[0].Services = append(new.Spec.Routes[0].Services, v1.Service{Name: "green", Port: 80, Weight: 50})
+
+	changes, err := fieldChanges(old, new)
+	if err != nil {
+		t.Fatal("fieldChanges() =", err)
+	}
+	var paths []string
+	for _, c := range changes {
+		paths = append(paths, c.Path)
+	}
+	want := []string{".spec.routes[0].services", ".spec.virtualhost.tls"}
+	if !cmp.Equal(paths, want) {
+		t.Errorf("fieldChanges() paths = %v, wanted %v", paths, want)
+	}
+
+	if changes, err := fieldChanges(old, old.DeepCopy()); err != nil || len(changes) != 0 {
+		t.Errorf("fieldChanges() = %v, %v, wanted no changes", changes, err)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+)
+
+// OwningGatewayLabel is placed by Contour's Gateway provisioner on the Envoy
+// Services it generates for a Gateway, and holds the name of that Gateway.
+const OwningGatewayLabel = "projectcontour.io/owning-gateway-name"
+
+// resolveVisibilityKeys returns the namespace/name keys of the Envoy Services
+// serving each visibility.  These are the Services configured statically in
+// config-contour, plus those discovered for the configured Contour Gateways.
+func resolveVisibilityKeys(ctx context.Context, serviceLister corev1listers.ServiceLister) (map[v1alpha1.IngressVisibility]sets.String, error) {
+	cfg := config.FromContext(ctx).Contour
+
+	keys := make(map[v1alpha1.IngressVisibility]sets.String, len(cfg.VisibilityKeys))
+	for vis, k := range cfg.VisibilityKeys {
+		keys[vis] = sets.NewString(k.UnsortedList()...)
+	}
+
+	for vis, gw := range cfg.VisibilityGateways {
+		svcs, err := serviceLister.Services(gw.Namespace).List(labels.SelectorFromSet(labels.Set{
+			OwningGatewayLabel: gw.Name,
+		}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Services for Gateway %s: %w", gw, err)
+		}
+		if len(svcs) == 0 {
+			return nil, fmt.Errorf("no Envoy Services found for Gateway %s", gw)
+		}
+		if _, ok := keys[vis]; !ok {
+			keys[vis] = make(sets.String, len(svcs))
+		}
+		for _, svc := range svcs {
+			keys[vis].Insert(svc.Namespace + "/" + svc.Name)
+		}
+	}
+
+	for vis, raw := range cfg.VisibilitySelectors {
+		selector, err := labels.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse selector %q: %w", raw, err)
+		}
+		svcs, err := serviceLister.List(selector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Services matching %q: %w", raw, err)
+		}
+		if len(svcs) == 0 {
+			return nil, fmt.Errorf("no Envoy Services found matching %q", raw)
+		}
+		if _, ok := keys[vis]; !ok {
+			keys[vis] = make(sets.String, len(svcs))
+		}
+		for _, svc := range svcs {
+			keys[vis].Insert(svc.Namespace + "/" + svc.Name)
+		}
+	}
+	return keys, nil
+}
+
+// isDiscoveredService returns whether the given object is a Service that
+// would be discovered as the Envoy Service of some visibility.
+func isDiscoveredService(cfg *config.Contour, obj interface{}) bool {
+	// Deletions we missed the final state of carry the Service in a tombstone.
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	svc, ok := obj.(*corev1.Service)
+	if !ok {
+		return false
+	}
+	for _, gw := range cfg.VisibilityGateways {
+		if svc.Namespace == gw.Namespace && svc.Labels[OwningGatewayLabel] == gw.Name {
+			return true
+		}
+	}
+	for _, raw := range cfg.VisibilitySelectors {
+		if selector, err := labels.Parse(raw); err == nil && selector.Matches(labels.Set(svc.Labels)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+)
+
+// driftRepairRecheckPeriod is how often we look at the configured drift repair
+// period again while it is disabled.
+const driftRepairRecheckPeriod = time.Minute
+
+// runDriftRepair calls resync every period until the context is done, so that
+// we reconcile every Ingress even when no events fired, e.g. because someone
+// deleted or edited one of our HTTPProxies while we weren't watching.  The
+// period is read again after every resync so that config changes apply, and
+// a period of zero disables the resyncs.
+func runDriftRepair(ctx context.Context, period func() time.Duration, resync func()) {
+	for {
+		wait := period()
+		enabled := wait > 0
+		if !enabled {
+			wait = driftRepairRecheckPeriod
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		if enabled && period() > 0 {
+			resync()
+		}
+	}
+}
+
+// writtenProxies remembers the HTTPProxies we last wrote, or found as we
+// would have written them, for the current generation of each Ingress.  When
+// we have to rewrite one of them although we would write it the same way
+// again, someone else deleted or edited it, which we count as a repair.  As
+// this is kept in memory, drift from before we became leader isn't counted.
+type writtenProxies struct {
+	mu        sync.Mutex
+	byIngress map[types.NamespacedName]*writtenGeneration
+}
+
+type writtenGeneration struct {
+	generation int64
+	proxies    map[string]*contourv1.HTTPProxy
+}
+
+func newWrittenProxies() *writtenProxies {
+	return &writtenProxies{
+		byIngress: make(map[types.NamespacedName]*writtenGeneration),
+	}
+}
+
+// drifted returns whether we already wrote the desired proxy for the Ingress'
+// current generation, so that the live one differing from it is drift
+// rather than a change of the Ingress or of our config.
+func (w *writtenProxies) drifted(ing *v1alpha1.Ingress, desired *contourv1.HTTPProxy) bool {
+	if w == nil {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	written, ok := w.byIngress[types.NamespacedName{Namespace: ing.Namespace, Name: ing.Name}]
+	if !ok || written.generation != ing.Generation {
+		return false
+	}
+	last, ok := written.proxies[desired.Name]
+	return ok && resources.StringMapsEqual(last.Annotations, desired.Annotations) &&
+		resources.StringMapsEqual(last.Labels, desired.Labels) &&
+		resources.HTTPProxySpecEqual(&last.Spec, &desired.Spec)
+}
+
+// wrote records that the desired proxy of the Ingress' current generation is
+// live, whether we just wrote it or found it unchanged.
+func (w *writtenProxies) wrote(ing *v1alpha1.Ingress, desired *contourv1.HTTPProxy) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	key := types.NamespacedName{Namespace: ing.Namespace, Name: ing.Name}
+	written, ok := w.byIngress[key]
+	if !ok || written.generation != ing.Generation {
+		written = &writtenGeneration{
+			generation: ing.Generation,
+			proxies:    make(map[string]*contourv1.HTTPProxy),
+		}
+		w.byIngress[key] = written
+	}
+	written.proxies[desired.Name] = desired
+}
+
+// forget stops tracking the Ingress, e.g. once it is deleted.
+func (w *writtenProxies) forget(key types.NamespacedName) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.byIngress, key)
+}
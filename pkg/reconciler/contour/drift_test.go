@@ -0,0 +1,107 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestRunDriftRepair(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var period, resyncs int64 = int64(10 * time.Millisecond), 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runDriftRepair(ctx,
+			func() time.Duration { return time.Duration(atomic.LoadInt64(&period)) },
+			func() { atomic.AddInt64(&resyncs, 1) })
+	}()
+
+	if err := wait.PollImmediate(5*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return atomic.LoadInt64(&resyncs) >= 3, nil
+	}); err != nil {
+		t.Fatal("Timed out waiting for resyncs:", err)
+	}
+
+	// Disabling the resyncs stops them once the current wait is over.
+	atomic.StoreInt64(&period, 0)
+	time.Sleep(50 * time.Millisecond)
+	before := atomic.LoadInt64(&resyncs)
+	time.Sleep(50 * time.Millisecond)
+	if got := atomic.LoadInt64(&resyncs); got != before {
+		t.Errorf("Got %d resyncs while disabled, wanted none", got-before)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("runDriftRepair did not return after the context was done")
+	}
+}
+
+func TestWrittenProxies(t *testing.T) {
+	w := newWrittenProxies()
+	i := ing("name", "ns", withBasicSpec, withContour)
+	proxy := mustMakeProxies(t, i)[0].(*contourv1.HTTPProxy)
+
+	if w.drifted(i, proxy) {
+		t.Error("drifted() = true before we wrote the proxy")
+	}
+	w.wrote(i, proxy)
+	if !w.drifted(i, proxy) {
+		t.Error("drifted() = false for the proxy we wrote")
+	}
+
+	// A proxy we'd write differently changed with the Ingress or our config.
+	changed := proxy.DeepCopy()
+	changed.Spec.Routes[0].TimeoutPolicy = nil
+	if w.drifted(i, changed) {
+		t.Error("drifted() = true for a proxy we'd write differently")
+	}
+
+	// So does every proxy of a new generation.
+	next := i.DeepCopy()
+	next.Generation++
+	if w.drifted(next, proxy) {
+		t.Error("drifted() = true for a new generation")
+	}
+	w.wrote(next, proxy)
+	if w.drifted(i, proxy) {
+		t.Error("drifted() = true for a generation we no longer track")
+	}
+
+	w.forget(types.NamespacedName{Namespace: "ns", Name: "name"})
+	if w.drifted(next, proxy) {
+		t.Error("drifted() = true for a forgotten Ingress")
+	}
+
+	var nilWritten *writtenProxies
+	nilWritten.wrote(i, proxy)
+	if nilWritten.drifted(i, proxy) {
+		t.Error("drifted() = true without a tracker")
+	}
+}
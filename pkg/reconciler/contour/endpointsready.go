@@ -0,0 +1,149 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	networkingv1alpha1 "knative.dev/networking/pkg/client/listers/networking/v1alpha1"
+)
+
+// readyAddresses returns the IPs of the ready addresses of the Endpoints.
+func readyAddresses(eps *corev1.Endpoints) sets.String {
+	ips := sets.NewString()
+	for _, subset := range eps.Subsets {
+		for _, addr := range subset.Addresses {
+			ips.Insert(addr.IP)
+		}
+	}
+	return ips
+}
+
+// referencesService returns whether a split of the Ingress targets the
+// Service with the given name.
+func referencesService(ing *v1alpha1.Ingress, name string) bool {
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			for _, split := range path.Splits {
+				if split.ServiceName == name {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// endpointsReadyHandler restarts probing the Ingresses which aren't ready and
+// reference a Service as soon as its Endpoints get a new ready address, e.g.
+// once a scaled to zero revision cold started or another of its pods became
+// ready.  The prober would otherwise only retry after backing off from the
+// failures of probing through Envoy while the pods weren't ready.
+type endpointsReadyHandler struct {
+	ingressLister networkingv1alpha1.IngressLister
+	filter        func(interface{}) bool
+
+	// cancel drops the prober's state of an Ingress, and enqueue makes us
+	// reconcile it, which starts probing it afresh.
+	cancel  func(interface{})
+	enqueue func(interface{})
+
+	mu sync.Mutex
+	// reset holds the version of the Endpoints of each Service we last
+	// restarted probing for, so that the redelivery of a version, e.g. when
+	// the informer relists, doesn't restart it again.
+	reset map[types.NamespacedName]string
+}
+
+var _ cache.ResourceEventHandler = (*endpointsReadyHandler)(nil)
+
+// OnAdd implements cache.ResourceEventHandler.
+func (h *endpointsReadyHandler) OnAdd(obj interface{}) {
+	if eps, ok := obj.(*corev1.Endpoints); ok && readyAddresses(eps).Len() != 0 {
+		h.reprobe(eps)
+	}
+}
+
+// OnUpdate implements cache.ResourceEventHandler.
+func (h *endpointsReadyHandler) OnUpdate(oldObj, newObj interface{}) {
+	oldEps, ok := oldObj.(*corev1.Endpoints)
+	if !ok {
+		return
+	}
+	if eps, ok := newObj.(*corev1.Endpoints); ok && readyAddresses(eps).Difference(readyAddresses(oldEps)).Len() != 0 {
+		h.reprobe(eps)
+	}
+}
+
+// OnDelete implements cache.ResourceEventHandler.
+func (h *endpointsReadyHandler) OnDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if eps, ok := obj.(*corev1.Endpoints); ok {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.reset, types.NamespacedName{Namespace: eps.Namespace, Name: eps.Name})
+	}
+}
+
+// firstReset returns whether we didn't restart probing for this version of
+// the Endpoints yet, and records that we do.
+func (h *endpointsReadyHandler) firstReset(eps *corev1.Endpoints) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := types.NamespacedName{Namespace: eps.Namespace, Name: eps.Name}
+	if eps.ResourceVersion != "" && h.reset[key] == eps.ResourceVersion {
+		return false
+	}
+	if h.reset == nil {
+		h.reset = make(map[types.NamespacedName]string)
+	}
+	h.reset[key] = eps.ResourceVersion
+	return true
+}
+
+func (h *endpointsReadyHandler) reprobe(eps *corev1.Endpoints) {
+	ings, err := h.ingressLister.Ingresses(eps.Namespace).List(labels.Everything())
+	if err != nil {
+		return
+	}
+	var probed []*v1alpha1.Ingress
+	for _, ing := range ings {
+		if ing.IsReady() || !h.filter(ing) || !referencesService(ing, eps.Name) {
+			continue
+		}
+		probed = append(probed, ing)
+	}
+	// Only the Services we probe for are tracked.
+	if len(probed) == 0 || !h.firstReset(eps) {
+		return
+	}
+	for _, ing := range probed {
+		h.cancel(ing)
+		h.enqueue(ing)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"knative.dev/networking/pkg/apis/networking"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/pkg/reconciler"
+
+	. "knative.dev/net-contour/pkg/reconciler/testing"
+)
+
+func TestEndpointsReadyHandler(t *testing.T) {
+	listers := NewListers([]runtime.Object{
+		ing("waiting", "ns", withBasicSpec, withContour),
+		ing("ready", "ns", withBasicSpec, withContour, makeItReady),
+		ing("other-service", "ns", withBasicSpec2, withContour),
+		ing("other-class", "ns", withBasicSpec),
+		ing("other-namespace", "other", withBasicSpec, withContour),
+	})
+	var canceled, enqueued []string
+	h := &endpointsReadyHandler{
+		ingressLister: listers.GetIngressLister(),
+		filter:        reconciler.AnnotationFilterFunc(networking.IngressClassAnnotationKey, ContourIngressClassName, false),
+		cancel: func(obj interface{}) {
+			canceled = append(canceled, obj.(*v1alpha1.Ingress).Name)
+		},
+		enqueue: func(obj interface{}) {
+			enqueued = append(enqueued, obj.(*v1alpha1.Ingress).Name)
+		},
+	}
+
+	endpoints := func(addresses ...corev1.EndpointAddress) *corev1.Endpoints {
+		return &corev1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "goo"},
+			Subsets: []corev1.EndpointSubset{{
+				Addresses:         addresses,
+				NotReadyAddresses: []corev1.EndpointAddress{{IP: "10.0.0.2"}},
+			}},
+		}
+	}
+	empty, ready := endpoints(), endpoints(corev1.EndpointAddress{IP: "10.0.0.1"})
+	more := endpoints(corev1.EndpointAddress{IP: "10.0.0.1"}, corev1.EndpointAddress{IP: "10.0.0.3"})
+	more.ResourceVersion = "2"
+
+	tests := []struct {
+		name   string
+		handle func()
+		want   []string
+	}{{
+		name:   "still not ready",
+		handle: func() { h.OnUpdate(empty, empty) },
+	}, {
+		name:   "still ready",
+		handle: func() { h.OnUpdate(ready, ready) },
+	}, {
+		name:   "no longer ready",
+		handle: func() { h.OnUpdate(ready, empty) },
+	}, {
+		name:   "added not ready",
+		handle: func() { h.OnAdd(empty) },
+	}, {
+		name:   "became ready",
+		handle: func() { h.OnUpdate(empty, ready) },
+		want:   []string{"waiting"},
+	}, {
+		name:   "added ready",
+		handle: func() { h.OnAdd(ready) },
+		want:   []string{"waiting"},
+	}, {
+		name:   "another pod became ready",
+		handle: func() { h.OnUpdate(ready, more) },
+		want:   []string{"waiting"},
+	}, {
+		name:   "redelivered version",
+		handle: func() { h.OnAdd(more) },
+	}, {
+		name:   "pod no longer ready",
+		handle: func() { h.OnUpdate(more, ready) },
+	}, {
+		name: "redelivered version after deletion",
+		handle: func() {
+			h.OnDelete(more)
+			h.OnAdd(more)
+		},
+		want: []string{"waiting"},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			canceled, enqueued = nil, nil
+			test.handle()
+			if !cmp.Equal(canceled, test.want) {
+				t.Errorf("Canceled = %v, wanted %v", canceled, test.want)
+			}
+			if !cmp.Equal(enqueued, test.want) {
+				t.Errorf("Enqueued = %v, wanted %v", enqueued, test.want)
+			}
+		})
+	}
+}
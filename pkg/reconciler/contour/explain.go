@@ -0,0 +1,198 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	contourlisters "knative.dev/net-contour/pkg/client/listers/projectcontour/v1"
+	"knative.dev/net-contour/pkg/ownership"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	networkingv1alpha1 "knative.dev/networking/pkg/client/listers/networking/v1alpha1"
+	"knative.dev/networking/pkg/ingress"
+	"knative.dev/networking/pkg/status"
+	"knative.dev/pkg/apis"
+)
+
+// Explanation is the machine-readable account of why an Ingress isn't ready,
+// which we serve on /debug/explain.
+type Explanation struct {
+	Ingress string `json:"ingress"`
+	Ready   bool   `json:"ready"`
+
+	// Conditions are the conditions of the Ingress which aren't true.
+	Conditions []apis.Condition `json:"conditions,omitempty"`
+	// InvalidProxies maps the HTTPProxies of the Ingress which Contour
+	// didn't accept (yet) to the reason.
+	InvalidProxies map[string]string `json:"invalidProxies,omitempty"`
+	// UnreadyPods lists the Envoy pods (as ip:port) which don't serve the
+	// current version of the Ingress.
+	UnreadyPods []string `json:"unreadyPods,omitempty"`
+	// Pods are the outcomes of probing each of the Envoy pods.
+	Pods []PodProbeResult `json:"pods,omitempty"`
+	// MissingSecrets lists the TLS secrets the Ingress references which
+	// don't exist.
+	MissingSecrets []string `json:"missingSecrets,omitempty"`
+}
+
+// explainer works out the Explanation of an Ingress from our caches, and by
+// probing the Envoy pods and looking up its secrets on demand.
+type explainer struct {
+	ingressLister networkingv1alpha1.IngressLister
+	contourLister contourlisters.HTTPProxyLister
+	targetLister  status.ProbeTargetLister
+	kubeClient    kubernetes.Interface
+
+	// podNames, when set, names the Envoy pods by IP in the explanations,
+	// whose probe results are remembered in results when set.
+	podNames func(context.Context) (map[string]string, error)
+	results  *probeResults
+
+	// toContext attaches the current configuration to the context.
+	toContext func(context.Context) context.Context
+}
+
+// explain returns the Explanation of the Ingress with the given name.
+func (e *explainer) explain(ctx context.Context, namespace, name string) (*Explanation, error) {
+	ing, err := e.ingressLister.Ingresses(namespace).Get(name)
+	if err != nil {
+		return nil, err
+	}
+	ctx = e.toContext(ctx)
+	if ctx, err = withProbeOverrides(ctx, ing); err != nil {
+		return nil, err
+	}
+
+	exp := &Explanation{
+		Ingress: namespace + "/" + name,
+		Ready:   ing.IsReady(),
+	}
+	for _, cond := range ing.Status.Conditions {
+		if !cond.IsTrue() {
+			exp.Conditions = append(exp.Conditions, cond)
+		}
+	}
+
+	proxies, err := e.contourLister.HTTPProxies(namespace).List(ownership.Parent(name))
+	if err != nil {
+		return nil, err
+	}
+	for _, proxy := range proxies {
+		switch valid := findValidCondition(proxy); {
+		case valid == nil || valid.ObservedGeneration != proxy.Generation:
+			exp.setInvalid(proxy.Name, "not validated by Contour yet")
+		case valid.Status != metav1.ConditionTrue:
+			exp.setInvalid(proxy.Name, valid.Message)
+		}
+	}
+
+	if !resources.IsTCPProxy(ing) {
+		if exp.Pods, err = e.probePods(ctx, ing); err != nil {
+			return nil, err
+		}
+		for _, result := range exp.Pods {
+			if !result.Ready {
+				exp.UnreadyPods = append(exp.UnreadyPods, result.Address)
+			}
+		}
+	}
+
+	for _, tls := range ing.Spec.TLS {
+		if _, err := e.kubeClient.CoreV1().Secrets(tls.SecretNamespace).Get(ctx, tls.SecretName, metav1.GetOptions{}); apierrs.IsNotFound(err) {
+			exp.MissingSecrets = append(exp.MissingSecrets, tls.SecretNamespace+"/"+tls.SecretName)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return exp, nil
+}
+
+func (exp *Explanation) setInvalid(proxy, reason string) {
+	if exp.InvalidProxies == nil {
+		exp.InvalidProxies = make(map[string]string, 1)
+	}
+	exp.InvalidProxies[proxy] = reason
+}
+
+// probePods probes every Envoy pod for the current version of the Ingress,
+// the way our quorum does, and returns the outcomes by address.
+func (e *explainer) probePods(ctx context.Context, ing *v1alpha1.Ingress) ([]PodProbeResult, error) {
+	bytes, err := ingress.ComputeHash(ing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute the hash of the Ingress: %w", err)
+	}
+	hash := fmt.Sprintf("%x", bytes)
+
+	targets, err := e.targetLister.ListProbeTargets(ctx, ing)
+	if err != nil {
+		return nil, err
+	}
+	pods := make(map[string][]*url.URL)
+	for _, target := range targets {
+		for ip := range target.PodIPs {
+			addr := net.JoinHostPort(ip, target.PodPort)
+			pods[addr] = append(pods[addr], target.URLs...)
+		}
+	}
+
+	var names map[string]string
+	if e.podNames != nil {
+		if names, err = e.podNames(ctx); err != nil {
+			return nil, err
+		}
+	}
+	results := make([]PodProbeResult, 0, len(pods))
+	for addr, urls := range pods {
+		result := probeEnvoyPod(ctx, nil, names, addr, urls, hash)
+		e.results.record(result)
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Address < results[j].Address
+	})
+	return results, nil
+}
+
+// ServeHTTP serves the Explanation of the Ingress named by the namespace and
+// name query parameters.
+func (e *explainer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	namespace, name := r.URL.Query().Get("namespace"), r.URL.Query().Get("name")
+	if namespace == "" || name == "" {
+		http.Error(w, "the namespace and name query parameters are required", http.StatusBadRequest)
+		return
+	}
+	exp, err := e.explain(r.Context(), namespace, name)
+	if apierrs.IsNotFound(err) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(exp)
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
+
+	. "knative.dev/net-contour/pkg/reconciler/testing"
+	. "knative.dev/pkg/reconciler/testing"
+)
+
+func TestExplain(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+
+	i := ing("name", "ns", withBasicSpec, withContour)
+	i.Spec.TLS = []v1alpha1.IngressTLS{{
+		Hosts:           []string{"example.com"},
+		SecretNamespace: "ns",
+		SecretName:      "missing",
+	}, {
+		Hosts:           []string{"example.com"},
+		SecretNamespace: "ns",
+		SecretName:      "present",
+	}}
+	i.Status.InitializeConditions()
+	if _, err := fakekubeclient.Get(ctx).CoreV1().Secrets("ns").Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "present"},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal("Create() =", err)
+	}
+
+	proxy := func(name string, valid metav1.ConditionStatus, message string) *contourv1.HTTPProxy {
+		return &contourv1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns",
+				Name:      name,
+				Labels:    map[string]string{resources.ParentKey: "name"},
+			},
+			Status: contourv1.HTTPProxyStatus{
+				Conditions: []contourv1.DetailedCondition{{
+					Condition: contourv1.Condition{
+						Type:    contourv1.ValidConditionType,
+						Status:  valid,
+						Message: message,
+					},
+				}},
+			},
+		}
+	}
+	listers := NewListers([]runtime.Object{
+		i,
+		proxy("valid", metav1.ConditionTrue, "Valid HTTPProxy"),
+		proxy("invalid", metav1.ConditionFalse, "Secret not found"),
+	})
+
+	// An Envoy pod which doesn't have the route programmed yet.
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(s.Close)
+	host, port, err := net.SplitHostPort(s.Listener.Addr().String())
+	if err != nil {
+		t.Fatal("SplitHostPort() =", err)
+	}
+
+	e := &explainer{
+		ingressLister: listers.GetIngressLister(),
+		contourLister: listers.GetHTTPProxyLister(),
+		targetLister: fakeProbeTargetLister{{
+			PodIPs:  sets.NewString(host),
+			PodPort: port,
+			URLs:    []*url.URL{{Scheme: "http", Host: "example.com"}},
+		}},
+		kubeClient: fakekubeclient.Get(ctx),
+		toContext: func(ctx context.Context) context.Context {
+			return (&testConfigStore{config: defaultConfig}).ToContext(ctx)
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/explain?namespace=ns&name=name", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/debug/explain = %d: %s", rec.Code, rec.Body)
+	}
+	var got Explanation
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("Unmarshal(%s) = %v", rec.Body, err)
+	}
+	if got.Ready || len(got.Conditions) == 0 {
+		t.Errorf("Ready = %v with conditions %v, wanted the unknown conditions of an unready Ingress", got.Ready, got.Conditions)
+	}
+	if want := map[string]string{"invalid": "Secret not found"}; !cmp.Equal(got.InvalidProxies, want) {
+		t.Errorf("InvalidProxies = %v, wanted %v", got.InvalidProxies, want)
+	}
+	if want := []string{s.Listener.Addr().String()}; !cmp.Equal(got.UnreadyPods, want) {
+		t.Errorf("UnreadyPods = %v, wanted %v", got.UnreadyPods, want)
+	}
+	if len(got.Pods) != 1 || got.Pods[0].Address != s.Listener.Addr().String() || got.Pods[0].Ready {
+		t.Errorf("Pods = %+v, wanted the unready pod at %s", got.Pods, s.Listener.Addr())
+	}
+	if want := []string{"ns/missing"}; !cmp.Equal(got.MissingSecrets, want) {
+		t.Errorf("MissingSecrets = %v, wanted %v", got.MissingSecrets, want)
+	}
+
+	for _, query := range []string{"namespace=ns", "namespace=ns&name=missing"} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/explain?"+query, nil))
+		if rec.Code == http.StatusOK {
+			t.Errorf("/debug/explain?%s = %d, wanted an error", query, rec.Code)
+		}
+	}
+}
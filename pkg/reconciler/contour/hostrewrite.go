@@ -0,0 +1,154 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"sync"
+
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	network "knative.dev/networking/pkg"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/networking/pkg/prober"
+	"knative.dev/pkg/logging"
+)
+
+// rewrittenHosts returns the host that each of the Ingress' hosts is
+// rewritten to by the path our probes take, which is its catch-all path.
+func rewrittenHosts(ing *v1alpha1.Ingress) map[string]string {
+	hosts := make(map[string]string)
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, p := range rule.HTTP.Paths {
+			if p.RewriteHost == "" || (p.Path != "" && p.Path != "/") {
+				continue
+			}
+			for _, host := range rule.Hosts {
+				hosts[host] = p.RewriteHost
+			}
+		}
+	}
+	return hosts
+}
+
+// hostRewritesVerified returns whether every Envoy pod rewrites the hosts of
+// the Ingress as it specifies, which the upstreams confirm by echoing the
+// Host they received in the configured header.  A misconfigured rewrite thus
+// keeps the Ingress, or the endpoint probe of its next generation, from
+// becoming ready instead of breaking its traffic.
+func (m *quorumManager) hostRewritesVerified(ctx context.Context, ing *v1alpha1.Ingress) (bool, error) {
+	header := config.FromContext(ctx).Contour.ProbeHostEchoHeader
+	if header == "" {
+		return true, nil
+	}
+	rewrites := rewrittenHosts(ing)
+	if len(rewrites) == 0 {
+		return true, nil
+	}
+
+	targets, err := m.targetLister.ListProbeTargets(ctx, ing)
+	if err != nil {
+		return false, err
+	}
+
+	// Group the URLs of the rewritten hosts by pod.
+	pods := make(map[string][]*url.URL)
+	for _, target := range targets {
+		for _, u := range target.URLs {
+			if _, ok := rewrites[u.Hostname()]; !ok {
+				continue
+			}
+			for ip := range target.PodIPs {
+				addr := net.JoinHostPort(ip, target.PodPort)
+				pods[addr] = append(pods[addr], u)
+			}
+		}
+	}
+
+	var (
+		mu     sync.Mutex
+		failed error
+		wg     sync.WaitGroup
+	)
+	for addr, urls := range pods {
+		wg.Add(1)
+		go func(addr string, urls []*url.URL) {
+			defer wg.Done()
+			if err := probeHostRewrites(ctx, m.transports, addr, urls, header, rewrites); err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+				failed = fmt.Errorf("pod %s: %w", addr, err)
+			}
+		}(addr, urls)
+	}
+	wg.Wait()
+
+	if failed != nil {
+		logging.FromContext(ctx).Warnf("The host rewrites of the Ingress aren't verified: %v", failed)
+		// The wrapped Manager already considers the Ingress ready, so check
+		// again later ourselves.
+		m.enqueueAfter(ing, quorumRecheckPeriod)
+		return false, nil
+	}
+	return true, nil
+}
+
+// probeHostRewrites probes the Envoy pod listening at addr for each of the
+// urls, and returns an error unless the upstream received the host the urls'
+// host is rewritten to.
+func probeHostRewrites(ctx context.Context, transports *probeTransports, addr string, urls []*url.URL, header string, rewrites map[string]string) error {
+	transport, release := transports.get(ctx, addr)
+	defer release()
+
+	for _, u := range urls {
+		probeURL := *u
+		probeURL.Path = path.Join(probeURL.Path, network.ProbePath)
+
+		ctx, cancel := context.WithTimeout(ctx, probeTimeout(ctx))
+		ok, err := prober.Do(ctx, transport, probeURL.String(), append(probeHeaders(ctx),
+			hostEchoVerifier(header, rewrites[u.Hostname()]))...)
+		cancel()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("probing %s failed", u.Host)
+		}
+	}
+	return nil
+}
+
+// hostEchoVerifier requires successful responses to echo the wanted host in
+// the header.
+func hostEchoVerifier(header, want string) prober.Verifier {
+	return func(r *http.Response, _ []byte) (bool, error) {
+		if r.StatusCode != http.StatusOK {
+			return false, fmt.Errorf("unexpected status code: want %v, got %v", http.StatusOK, r.StatusCode)
+		}
+		if got := r.Header.Get(header); got != want {
+			return false, fmt.Errorf("unexpected host in %s: want %q, got %q", header, want, got)
+		}
+		return true, nil
+	}
+}
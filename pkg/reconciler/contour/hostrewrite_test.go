@@ -0,0 +1,131 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/networking/pkg/status"
+)
+
+func TestHostRewritesVerified(t *testing.T) {
+	const header = "X-Echo-Host"
+
+	withRewrite := func(i *v1alpha1.Ingress) {
+		i.Spec.Rules[0].HTTP.Paths[0].RewriteHost = "rewritten.example.com"
+	}
+
+	// Simulate Envoy pods whose upstream echoes the given host.
+	envoys := func(hosts ...string) (targets fakeProbeTargetLister) {
+		for _, h := range hosts {
+			h := h
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set(header, h)
+			}))
+			t.Cleanup(s.Close)
+
+			host, port, err := net.SplitHostPort(s.Listener.Addr().String())
+			if err != nil {
+				t.Fatal("SplitHostPort() =", err)
+			}
+			targets = append(targets, status.ProbeTarget{
+				PodIPs:  sets.NewString(host),
+				PodPort: port,
+				URLs:    []*url.URL{{Scheme: "http", Host: "example.com"}},
+			})
+		}
+		return targets
+	}
+
+	tests := []struct {
+		name        string
+		header      string
+		ing         *v1alpha1.Ingress
+		targets     fakeProbeTargetLister
+		want        bool
+		wantEnqueue bool
+	}{{
+		name:    "verification disabled",
+		ing:     ing("name", "ns", withBasicSpec, withRewrite),
+		targets: envoys("example.com"),
+		want:    true,
+	}, {
+		name:    "no rewrites",
+		header:  header,
+		ing:     ing("name", "ns", withBasicSpec),
+		targets: envoys("example.com"),
+		want:    true,
+	}, {
+		name:    "rewritten",
+		header:  header,
+		ing:     ing("name", "ns", withBasicSpec, withRewrite),
+		targets: envoys("rewritten.example.com", "rewritten.example.com"),
+		want:    true,
+	}, {
+		name:        "not rewritten by a pod",
+		header:      header,
+		ing:         ing("name", "ns", withBasicSpec, withRewrite),
+		targets:     envoys("rewritten.example.com", "example.com"),
+		wantEnqueue: true,
+	}, {
+		name:        "not echoed",
+		header:      header,
+		ing:         ing("name", "ns", withBasicSpec, withRewrite),
+		targets:     envoys(""),
+		wantEnqueue: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := defaultConfig.DeepCopy()
+			cfg.Contour.ProbeHostEchoHeader = test.header
+			ctx := (&testConfigStore{config: cfg}).ToContext(context.Background())
+
+			enqueued := false
+			m := &quorumManager{
+				Manager: &fakeStatusManager{
+					FakeIsReady: func(context.Context, *v1alpha1.Ingress) (bool, error) {
+						return true, nil
+					},
+				},
+				targetLister: test.targets,
+				enqueueAfter: func(interface{}, time.Duration) {
+					enqueued = true
+				},
+			}
+
+			got, err := m.IsReady(ctx, test.ing)
+			if err != nil {
+				t.Fatal("IsReady() =", err)
+			}
+			if got != test.want {
+				t.Errorf("IsReady() = %v, wanted %v", got, test.want)
+			}
+			if enqueued != test.wantEnqueue {
+				t.Errorf("enqueued = %v, wanted %v", enqueued, test.wantEnqueue)
+			}
+		})
+	}
+}
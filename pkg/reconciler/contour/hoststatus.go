@@ -0,0 +1,77 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"encoding/json"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+)
+
+// HostStatusAnnotationKey is the status annotation in which we report how far
+// we got programming each host of an Ingress that isn't ready yet, as a JSON
+// object mapping the hosts to one of:
+//
+//	programmed: we wrote its HTTPProxy, which Contour didn't validate yet.
+//	valid: Contour accepted its HTTPProxy, which we wait to probe.
+//	invalid: <reason>: Contour rejected its HTTPProxy.
+//
+// Once the Ingress is ready, every host was probed and we drop the annotation.
+const HostStatusAnnotationKey = "contour.networking.knative.dev/host-status"
+
+// hostStates returns the state of the host of each of the programmed
+// HTTPProxies, as reported in HostStatusAnnotationKey.
+func hostStates(proxies []*contourv1.HTTPProxy) map[string]string {
+	states := make(map[string]string, len(proxies))
+	for _, proxy := range proxies {
+		if proxy.Spec.VirtualHost == nil {
+			continue
+		}
+		host := proxy.Spec.VirtualHost.Fqdn
+		switch valid := findValidCondition(proxy); {
+		case valid == nil || valid.ObservedGeneration != proxy.Generation:
+			states[host] = "programmed"
+		case valid.Status == metav1.ConditionTrue:
+			states[host] = "valid"
+		default:
+			states[host] = "invalid: " + valid.Message
+		}
+	}
+	return states
+}
+
+// markHostStatus reports the state of each programmed host while the Ingress
+// isn't ready, and drops the report once it is.
+func markHostStatus(ing *v1alpha1.Ingress, proxies []*contourv1.HTTPProxy) {
+	if ing.Status.GetCondition(v1alpha1.IngressConditionLoadBalancerReady).IsTrue() {
+		delete(ing.Status.Annotations, HostStatusAnnotationKey)
+		if len(ing.Status.Annotations) == 0 {
+			ing.Status.Annotations = nil
+		}
+		return
+	}
+	b, err := json.Marshal(hostStates(proxies))
+	if err != nil {
+		return
+	}
+	if ing.Status.Annotations == nil {
+		ing.Status.Annotations = make(map[string]string, 1)
+	}
+	ing.Status.Annotations[HostStatusAnnotationKey] = string(b)
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"testing"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+)
+
+func TestMarkHostStatus(t *testing.T) {
+	proxy := func(host string, status metav1.ConditionStatus, message string) *contourv1.HTTPProxy {
+		p := &contourv1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{Generation: 1},
+			Spec: contourv1.HTTPProxySpec{
+				VirtualHost: &contourv1.VirtualHost{Fqdn: host},
+			},
+		}
+		if status != "" {
+			p.Status.Conditions = []contourv1.DetailedCondition{{
+				Condition: metav1.Condition{
+					Type:               contourv1.ValidConditionType,
+					Status:             status,
+					ObservedGeneration: 1,
+					Message:            message,
+				},
+			}}
+		}
+		return p
+	}
+	proxies := []*contourv1.HTTPProxy{
+		proxy("a.example.com", "", ""),
+		proxy("b.example.com", metav1.ConditionTrue, "Valid HTTPProxy"),
+		proxy("c.example.com", metav1.ConditionFalse, "TLS Secret not found"),
+	}
+
+	i := ing("name", "ns")
+	i.Status.InitializeConditions()
+	i.Status.MarkLoadBalancerNotReady()
+	markHostStatus(i, proxies)
+	want := `{"a.example.com":"programmed","b.example.com":"valid","c.example.com":"invalid: TLS Secret not found"}`
+	if got := i.Status.Annotations[HostStatusAnnotationKey]; got != want {
+		t.Errorf("%s = %s, wanted %s", HostStatusAnnotationKey, got, want)
+	}
+
+	// Once the Ingress is ready, we drop the report.
+	i.Status.MarkLoadBalancerReady([]v1alpha1.LoadBalancerIngressStatus{}, []v1alpha1.LoadBalancerIngressStatus{})
+	markHostStatus(i, proxies)
+	if got, ok := i.Status.Annotations[HostStatusAnnotationKey]; ok {
+		t.Errorf("%s = %s once ready, wanted none", HostStatusAnnotationKey, got)
+	}
+}
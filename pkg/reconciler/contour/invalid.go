@@ -0,0 +1,134 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	contourlisters "knative.dev/net-contour/pkg/client/listers/projectcontour/v1"
+	"knative.dev/net-contour/pkg/ownership"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/metrics"
+)
+
+// invalidReportPeriod is how often we count the HTTPProxies Contour rejected.
+const invalidReportPeriod = 30 * time.Second
+
+// invalidProxies counts the HTTPProxies we programmed which Contour rejected,
+// by reason, and fails our readiness once there are too many of them.
+type invalidProxies struct {
+	contourLister contourlisters.HTTPProxyLister
+	maxInvalid    func() int
+
+	// next is the readiness check we defer to while there are few enough.
+	next http.Handler
+
+	mu sync.Mutex
+	// total is the number of invalid HTTPProxies we last counted.
+	total int
+	// reasons are the reasons we ever reported invalid HTTPProxies for.
+	reasons map[string]struct{}
+}
+
+// count returns the number of our HTTPProxies Contour rejected by reason.
+// HTTPProxies it didn't validate yet aren't counted.
+func (p *invalidProxies) count() (map[string]int, error) {
+	proxies, err := p.contourLister.List(ownership.Children())
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, proxy := range proxies {
+		if proxy.Status.CurrentStatus == "orphaned" {
+			counts["Orphaned"]++
+			continue
+		}
+		valid := findValidCondition(proxy)
+		if valid == nil || valid.Status != metav1.ConditionFalse {
+			continue
+		}
+		reason := valid.Reason
+		if reason == "" {
+			reason = "Unknown"
+		}
+		counts[reason]++
+	}
+	return counts, nil
+}
+
+// report records the number of invalid HTTPProxies by reason until the
+// context is done.
+func (p *invalidProxies) report(ctx context.Context) {
+	ticker := time.NewTicker(invalidReportPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := p.update(ctx); err != nil {
+			logging.FromContext(ctx).Warnw("Error counting invalid HTTPProxies", zap.Error(err))
+		}
+	}
+}
+
+// update counts the invalid HTTPProxies, and records their number by reason.
+func (p *invalidProxies) update(ctx context.Context) error {
+	counts, err := p.count()
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.reasons == nil {
+		p.reasons = make(map[string]struct{}, len(counts))
+	}
+	p.total = 0
+	for reason, n := range counts {
+		p.reasons[reason] = struct{}{}
+		p.total += n
+	}
+	// Keep reporting zero for reasons that no longer apply.
+	for reason := range p.reasons {
+		if tagged, err := tag.New(ctx, tag.Upsert(reasonKey, reason)); err == nil {
+			metrics.Record(tagged, invalidProxiesM.M(int64(counts[reason])))
+		}
+	}
+	return nil
+}
+
+// ServeHTTP implements our readiness endpoint, which fails while more
+// HTTPProxies than configured are invalid.
+func (p *invalidProxies) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	total := p.total
+	p.mu.Unlock()
+	if max := p.maxInvalid(); max >= 0 && total > max {
+		http.Error(w, fmt.Sprintf("Contour rejected %d HTTPProxies, more than the %d allowed.", total, max),
+			http.StatusServiceUnavailable)
+		return
+	}
+	p.next.ServeHTTP(w, r)
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+
+	. "knative.dev/net-contour/pkg/reconciler/testing"
+)
+
+func TestInvalidProxies(t *testing.T) {
+	proxy := func(name string, owned bool, status contourv1.HTTPProxyStatus) *contourv1.HTTPProxy {
+		p := &contourv1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name},
+			Status:     status,
+		}
+		if owned {
+			p.Labels = map[string]string{resources.ParentKey: "name"}
+		}
+		return p
+	}
+	valid := func(s metav1.ConditionStatus, reason string) contourv1.HTTPProxyStatus {
+		return contourv1.HTTPProxyStatus{
+			Conditions: []contourv1.DetailedCondition{{
+				Condition: contourv1.Condition{Type: contourv1.ValidConditionType, Status: s, Reason: reason},
+			}},
+		}
+	}
+	listers := NewListers([]runtime.Object{
+		proxy("valid", true, valid(metav1.ConditionTrue, "Valid")),
+		proxy("pending", true, contourv1.HTTPProxyStatus{}),
+		proxy("secret", true, valid(metav1.ConditionFalse, "SecretNotValid")),
+		proxy("secret2", true, valid(metav1.ConditionFalse, "SecretNotValid")),
+		proxy("orphan", true, contourv1.HTTPProxyStatus{CurrentStatus: "orphaned"}),
+		proxy("not-ours", false, valid(metav1.ConditionFalse, "SecretNotValid")),
+	})
+
+	maxInvalid := -1
+	p := &invalidProxies{
+		contourLister: listers.GetHTTPProxyLister(),
+		maxInvalid:    func() int { return maxInvalid },
+		next:          http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+	}
+
+	got, err := p.count()
+	if err != nil {
+		t.Fatal("count() =", err)
+	}
+	if want := map[string]int{"SecretNotValid": 2, "Orphaned": 1}; !cmp.Equal(got, want) {
+		t.Errorf("count() = %v, wanted %v", got, want)
+	}
+	if err := p.update(context.Background()); err != nil {
+		t.Fatal("update() =", err)
+	}
+
+	for _, test := range []struct {
+		max  int
+		want int
+	}{
+		{max: -1, want: http.StatusOK},
+		{max: 3, want: http.StatusOK},
+		{max: 2, want: http.StatusServiceUnavailable},
+	} {
+		maxInvalid = test.max
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if rec.Code != test.want {
+			t.Errorf("Readiness with at most %d invalid = %d, wanted %d", test.max, rec.Code, test.want)
+		}
+	}
+}
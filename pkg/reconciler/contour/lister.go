@@ -18,14 +18,20 @@ package contour
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
 	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	corev1listers "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
 	network "knative.dev/networking/pkg"
 	"knative.dev/networking/pkg/apis/networking/v1alpha1"
 	"knative.dev/networking/pkg/ingress"
@@ -41,17 +47,37 @@ var _ status.ProbeTargetLister = (*lister)(nil)
 
 // ListProbeTargets implements status.ProbeTargetLister
 func (l *lister) ListProbeTargets(ctx context.Context, ing *v1alpha1.Ingress) ([]status.ProbeTarget, error) {
+	if resources.IsTCPProxy(ing) {
+		// These don't route HTTP requests, the quorumManager probes them with
+		// TLS handshakes instead.
+		return nil, nil
+	}
+	return l.listProbeTargets(ctx, ing, false)
+}
+
+// ListTCPProbeTargets returns the Envoy pods to probe with TLS handshakes
+// for the hosts of an Ingress whose connections we proxy.
+func (l *lister) ListTCPProbeTargets(ctx context.Context, ing *v1alpha1.Ingress) ([]status.ProbeTarget, error) {
+	return l.listProbeTargets(ctx, ing, true)
+}
+
+func (l *lister) listProbeTargets(ctx context.Context, ing *v1alpha1.Ingress, tcp bool) ([]status.ProbeTarget, error) {
 	var results []status.ProbeTarget
 
-	cfg := config.FromContext(ctx)
-	visibilityKeys := cfg.Contour.VisibilityKeys
+	visibilityKeys, err := resolveVisibilityKeys(ctx, l.ServiceLister)
+	if err != nil {
+		return nil, err
+	}
 
-	for key, hosts := range ingress.HostsPerVisibility(ing, visibilityKeys) {
+	for key, hosts := range ingress.HostsPerVisibility(resources.WithoutDisallowedHosts(ctx, resources.WithoutUnmanagedHosts(ing)), visibilityKeys) {
 		port, scheme := int32(80), "http"
 
-		// Probe external servce with https.
-		if ing.Spec.HTTPOption == v1alpha1.HTTPOptionRedirected &&
-			!visibilityKeys["ClusterLocal"].Has(key) {
+		// Probe external servce with https, and every service when Envoy
+		// doesn't serve plain HTTP.  The URLs carry the probed host, which
+		// the TLS handshake sends as SNI.
+		if tcp || config.FromContext(ctx).Contour.ProbeOverHTTPS ||
+			(ing.Spec.HTTPOption == v1alpha1.HTTPOptionRedirected &&
+				!visibilityKeys["ClusterLocal"].Has(key)) {
 			port, scheme = 443, "https"
 		}
 
@@ -74,7 +100,8 @@ func (l *lister) ListProbeTargets(ctx context.Context, ing *v1alpha1.Ingress) ([
 		for _, host := range hosts.UnsortedList() {
 			urls = append(urls, &url.URL{
 				Scheme: scheme,
-				Host:   host,
+				Host:   probeHost(host),
+				Path:   config.FromContext(ctx).Contour.ProbePath,
 			})
 		}
 
@@ -82,11 +109,29 @@ func (l *lister) ListProbeTargets(ctx context.Context, ing *v1alpha1.Ingress) ([
 		if err != nil {
 			return nil, fmt.Errorf("failed to lookup port %d in %s/%s: %w", port, namespace, name, err)
 		}
+		if ip := service.Spec.ClusterIP; meshCompatible(ctx) && ip != "" && ip != corev1.ClusterIPNone {
+			// The mesh doesn't let us reach the Envoy pods directly, so probe
+			// through the Service instead.  Each probe only reaches one of the
+			// Envoys, so we know that at least one serves the Ingress.
+			results = append(results, status.ProbeTarget{
+				PodIPs:  sets.NewString(ip),
+				Port:    strconv.Itoa(int(port)),
+				PodPort: strconv.Itoa(int(port)),
+				URLs:    urls,
+			})
+			continue
+		}
+		podPortOverride := config.FromContext(ctx).Contour.ProbeHTTPPort
+		if scheme == "https" {
+			podPortOverride = config.FromContext(ctx).Contour.ProbeHTTPSPort
+		}
 		for _, sub := range endpoints.Subsets {
-			podPort, err := network.PortNumberForName(sub, portName)
-			if err != nil {
-				return nil, fmt.Errorf("failed to lookup port name %q in endpoints subset for %s/%s: %w",
-					portName, namespace, name, err)
+			podPort := int32(podPortOverride)
+			if podPort == 0 {
+				if podPort, err = network.PortNumberForName(sub, portName); err != nil {
+					return nil, fmt.Errorf("failed to lookup port name %q in endpoints subset for %s/%s: %w",
+						portName, namespace, name, err)
+				}
 			}
 
 			pt := status.ProbeTarget{
@@ -98,9 +143,84 @@ func (l *lister) ListProbeTargets(ctx context.Context, ing *v1alpha1.Ingress) ([
 			for _, addr := range sub.Addresses {
 				pt.PodIPs.Insert(addr.IP)
 			}
+			if n := config.FromContext(ctx).Contour.ProbeSampleSize; n > 0 {
+				pt.PodIPs = samplePods(ing, pt.PodIPs, n)
+			}
 			results = append(results, pt)
 		}
 	}
 
 	return results, nil
 }
+
+// envoyPodNames maps the IPs of the Envoy pods of every visibility to their
+// namespace/name, from the targetRefs of the Endpoints of their Services.
+func (l *lister) envoyPodNames(ctx context.Context) (map[string]string, error) {
+	visibilityKeys, err := resolveVisibilityKeys(ctx, l.ServiceLister)
+	if err != nil {
+		return nil, err
+	}
+	keys := sets.NewString()
+	for _, k := range visibilityKeys {
+		keys = keys.Union(k)
+	}
+
+	names := make(map[string]string)
+	for _, key := range keys.List() {
+		namespace, name, err := cache.SplitMetaNamespaceKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse key: %w", err)
+		}
+		endpoints, err := l.EndpointsLister.Endpoints(namespace).Get(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get Endpoints: %w", err)
+		}
+		for _, sub := range endpoints.Subsets {
+			for _, addr := range append(sub.Addresses, sub.NotReadyAddresses...) {
+				if ref := addr.TargetRef; ref != nil && ref.Kind == "Pod" {
+					names[addr.IP] = ref.Namespace + "/" + ref.Name
+				}
+			}
+		}
+	}
+	return names, nil
+}
+
+// probeHost returns the host to probe for the host of a rule.  Wildcard
+// hosts (e.g. *.example.com) can't be sent as the authority or SNI of a
+// request, so we probe a concrete host their virtual host matches instead,
+// derived from the wildcard so that every probe of it sends the same one.
+func probeHost(host string) string {
+	if !strings.HasPrefix(host, "*.") {
+		return host
+	}
+	sum := sha256.Sum256([]byte(host))
+	return fmt.Sprintf("knative-probe-%x%s", sum[:4], host[1:])
+}
+
+// meshCompatible returns whether config-network asks us to reach the Envoys
+// through their Service, because a mesh prevents probing their pods.
+func meshCompatible(ctx context.Context) bool {
+	n := config.FromContext(ctx).Network
+	return n != nil && n.MeshCompatibilityMode == network.MeshCompatibilityModeEnabled
+}
+
+// samplePods returns n of the pod IPs, picked by hashing them along with the
+// Ingress' generation.  Every probe of a generation samples the same pods,
+// while different Ingresses and generations spread over the whole fleet.
+func samplePods(ing *v1alpha1.Ingress, ips sets.String, n int) sets.String {
+	if ips.Len() <= n {
+		return ips
+	}
+	seed := fmt.Sprintf("%s/%s/%d/", ing.Namespace, ing.Name, ing.Generation)
+	rank := make(map[string]uint64, ips.Len())
+	sorted := ips.UnsortedList()
+	for _, ip := range sorted {
+		sum := sha256.Sum256([]byte(seed + ip))
+		rank[ip] = binary.BigEndian.Uint64(sum[:8])
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return rank[sorted[i]] < rank[sorted[j]]
+	})
+	return sets.NewString(sorted[:n]...)
+}
@@ -20,12 +20,18 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"strings"
 	"testing"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+	network "knative.dev/networking/pkg"
 	"knative.dev/networking/pkg/apis/networking/v1alpha1"
 	"knative.dev/networking/pkg/status"
 
@@ -35,11 +41,12 @@ import (
 
 func TestListProbeTargets(t *testing.T) {
 	tests := []struct {
-		name    string
-		ing     *v1alpha1.Ingress
-		objects []runtime.Object
-		want    []status.ProbeTarget
-		wantErr error
+		name         string
+		ing          *v1alpha1.Ingress
+		objects      []runtime.Object
+		modifyConfig func(*config.Config)
+		want         []status.ProbeTarget
+		wantErr      error
 	}{{
 		name: "public with single address to probe",
 		objects: []runtime.Object{
@@ -123,6 +130,200 @@ func TestListProbeTargets(t *testing.T) {
 		objects: []runtime.Object{publicService, publicEndpointsWrongPortName},
 		ing:     ing("name", "ns", withBasicSpec, withContour),
 		wantErr: fmt.Errorf(`failed to lookup port name "asdf" in endpoints subset for %s/%s: no port for name "asdf" found`, publicNS, publicName),
+	}, {
+		name: "public with single address to probe (probing over https)",
+		objects: []runtime.Object{
+			publicSecureService,
+			privateService,
+			publicEndpointsOneAddr,
+			privateEndpointsNoAddr,
+		},
+		modifyConfig: func(c *config.Config) {
+			c.Contour.ProbeOverHTTPS = true
+		},
+		ing: ing("name", "ns", withBasicSpec, withContour),
+		want: []status.ProbeTarget{{
+			PodIPs:  sets.NewString("1.2.3.4"),
+			Port:    "443",
+			PodPort: "1234",
+			URLs: []*url.URL{{
+				Scheme: "https",
+				Host:   "example.com",
+			}},
+		}},
+	}, {
+		name: "probe path and port overrides",
+		objects: []runtime.Object{
+			publicService,
+			privateService,
+			publicEndpointsOneAddr,
+			privateEndpointsNoAddr,
+		},
+		modifyConfig: func(c *config.Config) {
+			c.Contour.ProbePath = "/envoy"
+			c.Contour.ProbeHTTPPort = 8080
+		},
+		ing: ing("name", "ns", withBasicSpec, withContour),
+		want: []status.ProbeTarget{{
+			PodIPs:  sets.NewString("1.2.3.4"),
+			Port:    "80",
+			PodPort: "8080",
+			URLs: []*url.URL{{
+				Scheme: "http",
+				Host:   "example.com",
+				Path:   "/envoy",
+			}},
+		}},
+	}, {
+		name: "wildcard host",
+		objects: []runtime.Object{
+			publicService,
+			privateService,
+			publicEndpointsOneAddr,
+			privateEndpointsNoAddr,
+		},
+		ing: ing("name", "ns", withBasicSpec, withContour, func(i *v1alpha1.Ingress) {
+			i.Spec.Rules[0].Hosts = []string{"*.example.com"}
+		}),
+		want: []status.ProbeTarget{{
+			PodIPs:  sets.NewString("1.2.3.4"),
+			Port:    "80",
+			PodPort: "1234",
+			URLs: []*url.URL{{
+				Scheme: "http",
+				Host:   probeHost("*.example.com"),
+			}},
+		}},
+	}, {
+		name: "public service probed through its cluster IP (mesh compatibility)",
+		objects: []runtime.Object{
+			withClusterIP(publicService, "10.0.0.1"),
+			privateService,
+			publicEndpointsOneAddr,
+			privateEndpointsNoAddr,
+		},
+		modifyConfig: func(c *config.Config) {
+			c.Network = &network.Config{MeshCompatibilityMode: network.MeshCompatibilityModeEnabled}
+		},
+		ing: ing("name", "ns", withBasicSpec, withContour),
+		want: []status.ProbeTarget{{
+			PodIPs:  sets.NewString("10.0.0.1"),
+			Port:    "80",
+			PodPort: "80",
+			URLs: []*url.URL{{
+				Scheme: "http",
+				Host:   "example.com",
+			}},
+		}},
+	}, {
+		name: "headless public service probed through its pods (mesh compatibility)",
+		objects: []runtime.Object{
+			withClusterIP(publicService, corev1.ClusterIPNone),
+			privateService,
+			publicEndpointsOneAddr,
+			privateEndpointsNoAddr,
+		},
+		modifyConfig: func(c *config.Config) {
+			c.Network = &network.Config{MeshCompatibilityMode: network.MeshCompatibilityModeEnabled}
+		},
+		ing: ing("name", "ns", withBasicSpec, withContour),
+		want: []status.ProbeTarget{{
+			PodIPs:  sets.NewString("1.2.3.4"),
+			Port:    "80",
+			PodPort: "1234",
+			URLs: []*url.URL{{
+				Scheme: "http",
+				Host:   "example.com",
+			}},
+		}},
+	}, {
+		name: "unmanaged hosts aren't probed",
+		objects: []runtime.Object{
+			publicService,
+			privateService,
+			publicEndpointsOneAddr,
+			privateEndpointsNoAddr,
+		},
+		ing: ing("name", "ns", withBasicSpec, withContour,
+			withAnnotation(map[string]string{resources.UnmanagedHostsKey: "example.com"})),
+	}, {
+		name: "proxied tcp connections aren't probed over http",
+		objects: []runtime.Object{
+			publicService,
+			privateService,
+			publicEndpointsOneAddr,
+			privateEndpointsNoAddr,
+		},
+		ing: ing("name", "ns", withBasicSpec, withContour,
+			withAnnotation(map[string]string{resources.TCPProxyKey: "true"})),
+	}, {
+		name: "public service discovered from gateway",
+		objects: []runtime.Object{
+			withLabels(publicService, map[string]string{OwningGatewayLabel: "external"}),
+			privateService,
+			publicEndpointsOneAddr,
+			privateEndpointsNoAddr,
+		},
+		modifyConfig: func(c *config.Config) {
+			c.Contour.VisibilityKeys[v1alpha1.IngressVisibilityExternalIP] = sets.NewString()
+			c.Contour.VisibilityGateways = map[v1alpha1.IngressVisibility]types.NamespacedName{
+				v1alpha1.IngressVisibilityExternalIP: {Namespace: publicNS, Name: "external"},
+			}
+		},
+		ing: ing("name", "ns", withBasicSpec, withContour),
+		want: []status.ProbeTarget{{
+			PodIPs:  sets.NewString("1.2.3.4"),
+			Port:    "80",
+			PodPort: "1234",
+			URLs: []*url.URL{{
+				Scheme: "http",
+				Host:   "example.com",
+			}},
+		}},
+	}, {
+		name: "public service discovered by selector",
+		objects: []runtime.Object{
+			withLabels(publicService, map[string]string{"visibility": "external"}),
+			privateService,
+			publicEndpointsOneAddr,
+			privateEndpointsNoAddr,
+		},
+		modifyConfig: func(c *config.Config) {
+			c.Contour.VisibilityKeys[v1alpha1.IngressVisibilityExternalIP] = sets.NewString()
+			c.Contour.VisibilitySelectors = map[v1alpha1.IngressVisibility]string{
+				v1alpha1.IngressVisibilityExternalIP: "visibility=external",
+			}
+		},
+		ing: ing("name", "ns", withBasicSpec, withContour),
+		want: []status.ProbeTarget{{
+			PodIPs:  sets.NewString("1.2.3.4"),
+			Port:    "80",
+			PodPort: "1234",
+			URLs: []*url.URL{{
+				Scheme: "http",
+				Host:   "example.com",
+			}},
+		}},
+	}, {
+		name:    "no service matching selector",
+		objects: []runtime.Object{publicService, publicEndpointsOneAddr},
+		modifyConfig: func(c *config.Config) {
+			c.Contour.VisibilitySelectors = map[v1alpha1.IngressVisibility]string{
+				v1alpha1.IngressVisibilityExternalIP: "visibility=external",
+			}
+		},
+		ing:     ing("name", "ns", withBasicSpec, withContour),
+		wantErr: fmt.Errorf(`no Envoy Services found matching "visibility=external"`),
+	}, {
+		name:    "no service provisioned for gateway",
+		objects: []runtime.Object{publicService, publicEndpointsOneAddr},
+		modifyConfig: func(c *config.Config) {
+			c.Contour.VisibilityGateways = map[v1alpha1.IngressVisibility]types.NamespacedName{
+				v1alpha1.IngressVisibilityExternalIP: {Namespace: publicNS, Name: "external"},
+			}
+		},
+		ing:     ing("name", "ns", withBasicSpec, withContour),
+		wantErr: fmt.Errorf("no Envoy Services found for Gateway %s/external", publicNS),
 	}}
 
 	for _, test := range tests {
@@ -135,6 +336,9 @@ func TestListProbeTargets(t *testing.T) {
 			}
 
 			cfg := defaultConfig.DeepCopy()
+			if test.modifyConfig != nil {
+				test.modifyConfig(cfg)
+			}
 			ctx := (&testConfigStore{config: cfg}).ToContext(context.Background())
 
 			got, gotErr := l.ListProbeTargets(ctx, test.ing)
@@ -151,6 +355,85 @@ func TestListProbeTargets(t *testing.T) {
 	}
 }
 
+func TestListTCPProbeTargets(t *testing.T) {
+	tl := NewListers([]runtime.Object{
+		publicSecureService,
+		privateService,
+		publicEndpointsOneAddr,
+		privateEndpointsNoAddr,
+	})
+	l := &lister{
+		ServiceLister:   tl.GetK8sServiceLister(),
+		EndpointsLister: tl.GetEndpointsLister(),
+	}
+	ctx := (&testConfigStore{config: defaultConfig}).ToContext(context.Background())
+
+	got, err := l.ListTCPProbeTargets(ctx, ing("name", "ns", withBasicSpec, withContour,
+		withAnnotation(map[string]string{resources.TCPProxyKey: "true"})))
+	if err != nil {
+		t.Fatal("ListTCPProbeTargets() =", err)
+	}
+	want := []status.ProbeTarget{{
+		PodIPs:  sets.NewString("1.2.3.4"),
+		Port:    "443",
+		PodPort: "1234",
+		URLs: []*url.URL{{
+			Scheme: "https",
+			Host:   "example.com",
+		}},
+	}}
+	if !cmp.Equal(want, got) {
+		t.Error("ListTCPProbeTargets (-want, +got) =", cmp.Diff(want, got))
+	}
+}
+
+func TestEnvoyPodNames(t *testing.T) {
+	public := publicEndpointsOneAddr.DeepCopy()
+	public.Subsets[0].Addresses[0].TargetRef = &corev1.ObjectReference{Kind: "Pod", Namespace: publicNS, Name: "envoy-a"}
+	public.Subsets[0].NotReadyAddresses = []corev1.EndpointAddress{{
+		IP:        "1.2.3.5",
+		TargetRef: &corev1.ObjectReference{Kind: "Pod", Namespace: publicNS, Name: "envoy-b"},
+	}, {
+		// Not a pod, e.g. an external address.
+		IP: "1.2.3.6",
+	}}
+	tl := NewListers([]runtime.Object{
+		publicService,
+		privateService,
+		public,
+		privateEndpointsNoAddr,
+	})
+	l := &lister{
+		ServiceLister:   tl.GetK8sServiceLister(),
+		EndpointsLister: tl.GetEndpointsLister(),
+	}
+	ctx := (&testConfigStore{config: defaultConfig}).ToContext(context.Background())
+
+	got, err := l.envoyPodNames(ctx)
+	if err != nil {
+		t.Fatal("envoyPodNames() =", err)
+	}
+	want := map[string]string{
+		"1.2.3.4": publicNS + "/envoy-a",
+		"1.2.3.5": publicNS + "/envoy-b",
+	}
+	if !cmp.Equal(want, got) {
+		t.Error("envoyPodNames (-want, +got) =", cmp.Diff(want, got))
+	}
+}
+
+func withClusterIP(svc *corev1.Service, ip string) *corev1.Service {
+	svc = svc.DeepCopy()
+	svc.Spec.ClusterIP = ip
+	return svc
+}
+
+func withLabels(svc *corev1.Service, l map[string]string) *corev1.Service {
+	svc = svc.DeepCopy()
+	svc.Labels = l
+	return svc
+}
+
 var (
 	publicService = &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
@@ -268,3 +551,79 @@ var (
 		}},
 	}
 )
+
+func TestSamplePods(t *testing.T) {
+	ips := sets.NewString()
+	for i := 0; i < 100; i++ {
+		ips.Insert(fmt.Sprintf("10.0.0.%d", i))
+	}
+	ing := &v1alpha1.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "name", Generation: 1}}
+
+	sample := samplePods(ing, ips, 10)
+	if sample.Len() != 10 || !ips.IsSuperset(sample) {
+		t.Fatalf("samplePods() = %v, wanted 10 of the pods", sample.List())
+	}
+	if again := samplePods(ing, ips, 10); !again.Equal(sample) {
+		t.Errorf("samplePods() = %v, wanted the same sample %v for the same generation", again.List(), sample.List())
+	}
+	ing.Generation++
+	if next := samplePods(ing, ips, 10); next.Equal(sample) {
+		t.Errorf("samplePods() = %v for the next generation, wanted a different sample", next.List())
+	}
+	if all := samplePods(ing, ips, 100); !all.Equal(ips) {
+		t.Errorf("samplePods() = %v, wanted every pod", all.List())
+	}
+}
+
+func TestProbeHost(t *testing.T) {
+	if got := probeHost("example.com"); got != "example.com" {
+		t.Errorf("probeHost(example.com) = %s, wanted it unchanged", got)
+	}
+	got := probeHost("*.example.com")
+	if !strings.HasSuffix(got, ".example.com") || strings.Contains(got, "*") {
+		t.Errorf("probeHost(*.example.com) = %s, wanted a concrete subdomain of example.com", got)
+	}
+	if again := probeHost("*.example.com"); again != got {
+		t.Errorf("probeHost(*.example.com) = %s, then %s, wanted the same host", got, again)
+	}
+}
+
+func TestIsDiscoveredService(t *testing.T) {
+	cfg := &config.Contour{
+		VisibilitySelectors: map[v1alpha1.IngressVisibility]string{
+			v1alpha1.IngressVisibilityExternalIP: "app=envoy",
+		},
+	}
+	envoy := &corev1.Service{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "contour-external",
+		Name:      "envoy",
+		Labels:    map[string]string{"app": "envoy"},
+	}}
+	other := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "goo"}}
+
+	for _, test := range []struct {
+		name string
+		obj  interface{}
+		want bool
+	}{{
+		name: "matching service",
+		obj:  envoy,
+		want: true,
+	}, {
+		name: "other service",
+		obj:  other,
+	}, {
+		name: "tombstone of a matching service",
+		obj:  cache.DeletedFinalStateUnknown{Key: "contour-external/envoy", Obj: envoy},
+		want: true,
+	}, {
+		name: "tombstone of another service",
+		obj:  cache.DeletedFinalStateUnknown{Key: "ns/goo", Obj: other},
+	}} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := isDiscoveredService(cfg, test.obj); got != test.want {
+				t.Errorf("isDiscoveredService() = %v, wanted %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/system"
+)
+
+const (
+	// reconcilerComponent is the config-logging component (as in
+	// loglevel.contour-reconciler) setting the level of our reconciler.
+	reconcilerComponent = "contour-reconciler"
+
+	// proberComponent is the config-logging component setting the level of
+	// our status and endpoint probing.
+	proberComponent = "contour-prober"
+)
+
+// componentLogger returns a logger whose level follows the given component's
+// loglevel in config-logging, so that e.g. probe debug logs can be enabled on
+// a stuck cluster without restarting us.  Like sharedmain, we only follow
+// config-logging when it exists, and otherwise log as the controller does.
+func componentLogger(ctx context.Context, cmw configmap.Watcher, component string) *zap.SugaredLogger {
+	fallback := logging.FromContext(ctx).Named(component)
+
+	cm, err := kubeclient.Get(ctx).CoreV1().ConfigMaps(system.Namespace()).Get(ctx, logging.ConfigMapName(), metav1.GetOptions{})
+	if apierrs.IsNotFound(err) {
+		return fallback
+	} else if err != nil {
+		fallback.Warnw("Unable to read "+logging.ConfigMapName()+", the log level of "+component+" won't be updated", zap.Error(err))
+		return fallback
+	}
+	cfg, err := logging.NewConfigFromConfigMap(cm)
+	if err != nil {
+		fallback.Warnw("Unable to parse "+logging.ConfigMapName()+", the log level of "+component+" won't be updated", zap.Error(err))
+		return fallback
+	}
+
+	logger, level := logging.NewLoggerFromConfig(cfg, component)
+	cmw.Watch(logging.ConfigMapName(), logging.UpdateLevelFromConfigMap(logger, level, component))
+	return logger
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/system"
+
+	. "knative.dev/pkg/reconciler/testing"
+)
+
+func TestComponentLogger(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+	loggingConfig := func(level string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace(),
+				Name:      logging.ConfigMapName(),
+			},
+			Data: map[string]string{
+				"zap-logger-config":           `{"level": "info", "encoding": "json", "outputPaths": ["stdout"]}`,
+				"loglevel." + proberComponent: level,
+			},
+		}
+	}
+
+	// Without config-logging we log as the controller does.
+	if logger := componentLogger(ctx, configmap.NewStaticWatcher(), proberComponent); logger == nil {
+		t.Fatal("componentLogger() = nil")
+	}
+
+	cm := loggingConfig("info")
+	if _, err := fakekubeclient.Get(ctx).CoreV1().ConfigMaps(cm.Namespace).Create(ctx, cm, metav1.CreateOptions{}); err != nil {
+		t.Fatal("Create() =", err)
+	}
+	cmw := &configmap.ManualWatcher{Namespace: system.Namespace()}
+	logger := componentLogger(ctx, cmw, proberComponent)
+	if logger.Desugar().Core().Enabled(zapcore.DebugLevel) {
+		t.Error("Debug logs are enabled, wanted them disabled at info level")
+	}
+
+	cmw.OnChange(loggingConfig("debug"))
+	if !logger.Desugar().Core().Enabled(zapcore.DebugLevel) {
+		t.Error("Debug logs are disabled after switching to debug level")
+	}
+}
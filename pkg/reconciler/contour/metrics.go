@@ -0,0 +1,217 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+)
+
+var programmingLatencyM = stats.Float64(
+	"ingress_programming_latency",
+	"The time from programming the HTTPProxies of an Ingress generation until Envoy serves it",
+	stats.UnitMilliseconds)
+
+var driftRepairsM = stats.Int64(
+	"httpproxy_drift_repairs",
+	"The number of HTTPProxies we recreated or rewrote the way we already wrote them, because someone else deleted or edited them",
+	stats.UnitDimensionless)
+
+var (
+	informerKey = tag.MustNewKey("informer")
+	kindKey     = tag.MustNewKey("kind")
+
+	informerStalenessM = stats.Float64(
+		"informer_staleness",
+		"How long an informer has been unable to list or watch the API server",
+		stats.UnitSeconds)
+
+	informerWatchErrorsM = stats.Int64(
+		"informer_watch_errors",
+		"The number of times an informer failed to list or watch the API server",
+		stats.UnitDimensionless)
+
+	trackedObjectsM = stats.Int64(
+		"tracked_objects",
+		"The number of objects referenced by Ingresses whose changes we track",
+		stats.UnitDimensionless)
+
+	staleProbeResourcesM = stats.Int64(
+		"stale_probe_resources",
+		"The number of endpoint probe Ingresses and HTTPProxies older than the stale-probe-age of config-contour",
+		stats.UnitDimensionless)
+)
+
+var (
+	reasonKey = tag.MustNewKey("reason")
+
+	invalidProxiesM = stats.Int64(
+		"invalid_httpproxies",
+		"The number of HTTPProxies we programmed which Contour rejected",
+		stats.UnitDimensionless)
+)
+
+var (
+	stageKey = tag.MustNewKey("stage")
+
+	rolloutsPendingM = stats.Int64(
+		"ingress_rollouts_pending",
+		"The number of Ingresses whose current generation didn't reach each stage of its rollout yet",
+		stats.UnitDimensionless)
+)
+
+var (
+	resultKey = tag.MustNewKey("result")
+
+	envoyProbeLatencyM = stats.Float64(
+		"envoy_probe_latency",
+		"The time a round of our probes of an Envoy pod took, by whether it served the probed version",
+		stats.UnitMilliseconds)
+)
+
+var (
+	failureClassKey = tag.MustNewKey("failure_class")
+
+	httpProxyWriteFailuresM = stats.Int64(
+		"httpproxy_write_failures",
+		"The number of HTTPProxy writes the API server failed, by whether we retry them",
+		stats.UnitDimensionless)
+)
+
+func init() {
+	if err := view.Register(&view.View{
+		Description: programmingLatencyM.Description(),
+		Measure:     programmingLatencyM,
+		Aggregation: view.Distribution(100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000, 120000, 300000),
+	}, &view.View{
+		Description: driftRepairsM.Description(),
+		Measure:     driftRepairsM,
+		Aggregation: view.Sum(),
+	}, &view.View{
+		Description: informerStalenessM.Description(),
+		Measure:     informerStalenessM,
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{informerKey},
+	}, &view.View{
+		Description: informerWatchErrorsM.Description(),
+		Measure:     informerWatchErrorsM,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{informerKey},
+	}, &view.View{
+		Description: trackedObjectsM.Description(),
+		Measure:     trackedObjectsM,
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{kindKey},
+	}, &view.View{
+		Description: staleProbeResourcesM.Description(),
+		Measure:     staleProbeResourcesM,
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{kindKey},
+	}, &view.View{
+		Description: invalidProxiesM.Description(),
+		Measure:     invalidProxiesM,
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{reasonKey},
+	}, &view.View{
+		Description: httpProxyWriteFailuresM.Description(),
+		Measure:     httpProxyWriteFailuresM,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{failureClassKey},
+	}, &view.View{
+		Description: envoyProbeLatencyM.Description(),
+		Measure:     envoyProbeLatencyM,
+		Aggregation: view.Distribution(5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000),
+		TagKeys:     []tag.Key{resultKey},
+	}, &view.View{
+		Description: rolloutsPendingM.Description(),
+		Measure:     rolloutsPendingM,
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{stageKey},
+	}); err != nil {
+		panic(err)
+	}
+}
+
+// programmingTracker remembers when we programmed the HTTPProxies of each
+// Ingress' current generation, so we can tell how long it took Envoy to serve
+// it.  As this is kept in memory, generations programmed by a previous leader
+// aren't measured.
+type programmingTracker struct {
+	mu      sync.Mutex
+	started map[types.NamespacedName]generationStart
+}
+
+type generationStart struct {
+	generation int64
+	at         time.Time
+}
+
+func newProgrammingTracker() *programmingTracker {
+	return &programmingTracker{
+		started: make(map[types.NamespacedName]generationStart),
+	}
+}
+
+// programmed records that we wrote the HTTPProxies of the Ingress' current
+// generation, unless we already did so earlier.
+func (t *programmingTracker) programmed(ing *v1alpha1.Ingress) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := types.NamespacedName{Namespace: ing.Namespace, Name: ing.Name}
+	if start, ok := t.started[key]; ok && start.generation == ing.Generation {
+		return
+	}
+	t.started[key] = generationStart{generation: ing.Generation, at: time.Now()}
+}
+
+// ready returns how long ago we programmed the Ingress' current generation,
+// and stops tracking it.  It returns false when we didn't see it programmed.
+func (t *programmingTracker) ready(ing *v1alpha1.Ingress) (time.Duration, bool) {
+	if t == nil {
+		return 0, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := types.NamespacedName{Namespace: ing.Namespace, Name: ing.Name}
+	start, ok := t.started[key]
+	if !ok || start.generation != ing.Generation {
+		return 0, false
+	}
+	delete(t.started, key)
+	return time.Since(start.at), true
+}
+
+// forget stops tracking the Ingress, e.g. once it is deleted.
+func (t *programmingTracker) forget(key types.NamespacedName) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.started, key)
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestProgrammingTracker(t *testing.T) {
+	tracker := newProgrammingTracker()
+	gen1 := ing("name", "ns", withGeneration(1))
+	gen2 := ing("name", "ns", withGeneration(2))
+
+	if _, ok := tracker.ready(gen1); ok {
+		t.Error("ready() = true for a generation we never programmed")
+	}
+
+	tracker.programmed(gen1)
+	start := tracker.started[types.NamespacedName{Namespace: "ns", Name: "name"}].at
+	// Programming the same generation again keeps the original start.
+	tracker.programmed(gen1)
+	if got := tracker.started[types.NamespacedName{Namespace: "ns", Name: "name"}].at; !got.Equal(start) {
+		t.Errorf("start = %v, want: %v", got, start)
+	}
+
+	if _, ok := tracker.ready(gen2); ok {
+		t.Error("ready() = true for a generation other than the one we programmed")
+	}
+	if latency, ok := tracker.ready(gen1); !ok || latency < 0 {
+		t.Errorf("ready() = %v, %v, want a latency", latency, ok)
+	}
+	// We only measure each generation once.
+	if _, ok := tracker.ready(gen1); ok {
+		t.Error("ready() = true for a generation we already measured")
+	}
+
+	tracker.programmed(gen2)
+	tracker.forget(types.NamespacedName{Namespace: "ns", Name: "name"})
+	if _, ok := tracker.ready(gen2); ok {
+		t.Error("ready() = true for a forgotten Ingress")
+	}
+
+	// A nil tracker is a no-op.
+	var nilTracker *programmingTracker
+	nilTracker.programmed(gen1)
+	if _, ok := nilTracker.ready(gen1); ok {
+		t.Error("ready() = true for a nil tracker")
+	}
+}
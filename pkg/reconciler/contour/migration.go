@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"sync"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+	network "knative.dev/networking/pkg"
+)
+
+// networkConfigChanges tells apart the changes to config-network that alter
+// what we program or probe from those that don't.  Serving applies e.g. a
+// new domain template by updating the hosts of the Ingresses it concerns, so
+// we'd only resync every Ingress for nothing.
+type networkConfigChanges struct {
+	mu   sync.Mutex
+	last *network.Config
+}
+
+// relevant returns whether the configuration changed in a way that concerns
+// us since the last time it was called.
+func (c *networkConfigChanges) relevant(cfg *network.Config) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	last := c.last
+	c.last = cfg
+	return last == nil || last.MeshCompatibilityMode != cfg.MeshCompatibilityMode
+}
+
+// retiredHosts splits the HTTPProxies of older generations into those we can
+// delete right away and those whose host the current generation no longer
+// programs.  When e.g. a domain migration moves an Ingress to new hosts, we
+// keep serving the old ones until the new ones were probed, instead of
+// leaving a gap, along with the HTTPProxies they include.  The others share
+// a host with the current generation, which Contour would reject as a
+// duplicate.
+//
+// Hosts are told apart by their ingress class as well, as each class is
+// served by its own Contour.  When a host changes visibility, e.g. as its
+// Service is made public, we so keep serving it on the old visibility until
+// the new one was probed, instead of dropping its in-flight traffic.
+func retiredHosts(leftovers, desired []*contourv1.HTTPProxy) (stale, retired []*contourv1.HTTPProxy) {
+	hosts := sets.NewString()
+	for _, proxy := range desired {
+		if proxy.Spec.VirtualHost != nil {
+			hosts.Insert(classHost(proxy))
+		}
+	}
+	included := sets.NewString()
+	for _, proxy := range leftovers {
+		if vh := proxy.Spec.VirtualHost; vh != nil && !hosts.Has(classHost(proxy)) {
+			for _, include := range proxy.Spec.Includes {
+				included.Insert(include.Name)
+			}
+		}
+	}
+	for _, proxy := range leftovers {
+		if vh := proxy.Spec.VirtualHost; (vh != nil && !hosts.Has(classHost(proxy))) || (vh == nil && included.Has(proxy.Name)) {
+			retired = append(retired, proxy)
+		} else {
+			stale = append(stale, proxy)
+		}
+	}
+	return stale, retired
+}
+
+// classHost returns the ingress class and host of the virtual host of the
+// HTTPProxy, which identify it among the ones Contour serves.
+func classHost(proxy *contourv1.HTTPProxy) string {
+	return proxy.Labels[resources.ClassKey] + "/" + proxy.Spec.VirtualHost.Fqdn
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"testing"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+	network "knative.dev/networking/pkg"
+)
+
+func TestNetworkConfigChanges(t *testing.T) {
+	c := &networkConfigChanges{}
+
+	if !c.relevant(&network.Config{DomainTemplate: "{{.Name}}.{{.Namespace}}.{{.Domain}}"}) {
+		t.Error("relevant() = false for the first configuration, wanted true")
+	}
+	if c.relevant(&network.Config{DomainTemplate: "{{.Name}}-{{.Namespace}}.{{.Domain}}"}) {
+		t.Error("relevant() = true for a new domain template, wanted false")
+	}
+	if !c.relevant(&network.Config{MeshCompatibilityMode: network.MeshCompatibilityModeEnabled}) {
+		t.Error("relevant() = false for a new mesh compatibility mode, wanted true")
+	}
+}
+
+func TestRetiredHosts(t *testing.T) {
+	proxy := func(name, host string) *contourv1.HTTPProxy {
+		p := &contourv1.HTTPProxy{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if host != "" {
+			p.Spec.VirtualHost = &contourv1.VirtualHost{Fqdn: host}
+		}
+		return p
+	}
+
+	old := proxy("old-domain", "foo.old.example.com")
+	old.Spec.Includes = []contourv1.Include{{Name: "old-include"}}
+
+	stale, retired := retiredHosts(
+		[]*contourv1.HTTPProxy{
+			proxy("kept", "foo.example.com"),
+			old,
+			proxy("include", ""),
+			proxy("old-include", ""),
+		},
+		[]*contourv1.HTTPProxy{
+			proxy("new-kept", "foo.example.com"),
+			proxy("new-domain", "foo.new.example.com"),
+		})
+
+	names := func(proxies []*contourv1.HTTPProxy) (names []string) {
+		for _, p := range proxies {
+			names = append(names, p.Name)
+		}
+		return names
+	}
+	if got := names(stale); len(got) != 2 || got[0] != "kept" || got[1] != "include" {
+		t.Errorf("stale = %v, wanted [kept include]", got)
+	}
+	if got := names(retired); len(got) != 2 || got[0] != "old-domain" || got[1] != "old-include" {
+		t.Errorf("retired = %v, wanted [old-domain old-include]", got)
+	}
+}
+
+func TestRetiredHostsVisibility(t *testing.T) {
+	proxy := func(name, class string) *contourv1.HTTPProxy {
+		return &contourv1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{resources.ClassKey: class},
+			},
+			Spec: contourv1.HTTPProxySpec{
+				VirtualHost: &contourv1.VirtualHost{Fqdn: "foo.example.com"},
+			},
+		}
+	}
+
+	// The host was made public: serve it internally until probed.
+	stale, retired := retiredHosts(
+		[]*contourv1.HTTPProxy{proxy("internal", "contour-internal")},
+		[]*contourv1.HTTPProxy{proxy("external", "contour-external")})
+	if len(stale) != 0 || len(retired) != 1 || retired[0].Name != "internal" {
+		t.Errorf("retiredHosts() = %v, %v, wanted the internal proxy retired", stale, retired)
+	}
+
+	// Unchanged visibility: Contour would reject the duplicate.
+	stale, retired = retiredHosts(
+		[]*contourv1.HTTPProxy{proxy("old", "contour-external")},
+		[]*contourv1.HTTPProxy{proxy("new", "contour-external")})
+	if len(retired) != 0 || len(stale) != 1 || stale[0].Name != "old" {
+		t.Errorf("retiredHosts() = %v, %v, wanted the old proxy stale", stale, retired)
+	}
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/labels"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+)
+
+// namespaceVisibility returns the visibility the labels of the namespace
+// pin its Ingresses to, or empty when they keep the visibility of their
+// rules.  ClusterLocal wins over ExternalIP, so that a namespace matching
+// both is never exposed externally.
+func namespaceVisibility(ctx context.Context, namespaceLister corev1listers.NamespaceLister, namespace string) (v1alpha1.IngressVisibility, error) {
+	selectors := config.FromContext(ctx).Contour.NamespaceVisibility
+	if len(selectors) == 0 {
+		return "", nil
+	}
+	ns, err := namespaceLister.Get(namespace)
+	if err != nil {
+		return "", fmt.Errorf("failed to get namespace %s: %w", namespace, err)
+	}
+	for _, vis := range []v1alpha1.IngressVisibility{
+		v1alpha1.IngressVisibilityClusterLocal,
+		v1alpha1.IngressVisibilityExternalIP,
+	} {
+		raw, ok := selectors[vis]
+		if !ok {
+			continue
+		}
+		selector, err := labels.Parse(raw)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse selector %q: %w", raw, err)
+		}
+		if selector.Matches(labels.Set(ns.Labels)) {
+			return vis, nil
+		}
+	}
+	return "", nil
+}
+
+// pinVisibility sets the visibility of every rule of the Ingress.
+func pinVisibility(ing *v1alpha1.Ingress, vis v1alpha1.IngressVisibility) {
+	for i := range ing.Spec.Rules {
+		ing.Spec.Rules[i].Visibility = vis
+	}
+}
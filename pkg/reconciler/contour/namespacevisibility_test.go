@@ -0,0 +1,120 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+
+	. "knative.dev/net-contour/pkg/reconciler/testing"
+)
+
+func TestNamespaceVisibility(t *testing.T) {
+	namespace := func(name string, labels map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	listers := NewListers([]runtime.Object{
+		namespace("internal", map[string]string{"team": "internal"}),
+		namespace("public", map[string]string{"exposure": "public"}),
+		namespace("both", map[string]string{"team": "internal", "exposure": "public"}),
+		namespace("other", nil),
+	})
+
+	tests := []struct {
+		name      string
+		selectors map[v1alpha1.IngressVisibility]string
+		namespace string
+		want      v1alpha1.IngressVisibility
+		wantErr   bool
+	}{{
+		name:      "not configured",
+		namespace: "internal",
+	}, {
+		name: "cluster-local namespace",
+		selectors: map[v1alpha1.IngressVisibility]string{
+			v1alpha1.IngressVisibilityClusterLocal: "team=internal",
+		},
+		namespace: "internal",
+		want:      v1alpha1.IngressVisibilityClusterLocal,
+	}, {
+		name: "external namespace",
+		selectors: map[v1alpha1.IngressVisibility]string{
+			v1alpha1.IngressVisibilityClusterLocal: "team=internal",
+			v1alpha1.IngressVisibilityExternalIP:   "exposure=public",
+		},
+		namespace: "public",
+		want:      v1alpha1.IngressVisibilityExternalIP,
+	}, {
+		name: "cluster-local wins",
+		selectors: map[v1alpha1.IngressVisibility]string{
+			v1alpha1.IngressVisibilityClusterLocal: "team=internal",
+			v1alpha1.IngressVisibilityExternalIP:   "exposure=public",
+		},
+		namespace: "both",
+		want:      v1alpha1.IngressVisibilityClusterLocal,
+	}, {
+		name: "unmatched namespace",
+		selectors: map[v1alpha1.IngressVisibility]string{
+			v1alpha1.IngressVisibilityClusterLocal: "team=internal",
+		},
+		namespace: "other",
+	}, {
+		name: "missing namespace",
+		selectors: map[v1alpha1.IngressVisibility]string{
+			v1alpha1.IngressVisibilityClusterLocal: "team=internal",
+		},
+		namespace: "missing",
+		wantErr:   true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := defaultConfig.DeepCopy()
+			cfg.Contour.NamespaceVisibility = test.selectors
+			ctx := (&testConfigStore{config: cfg}).ToContext(context.Background())
+
+			got, err := namespaceVisibility(ctx, listers.GetNamespaceLister(), test.namespace)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("namespaceVisibility() = %v, wanted error %v", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("namespaceVisibility() = %q, wanted %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPinVisibility(t *testing.T) {
+	ingress := ing("name", "ns", withBasicSpec, withContour)
+	ingress.Spec.Rules = append(ingress.Spec.Rules, v1alpha1.IngressRule{
+		Hosts:      []string{"foo.ns.svc.cluster.local"},
+		Visibility: v1alpha1.IngressVisibilityClusterLocal,
+	})
+
+	pinVisibility(ingress, v1alpha1.IngressVisibilityClusterLocal)
+
+	for _, rule := range ingress.Spec.Rules {
+		if rule.Visibility != v1alpha1.IngressVisibilityClusterLocal {
+			t.Errorf("Visibility of %v = %s, wanted %s", rule.Hosts, rule.Visibility, v1alpha1.IngressVisibilityClusterLocal)
+		}
+	}
+}
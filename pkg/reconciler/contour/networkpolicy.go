@@ -0,0 +1,126 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/pkg/kmeta"
+	"knative.dev/pkg/logging"
+)
+
+// reconcileNetworkPolicy makes sure the NetworkPolicy of the Ingress allows
+// the Envoys to reach its backends in namespaces denying ingress traffic by
+// default.  Its policy selects every pod of the namespace, which would
+// isolate them anywhere else, so it is deleted once the feature is disabled
+// or the namespace stops denying traffic by default.
+func (r *Reconciler) reconcileNetworkPolicy(ctx context.Context, ing *v1alpha1.Ingress, services []*corev1.Service) error {
+	name := resources.NetworkPolicyName(ing)
+	if !config.FromContext(ctx).Contour.GenerateNetworkPolicies {
+		return r.deleteNetworkPolicy(ctx, ing, name)
+	}
+	policies, err := r.networkPolicyLister.NetworkPolicies(ing.Namespace).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	if !deniesIngressByDefault(policies) {
+		return r.deleteNetworkPolicy(ctx, ing, name)
+	}
+
+	visibilityKeys, err := resolveVisibilityKeys(ctx, r.serviceLister)
+	if err != nil {
+		return err
+	}
+	envoyNamespaces := sets.NewString()
+	for _, keys := range visibilityKeys {
+		for key := range keys {
+			if ns, _, err := cache.SplitMetaNamespaceKey(key); err == nil {
+				envoyNamespaces.Insert(ns)
+			}
+		}
+	}
+
+	desired := resources.MakeNetworkPolicy(ing, services, envoyNamespaces)
+	existing, err := r.networkPolicyLister.NetworkPolicies(desired.Namespace).Get(desired.Name)
+	if apierrs.IsNotFound(err) {
+		logging.FromContext(ctx).Infof("Creating NetworkPolicy %s/%s.", desired.Namespace, desired.Name)
+		_, err = r.kubeClient.NetworkingV1().NetworkPolicies(desired.Namespace).Create(ctx, desired, metav1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return err
+	}
+	// Keep the labels others put on the policy, but add ours to those we
+	// created before labeling them as managed.
+	labels := kmeta.UnionMaps(existing.Labels, desired.Labels)
+	if equality.Semantic.DeepEqual(existing.Spec, desired.Spec) && resources.StringMapsEqual(existing.Labels, labels) {
+		return nil
+	}
+	update := existing.DeepCopy()
+	update.Spec = desired.Spec
+	update.Labels = labels
+	logging.FromContext(ctx).Infof("Updating NetworkPolicy %s/%s.", desired.Namespace, desired.Name)
+	_, err = r.kubeClient.NetworkingV1().NetworkPolicies(desired.Namespace).Update(ctx, update, metav1.UpdateOptions{})
+	return err
+}
+
+// deleteNetworkPolicy deletes the NetworkPolicy we generated for the Ingress,
+// if any.
+func (r *Reconciler) deleteNetworkPolicy(ctx context.Context, ing *v1alpha1.Ingress, name string) error {
+	existing, err := r.networkPolicyLister.NetworkPolicies(ing.Namespace).Get(name)
+	if apierrs.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(existing, ing) {
+		return nil
+	}
+	logging.FromContext(ctx).Infof("Deleting NetworkPolicy %s/%s.", existing.Namespace, existing.Name)
+	err = r.kubeClient.NetworkingV1().NetworkPolicies(existing.Namespace).Delete(ctx, existing.Name, metav1.DeleteOptions{})
+	if apierrs.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// deniesIngressByDefault returns whether one of the policies isolates every
+// pod of their namespace from ingress traffic without allowing any.
+func deniesIngressByDefault(policies []*networkingv1.NetworkPolicy) bool {
+	for _, policy := range policies {
+		selector := policy.Spec.PodSelector
+		if len(selector.MatchLabels) != 0 || len(selector.MatchExpressions) != 0 || len(policy.Spec.Ingress) != 0 {
+			continue
+		}
+		for _, t := range policy.Spec.PolicyTypes {
+			if t == networkingv1.PolicyTypeIngress {
+				return true
+			}
+		}
+	}
+	return false
+}
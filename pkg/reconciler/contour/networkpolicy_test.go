@@ -0,0 +1,167 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"knative.dev/net-contour/pkg/ownership"
+	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
+	"knative.dev/pkg/kmeta"
+
+	. "knative.dev/net-contour/pkg/reconciler/testing"
+	. "knative.dev/pkg/reconciler/testing"
+)
+
+func TestReconcileNetworkPolicy(t *testing.T) {
+	ingress := ing("name", "ns", withBasicSpec, withContour)
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "goo"},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8012)}},
+		},
+	}
+	name := kmeta.ChildName(ingress.Name, "-contour")
+	outdatedPort := intstr.FromInt(9090)
+	denyAll := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "deny-all"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		},
+	}
+	ours := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "ns",
+			Name:            name,
+			Labels:          map[string]string{"team": "a"},
+			OwnerReferences: []metav1.OwnerReference{*kmeta.NewControllerRef(ingress)},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				Ports: []networkingv1.NetworkPolicyPort{{Port: &outdatedPort}},
+			}},
+		},
+	}
+	theirs := ours.DeepCopy()
+	theirs.OwnerReferences = nil
+
+	tests := []struct {
+		name     string
+		disabled bool
+		existing []*networkingv1.NetworkPolicy
+		// want is whether the policy exists afterwards, and wantOurs
+		// whether it is the one we generate.
+		want     bool
+		wantOurs bool
+	}{{
+		name:     "creates the policy",
+		existing: []*networkingv1.NetworkPolicy{denyAll},
+		want:     true,
+		wantOurs: true,
+	}, {
+		name:     "updates the policy",
+		existing: []*networkingv1.NetworkPolicy{denyAll, ours},
+		want:     true,
+		wantOurs: true,
+	}, {
+		name: "no policy without a default deny",
+	}, {
+		name:     "deletes the policy without a default deny",
+		existing: []*networkingv1.NetworkPolicy{ours},
+	}, {
+		name:     "deletes the policy when disabled",
+		disabled: true,
+		existing: []*networkingv1.NetworkPolicy{denyAll, ours},
+	}, {
+		name:     "keeps the policies of others when disabled",
+		disabled: true,
+		existing: []*networkingv1.NetworkPolicy{denyAll, theirs},
+		want:     true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, _ := SetupFakeContext(t)
+			cfg := defaultConfig.DeepCopy()
+			cfg.Contour.GenerateNetworkPolicies = !test.disabled
+			ctx = (&testConfigStore{config: cfg}).ToContext(ctx)
+
+			var objs []runtime.Object
+			for _, policy := range test.existing {
+				objs = append(objs, policy)
+				if _, err := fakekubeclient.Get(ctx).NetworkingV1().NetworkPolicies("ns").Create(
+					ctx, policy, metav1.CreateOptions{}); err != nil {
+					t.Fatal("Create() =", err)
+				}
+			}
+			listers := NewListers(objs)
+			r := &Reconciler{
+				serviceLister:       listers.GetK8sServiceLister(),
+				kubeClient:          fakekubeclient.Get(ctx),
+				networkPolicyLister: listers.GetNetworkPolicyLister(),
+			}
+
+			if err := r.reconcileNetworkPolicy(ctx, ingress, []*corev1.Service{svc}); err != nil {
+				t.Fatal("reconcileNetworkPolicy() =", err)
+			}
+
+			got, err := fakekubeclient.Get(ctx).NetworkingV1().NetworkPolicies("ns").Get(ctx, name, metav1.GetOptions{})
+			if apierrs.IsNotFound(err) {
+				if test.want {
+					t.Fatal("The NetworkPolicy doesn't exist, wanted it kept")
+				}
+				return
+			} else if err != nil {
+				t.Fatal("Get() =", err)
+			}
+			if !test.want {
+				t.Fatalf("NetworkPolicy = %v, wanted it deleted", got)
+			}
+			if !test.wantOurs {
+				if !cmp.Equal(theirs.Spec, got.Spec) {
+					t.Errorf("Spec (-want, +got) = %s", cmp.Diff(theirs.Spec, got.Spec))
+				}
+				return
+			}
+			rules := got.Spec.Ingress
+			if len(rules) != 1 || len(rules[0].From) != 1 || len(rules[0].Ports) != 1 {
+				t.Fatalf("Ingress = %v, wanted one rule from the Envoys to one port", rules)
+			}
+			if got, want := rules[0].From[0].NamespaceSelector.MatchExpressions[0].Values, []string{privateNS, publicNS}; !cmp.Equal(got, want) {
+				t.Errorf("Envoy namespaces = %v, wanted %v", got, want)
+			}
+			if got, want := rules[0].Ports[0].Port.IntValue(), 8012; got != want {
+				t.Errorf("Port = %d, wanted %d", got, want)
+			}
+			if !ownership.Managed().Matches(labels.Set(got.Labels)) || !ownership.Parent(ingress.Name).Matches(labels.Set(got.Labels)) {
+				t.Errorf("Labels = %v, wanted the ownership labels of %s", got.Labels, ingress.Name)
+			}
+			if len(test.existing) > 1 && got.Labels["team"] != "a" {
+				t.Errorf("Labels = %v, wanted the existing labels kept", got.Labels)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+
+	contourclientset "knative.dev/net-contour/pkg/client/clientset/versioned"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	ingressclientset "knative.dev/networking/pkg/client/clientset/versioned"
+	"knative.dev/networking/pkg/status"
+	"knative.dev/pkg/logging"
+)
+
+// Prober probes whether the Envoys serve the current version of Ingresses,
+// which status.Prober does by default.
+type Prober interface {
+	status.Manager
+
+	// CancelIngressProbing and CancelPodProbing stop probing an Ingress, or
+	// an Envoy pod, that was deleted or must be probed afresh.
+	CancelIngressProbing(obj interface{})
+	CancelPodProbing(obj interface{})
+}
+
+// Options customize the controller NewControllerWithOptions returns, so that
+// distributions can compile net-contour into a combined networking
+// controller with their own wiring.  The zero Options return the controller
+// NewController does.
+type Options struct {
+	// ClassName is the ingress class of the Ingresses we reconcile and claim,
+	// ContourIngressClassName when empty.
+	ClassName string
+
+	// IngressClient and ContourClient, when set, replace the clients
+	// injected into the context.
+	IngressClient ingressclientset.Interface
+	ContourClient contourclientset.Interface
+
+	// Filter, when set, further restricts the Ingresses we reconcile, e.g.
+	// to the namespaces of a tenant.
+	Filter func(interface{}) bool
+
+	// NewProber, when set, returns the Prober we use instead of starting a
+	// status.Prober.  It must call ready with the Ingresses that became
+	// ready, and stop probing once the context is done.
+	NewProber func(ctx context.Context, targets status.ProbeTargetLister, ready func(*v1alpha1.Ingress)) Prober
+
+	// SkipStatusUpdates makes us program the HTTPProxies of Ingresses but
+	// leave their status to an external component, e.g. the coordinator of
+	// a multi-controller setup.  We don't probe the Envoys then, neither for
+	// readiness nor for Endpoints, and program every generation right away.
+	SkipStatusUpdates bool
+}
+
+// className returns the ingress class of the Ingresses we reconcile.
+func (o Options) className() string {
+	if o.ClassName != "" {
+		return o.ClassName
+	}
+	return ContourIngressClassName
+}
+
+// filter returns whether the Filter lets us reconcile the object.
+func (o Options) filter(obj interface{}) bool {
+	return o.Filter == nil || o.Filter(obj)
+}
+
+// newProber returns the Prober of the options, or starts a status.Prober
+// logging with the logger of the context.  Without status updates we don't
+// probe at all.
+func (o Options) newProber(ctx context.Context, targets status.ProbeTargetLister, ready func(*v1alpha1.Ingress)) Prober {
+	if o.SkipStatusUpdates {
+		return noopProber{}
+	}
+	if o.NewProber != nil {
+		return o.NewProber(ctx, targets, ready)
+	}
+	prober := status.NewProber(logging.FromContext(ctx), targets, ready)
+	prober.Start(ctx.Done())
+	return prober
+}
+
+// noopProber is the Prober of controllers that skip status updates, which
+// never probes and never considers Ingresses ready.
+type noopProber struct{}
+
+var _ Prober = noopProber{}
+
+// IsReady implements status.Manager
+func (noopProber) IsReady(context.Context, *v1alpha1.Ingress) (bool, error) {
+	return false, nil
+}
+
+// CancelIngressProbing implements Prober
+func (noopProber) CancelIngressProbing(interface{}) {}
+
+// CancelPodProbing implements Prober
+func (noopProber) CancelPodProbing(interface{}) {}
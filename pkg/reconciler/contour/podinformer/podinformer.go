@@ -0,0 +1,130 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package podinformer provides an injected Pod informer that only caches the
+// parts of Pods we look at, instead of their whole spec and status.  On large
+// clusters the containers of the Pods we don't care about otherwise dominate
+// the memory of the controller.
+package podinformer
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	v1 "k8s.io/client-go/informers/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/injection"
+	"knative.dev/pkg/logging"
+)
+
+func init() {
+	// The informer only needs a kube client, which the fake injection
+	// provides as well.
+	injection.Default.RegisterInformer(withInformer)
+	injection.Fake.RegisterInformer(withInformer)
+}
+
+// Key is used for associating the Informer inside the context.Context.
+type Key struct{}
+
+type informer struct {
+	informer cache.SharedIndexInformer
+}
+
+var _ v1.PodInformer = (*informer)(nil)
+
+func (i *informer) Informer() cache.SharedIndexInformer {
+	return i.informer
+}
+
+func (i *informer) Lister() corev1listers.PodLister {
+	return corev1listers.NewPodLister(i.informer.GetIndexer())
+}
+
+func withInformer(ctx context.Context) (context.Context, controller.Informer) {
+	client := kubeclient.Get(ctx)
+	inf := &informer{informer: cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+				pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, opts)
+				if err != nil {
+					return nil, err
+				}
+				for i := range pods.Items {
+					pods.Items[i] = *Slim(&pods.Items[i])
+				}
+				return pods, nil
+			},
+			WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+				w, err := client.CoreV1().Pods(metav1.NamespaceAll).Watch(ctx, opts)
+				if err != nil {
+					return nil, err
+				}
+				return watch.Filter(w, func(e watch.Event) (watch.Event, bool) {
+					if pod, ok := e.Object.(*corev1.Pod); ok {
+						e.Object = Slim(pod)
+					}
+					return e, true
+				}), nil
+			},
+		},
+		&corev1.Pod{},
+		controller.GetResyncPeriod(ctx),
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)}
+	return context.WithValue(ctx, Key{}, inf), inf.Informer()
+}
+
+// Get extracts the typed informer from the context.
+func Get(ctx context.Context) v1.PodInformer {
+	untyped := ctx.Value(Key{})
+	if untyped == nil {
+		logging.FromContext(ctx).Panic(
+			"Unable to fetch the slim PodInformer from context.")
+	}
+	return untyped.(v1.PodInformer)
+}
+
+// Slim returns the parts of the Pod that we look at: its identity, labels
+// and owners, and its IPs, phase and conditions.
+func Slim(pod *corev1.Pod) *corev1.Pod {
+	return &corev1.Pod{
+		TypeMeta: pod.TypeMeta,
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              pod.Name,
+			Namespace:         pod.Namespace,
+			UID:               pod.UID,
+			ResourceVersion:   pod.ResourceVersion,
+			Generation:        pod.Generation,
+			CreationTimestamp: pod.CreationTimestamp,
+			DeletionTimestamp: pod.DeletionTimestamp,
+			Labels:            pod.Labels,
+			OwnerReferences:   pod.OwnerReferences,
+		},
+		Status: corev1.PodStatus{
+			Phase:      pod.Status.Phase,
+			Conditions: pod.Status.Conditions,
+			PodIP:      pod.Status.PodIP,
+			PodIPs:     pod.Status.PodIPs,
+		},
+	}
+}
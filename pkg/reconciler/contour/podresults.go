@@ -0,0 +1,127 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opencensus.io/tag"
+	"knative.dev/pkg/metrics"
+)
+
+// PodProbeResult is the outcome of a round of our probes of an Envoy pod.
+type PodProbeResult struct {
+	// Pod is the namespace/name of the pod, when we know it.
+	Pod string `json:"pod,omitempty"`
+	// Address is the ip:port we probed.
+	Address string `json:"address"`
+	// Ready is whether the pod served the version we probed for.
+	Ready bool `json:"ready"`
+	// Latency is how long the round of probes took.
+	Latency string `json:"latency"`
+	// ProbedAt is when the round of probes finished.
+	ProbedAt time.Time `json:"probedAt"`
+}
+
+// probeEnvoyPod probes the Envoy pod listening at addr like probePod, and
+// returns the outcome, which it also records in the envoy_probe_latency
+// metric.  The metric isn't tagged by pod, as pods are replaced on every
+// rollout and their series would pile up; our debug endpoint tells the
+// outcome by pod instead.
+func probeEnvoyPod(ctx context.Context, transports *probeTransports, names map[string]string, addr string, urls []*url.URL, hash string) PodProbeResult {
+	start := time.Now()
+	ready := probePod(ctx, transports, addr, urls, hash, probeTimeout(ctx))
+	now := time.Now()
+
+	result := PodProbeResult{
+		Address:  addr,
+		Ready:    ready,
+		Latency:  now.Sub(start).String(),
+		ProbedAt: now,
+	}
+	if ip, _, err := net.SplitHostPort(addr); err == nil {
+		result.Pod = names[ip]
+	}
+
+	outcome := "ready"
+	if !ready {
+		outcome = "not_ready"
+	}
+	if tagged, err := tag.New(ctx, tag.Upsert(resultKey, outcome)); err == nil {
+		metrics.Record(tagged, envoyProbeLatencyM.M(float64(now.Sub(start).Milliseconds())))
+	}
+	return result
+}
+
+// probeResults remembers the outcome of the last round of our probes of each
+// Envoy pod, for our debug endpoint.  The probes of the wrapped status.Prober
+// aren't ours, so it only sees pods once a quorum or an explanation probes
+// them.
+type probeResults struct {
+	mu     sync.Mutex
+	byAddr map[string]PodProbeResult
+}
+
+func newProbeResults() *probeResults {
+	return &probeResults{byAddr: make(map[string]PodProbeResult)}
+}
+
+// record remembers the result as the last of its pod.
+func (r *probeResults) record(result PodProbeResult) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byAddr[result.Address] = result
+}
+
+// forget drops the results of the pods gone from the names, so that those
+// of pods replaced long ago don't pile up.
+func (r *probeResults) forget(names map[string]string) {
+	if r == nil || names == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for addr := range r.byAddr {
+		if ip, _, err := net.SplitHostPort(addr); err == nil {
+			if _, ok := names[ip]; !ok {
+				delete(r.byAddr, addr)
+			}
+		}
+	}
+}
+
+// list returns the last results of every pod, by address.
+func (r *probeResults) list() []PodProbeResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	results := make([]PodProbeResult, 0, len(r.byAddr))
+	for _, result := range r.byAddr {
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Address < results[j].Address
+	})
+	return results
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	network "knative.dev/networking/pkg"
+)
+
+func TestProbeEnvoyPod(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(network.HashHeaderName, "hash")
+	}))
+	t.Cleanup(s.Close)
+	addr := s.Listener.Addr().String()
+	ip, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatal("SplitHostPort() =", err)
+	}
+	urls := []*url.URL{{Scheme: "http", Host: "example.com"}}
+	ctx := (&testConfigStore{config: defaultConfig}).ToContext(context.Background())
+
+	got := probeEnvoyPod(ctx, nil, map[string]string{ip: "envoy/envoy-a"}, addr, urls, "hash")
+	if got.Pod != "envoy/envoy-a" || got.Address != addr || !got.Ready {
+		t.Errorf("probeEnvoyPod() = %+v, wanted envoy/envoy-a at %s ready", got, addr)
+	}
+	if _, err := time.ParseDuration(got.Latency); err != nil {
+		t.Errorf("Latency = %q, wanted a duration", got.Latency)
+	}
+
+	got = probeEnvoyPod(ctx, nil, nil, addr, urls, "other")
+	if got.Pod != "" || got.Ready {
+		t.Errorf("probeEnvoyPod() = %+v, wanted an unnamed pod serving another version", got)
+	}
+}
+
+func TestProbeResults(t *testing.T) {
+	result := func(ip string, ready bool) PodProbeResult {
+		return PodProbeResult{Pod: "envoy/" + ip, Address: ip + ":8080", Ready: ready}
+	}
+
+	r := newProbeResults()
+	r.record(result("10.0.0.2", true))
+	r.record(result("10.0.0.1", true))
+	r.record(result("10.0.0.1", false))
+	if got, want := fmt.Sprint(r.list()), fmt.Sprint([]PodProbeResult{
+		result("10.0.0.1", false),
+		result("10.0.0.2", true),
+	}); got != want {
+		t.Errorf("list() = %s, wanted %s", got, want)
+	}
+
+	// Results of pods that are gone are dropped.
+	r.forget(map[string]string{"10.0.0.2": "envoy/10.0.0.2"})
+	if got, want := fmt.Sprint(r.list()), fmt.Sprint([]PodProbeResult{
+		result("10.0.0.2", true),
+	}); got != want {
+		t.Errorf("list() = %s after forget, wanted %s", got, want)
+	}
+
+	// A nil probeResults records nothing.
+	var none *probeResults
+	none.record(result("10.0.0.1", true))
+	none.forget(nil)
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/networking/pkg/status"
+)
+
+// verifiedEndpointsTTL is how long we trust that the Envoys have the
+// Endpoints an endpoint probe verified.  Envoy drops the clusters of Services
+// no HTTPProxy references anymore, so this only spans concurrent rollouts.
+const verifiedEndpointsTTL = time.Minute
+
+// endpointProbeCache remembers the Endpoints versions of the Services which
+// the endpoint probe of an Ingress verified every Envoy pod has, so that the
+// concurrent rollouts of other Ingresses referencing the same Services, e.g.
+// the activator's, skip probing them again.
+type endpointProbeCache struct {
+	targetLister    status.ProbeTargetLister
+	endpointsLister corev1listers.EndpointsLister
+
+	mu       sync.Mutex
+	verified map[verifiedEndpoints]verification
+}
+
+type verifiedEndpoints struct {
+	service         types.NamespacedName
+	resourceVersion string
+}
+
+type verification struct {
+	pods sets.String
+	at   time.Time
+}
+
+func newEndpointProbeCache(targetLister status.ProbeTargetLister, endpointsLister corev1listers.EndpointsLister) *endpointProbeCache {
+	return &endpointProbeCache{
+		targetLister:    targetLister,
+		endpointsLister: endpointsLister,
+		verified:        make(map[verifiedEndpoints]verification),
+	}
+}
+
+// envoyPods returns the IPs of the Envoy pods serving the Ingress.
+func (c *endpointProbeCache) envoyPods(ctx context.Context, ing *v1alpha1.Ingress) (sets.String, error) {
+	targets, err := c.targetLister.ListProbeTargets(ctx, ing)
+	if err != nil {
+		return nil, err
+	}
+	pods := sets.NewString()
+	for _, target := range targets {
+		pods = pods.Union(target.PodIPs)
+	}
+	return pods, nil
+}
+
+// keys returns the current Endpoints versions of the Services of the Ingress.
+func (c *endpointProbeCache) keys(ctx context.Context, ing *v1alpha1.Ingress) ([]verifiedEndpoints, error) {
+	var keys []verifiedEndpoints
+	for name := range resources.ServiceNames(ctx, ing) {
+		eps, err := c.endpointsLister.Endpoints(ing.Namespace).Get(name)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, verifiedEndpoints{
+			service:         types.NamespacedName{Namespace: ing.Namespace, Name: name},
+			resourceVersion: eps.ResourceVersion,
+		})
+	}
+	return keys, nil
+}
+
+// record remembers that the endpoint probe of the Ingress, which covers its
+// Services, passed on every one of the Envoy pods of probe.
+func (c *endpointProbeCache) record(ctx context.Context, ing, probe *v1alpha1.Ingress) {
+	if c == nil {
+		return
+	}
+	pods, err := c.envoyPods(ctx, probe)
+	if err != nil || pods.Len() == 0 {
+		return
+	}
+	keys, err := c.keys(ctx, ing)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for key, v := range c.verified {
+		if now.Sub(v.at) > verifiedEndpointsTTL {
+			delete(c.verified, key)
+		}
+	}
+	for _, key := range keys {
+		c.verified[key] = verification{pods: pods, at: now}
+	}
+}
+
+// covers returns whether the current Endpoints of every Service of the
+// Ingress were recently verified on each of the Envoy pods serving it.
+func (c *endpointProbeCache) covers(ctx context.Context, ing *v1alpha1.Ingress) bool {
+	if c == nil {
+		return false
+	}
+	pods, err := c.envoyPods(ctx, ing)
+	if err != nil || pods.Len() == 0 {
+		return false
+	}
+	keys, err := c.keys(ctx, ing)
+	if err != nil || len(keys) == 0 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		v, ok := c.verified[key]
+		if !ok || time.Since(v.at) > verifiedEndpointsTTL || !v.pods.IsSuperset(pods) {
+			return false
+		}
+	}
+	return true
+}
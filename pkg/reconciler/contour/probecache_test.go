@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+
+	. "knative.dev/net-contour/pkg/reconciler/testing"
+)
+
+func TestEndpointProbeCache(t *testing.T) {
+	ctx := (&testConfigStore{config: defaultConfig}).ToContext(context.Background())
+	endpoints := func(rv string) corev1listers.EndpointsLister {
+		listers := NewListers([]runtime.Object{&corev1.Endpoints{ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "ns",
+			Name:            "goo",
+			ResourceVersion: rv,
+		}}})
+		return listers.GetEndpointsLister()
+	}
+	pods := func(ips ...string) fakeProbeTargetLister {
+		return fakeProbeTargetLister{{PodIPs: sets.NewString(ips...)}}
+	}
+	i := ing("name", "ns", withBasicSpec, withContour)
+	probe := ing("name--ep", "ns", withBasicSpec, withContour)
+
+	var nilCache *endpointProbeCache
+	if nilCache.covers(ctx, i) {
+		t.Error("covers() = true without a cache")
+	}
+
+	c := newEndpointProbeCache(pods("10.0.0.1", "10.0.0.2"),
+		endpoints("1"))
+	if c.covers(ctx, i) {
+		t.Error("covers() = true before any probe")
+	}
+	c.record(ctx, i, probe)
+	if !c.covers(ctx, i) {
+		t.Error("covers() = false right after a probe")
+	}
+
+	// A new Envoy pod wasn't verified.
+	c.targetLister = pods("10.0.0.1", "10.0.0.2", "10.0.0.3")
+	if c.covers(ctx, i) {
+		t.Error("covers() = true with an unverified Envoy pod")
+	}
+	c.targetLister = pods("10.0.0.1")
+	if !c.covers(ctx, i) {
+		t.Error("covers() = false for a subset of the verified Envoy pods")
+	}
+
+	// Nor were the new Endpoints.
+	c.endpointsLister = endpoints("2")
+	if c.covers(ctx, i) {
+		t.Error("covers() = true for new Endpoints")
+	}
+
+	// Verifications expire.
+	c.endpointsLister = endpoints("1")
+	for key, v := range c.verified {
+		v.at = time.Now().Add(-2 * verifiedEndpointsTTL)
+		c.verified[key] = v
+	}
+	if c.covers(ctx, i) {
+		t.Error("covers() = true for an expired verification")
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+	network "knative.dev/networking/pkg"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/networking/pkg/prober"
+)
+
+// withProbeOverrides returns a context whose configuration carries the probe
+// settings the Ingress overrides with annotations, or an error when they are
+// invalid.
+func withProbeOverrides(ctx context.Context, ing *v1alpha1.Ingress) (context.Context, error) {
+	var (
+		quorum, hasQuorum     = ing.Annotations[resources.ReadinessQuorumKey]
+		timeout, hasTimeout   = ing.Annotations[resources.ProbeTimeoutKey]
+		deadline, hasDeadline = ing.Annotations[resources.EndpointProbeTimeoutKey]
+	)
+	if !hasQuorum && !hasTimeout && !hasDeadline {
+		return ctx, nil
+	}
+
+	cfg := config.FromContext(ctx).DeepCopy()
+	if hasQuorum {
+		q, err := strconv.ParseFloat(quorum, 64)
+		if err != nil || q <= 0 || q > 1 {
+			return ctx, fmt.Errorf("annotation %s must be in the range (0, 1], got %q", resources.ReadinessQuorumKey, quorum)
+		}
+		cfg.Contour.ReadinessQuorum = q
+	}
+	if hasTimeout {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			return ctx, fmt.Errorf("annotation %s must be a positive duration, got %q", resources.ProbeTimeoutKey, timeout)
+		}
+		cfg.Contour.ProbeTimeout = d
+	}
+	if hasDeadline {
+		d, err := time.ParseDuration(deadline)
+		if err != nil || d < 0 {
+			return ctx, fmt.Errorf("annotation %s must be a non-negative duration, got %q", resources.EndpointProbeTimeoutKey, deadline)
+		}
+		cfg.Contour.EndpointProbeTimeout = d
+	}
+	return config.ToContext(ctx, cfg), nil
+}
+
+// probeTimeout returns how long each of the probes we send may take.
+func probeTimeout(ctx context.Context) time.Duration {
+	if timeout := config.FromContext(ctx).Contour.ProbeTimeout; timeout > 0 {
+		return timeout
+	}
+	return defaultProbeTimeout
+}
+
+// probeHeaders returns the options setting the headers of the probes we send
+// ourselves: those of Knative's probes, with the User-Agent and the extra
+// headers of config-contour.
+func probeHeaders(ctx context.Context) []interface{} {
+	cfg := config.FromContext(ctx).Contour
+	userAgent := network.IngressReadinessUserAgent
+	if cfg.ProbeUserAgent != "" {
+		userAgent = cfg.ProbeUserAgent
+	}
+	ops := make([]interface{}, 0, len(cfg.ProbeHeaders)+3)
+	for name, value := range cfg.ProbeHeaders {
+		ops = append(ops, prober.WithHeader(name, value))
+	}
+	return append(ops,
+		prober.WithHeader(network.UserAgentKey, userAgent),
+		prober.WithHeader(network.ProbeHeaderName, network.ProbeHeaderValue),
+		prober.WithHeader(network.HashHeaderName, network.HashHeaderValue))
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+)
+
+func TestWithProbeOverrides(t *testing.T) {
+	cfg := defaultConfig.DeepCopy()
+	cfg.Contour.ReadinessQuorum = 1
+	cfg.Contour.ProbeTimeout = time.Second
+	cfg.Contour.EndpointProbeTimeout = time.Minute
+	ctx := (&testConfigStore{config: cfg}).ToContext(context.Background())
+
+	tests := []struct {
+		name         string
+		annotations  map[string]string
+		wantQuorum   float64
+		wantTimeout  time.Duration
+		wantDeadline time.Duration
+		wantErr      bool
+	}{{
+		name:         "no overrides",
+		wantQuorum:   1,
+		wantTimeout:  time.Second,
+		wantDeadline: time.Minute,
+	}, {
+		name: "all overridden",
+		annotations: map[string]string{
+			resources.ReadinessQuorumKey:      "0.8",
+			resources.ProbeTimeoutKey:         "5s",
+			resources.EndpointProbeTimeoutKey: "1h",
+		},
+		wantQuorum:   0.8,
+		wantTimeout:  5 * time.Second,
+		wantDeadline: time.Hour,
+	}, {
+		name:        "quorum out of range",
+		annotations: map[string]string{resources.ReadinessQuorumKey: "1.5"},
+		wantErr:     true,
+	}, {
+		name:        "zero probe timeout",
+		annotations: map[string]string{resources.ProbeTimeoutKey: "0s"},
+		wantErr:     true,
+	}, {
+		name:        "invalid endpoint probe timeout",
+		annotations: map[string]string{resources.EndpointProbeTimeoutKey: "forever"},
+		wantErr:     true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := withProbeOverrides(ctx, ing("name", "ns", withAnnotation(test.annotations)))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("withProbeOverrides() = %v, wanted error: %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			c := config.FromContext(got).Contour
+			if c.ReadinessQuorum != test.wantQuorum {
+				t.Errorf("ReadinessQuorum = %v, wanted %v", c.ReadinessQuorum, test.wantQuorum)
+			}
+			if c.ProbeTimeout != test.wantTimeout {
+				t.Errorf("ProbeTimeout = %v, wanted %v", c.ProbeTimeout, test.wantTimeout)
+			}
+			if c.EndpointProbeTimeout != test.wantDeadline {
+				t.Errorf("EndpointProbeTimeout = %v, wanted %v", c.EndpointProbeTimeout, test.wantDeadline)
+			}
+		})
+	}
+
+	// The overrides don't leak into the shared configuration.
+	if cfg.Contour.ReadinessQuorum != 1 {
+		t.Errorf("ReadinessQuorum = %v after overriding, wanted 1", cfg.Contour.ReadinessQuorum)
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+)
+
+// probeTransportSettings are the settings of config-contour the transports of
+// our probes are built with.
+type probeTransportSettings struct {
+	keepAlive           time.Duration
+	maxIdleConns        int
+	tlsHandshakeTimeout time.Duration
+	insecureSkipVerify  bool
+	// rootCAs are the certificates we verify those of Envoy with, the
+	// system roots when nil.
+	rootCAs *x509.CertPool
+}
+
+// settings returns the settings of the transports of our probes, which read
+// the CA bundle of config-contour through the pool when set.
+func (p *probeTransports) settings(ctx context.Context) probeTransportSettings {
+	cfg := config.FromContext(ctx).Contour
+	settings := probeTransportSettings{
+		keepAlive:           cfg.ProbeKeepAlive,
+		maxIdleConns:        cfg.ProbeMaxIdleConns,
+		tlsHandshakeTimeout: cfg.ProbeTLSHandshakeTimeout,
+		insecureSkipVerify:  cfg.ProbeInsecureSkipVerify,
+	}
+	if p != nil {
+		settings.rootCAs = p.caBundles.get(ctx, cfg.ProbeCABundle)
+	}
+	return settings
+}
+
+// tlsConfig returns the TLS configuration of our probes of serverName.
+func (s probeTransportSettings) tlsConfig(serverName string) *tls.Config {
+	return &tls.Config{
+		ServerName: serverName,
+		RootCAs:    s.rootCAs,
+		//nolint:gosec // Operators opt into it with probe-insecure-skip-verify.
+		InsecureSkipVerify: s.insecureSkipVerify,
+	}
+}
+
+// newProbeTransport returns a transport sending every request to the Envoy
+// pod listening at addr, whatever the host of its URL.
+func newProbeTransport(addr string, settings probeTransportSettings) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// The server name is that of the probed URL.
+	transport.TLSClientConfig = settings.tlsConfig("")
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, addr)
+	}
+	transport.TLSHandshakeTimeout = settings.tlsHandshakeTimeout
+	transport.MaxIdleConnsPerHost = settings.maxIdleConns
+	transport.IdleConnTimeout = settings.keepAlive
+	transport.DisableKeepAlives = settings.keepAlive == 0
+	return transport
+}
+
+// probeTransports holds a transport per Envoy pod, so that the connections
+// of our probes, and their TLS handshakes in particular, are reused across
+// the rounds of probes we send the pod.  Transports left unused for longer
+// than their connections stay open are dropped.
+type probeTransports struct {
+	mu     sync.Mutex
+	byAddr map[string]*pooledTransport
+
+	// caBundles, when set, reads the CA bundle of config-contour.
+	caBundles *caBundles
+}
+
+type pooledTransport struct {
+	transport *http.Transport
+	settings  probeTransportSettings
+	lastUsed  time.Time
+}
+
+func newProbeTransports(caBundles *caBundles) *probeTransports {
+	return &probeTransports{
+		byAddr:    make(map[string]*pooledTransport),
+		caBundles: caBundles,
+	}
+}
+
+// get returns the transport to probe the Envoy pod listening at addr with,
+// and the func to call once done with it.  Without a pool, or when keep-alive
+// is disabled, the transport is only used once.
+func (p *probeTransports) get(ctx context.Context, addr string) (*http.Transport, func()) {
+	settings := p.settings(ctx)
+	if p == nil || settings.keepAlive == 0 {
+		transport := newProbeTransport(addr, settings)
+		return transport, transport.CloseIdleConnections
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	for a, pooled := range p.byAddr {
+		if now.Sub(pooled.lastUsed) > pooled.settings.keepAlive {
+			pooled.transport.CloseIdleConnections()
+			delete(p.byAddr, a)
+		}
+	}
+	pooled, ok := p.byAddr[addr]
+	if !ok || pooled.settings != settings {
+		if ok {
+			pooled.transport.CloseIdleConnections()
+		}
+		pooled = &pooledTransport{transport: newProbeTransport(addr, settings), settings: settings}
+		p.byAddr[addr] = pooled
+	}
+	pooled.lastUsed = now
+	return pooled.transport, func() {}
+}
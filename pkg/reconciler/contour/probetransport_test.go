@@ -0,0 +1,163 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestProbeTransports(t *testing.T) {
+	var conns int32
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	s.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	s.StartTLS()
+	t.Cleanup(s.Close)
+	addr := s.Listener.Addr().String()
+	urls := []*url.URL{{Scheme: "https", Host: "example.com"}}
+
+	tests := []struct {
+		name       string
+		keepAlive  time.Duration
+		transports *probeTransports
+		want       int32
+	}{{
+		name:       "reused",
+		keepAlive:  time.Minute,
+		transports: newProbeTransports(nil),
+		want:       1,
+	}, {
+		name:      "without a pool",
+		keepAlive: time.Minute,
+		want:      3,
+	}, {
+		name:       "keep-alive disabled",
+		transports: newProbeTransports(nil),
+		want:       3,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := defaultConfig.DeepCopy()
+			cfg.Contour.ProbeKeepAlive = test.keepAlive
+			cfg.Contour.ProbeInsecureSkipVerify = true
+			ctx := (&testConfigStore{config: cfg}).ToContext(context.Background())
+
+			atomic.StoreInt32(&conns, 0)
+			for i := 0; i < 3; i++ {
+				if !probePod(ctx, test.transports, addr, urls, "", time.Second) {
+					t.Fatal("probePod() = false")
+				}
+			}
+			if got := atomic.LoadInt32(&conns); got != test.want {
+				t.Errorf("Opened %d connections, wanted %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestProbeTransportsDropUnused(t *testing.T) {
+	cfg := defaultConfig.DeepCopy()
+	cfg.Contour.ProbeKeepAlive = time.Minute
+	ctx := (&testConfigStore{config: cfg}).ToContext(context.Background())
+
+	p := newProbeTransports(nil)
+	first, _ := p.get(ctx, "10.0.0.1:443")
+	if again, _ := p.get(ctx, "10.0.0.1:443"); again != first {
+		t.Error("get() returned another transport for the same pod")
+	}
+	p.byAddr["10.0.0.1:443"].lastUsed = time.Now().Add(-2 * time.Minute)
+	p.get(ctx, "10.0.0.2:443")
+	if _, ok := p.byAddr["10.0.0.1:443"]; ok {
+		t.Error("The transport of an unused pod wasn't dropped")
+	}
+
+	// Changing the settings replaces the transports.
+	cfg.Contour.ProbeMaxIdleConns = 5
+	ctx = (&testConfigStore{config: cfg}).ToContext(context.Background())
+	if transport, _ := p.get(ctx, "10.0.0.2:443"); transport.MaxIdleConnsPerHost != 5 {
+		t.Errorf("MaxIdleConnsPerHost = %d, wanted 5", transport.MaxIdleConnsPerHost)
+	}
+}
+
+func TestProbeCertificates(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	t.Cleanup(s.Close)
+	addr := s.Listener.Addr().String()
+	urls := []*url.URL{{Scheme: "https", Host: "example.com"}}
+	ca := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw}))
+
+	tests := []struct {
+		name     string
+		insecure bool
+		bundle   *types.NamespacedName
+		want     bool
+	}{{
+		name: "system roots",
+		want: false,
+	}, {
+		name:     "insecure",
+		insecure: true,
+		want:     true,
+	}, {
+		name:   "CA bundle",
+		bundle: &types.NamespacedName{Namespace: "knative-serving", Name: "envoy-ca"},
+		want:   true,
+	}, {
+		name:   "missing CA bundle",
+		bundle: &types.NamespacedName{Namespace: "knative-serving", Name: "missing"},
+		want:   false,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := defaultConfig.DeepCopy()
+			cfg.Contour.ProbeInsecureSkipVerify = test.insecure
+			cfg.Contour.ProbeCABundle = test.bundle
+			ctx := (&testConfigStore{config: cfg}).ToContext(context.Background())
+
+			transports := newProbeTransports(&caBundles{
+				kubeClient: fakekubeclientset.NewSimpleClientset(&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "knative-serving", Name: "envoy-ca"},
+					Data:       map[string]string{caBundleKey: ca},
+				}),
+			})
+			if got := probePod(ctx, transports, addr, urls, "", time.Second); got != test.want {
+				t.Errorf("probePod() = %v, wanted %v", got, test.want)
+			}
+			if got := handshakePod(ctx, transports, addr, urls, time.Second); got != test.want {
+				t.Errorf("handshakePod() = %v, wanted %v", got, test.want)
+			}
+		})
+	}
+}
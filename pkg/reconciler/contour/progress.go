@@ -0,0 +1,118 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"time"
+
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/labels"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	networkingv1alpha1 "knative.dev/networking/pkg/client/listers/networking/v1alpha1"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/metrics"
+)
+
+// rolloutProgressReportPeriod is how often we count the Ingresses whose
+// rollout is in progress.
+const rolloutProgressReportPeriod = 15 * time.Second
+
+// The stages of an Ingress' rollout we report the progress of, in the order
+// a generation goes through them.
+const (
+	// stageObserved is the generation we last reconciled.
+	stageObserved = "observed"
+	// stageProgrammed is the generation whose HTTPProxies we last wrote.
+	stageProgrammed = "programmed"
+	// stageReady is the generation the Envoys were last probed to serve.
+	stageReady = "ready"
+)
+
+// rolloutProgress counts the Ingresses whose current generation didn't reach
+// each stage of its rollout yet, so that dashboards can follow Route rollouts
+// through their Ingresses.  We count rather than report the generation of
+// every Ingress, as series by Ingress would grow without bound with their
+// churn; the explain output of our debug endpoint has those of an Ingress.
+type rolloutProgress struct {
+	ingressLister networkingv1alpha1.IngressLister
+	filter        func(interface{}) bool
+}
+
+// count returns the number of Ingresses behind at each stage.
+func (p *rolloutProgress) count() (map[string]int, error) {
+	counts := map[string]int{stageObserved: 0, stageProgrammed: 0, stageReady: 0}
+
+	ings, err := p.ingressLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, ing := range ings {
+		if _, ok := ing.Annotations[resources.EndpointsProbeKey]; ok || !p.filter(ing) {
+			// Endpoint probes are part of the rollout of their parent.
+			continue
+		}
+		generations := rolloutGenerations(ing)
+		for stage := range counts {
+			if generations[stage] < ing.Generation {
+				counts[stage]++
+			}
+		}
+	}
+	return counts, nil
+}
+
+// report records the number of Ingresses behind at each stage until the
+// context is done.
+func (p *rolloutProgress) report(ctx context.Context) {
+	ticker := time.NewTicker(rolloutProgressReportPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		counts, err := p.count()
+		if err != nil {
+			logging.FromContext(ctx).Warnw("Error counting the Ingresses rolling out", zap.Error(err))
+			continue
+		}
+		for stage, n := range counts {
+			if tagged, err := tag.New(ctx, tag.Upsert(stageKey, stage)); err == nil {
+				metrics.Record(tagged, rolloutsPendingM.M(int64(n)))
+			}
+		}
+	}
+}
+
+// rolloutGenerations returns the generation of the Ingress that reached each
+// stage its status tells about.
+func rolloutGenerations(ing *v1alpha1.Ingress) map[string]int64 {
+	stages := map[string]int64{
+		stageObserved: ing.Status.ObservedGeneration,
+	}
+	if cond := ing.Status.GetCondition(ProxiesProgrammedCondition); cond != nil && cond.IsTrue() {
+		stages[stageProgrammed] = ing.Generation
+	}
+	if ing.IsReady() {
+		stages[stageReady] = ing.Status.ObservedGeneration
+	}
+	return stages
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+
+	. "knative.dev/net-contour/pkg/reconciler/testing"
+)
+
+func TestRolloutGenerations(t *testing.T) {
+	tests := []struct {
+		name string
+		ing  *v1alpha1.Ingress
+		want map[string]int64
+	}{{
+		name: "not reconciled yet",
+		ing:  ing("name", "ns", withBasicSpec, withContour, withGeneration(1)),
+		want: map[string]int64{stageObserved: 0},
+	}, {
+		name: "programmed, but the Envoys serve an older generation",
+		ing: ing("name", "ns", withBasicSpec, withContour, withGeneration(3), func(i *v1alpha1.Ingress) {
+			i.Status.InitializeConditions()
+			i.Status.MarkNetworkConfigured()
+			i.Status.MarkLoadBalancerNotReady()
+			i.Status.ObservedGeneration = 3
+			markProgrammed(i)
+		}),
+		want: map[string]int64{stageObserved: 3, stageProgrammed: 3},
+	}, {
+		name: "ready",
+		ing: ing("name", "ns", withBasicSpec, withContour, withGeneration(3), makeItReady, func(i *v1alpha1.Ingress) {
+			i.Status.ObservedGeneration = 3
+		}),
+		want: map[string]int64{stageObserved: 3, stageProgrammed: 3, stageReady: 3},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := rolloutGenerations(test.ing); !cmp.Equal(test.want, got) {
+				t.Error("rolloutGenerations (-want, +got) =", cmp.Diff(test.want, got))
+			}
+		})
+	}
+}
+
+func TestRolloutProgress(t *testing.T) {
+	listers := NewListers([]runtime.Object{
+		ing("new", "ns", withBasicSpec, withContour, withGeneration(1)),
+		ing("programmed", "ns", withBasicSpec, withContour, withGeneration(3), func(i *v1alpha1.Ingress) {
+			i.Status.InitializeConditions()
+			i.Status.ObservedGeneration = 3
+			markProgrammed(i)
+		}),
+		ing("ready", "ns", withBasicSpec, withContour, withGeneration(3), makeItReady, func(i *v1alpha1.Ingress) {
+			i.Status.ObservedGeneration = 3
+		}),
+		ing("probe", "ns", withBasicSpec, withContour, withGeneration(1), withAnnotation(map[string]string{
+			resources.EndpointsProbeKey: "true",
+		})),
+		ing("other", "ns", withBasicSpec, withGeneration(1)),
+	})
+
+	p := &rolloutProgress{
+		ingressLister: listers.GetIngressLister(),
+		filter: func(obj interface{}) bool {
+			return obj.(*v1alpha1.Ingress).Name != "other"
+		},
+	}
+	got, err := p.count()
+	if err != nil {
+		t.Fatal("count() =", err)
+	}
+	if want := map[string]int{stageObserved: 1, stageProgrammed: 1, stageReady: 2}; !cmp.Equal(got, want) {
+		t.Errorf("count() = %v, wanted %v", got, want)
+	}
+}
@@ -0,0 +1,274 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+	network "knative.dev/networking/pkg"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/networking/pkg/ingress"
+	"knative.dev/networking/pkg/prober"
+	"knative.dev/networking/pkg/status"
+	"knative.dev/pkg/logging"
+)
+
+const (
+	// defaultProbeTimeout bounds each of the probes we send when the
+	// configuration doesn't.
+	defaultProbeTimeout = time.Second
+
+	// quorumRecheckPeriod is how long we wait before counting again when
+	// too few Envoy pods serve an Ingress' current version.
+	quorumRecheckPeriod = 5 * time.Second
+)
+
+// quorumManager wraps a status.Manager, which requires every Envoy pod to
+// pass its probes, so that an Ingress is considered ready once the configured
+// fraction of the pods serve its current version.
+type quorumManager struct {
+	status.Manager
+
+	targetLister status.ProbeTargetLister
+	enqueueAfter func(interface{}, time.Duration)
+
+	// tcpTargets lists the Envoy pods to probe for the Ingresses whose
+	// connections we proxy, which the wrapped Manager can't probe.
+	tcpTargets func(context.Context, *v1alpha1.Ingress) ([]status.ProbeTarget, error)
+
+	// transports, when set, lets our probes reuse their connections to each
+	// Envoy pod.  The wrapped Manager opens new ones for every probe.
+	transports *probeTransports
+
+	// podNames, when set, names the Envoy pods by IP in the results of our
+	// probes, which results remembers when set.
+	podNames func(context.Context) (map[string]string, error)
+	results  *probeResults
+
+	// rounds holds the latest round of probes counting the Envoy pods that
+	// serve each Ingress, which run in the background so that reconciles
+	// don't wait for the probes.
+	mu     sync.Mutex
+	rounds map[types.NamespacedName]*quorumRound
+
+	// stopCh, when set, is closed once the controller is shutting down,
+	// which cancels the rounds of probes in flight.
+	stopCh <-chan struct{}
+}
+
+// quorumRound is a round of probes of the version of an Ingress with the hash.
+type quorumRound struct {
+	hash  string
+	done  bool
+	ready bool
+}
+
+var _ status.Manager = (*quorumManager)(nil)
+
+// IsReady implements status.Manager
+func (m *quorumManager) IsReady(ctx context.Context, ing *v1alpha1.Ingress) (bool, error) {
+	ready, err := m.quorumReady(ctx, ing)
+	if !ready || err != nil {
+		return ready, err
+	}
+	return m.hostRewritesVerified(ctx, ing)
+}
+
+// quorumReady returns whether enough of the Envoy pods serve the Ingress'
+// current version.
+func (m *quorumManager) quorumReady(ctx context.Context, ing *v1alpha1.Ingress) (bool, error) {
+	if m.tcpTargets != nil && resources.IsTCPProxy(ing) {
+		return m.tcpReady(ctx, ing)
+	}
+
+	ready, err := m.Manager.IsReady(ctx, ing)
+	quorum := config.FromContext(ctx).Contour.ReadinessQuorum
+	if ready || err != nil || quorum <= 0 || quorum >= 1 {
+		return ready, err
+	}
+
+	bytes, err := ingress.ComputeHash(ing)
+	if err != nil {
+		return false, fmt.Errorf("failed to compute the hash of the Ingress: %w", err)
+	}
+	hash := fmt.Sprintf("%x", bytes)
+
+	key := types.NamespacedName{Namespace: ing.Namespace, Name: ing.Name}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if round, ok := m.rounds[key]; ok && round.hash == hash {
+		switch {
+		case !round.done:
+			// The Ingress is enqueued once the round completes.
+			return false, nil
+		case round.ready:
+			return true, nil
+		default:
+			// The wrapped Manager only notifies us once every pod is ready,
+			// so count again later in case a wedged pod never is.
+			delete(m.rounds, key)
+			m.enqueueAfter(ing, quorumRecheckPeriod)
+			return false, nil
+		}
+	}
+
+	targets, err := m.targetLister.ListProbeTargets(ctx, ing)
+	if err != nil {
+		return false, err
+	}
+	if m.rounds == nil {
+		m.rounds = make(map[types.NamespacedName]*quorumRound)
+	}
+	round := &quorumRound{hash: hash}
+	m.rounds[key] = round
+	// The round outlives the reconcile starting it, but not the controller.
+	ctx, cancel := untilShutdown(ctx, m.stopCh)
+	go func() {
+		defer cancel()
+		ready := m.probeQuorum(ctx, targets, hash, quorum)
+		if ctx.Err() != nil {
+			// We are shutting down, whoever reconciles next probes again.
+			return
+		}
+		m.mu.Lock()
+		round.done, round.ready = true, ready
+		m.mu.Unlock()
+		m.enqueueAfter(ing, 0)
+	}()
+	return false, nil
+}
+
+// probeQuorum returns whether at least the quorum of the Envoy pods of the
+// targets serve the version with the given hash.
+func (m *quorumManager) probeQuorum(ctx context.Context, targets []status.ProbeTarget, hash string, quorum float64) bool {
+	// Group the URLs to probe by pod.
+	pods := make(map[string][]*url.URL)
+	for _, target := range targets {
+		for ip := range target.PodIPs {
+			addr := net.JoinHostPort(ip, target.PodPort)
+			pods[addr] = append(pods[addr], target.URLs...)
+		}
+	}
+
+	names := m.envoyPodNames(ctx)
+	m.results.forget(names)
+
+	var (
+		mu     sync.Mutex
+		passed int
+		wg     sync.WaitGroup
+	)
+	for addr, urls := range pods {
+		wg.Add(1)
+		go func(addr string, urls []*url.URL) {
+			defer wg.Done()
+			result := probeEnvoyPod(ctx, m.transports, names, addr, urls, hash)
+			m.results.record(result)
+			if result.Ready {
+				mu.Lock()
+				defer mu.Unlock()
+				passed++
+			}
+		}(addr, urls)
+	}
+	wg.Wait()
+
+	logger := logging.FromContext(ctx)
+	if float64(passed) >= quorum*float64(len(pods)) {
+		logger.Debugf("%d of %d Envoy pods serve the current version, which meets the quorum of %v.",
+			passed, len(pods), quorum)
+		return true
+	}
+	logger.Debugf("%d of %d Envoy pods serve the current version, short of the quorum of %v.",
+		passed, len(pods), quorum)
+	return false
+}
+
+// forget drops the round of probes of the Ingress, when it is deleted.
+func (m *quorumManager) forget(key types.NamespacedName) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.rounds, key)
+}
+
+// envoyPodNames returns the names of the Envoy pods by IP, or nil when we
+// can't tell them.
+func (m *quorumManager) envoyPodNames(ctx context.Context) map[string]string {
+	if m.podNames == nil {
+		return nil
+	}
+	names, err := m.podNames(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Debugw("Unable to name the Envoy pods", zap.Error(err))
+		return nil
+	}
+	return names
+}
+
+// probePod returns whether the Envoy pod listening at addr serves the version
+// with the given hash for every one of the urls, each within the timeout.
+// The connections of the probes are reused through the transports when set.
+func probePod(ctx context.Context, transports *probeTransports, addr string, urls []*url.URL, hash string, timeout time.Duration) bool {
+	transport, release := transports.get(ctx, addr)
+	defer release()
+
+	for _, u := range urls {
+		probeURL := *u
+		probeURL.Path = path.Join(probeURL.Path, network.ProbePath)
+
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		ok, err := prober.Do(ctx, transport, probeURL.String(), append(probeHeaders(ctx),
+			hashVerifier(hash, config.FromContext(ctx).Contour.ProbeExpectedStatusCodes))...)
+		cancel()
+		if err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// hashVerifier mirrors the verification of status.Prober: only a response
+// carrying a different hash, or a 404/503 from a route that isn't programmed
+// yet, means the pod doesn't serve the current version.  When codes are set,
+// responses must have one of them instead.
+func hashVerifier(hash string, codes []int) prober.Verifier {
+	return func(r *http.Response, _ []byte) (bool, error) {
+		if got := r.Header.Get(network.HashHeaderName); r.StatusCode == http.StatusOK && got != "" && got != hash {
+			return false, fmt.Errorf("unexpected hash: want %q, got %q", hash, got)
+		}
+		if len(codes) != 0 {
+			return prober.ExpectsStatusCodes(codes)(r, nil)
+		}
+		switch r.StatusCode {
+		case http.StatusNotFound, http.StatusServiceUnavailable:
+			return false, fmt.Errorf("unexpected status code: want %v, got %v", http.StatusOK, r.StatusCode)
+		default:
+			return true, nil
+		}
+	}
+}
@@ -0,0 +1,293 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	network "knative.dev/networking/pkg"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/networking/pkg/ingress"
+	"knative.dev/networking/pkg/status"
+)
+
+type fakeProbeTargetLister []status.ProbeTarget
+
+func (l fakeProbeTargetLister) ListProbeTargets(context.Context, *v1alpha1.Ingress) ([]status.ProbeTarget, error) {
+	return l, nil
+}
+
+func TestQuorumManager(t *testing.T) {
+	i := ing("name", "ns", withBasicSpec, withContour)
+	bytes, err := ingress.ComputeHash(i)
+	if err != nil {
+		t.Fatal("ComputeHash() =", err)
+	}
+	hash := fmt.Sprintf("%x", bytes)
+
+	// Simulate Envoy pods, each serving the given hash or, when empty,
+	// not having the route programmed yet.
+	envoys := func(hashes ...string) (targets fakeProbeTargetLister) {
+		for _, h := range hashes {
+			h := h
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if h == "" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.Header().Set(network.HashHeaderName, h)
+			}))
+			t.Cleanup(s.Close)
+
+			host, port, err := net.SplitHostPort(s.Listener.Addr().String())
+			if err != nil {
+				t.Fatal("SplitHostPort() =", err)
+			}
+			targets = append(targets, status.ProbeTarget{
+				PodIPs:  sets.NewString(host),
+				PodPort: port,
+				URLs:    []*url.URL{{Scheme: "http", Host: "example.com"}},
+			})
+		}
+		return targets
+	}
+
+	tests := []struct {
+		name        string
+		quorum      float64
+		targets     fakeProbeTargetLister
+		want        bool
+		wantEnqueue bool
+	}{{
+		name:    "quorum disabled",
+		quorum:  1,
+		targets: envoys(hash, hash, ""),
+	}, {
+		name:    "quorum met",
+		quorum:  0.6,
+		targets: envoys(hash, hash, ""),
+		want:    true,
+	}, {
+		name:        "quorum not met",
+		quorum:      0.9,
+		targets:     envoys(hash, hash, ""),
+		wantEnqueue: true,
+	}, {
+		name:        "stale version does not count",
+		quorum:      0.6,
+		targets:     envoys(hash, "stale", "stale"),
+		wantEnqueue: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := defaultConfig.DeepCopy()
+			cfg.Contour.ReadinessQuorum = test.quorum
+			ctx := (&testConfigStore{config: cfg}).ToContext(context.Background())
+
+			var (
+				completed = make(chan struct{}, 1)
+				enqueued  bool
+			)
+			m := &quorumManager{
+				Manager: &fakeStatusManager{
+					FakeIsReady: func(context.Context, *v1alpha1.Ingress) (bool, error) {
+						return false, nil
+					},
+				},
+				targetLister: test.targets,
+				enqueueAfter: func(_ interface{}, d time.Duration) {
+					if d == 0 {
+						completed <- struct{}{}
+						return
+					}
+					enqueued = true
+				},
+			}
+
+			got, err := m.IsReady(ctx, i)
+			if err != nil {
+				t.Fatal("IsReady() =", err)
+			}
+			if test.quorum > 0 && test.quorum < 1 {
+				// The pods are counted in the background, and the Ingress
+				// is enqueued with the outcome.
+				if got {
+					t.Fatal("IsReady() = true before counting the pods")
+				}
+				select {
+				case <-completed:
+				case <-time.After(5 * time.Second):
+					t.Fatal("Timed out waiting for the pods to be counted")
+				}
+				if got, err = m.IsReady(ctx, i); err != nil {
+					t.Fatal("IsReady() =", err)
+				}
+			}
+			if got != test.want {
+				t.Errorf("IsReady() = %v, wanted %v", got, test.want)
+			}
+			if enqueued != test.wantEnqueue {
+				t.Errorf("enqueued = %v, wanted %v", enqueued, test.wantEnqueue)
+			}
+		})
+	}
+}
+
+func TestProbePodRequests(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		headers   map[string]string
+		codes     []int
+		status    int
+		want      bool
+	}{{
+		name:   "defaults",
+		status: http.StatusOK,
+		want:   true,
+	}, {
+		name:   "route not programmed",
+		status: http.StatusNotFound,
+	}, {
+		name:   "other status",
+		status: http.StatusForbidden,
+		want:   true,
+	}, {
+		name:      "user agent and headers",
+		userAgent: "waf-friendly/1.0",
+		headers:   map[string]string{"X-Probe-Token": "secret"},
+		status:    http.StatusOK,
+		want:      true,
+	}, {
+		name:   "expected status",
+		codes:  []int{http.StatusOK, http.StatusNoContent},
+		status: http.StatusNoContent,
+		want:   true,
+	}, {
+		name:   "unexpected status",
+		codes:  []int{http.StatusOK},
+		status: http.StatusForbidden,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			wantUserAgent := network.IngressReadinessUserAgent
+			if test.userAgent != "" {
+				wantUserAgent = test.userAgent
+			}
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get(network.UserAgentKey); got != wantUserAgent {
+					t.Errorf("User-Agent = %q, wanted %q", got, wantUserAgent)
+				}
+				if got := r.Header.Get(network.ProbeHeaderName); got != network.ProbeHeaderValue {
+					t.Errorf("%s = %q, wanted %q", network.ProbeHeaderName, got, network.ProbeHeaderValue)
+				}
+				for name, want := range test.headers {
+					if got := r.Header.Get(name); got != want {
+						t.Errorf("%s = %q, wanted %q", name, got, want)
+					}
+				}
+				w.Header().Set(network.HashHeaderName, "hash")
+				w.WriteHeader(test.status)
+			}))
+			defer s.Close()
+
+			cfg := defaultConfig.DeepCopy()
+			cfg.Contour.ProbeUserAgent = test.userAgent
+			cfg.Contour.ProbeHeaders = test.headers
+			cfg.Contour.ProbeExpectedStatusCodes = test.codes
+			ctx := (&testConfigStore{config: cfg}).ToContext(context.Background())
+
+			urls := []*url.URL{{Scheme: "http", Host: "example.com"}}
+			if got := probePod(ctx, nil, s.Listener.Addr().String(), urls, "hash", time.Second); got != test.want {
+				t.Errorf("probePod() = %v, wanted %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestQuorumManagerShutdown(t *testing.T) {
+	probed := make(chan struct{})
+	canceled := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		close(probed)
+		<-r.Context().Done()
+		close(canceled)
+	}))
+	defer s.Close()
+	host, port, err := net.SplitHostPort(s.Listener.Addr().String())
+	if err != nil {
+		t.Fatal("SplitHostPort() =", err)
+	}
+
+	cfg := defaultConfig.DeepCopy()
+	cfg.Contour.ReadinessQuorum = 0.5
+	cfg.Contour.ProbeTimeout = time.Minute
+	ctx := (&testConfigStore{config: cfg}).ToContext(context.Background())
+
+	stopCh := make(chan struct{})
+	enqueued := make(chan struct{}, 1)
+	m := &quorumManager{
+		Manager: &fakeStatusManager{
+			FakeIsReady: func(context.Context, *v1alpha1.Ingress) (bool, error) {
+				return false, nil
+			},
+		},
+		targetLister: fakeProbeTargetLister{{
+			PodIPs:  sets.NewString(host),
+			PodPort: port,
+			URLs:    []*url.URL{{Scheme: "http", Host: "example.com"}},
+		}},
+		enqueueAfter: func(interface{}, time.Duration) { enqueued <- struct{}{} },
+		stopCh:       stopCh,
+	}
+
+	i := ing("name", "ns", withBasicSpec, withContour)
+	if ready, err := m.IsReady(ctx, i); err != nil || ready {
+		t.Fatalf("IsReady() = %v, %v, wanted false", ready, err)
+	}
+	<-probed
+
+	// Shutting down cancels the probes in flight, and drops their round.
+	close(stopCh)
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("The probe wasn't canceled once shutting down")
+	}
+	select {
+	case <-enqueued:
+		t.Error("The Ingress was enqueued with the outcome of a canceled round")
+	case <-time.After(100 * time.Millisecond):
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, round := range m.rounds {
+		if round.done {
+			t.Errorf("The canceled round of %v is done", key)
+		}
+	}
+}
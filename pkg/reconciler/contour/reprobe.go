@@ -0,0 +1,72 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+)
+
+// envoyReprober forces a fresh probe of ready Ingresses whose Envoy Services
+// were replaced, as the status manager would otherwise answer from probing
+// the Envoys that served them before.
+type envoyReprober struct {
+	// cancel drops the status manager's probing state of an Ingress.
+	cancel func(obj interface{})
+
+	mu sync.Mutex
+	// probing holds the addresses we are probing each Ingress for.
+	probing map[types.NamespacedName]string
+}
+
+func newEnvoyReprober(cancel func(obj interface{})) *envoyReprober {
+	return &envoyReprober{
+		cancel:  cancel,
+		probing: make(map[types.NamespacedName]string),
+	}
+}
+
+// reprobe makes sure the Ingress is probed against the Envoys behind the
+// given addresses, dropping any earlier probing state the first time we see
+// them.
+func (p *envoyReprober) reprobe(ing *v1alpha1.Ingress, addresses string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := types.NamespacedName{Namespace: ing.Namespace, Name: ing.Name}
+	if p.probing[key] == addresses {
+		return
+	}
+	p.probing[key] = addresses
+	p.cancel(ing)
+}
+
+// forget stops tracking the Ingress, once it serves from the new addresses
+// or is deleted.
+func (p *envoyReprober) forget(key types.NamespacedName) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.probing, key)
+}
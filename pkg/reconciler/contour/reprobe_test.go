@@ -0,0 +1,51 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestEnvoyReprober(t *testing.T) {
+	cancels := 0
+	p := newEnvoyReprober(func(interface{}) { cancels++ })
+	i := ing("name", "ns")
+
+	p.reprobe(i, "new")
+	p.reprobe(i, "new")
+	if cancels != 1 {
+		t.Errorf("Got %d cancellations for the same addresses, wanted 1", cancels)
+	}
+
+	p.reprobe(i, "newer")
+	if cancels != 2 {
+		t.Errorf("Got %d cancellations after the addresses changed again, wanted 2", cancels)
+	}
+
+	p.forget(types.NamespacedName{Namespace: "ns", Name: "name"})
+	p.reprobe(i, "newer")
+	if cancels != 3 {
+		t.Errorf("Got %d cancellations after forgetting the Ingress, wanted 3", cancels)
+	}
+
+	// A nil reprober does nothing.
+	var nilReprober *envoyReprober
+	nilReprober.reprobe(i, "new")
+	nilReprober.forget(types.NamespacedName{Namespace: "ns", Name: "name"})
+}
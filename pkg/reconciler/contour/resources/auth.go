@@ -0,0 +1,72 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+
+	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+)
+
+// AuthContext returns the authorization context of the routes of the
+// Ingress, or nil when they have none.  It errors when the AuthContextKey
+// annotation can't be programmed.
+func AuthContext(ing *v1alpha1.Ingress) (map[string]string, error) {
+	raw, ok := ing.Annotations[AuthContextKey]
+	if !ok {
+		return nil, nil
+	}
+	var authContext map[string]string
+	if err := json.Unmarshal([]byte(raw), &authContext); err != nil {
+		return nil, fmt.Errorf("failed to parse annotation %s: %w", AuthContextKey, err)
+	}
+	for key := range authContext {
+		if key == "" {
+			return nil, fmt.Errorf("annotation %s: context keys must not be empty", AuthContextKey)
+		}
+	}
+	return authContext, nil
+}
+
+// authPolicy returns the authorization policy of routes with the given
+// context, or nil when they have none.
+func authPolicy(authContext map[string]string) *v1.AuthorizationPolicy {
+	if len(authContext) == 0 {
+		return nil
+	}
+	policy := &v1.AuthorizationPolicy{Context: make(map[string]string, len(authContext))}
+	for k, v := range authContext {
+		policy.Context[k] = v
+	}
+	return policy
+}
+
+// authDisabled returns whether the route disables authorization.
+func authDisabled(route *v1.Route) bool {
+	return route.AuthPolicy != nil && route.AuthPolicy.Disabled
+}
+
+// disableAuth disables the authorization of the route, keeping its context
+// for the authorization servers of other scopes.
+func disableAuth(route *v1.Route) {
+	if route.AuthPolicy == nil {
+		route.AuthPolicy = &v1.AuthorizationPolicy{}
+	}
+	route.AuthPolicy.Disabled = true
+}
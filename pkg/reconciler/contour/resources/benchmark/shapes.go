@@ -0,0 +1,109 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package benchmark builds Ingresses of realistic shapes to benchmark the
+// translation of Ingresses into HTTPProxies with, so that regressions and
+// optimizations are measured on the same inputs.
+package benchmark
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+)
+
+// Shape describes the Ingresses to benchmark.
+type Shape struct {
+	// Name identifies the shape in benchmark names.
+	Name string
+	// Hosts is the number of external hosts of the Ingress, which also gets
+	// the usual cluster-local hosts.
+	Hosts int
+	// Splits is the number of traffic splits of each path.
+	Splits int
+	// Paths is the number of paths of each rule, the extra ones matching a
+	// header like tag routes do.
+	Paths int
+}
+
+// Shapes are the shapes we benchmark by default: an Ingress per Knative
+// Service with a growing number of custom domains, and a deep traffic split
+// over many tagged revisions.
+var Shapes = []Shape{
+	{Name: "1-host", Hosts: 1, Splits: 1, Paths: 1},
+	{Name: "10-hosts", Hosts: 10, Splits: 1, Paths: 1},
+	{Name: "100-hosts", Hosts: 100, Splits: 1, Paths: 1},
+	{Name: "deep-splits", Hosts: 1, Splits: 20, Paths: 10},
+}
+
+// Ingress returns an Ingress of the given shape, routing to the Services
+// rev-0, rev-1, ... in namespace "ns" as Knative Serving does.
+func Ingress(shape Shape) *v1alpha1.Ingress {
+	paths := make([]v1alpha1.HTTPIngressPath, 0, shape.Paths)
+	for p := 0; p < shape.Paths; p++ {
+		path := v1alpha1.HTTPIngressPath{
+			AppendHeaders: map[string]string{"Knative-Serving-Namespace": "ns"},
+		}
+		if p > 0 {
+			path.Headers = map[string]v1alpha1.HeaderMatch{
+				"Knative-Serving-Tag": {Exact: fmt.Sprintf("tag-%d", p)},
+			}
+		}
+		for s := 0; s < shape.Splits; s++ {
+			percent := 100 / shape.Splits
+			if s == 0 {
+				percent += 100 % shape.Splits
+			}
+			path.Splits = append(path.Splits, v1alpha1.IngressBackendSplit{
+				IngressBackend: v1alpha1.IngressBackend{
+					ServiceNamespace: "ns",
+					ServiceName:      fmt.Sprintf("rev-%d", s),
+					ServicePort:      intstr.FromInt(80),
+				},
+				Percent:       percent,
+				AppendHeaders: map[string]string{"Knative-Serving-Revision": fmt.Sprintf("rev-%d", s)},
+			})
+		}
+		paths = append(paths, path)
+	}
+
+	hosts := make([]string, 0, shape.Hosts)
+	for h := 0; h < shape.Hosts; h++ {
+		hosts = append(hosts, fmt.Sprintf("name-%d.ns.example.com", h))
+	}
+
+	return &v1alpha1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:  "ns",
+			Name:       "name",
+			Generation: 1,
+		},
+		Spec: v1alpha1.IngressSpec{
+			HTTPOption: v1alpha1.HTTPOptionEnabled,
+			Rules: []v1alpha1.IngressRule{{
+				Hosts:      []string{"name.ns", "name.ns.svc", "name.ns.svc.cluster.local"},
+				Visibility: v1alpha1.IngressVisibilityClusterLocal,
+				HTTP:       &v1alpha1.HTTPIngressRuleValue{Paths: paths},
+			}, {
+				Hosts:      hosts,
+				Visibility: v1alpha1.IngressVisibilityExternalIP,
+				HTTP:       &v1alpha1.HTTPIngressRuleValue{Paths: paths},
+			}},
+		},
+	}
+}
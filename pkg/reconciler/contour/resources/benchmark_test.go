@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"testing"
+
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources/benchmark"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+)
+
+// benchmarkConfig is the configuration Ingresses are translated with.
+var benchmarkConfig = &config.Config{
+	Contour: &config.Contour{
+		VisibilityClasses: map[v1alpha1.IngressVisibility]string{
+			v1alpha1.IngressVisibilityClusterLocal: privateClass,
+			v1alpha1.IngressVisibilityExternalIP:   publicClass,
+		},
+		TimeoutPolicyResponse: "infinity",
+		TimeoutPolicyIdle:     "infinity",
+	},
+}
+
+func BenchmarkMakeHTTPProxies(b *testing.B) {
+	ctx := config.ToContext(context.Background(), benchmarkConfig)
+	for _, shape := range benchmark.Shapes {
+		ing := benchmark.Ingress(shape)
+		b.Run(shape.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				MakeHTTPProxies(ctx, ing, nil)
+			}
+		})
+	}
+}
+
+func BenchmarkMakeEndpointProbeIngress(b *testing.B) {
+	ctx := config.ToContext(context.Background(), benchmarkConfig)
+	for _, shape := range benchmark.Shapes {
+		ing := benchmark.Ingress(shape)
+		// Contour accepted the previous generation, as usual on rollouts.
+		previous := MakeHTTPProxies(ctx, ing, nil)
+		for _, proxy := range previous {
+			proxy.Status.CurrentStatus = "valid"
+		}
+		b.Run(shape.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				MakeEndpointProbeIngress(ctx, ing, previous)
+			}
+		})
+	}
+}
+
+func BenchmarkServiceNames(b *testing.B) {
+	ctx := config.ToContext(context.Background(), benchmarkConfig)
+	for _, shape := range benchmark.Shapes {
+		ing := benchmark.Ingress(shape)
+		b.Run(shape.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ServiceNames(ctx, ing)
+			}
+		})
+	}
+}
+
+func BenchmarkHTTPProxySpecEqual(b *testing.B) {
+	ctx := config.ToContext(context.Background(), benchmarkConfig)
+	for _, shape := range benchmark.Shapes {
+		proxies := MakeHTTPProxies(ctx, benchmark.Ingress(shape), nil)
+		proxy, other := proxies[len(proxies)-1], proxies[len(proxies)-1].DeepCopy()
+		b.Run(shape.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				HTTPProxySpecEqual(&proxy.Spec, &other.Spec)
+			}
+		})
+	}
+}
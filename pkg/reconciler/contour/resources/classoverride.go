@@ -0,0 +1,50 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+)
+
+// ClassOverrides returns the Contour classes of the ClassOverrideKey
+// annotation of the Ingress by visibility, or nil when it has none.  It
+// errors when the annotation can't be parsed, but doesn't check that the
+// classes are approved, which takes the labels of the Ingress' namespace.
+func ClassOverrides(ing *v1alpha1.Ingress) (map[v1alpha1.IngressVisibility]string, error) {
+	raw, ok := ing.Annotations[ClassOverrideKey]
+	if !ok {
+		return nil, nil
+	}
+	var overrides map[v1alpha1.IngressVisibility]string
+	if err := json.Unmarshal([]byte(raw), &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse annotation %s: %w", ClassOverrideKey, err)
+	}
+	for vis, class := range overrides {
+		switch vis {
+		case v1alpha1.IngressVisibilityClusterLocal, v1alpha1.IngressVisibilityExternalIP:
+		default:
+			return nil, fmt.Errorf("annotation %s: unrecognized visibility %q", ClassOverrideKey, vis)
+		}
+		if class == "" {
+			return nil, fmt.Errorf("annotation %s: class of %s must not be empty", ClassOverrideKey, vis)
+		}
+	}
+	return overrides, nil
+}
@@ -16,15 +16,17 @@ limitations under the License.
 
 package resources
 
+import "knative.dev/net-contour/pkg/ownership"
+
 // These are the label keys that are applied to HTTP proxy resources to facilitate reconciliation.
 const (
 	// GenerationKey holds the generation of the parent KIngress resource that the HTTPProxy's
 	// spec is derived from.  This is updated along with the spec of child HTTPProxy resources
 	// and then used to cleanup stale HTTPProxy resources owned by the parent.
-	GenerationKey = "contour.networking.knative.dev/generation"
+	GenerationKey = ownership.GenerationKey
 	// ParentKey hold the name of the parent KIngress resource, since OwnerReferences cannot
 	// be used in filter expressions.
-	ParentKey = "contour.networking.knative.dev/parent"
+	ParentKey = ownership.ParentKey
 	// DomainHashKey contains the hash of the fqdn for which this HTTPProxy exists.  We use
 	// the hash in place of the actual fqdn because there is a limit on the length of label
 	// values.
@@ -37,4 +39,77 @@ const (
 	// EndpointsProbeKey is placed on child Ingress resources to bypass Endpoint probing,
 	// since the child ingress exists to be said endpoint probe.
 	EndpointsProbeKey = "contour.networking.knative.dev/endpointsProbe"
+
+	// HeaderMatchKey is placed on KIngress resources to match the named headers of
+	// their paths with another operator than exact, e.g. {"X-Canary": "contains"}.
+	// The negated operators (notexact, notcontains, notpresent) match requests
+	// without the header value, e.g. to keep probes out of a canary split.
+	HeaderMatchKey = "contour.networking.knative.dev/header-match"
+
+	// UnmanagedHostsKey is placed on KIngress resources to list, comma separated,
+	// hosts we must neither program nor probe, so users can hand-craft the
+	// HTTPProxies of these hosts.
+	UnmanagedHostsKey = "contour.networking.knative.dev/unmanaged-hosts"
+
+	// InsecurePathsKey is placed on KIngress resources to list, comma separated,
+	// path prefixes that stay reachable over plain HTTP when the KIngress
+	// redirects HTTP to HTTPS, e.g. for webhook receivers that can't follow
+	// redirects.
+	InsecurePathsKey = "contour.networking.knative.dev/insecure-paths"
+
+	// AuthDisabledPathsKey is placed on KIngress resources to list, comma
+	// separated, path prefixes whose routes disable the external
+	// authorization of their virtual hosts (authPolicy.disabled), e.g. for
+	// health endpoints and public webhooks.  It requires the
+	// authorization-server of config-contour.
+	AuthDisabledPathsKey = "contour.networking.knative.dev/auth-disabled-paths"
+
+	// AuthContextKey is placed on KIngress resources to send the given JSON
+	// object of strings, e.g. {"tenant": "acme"}, as the authPolicy context
+	// of their routes to the external authorization servers.
+	AuthContextKey = "contour.networking.knative.dev/auth-context"
+
+	// TCPProxyKey is placed on KIngress resources to proxy the TCP
+	// connections to their hosts to the backends of their first path, instead
+	// of routing HTTP requests, for services speaking other protocols over
+	// TLS.  The TLS of hosts without a certificate of their own is passed
+	// through to the backends.
+	TCPProxyKey = "contour.networking.knative.dev/tcp-proxy"
+
+	// CORSPolicyKey is placed on KIngress resources to override the default
+	// CORS policy of their external virtual hosts with the given HTTPProxy
+	// corsPolicy, or to disable CORS with the value "disabled".
+	CORSPolicyKey = "contour.networking.knative.dev/cors-policy"
+
+	// ReadinessQuorumKey, ProbeTimeoutKey and EndpointProbeTimeoutKey are
+	// placed on KIngress resources to override the readiness-quorum,
+	// probe-timeout and endpoint-probe-timeout of config-contour for them,
+	// e.g. to be more patient with Ingresses fanning out to many backends.
+	ReadinessQuorumKey      = "contour.networking.knative.dev/readiness-quorum"
+	ProbeTimeoutKey         = "contour.networking.knative.dev/probe-timeout"
+	EndpointProbeTimeoutKey = "contour.networking.knative.dev/endpoint-probe-timeout"
+
+	// MinimumTLSVersionKey is placed on KIngress resources to set the
+	// minimumProtocolVersion of the virtual hosts terminating their TLS, e.g.
+	// "1.3".  Contour never negotiates below its own configured minimum.
+	MinimumTLSVersionKey = "contour.networking.knative.dev/minimum-tls-version"
+
+	// PathRetryPoliciesKey is placed on KIngress resources to override the
+	// retry policy of routes for path prefixes, given as a JSON object like
+	// {"/orders": {"numRetries": 0}, "/": {"perTryTimeout": "2s"}}, e.g. so
+	// that non-idempotent paths aren't retried.  Only the longest prefix
+	// covering a path overrides the default retry policy for it.
+	PathRetryPoliciesKey = "contour.networking.knative.dev/path-retry-policies"
+
+	// ClassOverrideKey is placed on KIngress resources to route their
+	// visibilities through other Contour classes than those of config-contour,
+	// given as a JSON object like {"ExternalIP": "contour-pci"}, e.g. to serve
+	// them from a dedicated Envoy fleet.  The classes must be approved for the
+	// namespace of the KIngress in the class-overrides of config-contour.
+	ClassOverrideKey = "contour.networking.knative.dev/class-override"
+
+	// PausedKey is placed on KIngress resources with the value "true" to stop
+	// us from creating, updating or deleting the resources we generate for
+	// them, so operators can hand-patch their HTTPProxies during incidents.
+	PausedKey = "contour.networking.knative.dev/paused"
 )
@@ -0,0 +1,48 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"fmt"
+
+	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+)
+
+// corsDisabled is the value of the CORSPolicyKey annotation opting an
+// Ingress out of the default CORS policy.
+const corsDisabled = "disabled"
+
+// CORSPolicy returns the CORS policy of the external virtual hosts of the
+// Ingress, or nil when they have none.  It errors when the CORSPolicyKey
+// annotation can't be programmed.
+func CORSPolicy(ctx context.Context, ing *v1alpha1.Ingress) (*v1.CORSPolicy, error) {
+	raw, ok := ing.Annotations[CORSPolicyKey]
+	switch {
+	case !ok:
+		return config.FromContext(ctx).Contour.DefaultCORSPolicy, nil
+	case raw == corsDisabled:
+		return nil, nil
+	}
+	policy, err := config.ParseCORSPolicy(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse annotation %s: %w", CORSPolicyKey, err)
+	}
+	return policy, nil
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// HTTPProxySpecEqual returns whether the specs are equal, with the semantics
+// of equality.Semantic.DeepEqual (e.g. nil and empty slices are equal).  The
+// fields MakeHTTPProxies emits are compared by hand, as reflection dominated
+// the CPU of global resyncs, and only the others fall back to reflection.
+func HTTPProxySpecEqual(a, b *v1.HTTPProxySpec) bool {
+	if a.IngressClassName != b.IngressClassName ||
+		!virtualHostEqual(a.VirtualHost, b.VirtualHost) ||
+		!tcpProxyEqual(a.TCPProxy, b.TCPProxy) ||
+		len(a.Routes) != len(b.Routes) ||
+		len(a.Includes) != len(b.Includes) {
+		return false
+	}
+	for i := range a.Routes {
+		if !routeEqual(&a.Routes[i], &b.Routes[i]) {
+			return false
+		}
+	}
+	for i := range a.Includes {
+		x, y := &a.Includes[i], &b.Includes[i]
+		if x.Name != y.Name || x.Namespace != y.Namespace || !conditionsEqual(x.Conditions, y.Conditions) {
+			return false
+		}
+	}
+	return true
+}
+
+// StringMapsEqual returns whether the maps are equal, with nil and empty maps
+// being equal as in equality.Semantic.DeepEqual.
+func StringMapsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, va := range a {
+		if vb, ok := b[k]; !ok || va != vb {
+			return false
+		}
+	}
+	return true
+}
+
+func virtualHostEqual(a, b *v1.VirtualHost) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Fqdn == b.Fqdn &&
+		tlsEqual(a.TLS, b.TLS) &&
+		semanticEqual(a.CORSPolicy == nil, b.CORSPolicy == nil, a.CORSPolicy, b.CORSPolicy) &&
+		semanticEqual(a.Authorization == nil, b.Authorization == nil, a.Authorization, b.Authorization) &&
+		semanticEqual(a.RateLimitPolicy == nil, b.RateLimitPolicy == nil, a.RateLimitPolicy, b.RateLimitPolicy)
+}
+
+func tlsEqual(a, b *v1.TLS) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.SecretName == b.SecretName &&
+		a.MinimumProtocolVersion == b.MinimumProtocolVersion &&
+		a.Passthrough == b.Passthrough &&
+		a.EnableFallbackCertificate == b.EnableFallbackCertificate &&
+		semanticEqual(a.ClientValidation == nil, b.ClientValidation == nil, a.ClientValidation, b.ClientValidation)
+}
+
+func tcpProxyEqual(a, b *v1.TCPProxy) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return loadBalancerPolicyEqual(a.LoadBalancerPolicy, b.LoadBalancerPolicy) &&
+		servicesEqual(a.Services, b.Services) &&
+		semanticEqual(a.Include == nil, b.Include == nil, a.Include, b.Include) &&
+		semanticEqual(a.IncludesDeprecated == nil, b.IncludesDeprecated == nil, a.IncludesDeprecated, b.IncludesDeprecated) &&
+		semanticEqual(a.HealthCheckPolicy == nil, b.HealthCheckPolicy == nil, a.HealthCheckPolicy, b.HealthCheckPolicy)
+}
+
+func routeEqual(a, b *v1.Route) bool {
+	return a.EnableWebsockets == b.EnableWebsockets &&
+		a.PermitInsecure == b.PermitInsecure &&
+		conditionsEqual(a.Conditions, b.Conditions) &&
+		servicesEqual(a.Services, b.Services) &&
+		timeoutPolicyEqual(a.TimeoutPolicy, b.TimeoutPolicy) &&
+		retryPolicyEqual(a.RetryPolicy, b.RetryPolicy) &&
+		loadBalancerPolicyEqual(a.LoadBalancerPolicy, b.LoadBalancerPolicy) &&
+		headersPolicyEqual(a.RequestHeadersPolicy, b.RequestHeadersPolicy) &&
+		headersPolicyEqual(a.ResponseHeadersPolicy, b.ResponseHeadersPolicy) &&
+		semanticEqual(a.AuthPolicy == nil, b.AuthPolicy == nil, a.AuthPolicy, b.AuthPolicy) &&
+		semanticEqual(a.HealthCheckPolicy == nil, b.HealthCheckPolicy == nil, a.HealthCheckPolicy, b.HealthCheckPolicy) &&
+		semanticEqual(a.PathRewritePolicy == nil, b.PathRewritePolicy == nil, a.PathRewritePolicy, b.PathRewritePolicy) &&
+		semanticEqual(a.RateLimitPolicy == nil, b.RateLimitPolicy == nil, a.RateLimitPolicy, b.RateLimitPolicy)
+}
+
+func conditionsEqual(a, b []v1.MatchCondition) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Prefix != b[i].Prefix {
+			return false
+		}
+		x, y := a[i].Header, b[i].Header
+		if x == nil || y == nil {
+			if x != y {
+				return false
+			}
+		} else if *x != *y {
+			return false
+		}
+	}
+	return true
+}
+
+func servicesEqual(a, b []v1.Service) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		x, y := &a[i], &b[i]
+		if x.Name != y.Name || x.Port != y.Port || x.Weight != y.Weight || x.Mirror != y.Mirror ||
+			!stringPtrEqual(x.Protocol, y.Protocol) ||
+			!headersPolicyEqual(x.RequestHeadersPolicy, y.RequestHeadersPolicy) ||
+			!headersPolicyEqual(x.ResponseHeadersPolicy, y.ResponseHeadersPolicy) ||
+			!semanticEqual(x.UpstreamValidation == nil, y.UpstreamValidation == nil, x.UpstreamValidation, y.UpstreamValidation) {
+			return false
+		}
+	}
+	return true
+}
+
+func timeoutPolicyEqual(a, b *v1.TimeoutPolicy) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func retryPolicyEqual(a, b *v1.RetryPolicy) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.NumRetries != b.NumRetries || a.PerTryTimeout != b.PerTryTimeout ||
+		len(a.RetryOn) != len(b.RetryOn) || len(a.RetriableStatusCodes) != len(b.RetriableStatusCodes) {
+		return false
+	}
+	for i := range a.RetryOn {
+		if a.RetryOn[i] != b.RetryOn[i] {
+			return false
+		}
+	}
+	for i := range a.RetriableStatusCodes {
+		if a.RetriableStatusCodes[i] != b.RetriableStatusCodes[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func loadBalancerPolicyEqual(a, b *v1.LoadBalancerPolicy) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Strategy == b.Strategy &&
+		semanticEqual(len(a.RequestHashPolicies) == 0, len(b.RequestHashPolicies) == 0, a.RequestHashPolicies, b.RequestHashPolicies)
+}
+
+func headersPolicyEqual(a, b *v1.HeadersPolicy) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if len(a.Set) != len(b.Set) || len(a.Remove) != len(b.Remove) {
+		return false
+	}
+	for i := range a.Set {
+		if a.Set[i] != b.Set[i] {
+			return false
+		}
+	}
+	for i := range a.Remove {
+		if a.Remove[i] != b.Remove[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func stringPtrEqual(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// semanticEqual compares fields we don't emit, which are empty in the common
+// case, by reflection.  Their emptiness is passed in separately as a typed
+// nil pointer isn't a nil interface.
+func semanticEqual(aNil, bNil bool, a, b interface{}) bool {
+	if aNil || bNil {
+		return aNil == bNil
+	}
+	return equality.Semantic.DeepEqual(a, b)
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources/benchmark"
+)
+
+func TestHTTPProxySpecEqual(t *testing.T) {
+	base := MakeHTTPProxies(config.ToContext(context.Background(), benchmarkConfig), benchmark.Ingress(benchmark.Shape{Hosts: 2, Splits: 2, Paths: 2}), nil)[0].Spec
+	if base.VirtualHost == nil || len(base.Routes) < 2 {
+		t.Fatalf("Unexpected base spec: %#v", base)
+	}
+
+	protocol := "h2c"
+	tests := []struct {
+		name   string
+		modify func(*v1.HTTPProxySpec)
+		want   bool
+	}{{
+		name:   "identical",
+		modify: func(*v1.HTTPProxySpec) {},
+		want:   true,
+	}, {
+		name: "empty and nil includes",
+		modify: func(s *v1.HTTPProxySpec) {
+			s.Includes = []v1.Include{}
+		},
+		want: true,
+	}, {
+		name: "empty and nil route headers",
+		modify: func(s *v1.HTTPProxySpec) {
+			s.Routes[0].RequestHeadersPolicy.Remove = []string{}
+		},
+		want: true,
+	}, {
+		name: "fqdn",
+		modify: func(s *v1.HTTPProxySpec) {
+			s.VirtualHost.Fqdn = "other.example.com"
+		},
+	}, {
+		name: "tls",
+		modify: func(s *v1.HTTPProxySpec) {
+			s.VirtualHost.TLS = &v1.TLS{SecretName: "ns/secret"}
+		},
+	}, {
+		name: "cors",
+		modify: func(s *v1.HTTPProxySpec) {
+			s.VirtualHost.CORSPolicy = &v1.CORSPolicy{AllowOrigin: []string{"*"}}
+		},
+	}, {
+		name: "route order",
+		modify: func(s *v1.HTTPProxySpec) {
+			s.Routes[0], s.Routes[1] = s.Routes[1], s.Routes[0]
+		},
+	}, {
+		name: "header condition",
+		modify: func(s *v1.HTTPProxySpec) {
+			s.Routes[0].Conditions = []v1.MatchCondition{{Header: &v1.HeaderMatchCondition{Name: "Foo", Present: true}}}
+		},
+	}, {
+		name: "service weight",
+		modify: func(s *v1.HTTPProxySpec) {
+			s.Routes[0].Services[0].Weight++
+		},
+	}, {
+		name: "service protocol",
+		modify: func(s *v1.HTTPProxySpec) {
+			s.Routes[0].Services[0].Protocol = &protocol
+		},
+	}, {
+		name: "split header",
+		modify: func(s *v1.HTTPProxySpec) {
+			s.Routes[0].Services[0].RequestHeadersPolicy.Set[0].Value = "other"
+		},
+	}, {
+		name: "timeout",
+		modify: func(s *v1.HTTPProxySpec) {
+			s.Routes[0].TimeoutPolicy.Idle = "1s"
+		},
+	}, {
+		name: "retry",
+		modify: func(s *v1.HTTPProxySpec) {
+			s.Routes[0].RetryPolicy.RetryOn = append(s.Routes[0].RetryPolicy.RetryOn, "reset")
+		},
+	}, {
+		name: "load balancer",
+		modify: func(s *v1.HTTPProxySpec) {
+			s.Routes[0].LoadBalancerPolicy = &v1.LoadBalancerPolicy{Strategy: "Cookie"}
+		},
+	}, {
+		name: "path rewrite",
+		modify: func(s *v1.HTTPProxySpec) {
+			s.Routes[0].PathRewritePolicy = &v1.PathRewritePolicy{ReplacePrefix: []v1.ReplacePrefix{{Replacement: "/"}}}
+		},
+	}, {
+		name: "insecure",
+		modify: func(s *v1.HTTPProxySpec) {
+			s.Routes[0].PermitInsecure = !s.Routes[0].PermitInsecure
+		},
+	}, {
+		name: "tcp proxy",
+		modify: func(s *v1.HTTPProxySpec) {
+			s.Routes = nil
+			s.TCPProxy = &v1.TCPProxy{Services: []v1.Service{{Name: "foo", Port: 80}}}
+		},
+	}, {
+		name: "includes",
+		modify: func(s *v1.HTTPProxySpec) {
+			s.Includes = []v1.Include{{Name: "routes", Namespace: "ns"}}
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			other := base.DeepCopy()
+			test.modify(other)
+
+			if got := HTTPProxySpecEqual(&base, other); got != test.want {
+				t.Errorf("HTTPProxySpecEqual() = %v, wanted %v", got, test.want)
+			}
+			if got := HTTPProxySpecEqual(other, &base); got != test.want {
+				t.Errorf("HTTPProxySpecEqual() reversed = %v, wanted %v", got, test.want)
+			}
+			// Keep us honest about the semantics we replace.
+			if semantic := equality.Semantic.DeepEqual(base, *other); semantic != test.want {
+				t.Errorf("equality.Semantic.DeepEqual() = %v, wanted %v", semantic, test.want)
+			}
+		})
+	}
+}
+
+func TestStringMapsEqual(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b map[string]string
+		want bool
+	}{{
+		name: "nil and empty",
+		a:    nil,
+		b:    map[string]string{},
+		want: true,
+	}, {
+		name: "equal",
+		a:    map[string]string{"a": "b"},
+		b:    map[string]string{"a": "b"},
+		want: true,
+	}, {
+		name: "different value",
+		a:    map[string]string{"a": "b"},
+		b:    map[string]string{"a": "c"},
+	}, {
+		name: "different key",
+		a:    map[string]string{"a": ""},
+		b:    map[string]string{"b": ""},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := StringMapsEqual(test.a, test.b); got != test.want {
+				t.Errorf("StringMapsEqual() = %v, wanted %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+)
+
+// headerMatchers build the Contour header condition of each operator we
+// accept in the HeaderMatchKey annotation, from the header name and the
+// value of its KIngress match.
+var headerMatchers = map[string]func(name, value string) *v1.HeaderMatchCondition{
+	"exact": func(name, value string) *v1.HeaderMatchCondition {
+		return &v1.HeaderMatchCondition{Name: name, Exact: value}
+	},
+	"contains": func(name, value string) *v1.HeaderMatchCondition {
+		return &v1.HeaderMatchCondition{Name: name, Contains: value}
+	},
+	"present": func(name, _ string) *v1.HeaderMatchCondition {
+		return &v1.HeaderMatchCondition{Name: name, Present: true}
+	},
+	"notexact": func(name, value string) *v1.HeaderMatchCondition {
+		return &v1.HeaderMatchCondition{Name: name, NotExact: value}
+	},
+	"notcontains": func(name, value string) *v1.HeaderMatchCondition {
+		return &v1.HeaderMatchCondition{Name: name, NotContains: value}
+	},
+	"notpresent": func(name, _ string) *v1.HeaderMatchCondition {
+		return &v1.HeaderMatchCondition{Name: name, NotPresent: true}
+	},
+}
+
+// inexpressibleHeaderMatchers are operators users ask for, that the Contour
+// version we support has no header condition for.
+var inexpressibleHeaderMatchers = sets.NewString("prefix", "suffix")
+
+// HeaderMatchOperators returns the operators of the HeaderMatchKey annotation
+// keyed by canonical header name, or an error when they can't be programmed.
+func HeaderMatchOperators(ing *v1alpha1.Ingress) (map[string]string, error) {
+	raw, ok := ing.Annotations[HeaderMatchKey]
+	if !ok {
+		return nil, nil
+	}
+	var operators map[string]string
+	if err := json.Unmarshal([]byte(raw), &operators); err != nil {
+		return nil, fmt.Errorf("failed to parse annotation %s: %w", HeaderMatchKey, err)
+	}
+
+	canonical := make(map[string]string, len(operators))
+	for name, op := range operators {
+		if _, ok := headerMatchers[op]; !ok {
+			if inexpressibleHeaderMatchers.Has(op) {
+				return nil, fmt.Errorf("header %q: the installed Contour can't match headers by %s", name, op)
+			}
+			return nil, fmt.Errorf("header %q: unknown match operator %q", name, op)
+		}
+		canonical[http.CanonicalHeaderKey(name)] = op
+	}
+	return canonical, nil
+}
+
+// headerCondition returns the Contour condition matching the header name as
+// requested by the operators of its KIngress.
+func headerCondition(operators map[string]string, name string, match v1alpha1.HeaderMatch) *v1.HeaderMatchCondition {
+	if op, ok := operators[http.CanonicalHeaderKey(name)]; ok {
+		return headerMatchers[op](name, match.Exact)
+	}
+	return headerMatchers["exact"](name, match.Exact)
+}
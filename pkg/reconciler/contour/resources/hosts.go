@@ -0,0 +1,113 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/pkg/network"
+)
+
+// WithoutUnmanagedHosts returns the Ingress without the hosts listed in its
+// UnmanagedHostsKey annotation, dropping the rules left without hosts.  The
+// Ingress itself is returned when it lists none.
+func WithoutUnmanagedHosts(ing *v1alpha1.Ingress) *v1alpha1.Ingress {
+	raw := ing.Annotations[UnmanagedHostsKey]
+	if strings.TrimSpace(raw) == "" {
+		return ing
+	}
+	unmanaged := sets.NewString()
+	for _, host := range strings.Split(raw, ",") {
+		unmanaged.Insert(strings.TrimSpace(host))
+	}
+
+	return withoutHosts(ing, unmanaged)
+}
+
+// withoutHosts returns a copy of the Ingress without the hosts, dropping the
+// rules left without hosts.
+func withoutHosts(ing *v1alpha1.Ingress, drop sets.String) *v1alpha1.Ingress {
+	ing = ing.DeepCopy()
+	rules := ing.Spec.Rules[:0]
+	for _, rule := range ing.Spec.Rules {
+		hosts := rule.Hosts[:0]
+		for _, host := range rule.Hosts {
+			if !drop.Has(host) {
+				hosts = append(hosts, host)
+			}
+		}
+		if len(hosts) != 0 {
+			rule.Hosts = hosts
+			rules = append(rules, rule)
+		}
+	}
+	ing.Spec.Rules = rules
+	return ing
+}
+
+// DisallowedHosts returns the external hosts of the Ingress that aren't under
+// the allowed-domains of config-contour, sorted.  The cluster-local names of
+// external rules are always allowed, as they are never exposed externally.
+func DisallowedHosts(ctx context.Context, ing *v1alpha1.Ingress) []string {
+	allowed := config.FromContext(ctx).Contour.AllowedDomains
+	if allowed.Len() == 0 {
+		return nil
+	}
+	disallowed := sets.NewString()
+	for _, rule := range ing.Spec.Rules {
+		if rule.Visibility != v1alpha1.IngressVisibilityExternalIP {
+			continue
+		}
+		for _, host := range rule.Hosts {
+			if !strings.HasSuffix(host, network.GetClusterDomainName()) && !underDomains(allowed, host) {
+				disallowed.Insert(host)
+			}
+		}
+	}
+	return disallowed.List()
+}
+
+// underDomains returns whether the host is one of the domains or a
+// subdomain of one.
+func underDomains(domains sets.String, host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for {
+		if domains.Has(host) {
+			return true
+		}
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			return false
+		}
+		host = host[i+1:]
+	}
+}
+
+// WithoutDisallowedHosts returns the Ingress without its DisallowedHosts,
+// dropping the rules left without hosts.  The Ingress itself is returned
+// when it has none.
+func WithoutDisallowedHosts(ctx context.Context, ing *v1alpha1.Ingress) *v1alpha1.Ingress {
+	disallowed := DisallowedHosts(ctx, ing)
+	if len(disallowed) == 0 {
+		return ing
+	}
+	return withoutHosts(ing, sets.NewString(disallowed...))
+}
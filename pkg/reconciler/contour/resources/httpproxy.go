@@ -28,6 +28,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/net-contour/pkg/ownership"
 	"knative.dev/net-contour/pkg/reconciler/contour/config"
 	"knative.dev/networking/pkg/apis/networking/v1alpha1"
 	"knative.dev/networking/pkg/ingress"
@@ -93,9 +94,52 @@ func defaultRetryPolicy() *v1.RetryPolicy {
 	}
 }
 
+// loadBalancerPolicy returns the configured default load balancer policy
+// for routes of the given visibility, or nil to use Contour's default.
+func loadBalancerPolicy(ctx context.Context, vis v1alpha1.IngressVisibility) *v1.LoadBalancerPolicy {
+	if strategy, ok := config.FromContext(ctx).Contour.LoadBalancerPolicies[vis]; ok {
+		return &v1.LoadBalancerPolicy{Strategy: strategy}
+	}
+	return nil
+}
+
+// responseHeadersPolicy returns the headers routes set on their responses,
+// or nil when they set none.  Contour expands the %REQ()% of the request id
+// Envoy generated for requests without one.
+func responseHeadersPolicy(ctx context.Context) *v1.HeadersPolicy {
+	if !config.FromContext(ctx).Contour.ExposeRequestID {
+		return nil
+	}
+	return &v1.HeadersPolicy{
+		Set: []v1.HeaderValue{{
+			Name:  "X-Request-Id",
+			Value: "%REQ(X-Request-Id)%",
+		}},
+	}
+}
+
+// forwardedPrefix returns the X-Forwarded-Prefix the route of the path sets,
+// or empty when it sets none.  KIngresses don't rewrite paths, so backends
+// see the whole path and the prefix is just the one we match.  A header the
+// Ingress appends itself wins.
+func forwardedPrefix(ctx context.Context, path v1alpha1.HTTPIngressPath) string {
+	if !config.FromContext(ctx).Contour.ForwardedPrefix {
+		return ""
+	}
+	for key := range path.AppendHeaders {
+		if strings.EqualFold(key, "X-Forwarded-Prefix") {
+			return ""
+		}
+	}
+	return strings.TrimSuffix(path.Path, "/")
+}
+
 func MakeHTTPProxies(ctx context.Context, ing *v1alpha1.Ingress, serviceToProtocol map[string]string) []*v1.HTTPProxy {
 	ing = ing.DeepCopy()
 	ingress.InsertProbe(ing)
+	// Drop the hosts users program themselves, and those they may not
+	// claim, only now, as the probe hash covers the whole Ingress.
+	ing = WithoutDisallowedHosts(ctx, WithoutUnmanagedHosts(ing))
 
 	hostToTLS := make(map[string]*v1alpha1.IngressTLS, len(ing.Spec.TLS))
 	for _, tls := range ing.Spec.TLS {
@@ -115,9 +159,40 @@ func MakeHTTPProxies(ctx context.Context, ing *v1alpha1.Ingress, serviceToProtoc
 		allowInsecure = false
 	}
 
+	// Insecure paths are only exceptions to redirecting HTTP to HTTPS.
+	var insecurePaths []string
+	if !allowInsecure {
+		insecurePaths = InsecurePaths(ing)
+	}
+	authDisabledPaths := AuthDisabledPaths(ing)
+	tcp := IsTCPProxy(ing)
+
+	// Invalid operators are surfaced on the KIngress by the reconciler, fall
+	// back to exact matches if we are asked for proxies anyway.
+	headerOperators, _ := HeaderMatchOperators(ing)
+	// Likewise for invalid CORS policies, which fall back to the default.
+	cors, err := CORSPolicy(ctx, ing)
+	if err != nil {
+		cors = config.FromContext(ctx).Contour.DefaultCORSPolicy
+	}
+	// And for invalid TLS versions, which fall back to Contour's default.
+	minTLSVersion, _ := MinimumTLSVersion(ing)
+	authContext, _ := AuthContext(ing)
+	retryOverrides, _ := PathRetryPolicies(ing)
+
 	proxies := []*v1.HTTPProxy{}
-	for _, rule := range ing.Spec.Rules {
+	for ruleIndex, rule := range ing.Spec.Rules {
 		class := config.FromContext(ctx).Contour.VisibilityClasses[rule.Visibility]
+		// The HTTPProxies carrying the routes of this rule for the virtual
+		// hosts to include, by class, when ProxyIncludes is set.
+		routeProxies := make(map[string]*v1.HTTPProxy, 1)
+
+		rulePaths := make([]string, 0, len(rule.HTTP.Paths))
+		for _, path := range rule.HTTP.Paths {
+			if path.Path != "" {
+				rulePaths = append(rulePaths, path.Path)
+			}
+		}
 
 		routes := make([]v1.Route, 0, len(rule.HTTP.Paths))
 		for _, path := range rule.HTTP.Paths {
@@ -149,6 +224,13 @@ func MakeHTTPProxies(ctx context.Context, ing *v1alpha1.Ingress, serviceToProtoc
 				})
 			}
 
+			if prefix := forwardedPrefix(ctx, path); prefix != "" {
+				preSplitHeaders.Set = append(preSplitHeaders.Set, v1.HeaderValue{
+					Name:  "X-Forwarded-Prefix",
+					Value: prefix,
+				})
+			}
+
 			// This should never be empty due to the InsertProbe
 			sort.Slice(preSplitHeaders.Set, func(i, j int) bool {
 				return preSplitHeaders.Set[i].Name < preSplitHeaders.Set[j].Name
@@ -156,10 +238,18 @@ func MakeHTTPProxies(ctx context.Context, ing *v1alpha1.Ingress, serviceToProtoc
 
 			svcs := make([]v1.Service, 0, len(path.Splits))
 			for _, split := range path.Splits {
+				// The headers of splits, e.g. the Knative-Serving-Revision of
+				// tag routes, go on the request headers policy of their
+				// Service, so they are only added to the requests routed to it.
 				postSplitHeaders := &v1.HeadersPolicy{
 					Set: make([]v1.HeaderValue, 0, len(split.AppendHeaders)),
 				}
 				for key, value := range split.AppendHeaders {
+					if strings.EqualFold(key, "Host") {
+						// Contour rejects the whole HTTPProxy for host
+						// rewrites of a Service, see IgnoredFeatures.
+						continue
+					}
 					postSplitHeaders.Set = append(postSplitHeaders.Set, v1.HeaderValue{
 						Name:  key,
 						Value: value,
@@ -193,10 +283,7 @@ func MakeHTTPProxies(ctx context.Context, ing *v1alpha1.Ingress, serviceToProtoc
 			}
 			for header, match := range path.Headers {
 				conditions = append(conditions, v1.MatchCondition{
-					Header: &v1.HeaderMatchCondition{
-						Name:  header,
-						Exact: match.Exact,
-					},
+					Header: headerCondition(headerOperators, header, match),
 				})
 			}
 
@@ -215,24 +302,33 @@ func MakeHTTPProxies(ctx context.Context, ing *v1alpha1.Ingress, serviceToProtoc
 			}
 
 			routes = append(routes, v1.Route{
-				Conditions:           conditions,
-				TimeoutPolicy:        top,
-				RetryPolicy:          retry,
-				Services:             svcs,
-				EnableWebsockets:     true,
-				RequestHeadersPolicy: preSplitHeaders,
-				PermitInsecure:       allowInsecure,
+				Conditions:            conditions,
+				TimeoutPolicy:         top,
+				RetryPolicy:           retry,
+				Services:              svcs,
+				EnableWebsockets:      true,
+				RequestHeadersPolicy:  preSplitHeaders,
+				PermitInsecure:        allowInsecure,
+				LoadBalancerPolicy:    loadBalancerPolicy(ctx, rule.Visibility),
+				AuthPolicy:            authPolicy(authContext),
+				ResponseHeadersPolicy: responseHeadersPolicy(ctx),
 			})
+			route := len(routes) - 1
+			routes = append(routes, exceptionRoutes(&routes[route], rulePaths, insecurePaths, authDisabledPaths, retryOverrides)...)
+			if ws := websocketRoute(ctx, &routes[route]); ws != nil {
+				routes = append(routes, *ws)
+			}
+			for i, end := route, len(routes); i < end; i++ {
+				routes = append(routes, splitOverrideRoutes(ctx, &routes[i])...)
+			}
 		}
 
 		base := v1.HTTPProxy{
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: ing.Namespace,
-				Labels: map[string]string{
-					GenerationKey: fmt.Sprintf("%d", ing.Generation),
-					ParentKey:     ing.Name,
-					ClassKey:      class,
-				},
+				Labels: kmeta.UnionMaps(ownership.GenerationLabels(ing.Name, ing.Generation), map[string]string{
+					ClassKey: class,
+				}),
 				Annotations: map[string]string{
 					ClassKey: class,
 				},
@@ -249,28 +345,72 @@ func MakeHTTPProxies(ctx context.Context, ing *v1alpha1.Ingress, serviceToProtoc
 				hostProxy := base.DeepCopy()
 
 				class := class
+				external := rule.Visibility == v1alpha1.IngressVisibilityExternalIP
 
 				// Ideally these would just be marked ClusterLocal :(
 				if strings.HasSuffix(originalHost, network.GetClusterDomainName()) {
+					external = false
 					class = config.FromContext(ctx).Contour.VisibilityClasses[v1alpha1.IngressVisibilityClusterLocal]
 					hostProxy.Annotations[ClassKey] = class
 					hostProxy.Labels[ClassKey] = class
+					for i := range hostProxy.Spec.Routes {
+						hostProxy.Spec.Routes[i].LoadBalancerPolicy = loadBalancerPolicy(ctx, v1alpha1.IngressVisibilityClusterLocal)
+					}
 				}
 
 				hostProxy.Name = kmeta.ChildName(ing.Name+"-"+class+"-", host)
 				hostProxy.Spec.VirtualHost = &v1.VirtualHost{
 					Fqdn: host,
 				}
+				if external && !tcp {
+					hostProxy.Spec.VirtualHost.CORSPolicy = cors.DeepCopy()
+				}
 				// nolint:gosec // No strong cryptography needed.
 				hostProxy.Labels[DomainHashKey] = fmt.Sprintf("%x", sha1.Sum([]byte(host)))
 
 				if tls, ok := hostToTLS[host]; ok {
 					// TODO(mattmoor): How do we deal with custom secret schemas?
 					hostProxy.Spec.VirtualHost.TLS = &v1.TLS{
-						SecretName: fmt.Sprintf("%s/%s", tls.SecretNamespace, tls.SecretName),
+						SecretName:             fmt.Sprintf("%s/%s", tls.SecretNamespace, tls.SecretName),
+						MinimumProtocolVersion: minTLSVersion,
 					}
 				} else if s := config.FromContext(ctx).Contour.DefaultTLSSecret; s != nil {
-					hostProxy.Spec.VirtualHost.TLS = &v1.TLS{SecretName: s.String()}
+					hostProxy.Spec.VirtualHost.TLS = &v1.TLS{
+						SecretName:             s.String(),
+						MinimumProtocolVersion: minTLSVersion,
+					}
+				}
+
+				if s := config.FromContext(ctx).Contour.AuthorizationServer; s != nil && external && !tcp &&
+					hostProxy.Spec.VirtualHost.TLS != nil {
+					hostProxy.Spec.VirtualHost.Authorization = &v1.AuthorizationServer{
+						ExtensionServiceRef: v1.ExtensionServiceReference{
+							Namespace: s.Namespace,
+							Name:      s.Name,
+						},
+					}
+				}
+
+				if tcp {
+					// Contour picks the proxied backend by the SNI of the
+					// connection, so it must carry TLS.
+					hostProxy.Spec.Routes = nil
+					hostProxy.Spec.TCPProxy = tcpProxy(rule)
+					if _, ok := hostToTLS[host]; !ok {
+						hostProxy.Spec.VirtualHost.TLS = &v1.TLS{Passthrough: true}
+					}
+				} else if config.FromContext(ctx).Contour.ProxyIncludes {
+					routeProxy, ok := routeProxies[class]
+					if !ok {
+						routeProxy = makeRouteProxy(ing, hostProxy, ruleIndex)
+						routeProxies[class] = routeProxy
+						proxies = append(proxies, routeProxy)
+					}
+					hostProxy.Spec.Routes = nil
+					hostProxy.Spec.Includes = []v1.Include{{
+						Name:      routeProxy.Name,
+						Namespace: routeProxy.Namespace,
+					}}
 				}
 
 				proxies = append(proxies, hostProxy)
@@ -280,3 +420,18 @@ func MakeHTTPProxies(ctx context.Context, ing *v1alpha1.Ingress, serviceToProtoc
 
 	return proxies
 }
+
+// makeRouteProxy returns the HTTPProxy carrying the routes of the host proxy
+// of the rule, without a virtual host of its own, for the host proxies of the
+// rule in the same class to include.
+func makeRouteProxy(ing *v1alpha1.Ingress, hostProxy *v1.HTTPProxy, ruleIndex int) *v1.HTTPProxy {
+	routeProxy := hostProxy.DeepCopy()
+	class := routeProxy.Labels[ClassKey]
+	routeProxy.Name = kmeta.ChildName(ing.Name+"-"+class+"-routes-", fmt.Sprintf("%d", ruleIndex))
+	routeProxy.Spec.VirtualHost = nil
+	// The reconciler finds the proxies it programmed by this label, which
+	// must be unique among the proxies of the Ingress.
+	// nolint:gosec // No strong cryptography needed.
+	routeProxy.Labels[DomainHashKey] = fmt.Sprintf("%x", sha1.Sum([]byte(routeProxy.Name)))
+	return routeProxy
+}
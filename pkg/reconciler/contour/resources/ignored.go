@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/pkg/network"
+)
+
+// IgnoredFeatures returns the parts of the Ingress' spec that MakeHTTPProxies
+// can't express and drops or reinterprets, sorted for stable reporting.
+func IgnoredFeatures(ing *v1alpha1.Ingress) []string {
+	ing = WithoutUnmanagedHosts(ing)
+	tcp := IsTCPProxy(ing)
+
+	ignored := sets.NewString()
+	ruleHosts := sets.NewString()
+	for _, rule := range ing.Spec.Rules {
+		ruleHosts.Insert(rule.Hosts...)
+
+		if rule.Visibility == v1alpha1.IngressVisibilityExternalIP {
+			for _, host := range rule.Hosts {
+				if strings.HasSuffix(host, network.GetClusterDomainName()) {
+					ignored.Insert(fmt.Sprintf("host %q is only exposed cluster-locally despite its ExternalIP visibility", host))
+				}
+			}
+		}
+
+		if rule.HTTP == nil {
+			continue
+		}
+		for i, path := range rule.HTTP.Paths {
+			for _, split := range path.Splits {
+				if split.ServiceNamespace != "" && split.ServiceNamespace != ing.Namespace {
+					ignored.Insert(fmt.Sprintf("namespace %q of service %q, which is looked up in %q",
+						split.ServiceNamespace, split.ServiceName, ing.Namespace))
+				}
+				for key := range split.AppendHeaders {
+					if !tcp && strings.EqualFold(key, "Host") {
+						ignored.Insert(fmt.Sprintf("Host header of the split to service %q, Contour only rewrites the host of whole paths",
+							split.ServiceName))
+					}
+				}
+			}
+			if !tcp {
+				continue
+			}
+			if i > 0 {
+				ignored.Insert(fmt.Sprintf("path %q of a TCP proxy, only the first path is proxied", path.Path))
+				continue
+			}
+			if path.Path != "" || len(path.Headers) != 0 {
+				ignored.Insert("path and header matches of a TCP proxy")
+			}
+			if path.RewriteHost != "" || len(path.AppendHeaders) != 0 {
+				ignored.Insert("host rewrites and appended headers of a TCP proxy")
+			}
+			for _, split := range path.Splits {
+				if len(split.AppendHeaders) != 0 {
+					ignored.Insert("host rewrites and appended headers of a TCP proxy")
+				}
+			}
+		}
+	}
+
+	for _, tls := range ing.Spec.TLS {
+		for _, host := range tls.Hosts {
+			if !ruleHosts.Has(host) {
+				ignored.Insert(fmt.Sprintf("TLS for host %q, which no rule routes", host))
+			}
+		}
+	}
+	return ignored.List()
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"strings"
+
+	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+)
+
+// InsecurePaths returns the path prefixes of the InsecurePathsKey annotation
+// of the Ingress, skipping the entries that aren't absolute paths.
+func InsecurePaths(ing *v1alpha1.Ingress) []string {
+	return annotatedPaths(ing, InsecurePathsKey)
+}
+
+// AuthDisabledPaths returns the path prefixes of the AuthDisabledPathsKey
+// annotation of the Ingress, skipping the entries that aren't absolute paths.
+func AuthDisabledPaths(ing *v1alpha1.Ingress) []string {
+	return annotatedPaths(ing, AuthDisabledPathsKey)
+}
+
+func annotatedPaths(ing *v1alpha1.Ingress, key string) []string {
+	var paths []string
+	for _, path := range strings.Split(ing.Annotations[key], ",") {
+		if path = strings.TrimSpace(path); strings.HasPrefix(path, "/") {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// exceptionRoutes returns copies of the route that take over the insecure,
+// auth-disabled and retry overridden paths it serves, permitting plain HTTP,
+// disabling authorization and retrying differently for them respectively.
+// Contour prefers the longest matching prefix, so a copy inherits the
+// exceptions of the paths it is under.  A route whose own prefix is an
+// exception gets the exception instead.  Paths that a more specific path of
+// the rule, one of rulePaths, also serves are left to that path's route, as a
+// copy of ours would take their traffic away from it.
+func exceptionRoutes(route *v1.Route, rulePaths, insecurePaths, authDisabledPaths []string, retries map[string]RetryOverride) []v1.Route {
+	prefix := "/"
+	for _, cond := range route.Conditions {
+		if cond.Prefix != "" {
+			prefix = cond.Prefix
+		}
+	}
+	if coversPath(insecurePaths, prefix) {
+		route.PermitInsecure = true
+	}
+	if coversPath(authDisabledPaths, prefix) {
+		disableAuth(route)
+	}
+	baseRetry := route.RetryPolicy
+	route.RetryPolicy = pathRetryPolicy(retries, baseRetry, prefix)
+
+	var more []string
+	for _, path := range rulePaths {
+		if underPath(prefix, path) {
+			more = append(more, path)
+		}
+	}
+
+	var routes []v1.Route
+	seen := sets.NewString(prefix)
+	paths := append(append([]string{}, insecurePaths...), authDisabledPaths...)
+	for _, path := range append(paths, retryPaths(retries)...) {
+		if seen.Has(path) || !underPath(prefix, path) || coversPath(more, path) {
+			continue
+		}
+		seen.Insert(path)
+
+		insecure := route.PermitInsecure || coversPath(insecurePaths, path)
+		noAuth := authDisabled(route) || coversPath(authDisabledPaths, path)
+		retry := pathRetryPolicy(retries, baseRetry, path)
+		if insecure == route.PermitInsecure && noAuth == authDisabled(route) &&
+			equality.Semantic.DeepEqual(retry, route.RetryPolicy) {
+			// The route already treats the path this way.
+			continue
+		}
+		exception := route.DeepCopy()
+		exception.PermitInsecure = insecure
+		if noAuth {
+			disableAuth(exception)
+		}
+		exception.RetryPolicy = retry
+		conditions := []v1.MatchCondition{{Prefix: path}}
+		for _, cond := range exception.Conditions {
+			if cond.Prefix == "" {
+				conditions = append(conditions, cond)
+			}
+		}
+		exception.Conditions = conditions
+		routes = append(routes, *exception)
+	}
+	return routes
+}
+
+// coversPath returns whether the path is one of the prefixes or under one.
+func coversPath(prefixes []string, path string) bool {
+	for _, prefix := range prefixes {
+		if prefix == path || underPath(prefix, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// underPath returns whether the path is strictly under the prefix, segment
+// wise, e.g. "/api/hooks" is under "/api" but "/apiary" isn't.
+func underPath(prefix, path string) bool {
+	if prefix == "/" {
+		return path != "/"
+	}
+	return strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/")
+}
@@ -24,6 +24,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/net-contour/pkg/ownership"
 	"knative.dev/net-contour/pkg/reconciler/contour/config"
 	"knative.dev/net-contour/pkg/reconciler/contour/resources/names"
 	"knative.dev/networking/pkg/apis/networking/v1alpha1"
@@ -40,7 +41,7 @@ func MakeEndpointProbeIngress(ctx context.Context, ing *v1alpha1.Ingress, previo
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      names.EndpointProbeIngress(ing),
 			Namespace: ing.Namespace,
-			Labels:    ing.Labels,
+			Labels:    kmeta.UnionMaps(ing.Labels, ownership.Labels(ing.Name)),
 			Annotations: kmeta.UnionMaps(ing.Annotations, map[string]string{
 				EndpointsProbeKey: "true",
 			}),
@@ -24,6 +24,7 @@ import (
 	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"knative.dev/net-contour/pkg/ownership"
 	"knative.dev/net-contour/pkg/reconciler/contour/config"
 	"knative.dev/networking/pkg/apis/networking/v1alpha1"
 	"knative.dev/pkg/network"
@@ -94,6 +95,7 @@ func TestMakeEndpointProbeIngress(t *testing.T) {
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: "foo",
 				Name:      "bar--ep",
+				Labels:    ownership.Labels("bar"),
 				Annotations: map[string]string{
 					EndpointsProbeKey: "true",
 				},
@@ -172,6 +174,7 @@ func TestMakeEndpointProbeIngress(t *testing.T) {
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: "foo",
 				Name:      "bar--ep",
+				Labels:    ownership.Labels("bar"),
 				Annotations: map[string]string{
 					EndpointsProbeKey: "true",
 				},
@@ -232,6 +235,7 @@ func TestMakeEndpointProbeIngress(t *testing.T) {
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: "foo",
 				Name:      "bar--ep",
+				Labels:    ownership.Labels("bar"),
 				Annotations: map[string]string{
 					EndpointsProbeKey: "true",
 				},
@@ -311,6 +315,7 @@ func TestMakeEndpointProbeIngress(t *testing.T) {
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: "foo",
 				Name:      "bar--ep",
+				Labels:    ownership.Labels("bar"),
 				Annotations: map[string]string{
 					EndpointsProbeKey: "true",
 				},
@@ -456,6 +461,7 @@ func TestMakeEndpointProbeIngress(t *testing.T) {
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: "foo",
 				Name:      "bar--ep",
+				Labels:    ownership.Labels("bar"),
 				Annotations: map[string]string{
 					EndpointsProbeKey: "true",
 				},
@@ -625,6 +631,7 @@ func TestMakeEndpointProbeIngress(t *testing.T) {
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: "foo",
 				Name:      "bar--ep",
+				Labels:    ownership.Labels("bar"),
 				Annotations: map[string]string{
 					EndpointsProbeKey: "true",
 				},
@@ -825,6 +832,7 @@ func TestMakeEndpointProbeIngress(t *testing.T) {
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: "foo",
 				Name:      "bar--ep",
+				Labels:    ownership.Labels("bar"),
 				Annotations: map[string]string{
 					EndpointsProbeKey: "true",
 				},
@@ -902,6 +910,7 @@ func TestMakeEndpointProbeIngress(t *testing.T) {
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: "foo",
 				Name:      "bar--ep",
+				Labels:    ownership.Labels("bar"),
 				Annotations: map[string]string{
 					EndpointsProbeKey: "true",
 				},
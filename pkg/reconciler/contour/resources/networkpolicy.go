@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/net-contour/pkg/ownership"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/pkg/kmeta"
+)
+
+// namespaceNameLabel is the label Kubernetes gives every namespace with its
+// name, so NetworkPolicies can select namespaces by name.
+const namespaceNameLabel = "kubernetes.io/metadata.name"
+
+// NetworkPolicyName returns the name of the NetworkPolicy of the Ingress.
+func NetworkPolicyName(ing *v1alpha1.Ingress) string {
+	return kmeta.ChildName(ing.Name, "-contour")
+}
+
+// MakeNetworkPolicy returns the NetworkPolicy allowing the traffic from the
+// Envoys in the given namespaces to the target ports of the backends of the
+// Ingress.  The backends of Knative are selectorless Services, so the
+// policy selects every pod of the namespace.  Selecting a pod isolates it
+// from any traffic no policy allows, so it is only meant for namespaces
+// which already deny ingress traffic by default.
+func MakeNetworkPolicy(ing *v1alpha1.Ingress, services []*corev1.Service, envoyNamespaces sets.String) *networkingv1.NetworkPolicy {
+	tcp := corev1.ProtocolTCP
+	seen := sets.NewString()
+	var ports []networkingv1.NetworkPolicyPort
+	for _, svc := range services {
+		for _, port := range svc.Spec.Ports {
+			target := port.TargetPort
+			if target.Type == intstr.Int && target.IntVal == 0 {
+				// Kubernetes defaults the target port to the port.
+				target = intstr.FromInt(int(port.Port))
+			}
+			if seen.Has(target.String()) {
+				continue
+			}
+			seen.Insert(target.String())
+			ports = append(ports, networkingv1.NetworkPolicyPort{Protocol: &tcp, Port: &target})
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		return ports[i].Port.String() < ports[j].Port.String()
+	})
+
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            NetworkPolicyName(ing),
+			Namespace:       ing.Namespace,
+			Labels:          ownership.Labels(ing.Name),
+			OwnerReferences: []metav1.OwnerReference{*kmeta.NewControllerRef(ing)},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{{
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{{
+							Key:      namespaceNameLabel,
+							Operator: metav1.LabelSelectorOpIn,
+							Values:   envoyNamespaces.List(),
+						}},
+					},
+				}},
+				Ports: ports,
+			}},
+		},
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+)
+
+func TestMakeNetworkPolicy(t *testing.T) {
+	ing := &v1alpha1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "foo",
+			Name:      "bar",
+		},
+	}
+	service := func(name string, ports ...corev1.ServicePort) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ing.Namespace, Name: name},
+			Spec:       corev1.ServiceSpec{Ports: ports},
+		}
+	}
+	services := []*corev1.Service{
+		service("a", corev1.ServicePort{Port: 80, TargetPort: intstr.FromInt(8012)}),
+		service("b", corev1.ServicePort{Port: 80, TargetPort: intstr.FromInt(8012)},
+			corev1.ServicePort{Port: 81, TargetPort: intstr.FromString("http")}),
+		// Kubernetes defaults the target port to the port.
+		service("c", corev1.ServicePort{Port: 9090}),
+	}
+
+	got := MakeNetworkPolicy(ing, services, sets.NewString("contour-internal", "contour-external"))
+
+	if got.Namespace != ing.Namespace || got.Labels[ParentKey] != ing.Name {
+		t.Errorf("NetworkPolicy %s/%s with labels %v isn't the Ingress' child", got.Namespace, got.Name, got.Labels)
+	}
+	if len(got.OwnerReferences) != 1 || got.OwnerReferences[0].Name != ing.Name {
+		t.Errorf("OwnerReferences = %v, wanted the Ingress", got.OwnerReferences)
+	}
+	if len(got.Spec.Ingress) != 1 {
+		t.Fatalf("Ingress = %v, wanted one rule", got.Spec.Ingress)
+	}
+	rule := got.Spec.Ingress[0]
+	if got, want := rule.From[0].NamespaceSelector.MatchExpressions[0].Values, []string{"contour-external", "contour-internal"}; !cmp.Equal(got, want) {
+		t.Errorf("Envoy namespaces = %v, wanted %v", got, want)
+	}
+	var ports []string
+	for _, p := range rule.Ports {
+		ports = append(ports, p.Port.String())
+	}
+	if want := []string{"8012", "9090", "http"}; !cmp.Equal(ports, want) {
+		t.Errorf("Ports = %v, wanted %v", ports, want)
+	}
+}
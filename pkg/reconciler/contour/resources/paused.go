@@ -0,0 +1,29 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"strings"
+
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+)
+
+// IsPaused returns whether the PausedKey annotation asks us to leave the
+// generated resources of the Ingress alone.
+func IsPaused(ing *v1alpha1.Ingress) bool {
+	return strings.EqualFold(ing.Annotations[PausedKey], "true")
+}
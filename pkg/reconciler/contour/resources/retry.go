@@ -0,0 +1,118 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+)
+
+// RetryOverride is the retry policy of a path prefix of the
+// PathRetryPoliciesKey annotation.
+type RetryOverride struct {
+	// NumRetries is the number of retries, 0 disabling them.  It defaults
+	// to the retries of every route.
+	NumRetries *int64 `json:"numRetries,omitempty"`
+
+	// PerTryTimeout bounds each try, e.g. "2s".  It defaults to Contour's
+	// default.
+	PerTryTimeout string `json:"perTryTimeout,omitempty"`
+}
+
+// apply returns the retry policy of routes overriding the base one, which is
+// nil when retries are disabled.
+func (o RetryOverride) apply(base *v1.RetryPolicy) *v1.RetryPolicy {
+	if o.NumRetries != nil && *o.NumRetries == 0 {
+		return nil
+	}
+	retry := defaultRetryPolicy()
+	if base != nil {
+		retry = base.DeepCopy()
+	}
+	if o.NumRetries != nil {
+		retry.NumRetries = *o.NumRetries
+	}
+	if o.PerTryTimeout != "" {
+		retry.PerTryTimeout = o.PerTryTimeout
+	}
+	return retry
+}
+
+// PathRetryPolicies returns the retry policies of the PathRetryPoliciesKey
+// annotation of the Ingress by path prefix, or nil when it has none.  It
+// errors when the annotation can't be programmed.
+func PathRetryPolicies(ing *v1alpha1.Ingress) (map[string]RetryOverride, error) {
+	raw, ok := ing.Annotations[PathRetryPoliciesKey]
+	if !ok {
+		return nil, nil
+	}
+	var overrides map[string]RetryOverride
+	decoder := json.NewDecoder(bytes.NewReader([]byte(raw)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse annotation %s: %w", PathRetryPoliciesKey, err)
+	}
+	for path, o := range overrides {
+		if !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("annotation %s: path %q must be absolute", PathRetryPoliciesKey, path)
+		}
+		if o.NumRetries != nil && *o.NumRetries < 0 {
+			return nil, fmt.Errorf("annotation %s: numRetries of %s must not be negative", PathRetryPoliciesKey, path)
+		}
+		if o.PerTryTimeout != "" {
+			if d, err := time.ParseDuration(o.PerTryTimeout); err != nil || d <= 0 {
+				return nil, fmt.Errorf("annotation %s: perTryTimeout of %s must be a positive duration, got %q",
+					PathRetryPoliciesKey, path, o.PerTryTimeout)
+			}
+		}
+	}
+	return overrides, nil
+}
+
+// retryPaths returns the path prefixes of the overrides, sorted so that the
+// routes we derive from them are stable.
+func retryPaths(overrides map[string]RetryOverride) []string {
+	paths := make([]string, 0, len(overrides))
+	for path := range overrides {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// pathRetryPolicy returns the retry policy of the path: the one of the
+// longest prefix of the overrides covering it applied to the base one, or the
+// base one when none covers it.
+func pathRetryPolicy(overrides map[string]RetryOverride, base *v1.RetryPolicy, path string) *v1.RetryPolicy {
+	longest := ""
+	for prefix := range overrides {
+		if len(prefix) > len(longest) && (prefix == path || underPath(prefix, path)) {
+			longest = prefix
+		}
+	}
+	if longest == "" {
+		return base
+	}
+	return overrides[longest].apply(base)
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+
+	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+)
+
+// splitOverrideRoutes returns a copy of the route per backend of its split,
+// which only routes to that backend the requests whose configured override
+// header names it, or nil when the route doesn't split or the header isn't
+// configured.  Contour prefers the route with more conditions, so the copies
+// take over these requests from the weighted route.
+func splitOverrideRoutes(ctx context.Context, route *v1.Route) []v1.Route {
+	header := config.FromContext(ctx).Contour.SplitOverrideHeader
+	if header == "" || len(route.Services) < 2 {
+		return nil
+	}
+
+	routes := make([]v1.Route, 0, len(route.Services))
+	for _, svc := range route.Services {
+		override := route.DeepCopy()
+		override.Services = []v1.Service{*svc.DeepCopy()}
+		override.Services[0].Weight = 100
+		override.Conditions = append(override.Conditions, v1.MatchCondition{
+			Header: &v1.HeaderMatchCondition{
+				Name:  header,
+				Exact: svc.Name,
+			},
+		})
+		routes = append(routes, *override)
+	}
+	return routes
+}
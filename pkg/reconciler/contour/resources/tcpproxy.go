@@ -0,0 +1,51 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"strings"
+
+	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	network "knative.dev/networking/pkg"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+)
+
+// IsTCPProxy returns whether the TCPProxyKey annotation asks us to proxy the
+// TCP connections to the hosts of the Ingress.
+func IsTCPProxy(ing *v1alpha1.Ingress) bool {
+	return strings.EqualFold(ing.Annotations[TCPProxyKey], "true")
+}
+
+// tcpProxy returns the TCPProxy forwarding connections to the splits of the
+// first path of the rule, skipping the paths InsertProbe added.
+func tcpProxy(rule v1alpha1.IngressRule) *v1.TCPProxy {
+	for _, path := range rule.HTTP.Paths {
+		if _, ok := path.Headers[network.HashHeaderName]; ok {
+			continue
+		}
+		svcs := make([]v1.Service, 0, len(path.Splits))
+		for _, split := range path.Splits {
+			svcs = append(svcs, v1.Service{
+				Name:   split.ServiceName,
+				Port:   split.ServicePort.IntValue(),
+				Weight: int64(split.Percent),
+			})
+		}
+		return &v1.TCPProxy{Services: svcs}
+	}
+	return nil
+}
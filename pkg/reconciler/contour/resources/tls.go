@@ -0,0 +1,43 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+)
+
+// tlsVersions are the minimumProtocolVersions the Contour version we
+// support understands, it silently falls back to 1.2 for any other.
+var tlsVersions = sets.NewString("1.2", "1.3")
+
+// MinimumTLSVersion returns the minimumProtocolVersion of the virtual hosts
+// of the Ingress, or empty for Contour's default.  It errors when the
+// MinimumTLSVersionKey annotation can't be programmed.
+func MinimumTLSVersion(ing *v1alpha1.Ingress) (string, error) {
+	version, ok := ing.Annotations[MinimumTLSVersionKey]
+	if !ok {
+		return "", nil
+	}
+	if !tlsVersions.Has(version) {
+		return "", fmt.Errorf("annotation %s: unsupported TLS version %q, must be one of %v",
+			MinimumTLSVersionKey, version, tlsVersions.List())
+	}
+	return version, nil
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+
+	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+)
+
+// websocketRoute returns a copy of the route for its websocket upgrade
+// requests, with the configured websocket timeouts, or nil when these don't
+// differ from the route's.  Envoy applies the route timeouts to the whole
+// upgraded connection, so finite ones would sever long-lived websockets.
+// Contour prefers the route with more conditions, so the copy takes over the
+// upgrade requests from the route.
+func websocketRoute(ctx context.Context, route *v1.Route) *v1.Route {
+	cfg := config.FromContext(ctx).Contour
+	top := &v1.TimeoutPolicy{
+		Response: route.TimeoutPolicy.Response,
+		Idle:     route.TimeoutPolicy.Idle,
+	}
+	if cfg.WebsocketResponseTimeout != "" {
+		top.Response = cfg.WebsocketResponseTimeout
+	}
+	if cfg.WebsocketIdleTimeout != "" {
+		top.Idle = cfg.WebsocketIdleTimeout
+	}
+	if *top == *route.TimeoutPolicy {
+		return nil
+	}
+
+	ws := route.DeepCopy()
+	ws.TimeoutPolicy = top
+	ws.Conditions = append(ws.Conditions, v1.MatchCondition{
+		Header: &v1.HeaderMatchCondition{
+			Name:     "Upgrade",
+			Contains: "websocket",
+		},
+	})
+	return ws
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// rolloutRecheckPeriod is how long we hold changes before checking whether
+// the Envoy pods are done rolling out.
+const rolloutRecheckPeriod = 10 * time.Second
+
+// rolloutStuckAfter is how long a rollout may go without creating new Envoy
+// pods before we consider it stuck and stop holding changes for it, so that
+// e.g. a crash looping pod can't freeze every Ingress indefinitely.
+const rolloutStuckAfter = 10 * time.Minute
+
+// revisionLabels are the labels Deployments and DaemonSets stamp on their
+// pods to identify the revision of the template they were created from.
+var revisionLabels = []string{
+	appsv1.DefaultDeploymentUniqueLabelKey,
+	appsv1.ControllerRevisionHashLabelKey,
+}
+
+// envoyRollingOut returns the first Envoy Service whose pods are rolling out,
+// either because some of them are terminating or not ready, or because they
+// run more than one revision, or empty if none is.  It also returns when the
+// newest of its pods was created, i.e. when the rollout last made progress.
+func envoyRollingOut(ctx context.Context, serviceLister corev1listers.ServiceLister, podLister corev1listers.PodLister) (string, time.Time, error) {
+	visibilityKeys, err := resolveVisibilityKeys(ctx, serviceLister)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	keys := sets.NewString()
+	for _, k := range visibilityKeys {
+		keys.Insert(k.UnsortedList()...)
+	}
+	for _, key := range keys.List() {
+		namespace, name, err := cache.SplitMetaNamespaceKey(key)
+		if err != nil {
+			return "", time.Time{}, fmt.Errorf("failed to parse key: %w", err)
+		}
+		svc, err := serviceLister.Services(namespace).Get(name)
+		if err != nil {
+			return "", time.Time{}, fmt.Errorf("failed to get Service: %w", err)
+		}
+		if len(svc.Spec.Selector) == 0 {
+			// We can't tell which pods back this Service.
+			continue
+		}
+		pods, err := podLister.Pods(namespace).List(labels.SelectorFromSet(svc.Spec.Selector))
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		if podsRollingOut(pods) {
+			return key, newestPod(pods), nil
+		}
+	}
+	return "", time.Time{}, nil
+}
+
+func podsRollingOut(pods []*corev1.Pod) bool {
+	revisions := make(map[string]sets.String, len(revisionLabels))
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil || !podReady(pod) {
+			return true
+		}
+		for _, l := range revisionLabels {
+			if rev, ok := pod.Labels[l]; ok {
+				if revisions[l] == nil {
+					revisions[l] = sets.NewString()
+				}
+				revisions[l].Insert(rev)
+			}
+		}
+	}
+	for _, revs := range revisions {
+		if revs.Len() > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func newestPod(pods []*corev1.Pod) time.Time {
+	var newest time.Time
+	for _, pod := range pods {
+		if created := pod.CreationTimestamp.Time; created.After(newest) {
+			newest = created
+		}
+	}
+	return newest
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	. "knative.dev/net-contour/pkg/reconciler/testing"
+)
+
+func TestEnvoyRollingOut(t *testing.T) {
+	envoySelector := map[string]string{"app": "envoy"}
+	public := publicService.DeepCopy()
+	public.Spec.Selector = envoySelector
+	private := privateService.DeepCopy()
+	private.Spec.Selector = envoySelector
+
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		want    string
+	}{{
+		name:    "stable",
+		objects: []runtime.Object{public, private, envoyPod(publicNS, "a", "v1"), envoyPod(publicNS, "b", "v1")},
+	}, {
+		name:    "no selector",
+		objects: []runtime.Object{publicService, privateService, envoyPod(publicNS, "a", "v1", notReady)},
+	}, {
+		name:    "pod not ready",
+		objects: []runtime.Object{public, private, envoyPod(publicNS, "a", "v1"), envoyPod(publicNS, "b", "v1", notReady)},
+		want:    publicKey,
+	}, {
+		name:    "pod terminating",
+		objects: []runtime.Object{public, private, envoyPod(privateNS, "a", "v1", terminating)},
+		want:    privateKey,
+	}, {
+		name:    "mixed revisions",
+		objects: []runtime.Object{public, private, envoyPod(publicNS, "a", "v1"), envoyPod(publicNS, "b", "v2")},
+		want:    publicKey,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tl := NewListers(test.objects)
+			ctx := (&testConfigStore{config: defaultConfig}).ToContext(context.Background())
+
+			got, _, err := envoyRollingOut(ctx, tl.GetK8sServiceLister(), tl.GetPodsLister())
+			if err != nil {
+				t.Fatal("envoyRollingOut() =", err)
+			}
+			if got != test.want {
+				t.Errorf("envoyRollingOut() = %q, wanted %q", got, test.want)
+			}
+		})
+	}
+}
+
+type podOption func(*corev1.Pod)
+
+func notReady(p *corev1.Pod) {
+	p.Status.Conditions[0].Status = corev1.ConditionFalse
+}
+
+func terminating(p *corev1.Pod) {
+	now := metav1.Now()
+	p.DeletionTimestamp = &now
+}
+
+func stuck(p *corev1.Pod) {
+	p.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+}
+
+func envoyPod(namespace, name, revision string, opts ...podOption) *corev1.Pod {
+	p := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         namespace,
+			Name:              name,
+			CreationTimestamp: metav1.Now(),
+			Labels: map[string]string{
+				"app":                      "envoy",
+				"controller-revision-hash": revision,
+			},
+		},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{{
+				Type:   corev1.PodReady,
+				Status: corev1.ConditionTrue,
+			}},
+		},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
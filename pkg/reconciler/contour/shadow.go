@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"encoding/json"
+
+	"go.uber.org/zap"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+	networkingv1alpha1 "knative.dev/networking/pkg/client/listers/networking/v1alpha1"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+)
+
+// shadowReconciler wraps the Ingress reconciler so that in shadow mode we only
+// log the HTTPProxies we would program for every Ingress, whatever its class.
+// It doesn't delegate to the generated reconciler at all then, which would
+// update the status of the Ingress.  Every resync logs every Ingress again,
+// so these are debug logs.
+type shadowReconciler struct {
+	leaderAwareReconciler
+
+	ingressLister networkingv1alpha1.IngressLister
+	serviceLister corev1listers.ServiceLister
+	toContext     func(context.Context) context.Context
+}
+
+var _ controller.Reconciler = (*shadowReconciler)(nil)
+
+// Reconcile implements controller.Reconciler
+func (s *shadowReconciler) Reconcile(ctx context.Context, key string) error {
+	ctx = s.toContext(ctx)
+	if !config.FromContext(ctx).Contour.ShadowMode {
+		return s.leaderAwareReconciler.Reconcile(ctx, key)
+	}
+
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil
+	}
+	if !s.IsLeaderFor(types.NamespacedName{Namespace: namespace, Name: name}) {
+		return nil
+	}
+	ing, err := s.ingressLister.Ingresses(namespace).Get(name)
+	if apierrs.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if _, ok := ing.Annotations[resources.EndpointsProbeKey]; ok {
+		// We never create endpoint probes in shadow mode, these belong to
+		// another net-contour.
+		return nil
+	}
+
+	logger := logging.FromContext(ctx)
+	if _, err := checkAnnotations(ctx, ing); err != nil {
+		logger.Debugw("Shadow mode: would fail the Ingress", zap.Error(err))
+		return nil
+	}
+	proxies, err := DesiredHTTPProxies(ctx, ing, s.serviceLister)
+	if err != nil {
+		return err
+	}
+	for _, proxy := range proxies {
+		b, err := json.Marshal(proxy)
+		if err != nil {
+			return err
+		}
+		logger.Debugw("Shadow mode: would program HTTPProxy "+proxy.Name, zap.String("httpproxy", string(b)))
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/pkg/reconciler"
+
+	. "knative.dev/net-contour/pkg/reconciler/testing"
+)
+
+func TestShadowReconciler(t *testing.T) {
+	istio := withAnnotation(map[string]string{"networking.knative.dev/ingress.class": "istio"})
+
+	tests := []struct {
+		name          string
+		shadow        bool
+		ing           *v1alpha1.Ingress
+		objects       []runtime.Object
+		wantErr       bool
+		wantReconcile bool
+	}{{
+		name:          "shadow mode disabled",
+		ing:           ing("name", "ns", withBasicSpec, withContour),
+		objects:       servicesAndEndpoints,
+		wantReconcile: true,
+	}, {
+		name:    "ingress of another class",
+		shadow:  true,
+		ing:     ing("name", "ns", withBasicSpec, istio),
+		objects: servicesAndEndpoints,
+	}, {
+		name:    "our ingress",
+		shadow:  true,
+		ing:     ing("name", "ns", withBasicSpec, withContour),
+		objects: servicesAndEndpoints,
+	}, {
+		name:    "missing services",
+		shadow:  true,
+		ing:     ing("name", "ns", withBasicSpec, istio),
+		wantErr: true,
+	}, {
+		name:   "endpoint probe",
+		shadow: true,
+		ing: ing("name", "ns", withBasicSpec, withContour,
+			withAnnotation(map[string]string{resources.EndpointsProbeKey: "true"})),
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inner := &fakeIngressReconciler{}
+			if err := inner.Promote(reconciler.UniversalBucket(), nil); err != nil {
+				t.Fatal("Promote() =", err)
+			}
+			cfg := defaultConfig.DeepCopy()
+			cfg.Contour.ShadowMode = test.shadow
+			tl := NewListers(append([]runtime.Object{test.ing}, test.objects...))
+			s := &shadowReconciler{
+				leaderAwareReconciler: inner,
+				ingressLister:         tl.GetIngressLister(),
+				serviceLister:         tl.GetK8sServiceLister(),
+				toContext: func(ctx context.Context) context.Context {
+					return config.ToContext(ctx, cfg)
+				},
+			}
+
+			if err := s.Reconcile(context.Background(), "ns/name"); (err != nil) != test.wantErr {
+				t.Fatalf("Reconcile() = %v, wanted error: %v", err, test.wantErr)
+			}
+			if got := len(inner.reconciled) != 0; got != test.wantReconcile {
+				t.Errorf("Reconciled = %v, wanted %v", got, test.wantReconcile)
+			}
+		})
+	}
+}
+
+func TestDesiredHTTPProxies(t *testing.T) {
+	tests := []struct {
+		name    string
+		ing     *v1alpha1.Ingress
+		objects []runtime.Object
+		wantErr bool
+	}{{
+		name:    "our ingress",
+		ing:     ing("name", "ns", withBasicSpec, withContour),
+		objects: servicesAndEndpoints,
+	}, {
+		name: "annotation that can't be programmed",
+		ing: ing("name", "ns", withBasicSpec, withContour, withAnnotation(map[string]string{
+			resources.MinimumTLSVersionKey: "1.1",
+		})),
+		objects: servicesAndEndpoints,
+		wantErr: true,
+	}, {
+		name:    "missing services",
+		ing:     ing("name", "ns", withBasicSpec, withContour),
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := config.ToContext(context.Background(), defaultConfig.DeepCopy())
+			tl := NewListers(test.objects)
+
+			got, err := DesiredHTTPProxies(ctx, test.ing, tl.GetK8sServiceLister())
+			if (err != nil) != test.wantErr {
+				t.Fatalf("DesiredHTTPProxies() = %v, wanted error: %v", err, test.wantErr)
+			}
+			if want := resources.MakeHTTPProxies(ctx, test.ing, nil); err == nil && !cmp.Equal(want, got) {
+				t.Errorf("DesiredHTTPProxies() (-want, +got) = %s", cmp.Diff(want, got))
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"time"
+
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+)
+
+// shuttingDown returns whether stopCh is closed, i.e. the controller is
+// shutting down.  Once sharedmain cancels our context on SIGTERM, the
+// workqueue stops taking keys, our in-flight reconciles run to completion,
+// including their status updates, and the prober stops probing.  The
+// workqueue still drains the keys it holds though, which at e.g. a global
+// resync can outlast the grace period, and being killed halfway through
+// writing the HTTPProxies of an Ingress is what we want to avoid.  We leave
+// those keys to whoever reconciles next, which resyncs every Ingress when it
+// starts anyway.
+func shuttingDown(stopCh <-chan struct{}) bool {
+	select {
+	case <-stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// stoppingReconciler wraps the generated Ingress reconciler to requeue the
+// keys drained once we shut down before it sees them.  It would otherwise
+// bump the ObservedGeneration of the Ingresses we leave alone in
+// PostProcessReconcile.
+type stoppingReconciler struct {
+	leaderAwareReconciler
+
+	stopCh <-chan struct{}
+}
+
+// Reconcile implements controller.Reconciler
+func (r *stoppingReconciler) Reconcile(ctx context.Context, key string) error {
+	if shuttingDown(r.stopCh) {
+		logging.FromContext(ctx).Infof("Shutting down, leaving %s to the next reconcile.", key)
+		return controller.NewRequeueImmediately()
+	}
+	return r.leaderAwareReconciler.Reconcile(ctx, key)
+}
+
+// valuesContext carries the values of a context, but is never done.
+type valuesContext struct {
+	context.Context
+}
+
+func (valuesContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (valuesContext) Done() <-chan struct{}       { return nil }
+func (valuesContext) Err() error                  { return nil }
+
+// untilShutdown returns a context carrying the values of ctx, e.g. of the
+// reconcile starting background work, which is only done once stopCh closes
+// or the returned func is called.
+func untilShutdown(ctx context.Context, stopCh <-chan struct{}) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(valuesContext{ctx})
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"knative.dev/pkg/controller"
+)
+
+type ctxKey struct{}
+
+func TestStoppingReconciler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	inner := &fakeIngressReconciler{}
+	r := &stoppingReconciler{leaderAwareReconciler: inner, stopCh: ctx.Done()}
+
+	if err := r.Reconcile(ctx, "ns/name"); err != nil {
+		t.Errorf("Reconcile() = %v, wanted nil", err)
+	}
+	cancel()
+	err := r.Reconcile(ctx, "ns/other")
+	if ok, _ := controller.IsRequeueKey(err); !ok {
+		t.Errorf("Reconcile() = %v while shutting down, wanted a requeue", err)
+	}
+	if got := inner.reconciled; len(got) != 1 || got[0] != "ns/name" {
+		t.Errorf("Reconciled %v, wanted only ns/name before shutting down", got)
+	}
+
+	if shuttingDown(nil) {
+		t.Error("shuttingDown() = true without a stop channel")
+	}
+}
+
+func TestUntilShutdown(t *testing.T) {
+	stopCh := make(chan struct{})
+	reconcileCtx, reconcileDone := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "value"))
+
+	ctx, cancel := untilShutdown(reconcileCtx, stopCh)
+	defer cancel()
+	reconcileDone()
+	if got := ctx.Value(ctxKey{}); got != "value" {
+		t.Errorf("Value() = %v, wanted the value of the reconcile", got)
+	}
+	if ctx.Err() != nil {
+		t.Error("The context is done with the reconcile, wanted it to outlive it")
+	}
+
+	close(stopCh)
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Error("The context isn't done once shutting down")
+	}
+}
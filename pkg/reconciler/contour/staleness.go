@@ -0,0 +1,203 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+	"k8s.io/client-go/tools/cache"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/metrics"
+)
+
+const (
+	// stalenessReportPeriod is how often we record the staleness of our
+	// informers.
+	stalenessReportPeriod = 10 * time.Second
+
+	// staleRecheckPeriod is how long we wait to reconcile again while our
+	// informers are stale.
+	staleRecheckPeriod = 15 * time.Second
+
+	// healthPort is the port of our readiness endpoint.
+	healthPort = 8080
+)
+
+// informerHealth follows whether an informer is able to list and watch the
+// API server.
+type informerHealth struct {
+	name     string
+	informer cache.SharedIndexInformer
+
+	mu sync.Mutex
+	// failingSince is when the informer first failed to list or watch since
+	// it was last known to be current, or zero when it is current.
+	failingSince time.Time
+	// failedVersion is the last resource version the informer synced before
+	// failing.  The reflector syncs a new one once it lists again.
+	failedVersion string
+}
+
+// watchError is the informer's WatchErrorHandler.
+func (h *informerHealth) watchError(ctx context.Context, err error) {
+	logging.FromContext(ctx).Warnw("Informer failed to list or watch", zap.String("informer", h.name), zap.Error(err))
+	if tagged, terr := tag.New(ctx, tag.Upsert(informerKey, h.name)); terr == nil {
+		metrics.Record(tagged, informerWatchErrorsM.M(1))
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.failingSince.IsZero() {
+		h.failingSince = time.Now()
+		h.failedVersion = h.informer.LastSyncResourceVersion()
+	}
+}
+
+// observed records that the informer delivered an event, so it is watching.
+func (h *informerHealth) observed() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.failingSince = time.Time{}
+}
+
+// staleness returns how long the informer has been unable to list or watch
+// the API server.
+func (h *informerHealth) staleness(now time.Time) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.failingSince.IsZero() {
+		return 0
+	}
+	if h.informer.LastSyncResourceVersion() != h.failedVersion {
+		// The reflector listed again since it failed.
+		h.failingSince = time.Time{}
+		return 0
+	}
+	return now.Sub(h.failingSince)
+}
+
+// cacheHealth follows the health of the informers we depend on, so we neither
+// reconcile nor report ourselves ready based on an outdated view of the
+// cluster after API server hiccups.
+type cacheHealth struct {
+	informers    []*informerHealth
+	maxStaleness func() time.Duration
+}
+
+// newCacheHealth follows the given informers, which must not be started yet.
+func newCacheHealth(ctx context.Context, maxStaleness func() time.Duration, informers map[string]cache.SharedIndexInformer) *cacheHealth {
+	names := make([]string, 0, len(informers))
+	for name := range informers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	c := &cacheHealth{maxStaleness: maxStaleness}
+	for _, name := range names {
+		h := &informerHealth{name: name, informer: informers[name]}
+		if err := h.informer.SetWatchErrorHandler(func(r *cache.Reflector, err error) {
+			// Keep the default behaviour of logging the error.
+			cache.DefaultWatchErrorHandler(r, err)
+			h.watchError(ctx, err)
+		}); err != nil {
+			logging.FromContext(ctx).Warnw("Unable to follow the health of informer "+name, zap.Error(err))
+		}
+		h.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(interface{}) { h.observed() },
+			UpdateFunc: func(interface{}, interface{}) { h.observed() },
+			DeleteFunc: func(interface{}) { h.observed() },
+		})
+		c.informers = append(c.informers, h)
+	}
+	return c
+}
+
+// stale returns the name and staleness of the stalest informer when it
+// exceeds the configured maximum, or an empty name otherwise.
+func (c *cacheHealth) stale() (string, time.Duration) {
+	if c == nil {
+		return "", 0
+	}
+	max := c.maxStaleness()
+	if max <= 0 {
+		return "", 0
+	}
+	var (
+		stalest   string
+		staleness time.Duration
+		now       = time.Now()
+	)
+	for _, h := range c.informers {
+		if s := h.staleness(now); s > max && s > staleness {
+			stalest, staleness = h.name, s
+		}
+	}
+	return stalest, staleness
+}
+
+// report records the staleness of every informer until the context is done.
+func (c *cacheHealth) report(ctx context.Context) {
+	ticker := time.NewTicker(stalenessReportPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		now := time.Now()
+		for _, h := range c.informers {
+			if tagged, err := tag.New(ctx, tag.Upsert(informerKey, h.name)); err == nil {
+				metrics.Record(tagged, informerStalenessM.M(h.staleness(now).Seconds()))
+			}
+		}
+	}
+}
+
+// ServeHTTP implements our readiness endpoint, which fails while our
+// informers are stale.
+func (c *cacheHealth) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	if name, staleness := c.stale(); name != "" {
+		http.Error(w, fmt.Sprintf("The %s informer has been unable to watch the API server for %v.",
+			name, staleness.Round(time.Second)), http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok"))
+}
+
+// serveHealth serves our readiness endpoint until the context is done.
+func serveHealth(ctx context.Context, health http.Handler) {
+	mux := http.NewServeMux()
+	mux.Handle("/readyz", health)
+	server := &http.Server{Addr: ":" + strconv.Itoa(healthPort), Handler: mux}
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logging.FromContext(ctx).Errorw("Error serving the readiness endpoint", zap.Error(err))
+	}
+}
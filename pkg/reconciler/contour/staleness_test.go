@@ -0,0 +1,81 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestCacheHealth(t *testing.T) {
+	informer := cache.NewSharedIndexInformer(&cache.ListWatch{}, &corev1.Service{}, 0, cache.Indexers{})
+	maxStaleness := time.Minute
+	health := newCacheHealth(context.Background(), func() time.Duration { return maxStaleness },
+		map[string]cache.SharedIndexInformer{"service": informer})
+	h := health.informers[0]
+
+	ready := func() int {
+		rec := httptest.NewRecorder()
+		health.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code
+	}
+
+	if name, _ := health.stale(); name != "" {
+		t.Errorf("stale() = %q initially, wanted none", name)
+	}
+	if got := ready(); got != http.StatusOK {
+		t.Errorf("Readiness = %d initially, wanted %d", got, http.StatusOK)
+	}
+
+	h.watchError(context.Background(), errors.New("connection refused"))
+	if got := h.staleness(time.Now().Add(30 * time.Second)); got < 30*time.Second {
+		t.Errorf("staleness() = %v after a watch error, wanted at least 30s", got)
+	}
+	// Not stale for longer than the maximum yet.
+	if name, _ := health.stale(); name != "" {
+		t.Errorf("stale() = %q right after the watch error, wanted none", name)
+	}
+
+	// Pretend the watch error happened long ago.
+	h.failingSince = time.Now().Add(-2 * time.Minute)
+	if name, staleness := health.stale(); name != "service" || staleness < 2*time.Minute {
+		t.Errorf("stale() = %q, %v, wanted service, at least 2m", name, staleness)
+	}
+	if got := ready(); got != http.StatusServiceUnavailable {
+		t.Errorf("Readiness = %d while stale, wanted %d", got, http.StatusServiceUnavailable)
+	}
+
+	// Disabling the check makes us ready again.
+	maxStaleness = 0
+	if got := ready(); got != http.StatusOK {
+		t.Errorf("Readiness = %d with the check disabled, wanted %d", got, http.StatusOK)
+	}
+	maxStaleness = time.Minute
+
+	// Receiving an event means the informer is watching again.
+	h.observed()
+	if name, _ := health.stale(); name != "" {
+		t.Errorf("stale() = %q after an event, wanted none", name)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/labels"
+	contourlisters "knative.dev/net-contour/pkg/client/listers/projectcontour/v1"
+	"knative.dev/net-contour/pkg/ownership"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+	networkingv1alpha1 "knative.dev/networking/pkg/client/listers/networking/v1alpha1"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/metrics"
+)
+
+// staleProbeReportPeriod is how often we count the stale endpoint probe
+// resources.
+const staleProbeReportPeriod = time.Minute
+
+// staleProbes counts the endpoint probe Ingresses, and the HTTPProxies of
+// their ".invalid" hosts, that outlived the configured age.  We delete them
+// once their rollout completes, so old ones were leaked by e.g. a reconcile
+// that crashed, and the metric lets monitoring notice them.
+type staleProbes struct {
+	ingressLister networkingv1alpha1.IngressLister
+	contourLister contourlisters.HTTPProxyLister
+	maxAge        func() time.Duration
+}
+
+// count returns the number of stale endpoint probe resources by kind.
+func (s *staleProbes) count(now time.Time) (map[string]int, error) {
+	cutoff := now.Add(-s.maxAge())
+	counts := map[string]int{"Ingress": 0, "HTTPProxy": 0}
+
+	ings, err := s.ingressLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, ing := range ings {
+		if ing.Annotations[resources.EndpointsProbeKey] == "true" && ing.CreationTimestamp.Time.Before(cutoff) {
+			counts["Ingress"]++
+		}
+	}
+
+	proxies, err := s.contourLister.List(ownership.Children())
+	if err != nil {
+		return nil, err
+	}
+	for _, proxy := range proxies {
+		vh := proxy.Spec.VirtualHost
+		if vh != nil && strings.HasSuffix(vh.Fqdn, ".invalid") && proxy.CreationTimestamp.Time.Before(cutoff) {
+			counts["HTTPProxy"]++
+		}
+	}
+	return counts, nil
+}
+
+// report records the number of stale endpoint probe resources by kind until
+// the context is done.
+func (s *staleProbes) report(ctx context.Context) {
+	ticker := time.NewTicker(staleProbeReportPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		counts, err := s.count(time.Now())
+		if err != nil {
+			logging.FromContext(ctx).Warnw("Error counting stale endpoint probes", zap.Error(err))
+			continue
+		}
+		for kind, n := range counts {
+			if tagged, err := tag.New(ctx, tag.Upsert(kindKey, kind)); err == nil {
+				metrics.Record(tagged, staleProbeResourcesM.M(int64(n)))
+			}
+		}
+	}
+}
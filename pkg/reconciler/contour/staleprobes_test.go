@@ -0,0 +1,80 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+
+	. "knative.dev/net-contour/pkg/reconciler/testing"
+)
+
+func TestStaleProbes(t *testing.T) {
+	now := time.Now()
+	old := metav1.NewTime(now.Add(-time.Hour))
+	recent := metav1.NewTime(now.Add(-time.Minute))
+
+	probeIng := func(name string, created metav1.Time) *v1alpha1.Ingress {
+		return ing(name, "ns", withBasicSpec, withContour, func(i *v1alpha1.Ingress) {
+			i.CreationTimestamp = created
+			i.Annotations[resources.EndpointsProbeKey] = "true"
+		})
+	}
+	proxy := func(name, fqdn string, created metav1.Time) *contourv1.HTTPProxy {
+		return &contourv1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "ns",
+				Name:              name,
+				CreationTimestamp: created,
+				Labels:            map[string]string{resources.ParentKey: "name"},
+			},
+			Spec: contourv1.HTTPProxySpec{
+				VirtualHost: &contourv1.VirtualHost{Fqdn: fqdn},
+			},
+		}
+	}
+	listers := NewListers([]runtime.Object{
+		probeIng("stale-probe", old),
+		probeIng("recent-probe", recent),
+		ing("old-ingress", "ns", withBasicSpec, withContour, func(i *v1alpha1.Ingress) {
+			i.CreationTimestamp = old
+		}),
+		proxy("stale", "goo.gen-1.name.ns.net-contour.invalid", old),
+		proxy("recent", "goo.gen-2.name.ns.net-contour.invalid", recent),
+		proxy("serving", "example.com", old),
+	})
+
+	s := &staleProbes{
+		ingressLister: listers.GetIngressLister(),
+		contourLister: listers.GetHTTPProxyLister(),
+		maxAge:        func() time.Duration { return 30 * time.Minute },
+	}
+	got, err := s.count(now)
+	if err != nil {
+		t.Fatal("count() =", err)
+	}
+	if want := map[string]int{"Ingress": 1, "HTTPProxy": 1}; !cmp.Equal(got, want) {
+		t.Errorf("count() = %v, wanted %v", got, want)
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/pkg/logging"
+)
+
+// tcpReady returns whether enough Envoy pods serve every host of an Ingress
+// whose connections we proxy.  Envoy only completes a TLS handshake for a
+// host once it has a filter chain for its SNI, so we count the pods that
+// complete one for each host.
+func (m *quorumManager) tcpReady(ctx context.Context, ing *v1alpha1.Ingress) (bool, error) {
+	targets, err := m.tcpTargets(ctx, ing)
+	if err != nil {
+		return false, err
+	}
+
+	// Group the hosts to probe by pod.
+	pods := make(map[string][]*url.URL)
+	for _, target := range targets {
+		for ip := range target.PodIPs {
+			addr := net.JoinHostPort(ip, target.PodPort)
+			pods[addr] = append(pods[addr], target.URLs...)
+		}
+	}
+
+	var (
+		mu     sync.Mutex
+		passed int
+		wg     sync.WaitGroup
+	)
+	for addr, urls := range pods {
+		wg.Add(1)
+		go func(addr string, urls []*url.URL) {
+			defer wg.Done()
+			if handshakePod(ctx, m.transports, addr, urls, probeTimeout(ctx)) {
+				mu.Lock()
+				defer mu.Unlock()
+				passed++
+			}
+		}(addr, urls)
+	}
+	wg.Wait()
+
+	// Without any Envoy pod to probe, nothing serves the hosts yet.
+	quorum := config.FromContext(ctx).Contour.ReadinessQuorum
+	if len(pods) > 0 && float64(passed) >= quorum*float64(len(pods)) {
+		return true, nil
+	}
+	logging.FromContext(ctx).Debugf("%d of %d Envoy pods complete TLS handshakes for the proxied hosts.", passed, len(pods))
+	m.enqueueAfter(ing, quorumRecheckPeriod)
+	return false, nil
+}
+
+// handshakePod returns whether the Envoy pod listening at addr completes a
+// TLS handshake for the host of every one of the urls, each within the
+// timeout.  The certificates are verified as the transports of our other
+// probes verify them.
+func handshakePod(ctx context.Context, transports *probeTransports, addr string, urls []*url.URL, timeout time.Duration) bool {
+	settings := transports.settings(ctx)
+	for _, u := range urls {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+		if err != nil {
+			cancel()
+			return false
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+		tlsConn := tls.Client(conn, settings.tlsConfig(u.Hostname()))
+		err = tlsConn.Handshake()
+		tlsConn.Close()
+		cancel()
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/networking/pkg/status"
+
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+)
+
+func TestHandshakePod(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer s.Close()
+	urls := []*url.URL{{Scheme: "https", Host: "example.com"}}
+	cfg := defaultConfig.DeepCopy()
+	cfg.Contour.ProbeInsecureSkipVerify = true
+	ctx := (&testConfigStore{config: cfg}).ToContext(context.Background())
+
+	if !handshakePod(ctx, nil, s.Listener.Addr().String(), urls, time.Second) {
+		t.Error("handshakePod() = false, wanted true")
+	}
+
+	// Find an address nothing listens on.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Listen() =", err)
+	}
+	closed := l.Addr().String()
+	l.Close()
+	if handshakePod(ctx, nil, closed, urls, time.Second) {
+		t.Error("handshakePod() = true, wanted false")
+	}
+}
+
+func TestQuorumManagerTCPProxy(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer s.Close()
+	host, port, err := net.SplitHostPort(s.Listener.Addr().String())
+	if err != nil {
+		t.Fatal("SplitHostPort() =", err)
+	}
+
+	i := ing("name", "ns", withBasicSpec, withContour,
+		withAnnotation(map[string]string{resources.TCPProxyKey: "true"}))
+	cfg := defaultConfig.DeepCopy()
+	cfg.Contour.ReadinessQuorum = 1
+	cfg.Contour.ProbeInsecureSkipVerify = true
+	ctx := (&testConfigStore{config: cfg}).ToContext(context.Background())
+
+	m := &quorumManager{
+		Manager: &fakeStatusManager{
+			FakeIsReady: func(context.Context, *v1alpha1.Ingress) (bool, error) {
+				t.Error("Unexpected call to the HTTP prober")
+				return false, nil
+			},
+		},
+		enqueueAfter: func(interface{}, time.Duration) {},
+		tcpTargets: func(context.Context, *v1alpha1.Ingress) ([]status.ProbeTarget, error) {
+			return []status.ProbeTarget{{
+				PodIPs:  sets.NewString(host),
+				Port:    "443",
+				PodPort: port,
+				URLs:    []*url.URL{{Scheme: "https", Host: "example.com"}},
+			}}, nil
+		},
+	}
+
+	ready, err := m.IsReady(ctx, i)
+	if err != nil {
+		t.Fatal("IsReady() =", err)
+	}
+	if !ready {
+		t.Error("IsReady() = false, wanted true")
+	}
+
+	// Without Envoy pods nothing serves the hosts.
+	m.tcpTargets = func(context.Context, *v1alpha1.Ingress) ([]status.ProbeTarget, error) {
+		return nil, nil
+	}
+	if ready, err = m.IsReady(ctx, i); err != nil {
+		t.Fatal("IsReady() =", err)
+	}
+	if ready {
+		t.Error("IsReady() = true without Envoy pods, wanted false")
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/kmeta"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/metrics"
+	"knative.dev/pkg/tracker"
+)
+
+const (
+	// trackerLeaseEnvKey overrides how long the tracker keeps tracking a
+	// reference after it was last asked to, which defaults to three resync
+	// periods.
+	trackerLeaseEnvKey = "TRACKER_LEASE_DURATION"
+
+	// trackedReportPeriod is how often we record the number of objects we
+	// track.
+	trackedReportPeriod = 30 * time.Second
+)
+
+// trackerLease returns the lease of our tracker.
+func trackerLease(ctx context.Context) time.Duration {
+	lease := controller.GetTrackerLease(ctx)
+	if v := os.Getenv(trackerLeaseEnvKey); v != "" {
+		if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+			logging.FromContext(ctx).Errorw("Ignoring invalid "+trackerLeaseEnvKey, zap.String("value", v), zap.Error(err))
+		} else {
+			lease = d
+		}
+	}
+	return lease
+}
+
+// countingTracker mirrors the references of a tracker along with their
+// leases, so that we can tell how many objects of each kind it tracks.
+type countingTracker struct {
+	tracker.Interface
+	lease time.Duration
+
+	mu sync.Mutex
+	// tracked holds when each observer's interest in a reference expires.
+	tracked map[tracker.Reference]map[types.NamespacedName]time.Time
+}
+
+func newCountingTracker(t tracker.Interface, lease time.Duration) *countingTracker {
+	return &countingTracker{
+		Interface: t,
+		lease:     lease,
+		tracked:   make(map[tracker.Reference]map[types.NamespacedName]time.Time),
+	}
+}
+
+// TrackReference implements tracker.Interface.
+func (t *countingTracker) TrackReference(ref tracker.Reference, obj interface{}) error {
+	if err := t.Interface.TrackReference(ref, obj); err != nil {
+		return err
+	}
+	acc, err := kmeta.DeletionHandlingAccessor(obj)
+	if err != nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	observers, ok := t.tracked[ref]
+	if !ok {
+		observers = make(map[types.NamespacedName]time.Time, 1)
+		t.tracked[ref] = observers
+	}
+	observers[types.NamespacedName{Namespace: acc.GetNamespace(), Name: acc.GetName()}] = time.Now().Add(t.lease)
+	return nil
+}
+
+// OnDeletedObserver implements tracker.Interface.
+func (t *countingTracker) OnDeletedObserver(obj interface{}) {
+	t.Interface.OnDeletedObserver(obj)
+	acc, err := kmeta.DeletionHandlingAccessor(obj)
+	if err != nil {
+		return
+	}
+	key := types.NamespacedName{Namespace: acc.GetNamespace(), Name: acc.GetName()}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for ref, observers := range t.tracked {
+		delete(observers, key)
+		if len(observers) == 0 {
+			delete(t.tracked, ref)
+		}
+	}
+}
+
+// counts returns the number of objects of each kind tracked by an unexpired
+// lease, forgetting the expired ones.
+func (t *countingTracker) counts(now time.Time) map[string]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	counts := make(map[string]int)
+	for ref, observers := range t.tracked {
+		for key, expiry := range observers {
+			if now.After(expiry) {
+				delete(observers, key)
+			}
+		}
+		if len(observers) == 0 {
+			delete(t.tracked, ref)
+			continue
+		}
+		counts[ref.Kind]++
+	}
+	return counts
+}
+
+// report records the number of tracked objects of each kind until the
+// context is done.
+func (t *countingTracker) report(ctx context.Context) {
+	ticker := time.NewTicker(trackedReportPeriod)
+	defer ticker.Stop()
+	// Keep reporting zero for kinds we no longer track.
+	kinds := make(map[string]struct{})
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		counts := t.counts(time.Now())
+		for kind := range counts {
+			kinds[kind] = struct{}{}
+		}
+		for kind := range kinds {
+			if tagged, err := tag.New(ctx, tag.Upsert(kindKey, kind)); err == nil {
+				metrics.Record(tagged, trackedObjectsM.M(int64(counts[kind])))
+			}
+		}
+	}
+}
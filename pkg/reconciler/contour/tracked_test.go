@@ -0,0 +1,83 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/tracker"
+)
+
+func TestCountingTracker(t *testing.T) {
+	lease := time.Hour
+	ct := newCountingTracker(tracker.New(func(types.NamespacedName) {}, lease), lease)
+
+	svc := func(name string) tracker.Reference {
+		return tracker.Reference{APIVersion: "v1", Kind: "Service", Namespace: "ns", Name: name}
+	}
+	first, second := ing("first", "ns"), ing("second", "ns")
+	for _, track := range []struct {
+		ref tracker.Reference
+		obj interface{}
+	}{
+		{svc("a"), first},
+		{svc("b"), first},
+		{svc("a"), second},
+	} {
+		if err := ct.TrackReference(track.ref, track.obj); err != nil {
+			t.Fatal("TrackReference() =", err)
+		}
+	}
+
+	if got, want := ct.counts(time.Now()), map[string]int{"Service": 2}; !cmp.Equal(got, want) {
+		t.Errorf("counts() = %v, wanted %v", got, want)
+	}
+
+	// Deleting the only observer of b stops tracking it.
+	ct.OnDeletedObserver(first)
+	if got, want := ct.counts(time.Now()), map[string]int{"Service": 1}; !cmp.Equal(got, want) {
+		t.Errorf("counts() after deleting an observer = %v, wanted %v", got, want)
+	}
+
+	// Expired leases are no longer counted.
+	if got := ct.counts(time.Now().Add(2 * lease)); len(got) != 0 {
+		t.Errorf("counts() after the lease expired = %v, wanted none", got)
+	}
+}
+
+func TestTrackerLease(t *testing.T) {
+	ctx := context.Background()
+	defer os.Unsetenv(trackerLeaseEnvKey)
+	if got, want := trackerLease(ctx), 30*time.Hour; got != want {
+		t.Errorf("trackerLease() = %v by default, wanted %v", got, want)
+	}
+
+	os.Setenv(trackerLeaseEnvKey, "90m")
+	if got, want := trackerLease(ctx), 90*time.Minute; got != want {
+		t.Errorf("trackerLease() = %v, wanted %v", got, want)
+	}
+
+	os.Setenv(trackerLeaseEnvKey, "bogus")
+	if got, want := trackerLease(ctx), 30*time.Hour; got != want {
+		t.Errorf("trackerLease() = %v with an invalid value, wanted %v", got, want)
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"go.opencensus.io/tag"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"knative.dev/pkg/metrics"
+)
+
+// The classes of failed writes we report, of which only permanent ones
+// aren't retried.
+const (
+	writeConflict    = "conflict"
+	writeThrottled   = "throttled"
+	writeUnavailable = "unavailable"
+	writePermanent   = "permanent"
+)
+
+// writeBackoff spaces the retries of a failed HTTPProxy write.  It gives up
+// within about a second, after which requeuing the key is as good.
+var writeBackoff = wait.Backoff{
+	Steps:    4,
+	Duration: 50 * time.Millisecond,
+	Factor:   2,
+	Jitter:   0.5,
+}
+
+// writeFailureClass classifies the error of a failed write.
+func writeFailureClass(err error) string {
+	switch {
+	case apierrs.IsConflict(err):
+		return writeConflict
+	case apierrs.IsTooManyRequests(err):
+		return writeThrottled
+	case apierrs.IsServerTimeout(err), apierrs.IsTimeout(err),
+		apierrs.IsServiceUnavailable(err), apierrs.IsInternalError(err):
+		return writeUnavailable
+	default:
+		return writePermanent
+	}
+}
+
+// retryWrite retries the write while it fails with conflicts or transient
+// errors, recording each failure.  A 409 would otherwise requeue the whole
+// key and redo its translation, only to write the same HTTPProxy again.  The
+// Ingress status needs none of this: the generated reconciler already
+// retries its conflicts with the latest Ingress.
+func retryWrite(ctx context.Context, write func() error) error {
+	return retry.OnError(writeBackoff, func(err error) bool {
+		class := writeFailureClass(err)
+		if tagged, terr := tag.New(ctx, tag.Upsert(failureClassKey, class)); terr == nil {
+			metrics.Record(tagged, httpProxyWriteFailuresM.M(1))
+		}
+		return class != writePermanent
+	}, write)
+}
+
+// createHTTPProxy creates the HTTPProxy, retrying transient errors.
+func (r *Reconciler) createHTTPProxy(ctx context.Context, proxy *contourv1.HTTPProxy) (created *contourv1.HTTPProxy, err error) {
+	err = retryWrite(ctx, func() error {
+		created, err = r.contourClient.ProjectcontourV1().HTTPProxies(proxy.Namespace).Create(ctx, proxy, metav1.CreateOptions{})
+		return err
+	})
+	r.recheckDependencies(err)
+	return created, err
+}
+
+// updateHTTPProxy writes the update, retrying conflicts and transient
+// errors.  Conflicts retry with the metadata and spec of the update on top of
+// the HTTPProxy fetched from the API server, as our informer likely hasn't
+// seen the write that beat us yet.
+func (r *Reconciler) updateHTTPProxy(ctx context.Context, update *contourv1.HTTPProxy) (updated *contourv1.HTTPProxy, err error) {
+	client := r.contourClient.ProjectcontourV1().HTTPProxies(update.Namespace)
+	err = retryWrite(ctx, func() error {
+		updated, err = client.Update(ctx, update, metav1.UpdateOptions{})
+		if apierrs.IsConflict(err) {
+			if latest, gerr := client.Get(ctx, update.Name, metav1.GetOptions{}); gerr == nil {
+				latest.Annotations = update.Annotations
+				latest.Labels = update.Labels
+				latest.Spec = update.Spec
+				update = latest
+			}
+		}
+		return err
+	})
+	r.recheckDependencies(err)
+	return updated, err
+}
+
+// recheckDependencies makes us check that the HTTPProxy API is still served
+// when a write fails as if it wasn't, e.g. as its CRD is being reinstalled.
+func (r *Reconciler) recheckDependencies(err error) {
+	if r.apiChecker != nil && apierrs.IsNotFound(err) {
+		r.apiChecker.forget()
+	}
+}
+
+// deleteHTTPProxy deletes the HTTPProxy, retrying transient errors.  It is
+// fine for the HTTPProxy to be gone already.
+func (r *Reconciler) deleteHTTPProxy(ctx context.Context, namespace, name string) error {
+	return retryWrite(ctx, func() error {
+		err := r.contourClient.ProjectcontourV1().HTTPProxies(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+		if apierrs.IsNotFound(err) {
+			return nil
+		}
+		return err
+	})
+}
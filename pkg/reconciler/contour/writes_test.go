@@ -0,0 +1,143 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"errors"
+	"testing"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgotesting "k8s.io/client-go/testing"
+	fakecontourclient "knative.dev/net-contour/pkg/client/injection/client/fake"
+
+	. "knative.dev/pkg/reconciler/testing"
+)
+
+func TestWriteFailureClass(t *testing.T) {
+	resource := schema.GroupResource{Group: "projectcontour.io", Resource: "httpproxies"}
+	tests := []struct {
+		err  error
+		want string
+	}{{
+		err:  apierrs.NewConflict(resource, "name", errors.New("stale")),
+		want: writeConflict,
+	}, {
+		err:  apierrs.NewTooManyRequests("slow down", 1),
+		want: writeThrottled,
+	}, {
+		err:  apierrs.NewServerTimeout(resource, "update", 1),
+		want: writeUnavailable,
+	}, {
+		err:  apierrs.NewInternalError(errors.New("etcd")),
+		want: writeUnavailable,
+	}, {
+		err:  apierrs.NewBadRequest("invalid"),
+		want: writePermanent,
+	}, {
+		err:  apierrs.NewForbidden(resource, "name", errors.New("denied")),
+		want: writePermanent,
+	}}
+	for _, test := range tests {
+		if got := writeFailureClass(test.err); got != test.want {
+			t.Errorf("writeFailureClass(%v) = %s, wanted %s", test.err, got, test.want)
+		}
+	}
+}
+
+func TestUpdateHTTPProxyRetriesConflicts(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+	client := fakecontourclient.Get(ctx)
+	existing := &contourv1.HTTPProxy{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "name"}}
+	// What Contour wrote since our informer saw the HTTPProxy.
+	latest := existing.DeepCopy()
+	latest.Status.CurrentStatus = "valid"
+	if _, err := client.ProjectcontourV1().HTTPProxies("ns").Create(ctx, latest, metav1.CreateOptions{}); err != nil {
+		t.Fatal("Create() =", err)
+	}
+
+	conflicts := 0
+	client.PrependReactor("update", "httpproxies", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+		update := action.(clientgotesting.UpdateAction).GetObject().(*contourv1.HTTPProxy)
+		if update.Status.CurrentStatus == "valid" {
+			return false, nil, nil
+		}
+		conflicts++
+		return true, nil, apierrs.NewConflict(contourv1.HTTPProxyGVR.GroupResource(), update.Name, errors.New("stale"))
+	})
+
+	update := existing.DeepCopy()
+	update.Labels = map[string]string{"ours": "true"}
+	update.Spec.VirtualHost = &contourv1.VirtualHost{Fqdn: "example.com"}
+	r := &Reconciler{contourClient: client}
+	got, err := r.updateHTTPProxy(ctx, update)
+	if err != nil {
+		t.Fatal("updateHTTPProxy() =", err)
+	}
+	if conflicts != 1 {
+		t.Errorf("Got %d conflicts, wanted 1", conflicts)
+	}
+	if got.Spec.VirtualHost == nil || got.Spec.VirtualHost.Fqdn != "example.com" || got.Labels["ours"] != "true" {
+		t.Errorf("updateHTTPProxy() = %#v, wanted the update", got)
+	}
+}
+
+func TestCreateHTTPProxyGivesUpOnPermanentErrors(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+	client := fakecontourclient.Get(ctx)
+	attempts := 0
+	client.PrependReactor("create", "httpproxies", func(clientgotesting.Action) (bool, runtime.Object, error) {
+		attempts++
+		if attempts == 1 {
+			return true, nil, apierrs.NewTooManyRequests("slow down", 0)
+		}
+		return true, nil, apierrs.NewBadRequest("invalid")
+	})
+
+	r := &Reconciler{contourClient: client}
+	proxy := &contourv1.HTTPProxy{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "name"}}
+	if _, err := r.createHTTPProxy(ctx, proxy); !apierrs.IsBadRequest(err) {
+		t.Errorf("createHTTPProxy() = %v, wanted the bad request", err)
+	}
+	if attempts != 2 {
+		t.Errorf("Got %d attempts, wanted 2", attempts)
+	}
+}
+
+func TestCreateHTTPProxyRechecksMissingAPI(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+	client := fakecontourclient.Get(ctx)
+	client.PrependReactor("create", "httpproxies", func(clientgotesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrs.NewNotFound(schema.GroupResource{Group: contourv1.GroupName, Resource: httpProxyResource}, "")
+	})
+
+	checker := &apiChecker{discovery: fakeDiscovery(httpProxyResource)}
+	if served, err := checker.HTTPProxyServed(); err != nil || !served {
+		t.Fatalf("HTTPProxyServed() = %v, %v, wanted true", served, err)
+	}
+	r := &Reconciler{contourClient: client, apiChecker: checker}
+	proxy := &contourv1.HTTPProxy{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "name"}}
+	if _, err := r.createHTTPProxy(ctx, proxy); !apierrs.IsNotFound(err) {
+		t.Errorf("createHTTPProxy() = %v, wanted not found", err)
+	}
+	if checker.served {
+		t.Error("The served version is still cached after the API went missing")
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeingress
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	ingressclient "knative.dev/networking/pkg/client/injection/client"
+	ingressinformer "knative.dev/networking/pkg/client/injection/informers/networking/v1alpha1/ingress"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	serviceinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/service"
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/reconciler"
+)
+
+const controllerAgentName = "kubernetes-ingress-controller"
+
+// Options customize the controller NewControllerWithOptions returns.
+type Options struct {
+	// Enabled makes the controller watch the Kubernetes Ingresses of the
+	// cluster, and translate those of the kubernetes-ingress-class of
+	// config-contour.  Disabled controllers watch nothing and never
+	// reconcile.
+	Enabled bool
+}
+
+// NewController returns a new controller translating Kubernetes Ingresses
+// into Knative Ingresses for Project Contour.
+func NewController(
+	ctx context.Context,
+	cmw configmap.Watcher,
+) *controller.Impl {
+	return NewControllerWithOptions(ctx, cmw, Options{Enabled: true})
+}
+
+// NewControllerWithOptions returns a new controller translating Kubernetes
+// Ingresses into Knative Ingresses for Project Contour, customized by the
+// options.
+func NewControllerWithOptions(
+	ctx context.Context,
+	cmw configmap.Watcher,
+	opts Options,
+) *controller.Impl {
+	logger := logging.FromContext(ctx)
+	if !opts.Enabled {
+		logger.Info("Translating Kubernetes Ingresses is disabled, not watching them.")
+		return controller.NewContext(ctx, &Reconciler{}, controller.ControllerOptions{
+			WorkQueueName: "KubernetesIngresses",
+			Logger:        logger.Named(controllerAgentName),
+		})
+	}
+
+	// The informer isn't injected, so that the Kubernetes Ingresses of the
+	// cluster are only watched while the translation is enabled.
+	factory := kubeinformers.NewSharedInformerFactory(kubeclient.Get(ctx), controller.GetResyncPeriod(ctx))
+	kubeIngressInformer := factory.Networking().V1().Ingresses()
+	ingressInformer := ingressinformer.Get(ctx)
+	serviceInformer := serviceinformer.Get(ctx)
+
+	r := &Reconciler{
+		kubeClient:        kubeclient.Get(ctx),
+		ingressClient:     ingressclient.Get(ctx),
+		kubeIngressLister: kubeIngressInformer.Lister(),
+		ingressLister:     ingressInformer.Lister(),
+		serviceLister:     serviceInformer.Lister(),
+		recorder:          newRecorder(ctx),
+	}
+	r.LeaderAwareFuncs = reconciler.LeaderAwareFuncs{
+		PromoteFunc: func(bkt reconciler.Bucket, enq func(reconciler.Bucket, types.NamespacedName)) error {
+			all, err := r.kubeIngressLister.List(labels.Everything())
+			if err != nil {
+				return err
+			}
+			for _, elt := range all {
+				enq(bkt, types.NamespacedName{Namespace: elt.Namespace, Name: elt.Name})
+			}
+			return nil
+		},
+	}
+	impl := controller.NewContext(ctx, r, controller.ControllerOptions{
+		WorkQueueName: "KubernetesIngresses",
+		Logger:        logger.Named(controllerAgentName),
+	})
+
+	configStore := config.NewStore(logger.Named("config-store"), func(string, interface{}) {
+		impl.GlobalResync(kubeIngressInformer.Informer())
+	})
+	configStore.WatchConfigs(cmw)
+	r.configStore = configStore
+
+	// The class is checked during reconciliation, so that Ingresses leaving
+	// our class are cleaned up.
+	kubeIngressInformer.Informer().AddEventHandler(controller.HandleAll(impl.Enqueue))
+
+	ingressInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: controller.FilterControllerGVK(networkingv1.SchemeGroupVersion.WithKind("Ingress")),
+		Handler:    controller.HandleAll(impl.EnqueueControllerOf),
+	})
+
+	factory.Start(ctx.Done())
+	factory.WaitForCacheSync(ctx.Done())
+
+	return impl
+}
+
+// newRecorder returns the recorder of the events of the context, or one
+// sending them to the API server, as the generated reconcilers do.
+func newRecorder(ctx context.Context) record.EventRecorder {
+	if recorder := controller.GetEventRecorder(ctx); recorder != nil {
+		return recorder
+	}
+	logger := logging.FromContext(ctx)
+	broadcaster := record.NewBroadcaster()
+	watches := []watch.Interface{
+		broadcaster.StartLogging(logger.Named("event-broadcaster").Infof),
+		broadcaster.StartRecordingToSink(
+			&typedcorev1.EventSinkImpl{Interface: kubeclient.Get(ctx).CoreV1().Events("")}),
+	}
+	go func() {
+		<-ctx.Done()
+		for _, w := range watches {
+			w.Stop()
+		}
+	}()
+	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: controllerAgentName})
+}
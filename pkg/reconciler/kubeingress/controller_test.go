@@ -0,0 +1,90 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeingress
+
+import (
+	"context"
+	"testing"
+
+	_ "knative.dev/networking/pkg/client/injection/informers/networking/v1alpha1/ingress/fake"
+	_ "knative.dev/pkg/client/injection/kube/informers/core/v1/service/fake"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	network "knative.dev/networking/pkg"
+	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/system"
+
+	. "knative.dev/pkg/reconciler/testing"
+)
+
+func TestNew(t *testing.T) {
+	ctx, cancel, _ := SetupFakeContextWithCancel(t)
+	defer cancel()
+
+	c := NewController(ctx, watcher())
+	if c == nil {
+		t.Fatal("Expected NewController to return a non-nil value")
+	}
+	if !listedIngresses(ctx) {
+		t.Error("NewController() didn't list the Kubernetes Ingresses")
+	}
+}
+
+func TestNewDisabled(t *testing.T) {
+	ctx, cancel, _ := SetupFakeContextWithCancel(t)
+	defer cancel()
+
+	c := NewControllerWithOptions(ctx, watcher(), Options{})
+	if c == nil {
+		t.Fatal("Expected NewControllerWithOptions to return a non-nil value")
+	}
+	if listedIngresses(ctx) {
+		t.Error("NewControllerWithOptions() listed the Kubernetes Ingresses while disabled")
+	}
+}
+
+func watcher() configmap.Watcher {
+	return configmap.NewStaticWatcher(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      config.ContourConfigName,
+		},
+	}, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      network.ConfigName,
+		},
+	}, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      config.FeaturesConfigName,
+		},
+	})
+}
+
+// listedIngresses returns whether the Kubernetes Ingresses were listed.
+func listedIngresses(ctx context.Context) bool {
+	for _, action := range fakekubeclient.Get(ctx).Actions() {
+		if action.GetVerb() == "list" && action.GetResource().Resource == "ingresses" {
+			return true
+		}
+	}
+	return false
+}
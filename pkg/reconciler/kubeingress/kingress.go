@@ -0,0 +1,194 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeingress
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"knative.dev/net-contour/pkg/ownership"
+	"knative.dev/net-contour/pkg/reconciler/contour"
+	"knative.dev/networking/pkg/apis/networking"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/pkg/kmeta"
+)
+
+const (
+	// kubeIngressClassAnnotation is the legacy annotation selecting the
+	// class of a Kubernetes Ingress.
+	kubeIngressClassAnnotation = "kubernetes.io/ingress.class"
+
+	// ParentKey is the label on Knative Ingresses with the name of the
+	// Kubernetes Ingress they were translated from.
+	ParentKey = "contour.networking.knative.dev/kubernetes-ingress"
+)
+
+// hasClass returns whether the Kubernetes Ingress is of the given class.
+func hasClass(ing *networkingv1.Ingress, class string) bool {
+	if class == "" {
+		return false
+	}
+	if ing.Spec.IngressClassName != nil {
+		return *ing.Spec.IngressClassName == class
+	}
+	return ing.Annotations[kubeIngressClassAnnotation] == class
+}
+
+// kingressName returns the name of the Knative Ingress translated from ing.
+func kingressName(ing *networkingv1.Ingress) string {
+	return kmeta.ChildName(ing.Name, "-kubernetes")
+}
+
+// makeKIngress translates a Kubernetes Ingress into a Knative Ingress of our
+// class.  Rules without a host, Exact paths and the default backend have no
+// equivalent and are dropped, and the other paths match as prefixes.  It
+// returns nil when nothing is left to translate.
+func makeKIngress(ing *networkingv1.Ingress, serviceLister corev1listers.ServiceLister) (*v1alpha1.Ingress, error) {
+	kingress := &v1alpha1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      kingressName(ing),
+			Namespace: ing.Namespace,
+			Labels: map[string]string{
+				ParentKey:              ing.Name,
+				ownership.ManagedByKey: ownership.ManagedByValue,
+			},
+			Annotations: map[string]string{
+				networking.IngressClassAnnotationKey: contour.ContourIngressClassName,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(ing, networkingv1.SchemeGroupVersion.WithKind("Ingress")),
+			},
+		},
+		Spec: v1alpha1.IngressSpec{
+			HTTPOption: v1alpha1.HTTPOptionEnabled,
+		},
+	}
+
+	for _, tls := range ing.Spec.TLS {
+		kingress.Spec.TLS = append(kingress.Spec.TLS, v1alpha1.IngressTLS{
+			Hosts:           tls.Hosts,
+			SecretName:      tls.SecretName,
+			SecretNamespace: ing.Namespace,
+		})
+	}
+
+	for _, rule := range ing.Spec.Rules {
+		if rule.Host == "" || rule.HTTP == nil {
+			continue
+		}
+		paths := make([]v1alpha1.HTTPIngressPath, 0, len(rule.HTTP.Paths))
+		for _, path := range rule.HTTP.Paths {
+			if path.Backend.Service == nil || isExact(path) {
+				continue
+			}
+			port, err := servicePort(ing.Namespace, path.Backend.Service, serviceLister)
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, v1alpha1.HTTPIngressPath{
+				Path: path.Path,
+				Splits: []v1alpha1.IngressBackendSplit{{
+					IngressBackend: v1alpha1.IngressBackend{
+						ServiceName:      path.Backend.Service.Name,
+						ServiceNamespace: ing.Namespace,
+						ServicePort:      port,
+					},
+					Percent: 100,
+				}},
+			})
+		}
+		if len(paths) == 0 {
+			continue
+		}
+		kingress.Spec.Rules = append(kingress.Spec.Rules, v1alpha1.IngressRule{
+			Hosts:      []string{rule.Host},
+			Visibility: v1alpha1.IngressVisibilityExternalIP,
+			HTTP: &v1alpha1.HTTPIngressRuleValue{
+				Paths: paths,
+			},
+		})
+	}
+	if len(kingress.Spec.Rules) == 0 {
+		return nil, nil
+	}
+	return kingress, nil
+}
+
+// isExact returns whether the path only matches exactly, which Knative
+// Ingresses can't express.
+func isExact(path networkingv1.HTTPIngressPath) bool {
+	return path.PathType != nil && *path.PathType == networkingv1.PathTypeExact
+}
+
+// exactPaths returns the Exact paths of the Kubernetes Ingress, which
+// makeKIngress drops.
+func exactPaths(ing *networkingv1.Ingress) []string {
+	var paths []string
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			if isExact(path) {
+				paths = append(paths, rule.Host+path.Path)
+			}
+		}
+	}
+	return paths
+}
+
+// servicePort resolves the number of the backend's Service port, since
+// Contour only routes to ports by number.
+func servicePort(namespace string, backend *networkingv1.IngressServiceBackend, serviceLister corev1listers.ServiceLister) (intstr.IntOrString, error) {
+	if backend.Port.Name == "" {
+		return intstr.FromInt(int(backend.Port.Number)), nil
+	}
+	svc, err := serviceLister.Services(namespace).Get(backend.Name)
+	if err != nil {
+		return intstr.IntOrString{}, fmt.Errorf("failed to get Service %s/%s: %w", namespace, backend.Name, err)
+	}
+	for _, port := range svc.Spec.Ports {
+		if port.Name == backend.Port.Name {
+			return intstr.FromInt(int(port.Port)), nil
+		}
+	}
+	return intstr.IntOrString{}, fmt.Errorf("no port named %q in Service %s/%s", backend.Port.Name, namespace, backend.Name)
+}
+
+// loadBalancerStatus translates the public load balancer of a Knative
+// Ingress into the status of a Kubernetes Ingress.
+func loadBalancerStatus(kingress *v1alpha1.Ingress) corev1.LoadBalancerStatus {
+	var status corev1.LoadBalancerStatus
+	if kingress.Status.PublicLoadBalancer == nil {
+		return status
+	}
+	for _, lb := range kingress.Status.PublicLoadBalancer.Ingress {
+		switch {
+		case lb.IP != "":
+			status.Ingress = append(status.Ingress, corev1.LoadBalancerIngress{IP: lb.IP})
+		case lb.Domain != "":
+			status.Ingress = append(status.Ingress, corev1.LoadBalancerIngress{Hostname: lb.Domain})
+		case lb.DomainInternal != "":
+			status.Ingress = append(status.Ingress, corev1.LoadBalancerIngress{Hostname: lb.DomainInternal})
+		}
+	}
+	return status
+}
@@ -0,0 +1,253 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeingress
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+
+	. "knative.dev/net-contour/pkg/reconciler/testing"
+)
+
+func TestHasClass(t *testing.T) {
+	class := "contour-knative"
+	other := "nginx"
+
+	tests := []struct {
+		name  string
+		ing   *networkingv1.Ingress
+		class string
+		want  bool
+	}{{
+		name:  "ingressClassName",
+		ing:   &networkingv1.Ingress{Spec: networkingv1.IngressSpec{IngressClassName: &class}},
+		class: class,
+		want:  true,
+	}, {
+		name:  "other ingressClassName",
+		ing:   &networkingv1.Ingress{Spec: networkingv1.IngressSpec{IngressClassName: &other}},
+		class: class,
+	}, {
+		name: "annotation",
+		ing: &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{kubeIngressClassAnnotation: class},
+		}},
+		class: class,
+		want:  true,
+	}, {
+		name: "ingressClassName takes precedence",
+		ing: &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{kubeIngressClassAnnotation: class},
+			},
+			Spec: networkingv1.IngressSpec{IngressClassName: &other},
+		},
+		class: class,
+	}, {
+		name: "translation disabled",
+		ing:  &networkingv1.Ingress{Spec: networkingv1.IngressSpec{IngressClassName: &class}},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := hasClass(test.ing, test.class); got != test.want {
+				t.Errorf("hasClass() = %v, wanted %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMakeKIngress(t *testing.T) {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "named"},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Name: "http", Port: 8080}},
+		},
+	}
+	tl := NewListers([]runtime.Object{svc})
+
+	ing := kubeIngress("name", "ns", func(i *networkingv1.Ingress) {
+		i.Spec.TLS = []networkingv1.IngressTLS{{
+			Hosts:      []string{"example.com"},
+			SecretName: "cert",
+		}}
+		i.Spec.Rules = append(i.Spec.Rules, networkingv1.IngressRule{
+			// Hostless rules are dropped.
+			IngressRuleValue: networkingv1.IngressRuleValue{
+				HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{backendPath("/", "goo", networkingv1.ServiceBackendPort{Number: 80})},
+				},
+			},
+		}, networkingv1.IngressRule{
+			Host: "named.example.com",
+			IngressRuleValue: networkingv1.IngressRuleValue{
+				HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{backendPath("/api", "named", networkingv1.ServiceBackendPort{Name: "http"})},
+				},
+			},
+		})
+	})
+
+	got, err := makeKIngress(ing, tl.GetK8sServiceLister())
+	if err != nil {
+		t.Fatal("makeKIngress() =", err)
+	}
+
+	want := v1alpha1.IngressSpec{
+		HTTPOption: v1alpha1.HTTPOptionEnabled,
+		TLS: []v1alpha1.IngressTLS{{
+			Hosts:           []string{"example.com"},
+			SecretName:      "cert",
+			SecretNamespace: "ns",
+		}},
+		Rules: []v1alpha1.IngressRule{{
+			Hosts:      []string{"example.com"},
+			Visibility: v1alpha1.IngressVisibilityExternalIP,
+			HTTP: &v1alpha1.HTTPIngressRuleValue{
+				Paths: []v1alpha1.HTTPIngressPath{{
+					Path: "/",
+					Splits: []v1alpha1.IngressBackendSplit{{
+						IngressBackend: v1alpha1.IngressBackend{
+							ServiceName:      "goo",
+							ServiceNamespace: "ns",
+							ServicePort:      intstr.FromInt(80),
+						},
+						Percent: 100,
+					}},
+				}},
+			},
+		}, {
+			Hosts:      []string{"named.example.com"},
+			Visibility: v1alpha1.IngressVisibilityExternalIP,
+			HTTP: &v1alpha1.HTTPIngressRuleValue{
+				Paths: []v1alpha1.HTTPIngressPath{{
+					Path: "/api",
+					Splits: []v1alpha1.IngressBackendSplit{{
+						IngressBackend: v1alpha1.IngressBackend{
+							ServiceName:      "named",
+							ServiceNamespace: "ns",
+							ServicePort:      intstr.FromInt(8080),
+						},
+						Percent: 100,
+					}},
+				}},
+			},
+		}},
+	}
+	if !cmp.Equal(want, got.Spec) {
+		t.Error("makeKIngress (-want, +got) =", cmp.Diff(want, got.Spec))
+	}
+	if !metav1.IsControlledBy(got, ing) {
+		t.Error("makeKIngress() is not controlled by the Kubernetes Ingress")
+	}
+
+	// Exact paths are dropped, and so are the rules they leave empty.
+	exact := networkingv1.PathTypeExact
+	ing.Spec.Rules[2].HTTP.Paths[0].PathType = &exact
+	if got, err := makeKIngress(ing, tl.GetK8sServiceLister()); err != nil {
+		t.Fatal("makeKIngress() =", err)
+	} else if len(got.Spec.Rules) != 1 {
+		t.Errorf("makeKIngress() has %d rules, wanted 1 without the Exact path", len(got.Spec.Rules))
+	}
+	ing.Spec.Rules[0].HTTP.Paths[0].PathType = &exact
+	if got, err := makeKIngress(ing, tl.GetK8sServiceLister()); err != nil {
+		t.Fatal("makeKIngress() =", err)
+	} else if got != nil {
+		t.Errorf("makeKIngress() = %v, wanted nil without paths left", got)
+	}
+	if got, want := exactPaths(ing), []string{"example.com/", "named.example.com/api"}; !cmp.Equal(got, want) {
+		t.Error("exactPaths (-want, +got) =", cmp.Diff(want, got))
+	}
+
+	// Named ports that don't exist can't be translated.
+	ing.Spec.Rules[0].HTTP.Paths[0].PathType = nil
+	ing.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Port = networkingv1.ServiceBackendPort{Name: "grpc"}
+	if _, err := makeKIngress(ing, tl.GetK8sServiceLister()); err == nil {
+		t.Error("makeKIngress() succeeded with an unknown port name")
+	}
+}
+
+func TestLoadBalancerStatus(t *testing.T) {
+	kingress := &v1alpha1.Ingress{}
+	kingress.Status.MarkLoadBalancerReady([]v1alpha1.LoadBalancerIngressStatus{{
+		IP: "1.2.3.4",
+	}, {
+		Domain: "lb.example.com",
+	}, {
+		DomainInternal: "envoy.contour-external.svc.cluster.local",
+	}}, nil)
+
+	want := corev1.LoadBalancerStatus{
+		Ingress: []corev1.LoadBalancerIngress{{
+			IP: "1.2.3.4",
+		}, {
+			Hostname: "lb.example.com",
+		}, {
+			Hostname: "envoy.contour-external.svc.cluster.local",
+		}},
+	}
+	if got := loadBalancerStatus(kingress); !cmp.Equal(want, got) {
+		t.Error("loadBalancerStatus (-want, +got) =", cmp.Diff(want, got))
+	}
+}
+
+func backendPath(path, service string, port networkingv1.ServiceBackendPort) networkingv1.HTTPIngressPath {
+	pathType := networkingv1.PathTypePrefix
+	return networkingv1.HTTPIngressPath{
+		Path:     path,
+		PathType: &pathType,
+		Backend: networkingv1.IngressBackend{
+			Service: &networkingv1.IngressServiceBackend{
+				Name: service,
+				Port: port,
+			},
+		},
+	}
+}
+
+func kubeIngress(name, namespace string, opts ...func(*networkingv1.Ingress)) *networkingv1.Ingress {
+	class := "contour-knative"
+	i := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			UID:       "8675309",
+		},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: &class,
+			Rules: []networkingv1.IngressRule{{
+				Host: "example.com",
+				IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{backendPath("/", "goo", networkingv1.ServiceBackendPort{Number: 80})},
+					},
+				},
+			}},
+		},
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
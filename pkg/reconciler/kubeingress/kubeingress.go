@@ -0,0 +1,148 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeingress
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	networkingv1listers "k8s.io/client-go/listers/networking/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	ingressclientset "knative.dev/networking/pkg/client/clientset/versioned"
+	networkingv1alpha1 "knative.dev/networking/pkg/client/listers/networking/v1alpha1"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/reconciler"
+)
+
+// Reconciler translates Kubernetes Ingresses of the configured class into
+// Knative Ingresses reconciled by net-contour, and reflects their readiness.
+type Reconciler struct {
+	// LeaderAwareFuncs is inlined to help us implement reconciler.LeaderAware.
+	reconciler.LeaderAwareFuncs
+
+	kubeClient    kubernetes.Interface
+	ingressClient ingressclientset.Interface
+
+	kubeIngressLister networkingv1listers.IngressLister
+	ingressLister     networkingv1alpha1.IngressLister
+	serviceLister     corev1listers.ServiceLister
+
+	// recorder reports what we can't translate on the Kubernetes Ingresses.
+	recorder record.EventRecorder
+
+	configStore reconciler.ConfigStore
+}
+
+var (
+	_ controller.Reconciler  = (*Reconciler)(nil)
+	_ reconciler.LeaderAware = (*Reconciler)(nil)
+)
+
+// Reconcile implements controller.Reconciler
+func (r *Reconciler) Reconcile(ctx context.Context, key string) error {
+	ctx = r.configStore.ToContext(ctx)
+	logger := logging.FromContext(ctx)
+
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		logger.Errorf("Invalid resource key %s", key)
+		return nil
+	}
+	if !r.IsLeaderFor(types.NamespacedName{Namespace: namespace, Name: name}) {
+		return nil
+	}
+	ing, err := r.kubeIngressLister.Ingresses(namespace).Get(name)
+	if apierrs.IsNotFound(err) {
+		// Our Knative Ingress is garbage collected with its owner.
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	kingress, err := r.ingressLister.Ingresses(namespace).Get(kingressName(ing))
+	if err != nil && !apierrs.IsNotFound(err) {
+		return err
+	}
+
+	if !hasClass(ing, config.FromContext(ctx).Contour.KubernetesIngressClass) {
+		if kingress != nil && metav1.IsControlledBy(kingress, ing) {
+			logger.Info("Ingress is no longer of our class, deleting its Knative Ingress.")
+			return r.ingressClient.NetworkingV1alpha1().Ingresses(namespace).Delete(ctx, kingress.Name, metav1.DeleteOptions{})
+		}
+		return nil
+	}
+
+	if exact := exactPaths(ing); len(exact) != 0 {
+		r.recorder.Eventf(ing, corev1.EventTypeWarning, "ExactPathsIgnored",
+			"Knative Ingresses only match path prefixes, so the Exact paths %s are not translated.", strings.Join(exact, ", "))
+	}
+	desired, err := makeKIngress(ing, r.serviceLister)
+	if err != nil {
+		return err
+	}
+	if desired == nil {
+		if kingress != nil && metav1.IsControlledBy(kingress, ing) {
+			logger.Info("Ingress has nothing left to translate, deleting its Knative Ingress.")
+			return r.ingressClient.NetworkingV1alpha1().Ingresses(namespace).Delete(ctx, kingress.Name, metav1.DeleteOptions{})
+		}
+		return nil
+	}
+
+	if kingress == nil {
+		if kingress, err = r.ingressClient.NetworkingV1alpha1().Ingresses(namespace).Create(ctx, desired, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+		logger.Infof("Created Knative Ingress %s.", kingress.Name)
+	} else if !metav1.IsControlledBy(kingress, ing) {
+		logger.Warnf("Knative Ingress %s is not owned by this Ingress.", kingress.Name)
+		return nil
+	} else if !equality.Semantic.DeepEqual(kingress.Spec, desired.Spec) ||
+		!equality.Semantic.DeepEqual(kingress.Labels, desired.Labels) ||
+		!equality.Semantic.DeepEqual(kingress.Annotations, desired.Annotations) {
+		update := kingress.DeepCopy()
+		update.Spec = desired.Spec
+		update.Labels = desired.Labels
+		update.Annotations = desired.Annotations
+		if kingress, err = r.ingressClient.NetworkingV1alpha1().Ingresses(namespace).Update(ctx, update, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+		logger.Infof("Updated Knative Ingress %s.", kingress.Name)
+	}
+
+	if !kingress.IsReady() {
+		// We keep reporting the addresses of the last ready programming.
+		return nil
+	}
+	lbStatus := loadBalancerStatus(kingress)
+	if equality.Semantic.DeepEqual(ing.Status.LoadBalancer, lbStatus) {
+		return nil
+	}
+	update := ing.DeepCopy()
+	update.Status.LoadBalancer = lbStatus
+	_, err = r.kubeClient.NetworkingV1().Ingresses(namespace).UpdateStatus(ctx, update, metav1.UpdateOptions{})
+	return err
+}
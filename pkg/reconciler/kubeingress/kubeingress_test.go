@@ -0,0 +1,215 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeingress
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgotesting "k8s.io/client-go/testing"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	fakeingressclient "knative.dev/networking/pkg/client/injection/client/fake"
+	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/controller"
+
+	. "knative.dev/net-contour/pkg/reconciler/testing"
+	. "knative.dev/pkg/reconciler/testing"
+)
+
+func TestReconcile(t *testing.T) {
+	table := TableTest{{
+		Name: "bad workqueue key",
+		Key:  "too/many/parts",
+	}, {
+		Name: "key not found",
+		Key:  "foo/not-found",
+	}, {
+		Name: "first reconcile",
+		Key:  "ns/name",
+		Objects: []runtime.Object{
+			kubeIngress("name", "ns"),
+		},
+		WantCreates: []runtime.Object{
+			mustMakeKIngress(t, kubeIngress("name", "ns")),
+		},
+	}, {
+		Name: "other class",
+		Key:  "ns/name",
+		Objects: []runtime.Object{
+			kubeIngress("name", "ns", withClass("nginx")),
+		},
+	}, {
+		Name: "class changed",
+		Key:  "ns/name",
+		Objects: []runtime.Object{
+			kubeIngress("name", "ns", withClass("nginx")),
+			mustMakeKIngress(t, kubeIngress("name", "ns")),
+		},
+		WantDeletes: []clientgotesting.DeleteActionImpl{{
+			ActionImpl: clientgotesting.ActionImpl{
+				Namespace: "ns",
+				Resource:  v1alpha1.SchemeGroupVersion.WithResource("ingresses"),
+			},
+			Name: "name-kubernetes",
+		}},
+	}, {
+		Name: "rules changed",
+		Key:  "ns/name",
+		Objects: []runtime.Object{
+			kubeIngress("name", "ns", withHost("changed.example.com")),
+			mustMakeKIngress(t, kubeIngress("name", "ns")),
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: mustMakeKIngress(t, kubeIngress("name", "ns", withHost("changed.example.com"))),
+		}},
+	}, {
+		Name: "exact paths are not translated",
+		Key:  "ns/name",
+		Objects: []runtime.Object{
+			kubeIngress("name", "ns", withExactPath("/exact")),
+		},
+		WantCreates: []runtime.Object{
+			mustMakeKIngress(t, kubeIngress("name", "ns")),
+		},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeWarning, "ExactPathsIgnored",
+				"Knative Ingresses only match path prefixes, so the Exact paths example.com/exact are not translated."),
+		},
+	}, {
+		Name: "nothing to translate",
+		Key:  "ns/name",
+		Objects: []runtime.Object{
+			kubeIngress("name", "ns", func(i *networkingv1.Ingress) {
+				i.Spec.Rules[0].Host = ""
+			}),
+		},
+	}, {
+		Name: "nothing left to translate",
+		Key:  "ns/name",
+		Objects: []runtime.Object{
+			kubeIngress("name", "ns", func(i *networkingv1.Ingress) {
+				exact := networkingv1.PathTypeExact
+				i.Spec.Rules[0].HTTP.Paths[0].PathType = &exact
+			}),
+			mustMakeKIngress(t, kubeIngress("name", "ns")),
+		},
+		WantDeletes: []clientgotesting.DeleteActionImpl{{
+			ActionImpl: clientgotesting.ActionImpl{
+				Namespace: "ns",
+				Resource:  v1alpha1.SchemeGroupVersion.WithResource("ingresses"),
+			},
+			Name: "name-kubernetes",
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeWarning, "ExactPathsIgnored",
+				"Knative Ingresses only match path prefixes, so the Exact paths example.com/ are not translated."),
+		},
+	}, {
+		Name: "knative ingress ready",
+		Key:  "ns/name",
+		Objects: []runtime.Object{
+			kubeIngress("name", "ns"),
+			mustMakeKIngress(t, kubeIngress("name", "ns"), makeItReady),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: kubeIngress("name", "ns", func(i *networkingv1.Ingress) {
+				i.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{
+					Hostname: "envoy.contour-external.svc.cluster.local",
+				}}
+			}),
+		}},
+	}, {
+		Name: "knative ingress ready (steady state)",
+		Key:  "ns/name",
+		Objects: []runtime.Object{
+			kubeIngress("name", "ns", func(i *networkingv1.Ingress) {
+				i.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{
+					Hostname: "envoy.contour-external.svc.cluster.local",
+				}}
+			}),
+			mustMakeKIngress(t, kubeIngress("name", "ns"), makeItReady),
+		},
+	}}
+
+	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
+		return &Reconciler{
+			kubeClient:        fakekubeclient.Get(ctx),
+			ingressClient:     fakeingressclient.Get(ctx),
+			kubeIngressLister: listers.GetKubeIngressLister(),
+			ingressLister:     listers.GetIngressLister(),
+			serviceLister:     listers.GetK8sServiceLister(),
+			recorder:          controller.GetEventRecorder(ctx),
+			configStore: &testConfigStore{config: &config.Config{
+				Contour: &config.Contour{KubernetesIngressClass: "contour-knative"},
+			}},
+		}
+	}))
+}
+
+func mustMakeKIngress(t *testing.T, ing *networkingv1.Ingress, opts ...func(*v1alpha1.Ingress)) *v1alpha1.Ingress {
+	t.Helper()
+	kingress, err := makeKIngress(ing, nil)
+	if err != nil {
+		t.Fatal("makeKIngress() =", err)
+	}
+	for _, opt := range opts {
+		opt(kingress)
+	}
+	return kingress
+}
+
+func makeItReady(i *v1alpha1.Ingress) {
+	i.Status.InitializeConditions()
+	i.Status.MarkNetworkConfigured()
+	i.Status.MarkLoadBalancerReady([]v1alpha1.LoadBalancerIngressStatus{{
+		DomainInternal: "envoy.contour-external.svc.cluster.local",
+	}}, nil)
+}
+
+func withClass(class string) func(*networkingv1.Ingress) {
+	return func(i *networkingv1.Ingress) {
+		i.Spec.IngressClassName = &class
+	}
+}
+
+func withHost(host string) func(*networkingv1.Ingress) {
+	return func(i *networkingv1.Ingress) {
+		i.Spec.Rules[0].Host = host
+	}
+}
+
+func withExactPath(path string) func(*networkingv1.Ingress) {
+	return func(i *networkingv1.Ingress) {
+		exact := networkingv1.PathTypeExact
+		p := backendPath(path, "goo", networkingv1.ServiceBackendPort{Number: 80})
+		p.PathType = &exact
+		i.Spec.Rules[0].HTTP.Paths = append(i.Spec.Rules[0].HTTP.Paths, p)
+	}
+}
+
+type testConfigStore struct {
+	config *config.Config
+}
+
+func (t *testConfigStore) ToContext(ctx context.Context) context.Context {
+	return config.ToContext(ctx, t.config)
+}
@@ -19,9 +19,11 @@ package contour
 import (
 	contour "github.com/projectcontour/contour/apis/projectcontour/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
 	corev1listers "k8s.io/client-go/listers/core/v1"
+	networkingv1listers "k8s.io/client-go/listers/networking/v1"
 	"k8s.io/client-go/tools/cache"
 	fakecontourclientset "knative.dev/net-contour/pkg/client/clientset/versioned/fake"
 	contourlisters "knative.dev/net-contour/pkg/client/listers/projectcontour/v1"
@@ -93,6 +95,11 @@ func (l *Listers) GetHTTPProxyLister() contourlisters.HTTPProxyLister {
 	return contourlisters.NewHTTPProxyLister(l.IndexerFor(&contour.HTTPProxy{}))
 }
 
+// GetTLSCertificateDelegationLister get lister for TLSCertificateDelegation resource.
+func (l *Listers) GetTLSCertificateDelegationLister() contourlisters.TLSCertificateDelegationLister {
+	return contourlisters.NewTLSCertificateDelegationLister(l.IndexerFor(&contour.TLSCertificateDelegation{}))
+}
+
 // GetK8sServiceLister get lister for K8s Service resource.
 func (l *Listers) GetK8sServiceLister() corev1listers.ServiceLister {
 	return corev1listers.NewServiceLister(l.IndexerFor(&corev1.Service{}))
@@ -102,3 +109,23 @@ func (l *Listers) GetK8sServiceLister() corev1listers.ServiceLister {
 func (l *Listers) GetEndpointsLister() corev1listers.EndpointsLister {
 	return corev1listers.NewEndpointsLister(l.IndexerFor(&corev1.Endpoints{}))
 }
+
+// GetPodsLister get lister for K8s Pod resource.
+func (l *Listers) GetPodsLister() corev1listers.PodLister {
+	return corev1listers.NewPodLister(l.IndexerFor(&corev1.Pod{}))
+}
+
+// GetKubeIngressLister get lister for K8s Ingress resource.
+func (l *Listers) GetKubeIngressLister() networkingv1listers.IngressLister {
+	return networkingv1listers.NewIngressLister(l.IndexerFor(&networkingv1.Ingress{}))
+}
+
+// GetNetworkPolicyLister get lister for K8s NetworkPolicy resource.
+func (l *Listers) GetNetworkPolicyLister() networkingv1listers.NetworkPolicyLister {
+	return networkingv1listers.NewNetworkPolicyLister(l.IndexerFor(&networkingv1.NetworkPolicy{}))
+}
+
+// GetNamespaceLister get lister for K8s Namespace resource.
+func (l *Listers) GetNamespaceLister() corev1listers.NamespaceLister {
+	return corev1listers.NewNamespaceLister(l.IndexerFor(&corev1.Namespace{}))
+}
@@ -0,0 +1,97 @@
+// +build e2e
+
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conformance
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"knative.dev/networking/pkg/apis/networking"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/networking/test"
+	"knative.dev/networking/test/conformance/ingress"
+)
+
+// TestAuthorityWithPort verifies that requests whose authority carries an
+// explicit port (e.g. "Host: example.com:443") reach the virtual host of
+// the bare host name.  We don't generate anything for this: Contour
+// configures its HTTP connection managers to strip any port from the
+// authority before routing, so backends see the bare host name too.
+func TestAuthorityWithPort(t *testing.T) {
+	t.Parallel()
+	ctx, clients := context.Background(), test.Setup(t)
+
+	name, port, _ := ingress.CreateRuntimeService(ctx, t, clients, networking.ServicePortNameHTTP1)
+
+	host := name + ".example.com"
+	hosts := []string{host}
+
+	secretName, tlsConfig, _ := ingress.CreateTLSSecret(ctx, t, clients, hosts)
+
+	_, client, _ := ingress.CreateIngressReadyWithTLS(ctx, t, clients, v1alpha1.IngressSpec{
+		Rules: []v1alpha1.IngressRule{{
+			Hosts:      hosts,
+			Visibility: v1alpha1.IngressVisibilityExternalIP,
+			HTTP: &v1alpha1.HTTPIngressRuleValue{
+				Paths: []v1alpha1.HTTPIngressPath{{
+					Splits: []v1alpha1.IngressBackendSplit{{
+						IngressBackend: v1alpha1.IngressBackend{
+							ServiceName:      name,
+							ServiceNamespace: test.ServingNamespace,
+							ServicePort:      intstr.FromInt(port),
+						},
+					}},
+				}},
+			},
+		}},
+		TLS: []v1alpha1.IngressTLS{{
+			Hosts:           hosts,
+			SecretName:      secretName,
+			SecretNamespace: test.ServingNamespace,
+		}},
+	}, tlsConfig)
+
+	for _, tc := range []struct {
+		url       string
+		authority string
+	}{{
+		url:       "http://" + host,
+		authority: host + ":80",
+	}, {
+		url:       "https://" + host,
+		authority: host + ":443",
+	}, {
+		// A port the listener doesn't serve on is stripped as well.
+		url:       "http://" + host,
+		authority: host + ":8080",
+	}} {
+		authority := tc.authority
+		ri := ingress.RuntimeRequest(ctx, t, client, tc.url, func(r *http.Request) {
+			r.Host = authority
+		})
+		if ri == nil {
+			continue
+		}
+		if got := ri.Request.Host; got != host {
+			t.Errorf("Host = %q, wanted %q", got, host)
+		}
+	}
+}